@@ -4,17 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"os"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/agent"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/scale"
+	"github.com/stretchr/objx"
 
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"k8s.io/apimachinery/pkg/types"
@@ -37,7 +43,20 @@ const (
 )
 
 const (
-	defaultPasswordKey = "password"
+	// ConditionDegraded is true if the operator observed a problem with the members of the replica set
+	// which doesn't show in the readiness of their pods, e.g. members on different replica set configs.
+	ConditionDegraded = "Degraded"
+)
+
+const (
+	// ClusterDNSNameEnv holds the DNS domain of the cluster, if it is not "cluster.local".
+	ClusterDNSNameEnv = "CLUSTER_DNS_NAME"
+)
+
+const (
+	defaultPasswordKey         = "password"
+	defaultUserDatabase        = "admin"
+	defaultMongodConfigFileKey = "mongod.conf"
 )
 
 // SCRAM-SHA-256 and SCRAM-SHA-1 are the supported auth modes.
@@ -47,7 +66,8 @@ const (
 
 // MongoDBCommunitySpec defines the desired state of MongoDB
 type MongoDBCommunitySpec struct {
-	// Members is the number of members in the replica set
+	// Members is the number of members in the replica set. A replica set can have at most 7
+	// voting members, the members after the 7th are added as non-voting members with priority 0
 	// +optional
 	Members int `json:"members"`
 	// Type defines which type of MongoDB deployment the resource should create
@@ -65,6 +85,17 @@ type MongoDBCommunitySpec struct {
 	// +optional
 	FeatureCompatibilityVersion string `json:"featureCompatibilityVersion,omitempty"`
 
+	// ProtocolVersion configures the replica set protocol version, defaults to "1".
+	// Protocol version "0" is not supported by MongoDB 4.0 and later.
+	// +optional
+	ProtocolVersion string `json:"protocolVersion,omitempty"`
+
+	// WriteConcernMajorityJournalDefault configures whether w:majority writes wait until they have been written
+	// to the journal of a majority of the members, it defaults to true, the default of mongod. It should be
+	// false if the members don't journal, e.g. with the inMemory storage engine.
+	// +optional
+	WriteConcernMajorityJournalDefault *bool `json:"writeConcernMajorityJournalDefault,omitempty"`
+
 	// ReplicaSetHorizons Add this parameter and values if you need your database
 	// to be accessed outside of Kubernetes. This setting allows you to
 	// provide different DNS settings within the Kubernetes cluster and
@@ -74,6 +105,23 @@ type MongoDBCommunitySpec struct {
 	// +optional
 	ReplicaSetHorizons ReplicaSetHorizonConfiguration `json:"replicaSetHorizons,omitempty"`
 
+	// MemberAddressing configures the addresses of the members in the replica set configuration, e.g. for
+	// networks in which the hostnames of the pods can't be resolved from the other pods.
+	// +optional
+	MemberAddressing MemberAddressingConfiguration `json:"memberAddressing,omitempty"`
+
+	// MemberConfig overrides the replica set settings of the data-bearing members, the n-th entry
+	// applies to the n-th member. Entries without a corresponding member are ignored.
+	// +optional
+	MemberConfig []MemberConfiguration `json:"memberConfig,omitempty"`
+
+	// MembersToRemove are the names of the members, e.g. <name>-1, which are removed from the replica set
+	// although their pods are not the last ones of the StatefulSet. Their processes are disabled and the
+	// members are removed one at a time, their pods keep running until the StatefulSet is scaled down.
+	// Scaling down is held back until the listed members have been removed.
+	// +optional
+	MembersToRemove []string `json:"membersToRemove,omitempty"`
+
 	// Security configures security features, such as TLS, and authentication settings for a deployment
 	// +required
 	Security Security `json:"security"`
@@ -85,6 +133,13 @@ type MongoDBCommunitySpec struct {
 	// +optional
 	StatefulSetConfiguration StatefulSetConfiguration `json:"statefulSet,omitempty"`
 
+	// ArbiterStatefulSetConfiguration deploys the arbiters in their own StatefulSet, without persistent
+	// storage, which can be scheduled and sized independently of the data-bearing members through this
+	// StatefulSet override. When set, Arbiters is the number of pods of this StatefulSet and the arbiters
+	// are not counted in Members. The arbiters are addressed through their own headless Service, <name>-arb-svc.
+	// +optional
+	ArbiterStatefulSetConfiguration *StatefulSetConfiguration `json:"arbiterStatefulSet,omitempty"`
+
 	// AdditionalMongodConfig is additional configuration that can be passed to
 	// each data-bearing mongod at runtime. Uses the same structure as the mongod
 	// configuration file: https://docs.mongodb.com/manual/reference/configuration-options/
@@ -93,6 +148,466 @@ type MongoDBCommunitySpec struct {
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +nullable
 	AdditionalMongodConfig MongodConfiguration `json:"additionalMongodConfig,omitempty"`
+
+	// AdditionalMongodConfigMap is a reference to a ConfigMap containing a mongod configuration
+	// file in YAML format. The ConfigMap is mounted into the mongod container and the settings in
+	// the file are merged into the configuration of each data-bearing mongod.
+	// Settings managed by the operator and settings in AdditionalMongodConfig take precedence
+	// over the settings defined in this file.
+	// +optional
+	AdditionalMongodConfigMap *ConfigMapKeyReference `json:"additionalMongodConfigMap,omitempty"`
+
+	// AdditionalMongodArgs is a list of command line arguments which are appended to the mongod
+	// command of each mongod, e.g. for debugging. The arguments are passed to mongod
+	// as they are and are never interpreted by the shell. Settings which can be configured through
+	// AdditionalMongodConfig should be configured there instead.
+	// +optional
+	AdditionalMongodArgs []string `json:"additionalMongodArgs,omitempty"`
+
+	// MongodCommand replaces the command and the arguments of the mongod container, e.g. to run mongod
+	// through a wrapper. The command replaces the version upgrade post-hook and waiting for the configuration
+	// written by the agent, it has to run "/hooks/version-upgrade" before starting mongod to keep version
+	// upgrades safe. It cannot be combined with AdditionalMongodArgs.
+	// +optional
+	MongodCommand *CommandConfiguration `json:"mongodCommand,omitempty"`
+
+	// DisableVersionUpgradeHook skips the version upgrade post-hook which is run before mongod is started.
+	// Disabling the hook breaks safe version upgrades of the deployment, it should only be used
+	// for advanced or debugging use cases where a plain mongod needs to be run.
+	// +optional
+	DisableVersionUpgradeHook bool `json:"disableVersionUpgradeHook,omitempty"`
+
+	// MongodStartupProbe configures the startup probe of the mongod container, which gives mongod time to
+	// start, e.g. to recover a large data set, before its readiness and liveness are probed. By default
+	// mongod is checked every 10 seconds and restarted after 180 consecutive failures, i.e. after 30 minutes.
+	// +optional
+	MongodStartupProbe *ProbeConfiguration `json:"mongodStartupProbe,omitempty"`
+
+	// EnableSecondaryService creates a Service named "<name>-secondary" which only routes to the
+	// secondary members, e.g. to scale reads. The operator periodically labels the pods with the
+	// current role of their member, a pod is only routed to once it has been labeled as a secondary.
+	// +optional
+	EnableSecondaryService bool `json:"enableSecondaryService,omitempty"`
+
+	// SecondaryServiceSessionAffinity is the session affinity of the secondary Service. With ClientIP,
+	// the connections of a client are routed to the same secondary as long as it stays a secondary.
+	// +kubebuilder:validation:Enum=None;ClientIP
+	// +optional
+	SecondaryServiceSessionAffinity corev1.ServiceAffinity `json:"secondaryServiceSessionAffinity,omitempty"`
+
+	// EnableAgentStatusService creates a headless Service named "<name>-agent-status" which exposes the
+	// status port of the agents within the cluster, e.g. to debug agents which don't reach the goal state.
+	// The status port reports the internal state of the agent, access to the Service should be restricted.
+	// +optional
+	EnableAgentStatusService bool `json:"enableAgentStatusService,omitempty"`
+
+	// Service configures the headless Service of the replica set.
+	// +optional
+	Service ServiceConfiguration `json:"service,omitempty"`
+
+	// UpdateStrategy configures how the pods of the data-bearing members are updated
+	// +optional
+	UpdateStrategy UpdateStrategyConfiguration `json:"updateStrategy,omitempty"`
+
+	// Shutdown configures how the mongod processes are shut down when their pods are terminated
+	// +optional
+	Shutdown ShutdownConfiguration `json:"shutdown,omitempty"`
+
+	// Storage configures the storage settings of each data-bearing mongod
+	// +optional
+	Storage StorageConfiguration `json:"storage,omitempty"`
+
+	// SystemLog configures the log verbosity of each mongod
+	// +optional
+	SystemLog SystemLogConfiguration `json:"systemLog,omitempty"`
+
+	// Diagnostics configures the diagnostic data each mongod collects and sends
+	// +optional
+	Diagnostics DiagnosticsConfiguration `json:"diagnostics,omitempty"`
+
+	// Net configures the connection limit of each mongod
+	// +optional
+	Net NetConfiguration `json:"net,omitempty"`
+
+	// OperationProfiling configures the database profiler and the slow operation threshold of each mongod
+	// +optional
+	OperationProfiling OperationProfilingConfiguration `json:"operationProfiling,omitempty"`
+
+	// Agent configures the MongoDB Agent of each member
+	// +optional
+	Agent AgentConfiguration `json:"agent,omitempty"`
+
+	// AdditionalEnv is a list of environment variables which are added to the mongod and
+	// the mongodb-agent containers, e.g. to configure a proxy. Environment variables managed
+	// by the operator take precedence. Environment variables of a single container can be
+	// configured through the StatefulSet override.
+	// +optional
+	AdditionalEnv []corev1.EnvVar `json:"additionalEnv,omitempty"`
+
+	// AdditionalInitContainers is a list of init containers which run before the mongod and the
+	// mongodb-agent containers start, after the init containers of the operator and in the given
+	// order, e.g. to restore the data from a snapshot. The volumes of the pod, such as "data-volume",
+	// can be mounted into them.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	AdditionalInitContainers []corev1.Container `json:"additionalInitContainers,omitempty"`
+
+	// AgentCAConfigMap is a reference to a ConfigMap containing a CA certificate bundle which the
+	// mongodb-agent trusts for its outbound HTTPS connections, e.g. to download the MongoDB binaries
+	// from a mirror protected by a private CA. The bundle is expected under the key "ca.crt".
+	// +optional
+	AgentCAConfigMap *LocalObjectReference `json:"agentCaConfigMapRef,omitempty"`
+
+	// ReadinessGates are additional conditions which are evaluated for the readiness of the pods,
+	// e.g. so that a load balancer controller marks a pod ready only once it has been registered.
+	// +optional
+	ReadinessGates []corev1.PodReadinessGate `json:"readinessGates,omitempty"`
+}
+
+// CommandConfiguration holds the command and the arguments of a container.
+type CommandConfiguration struct {
+	// Command is the entrypoint of the container, it is not run in a shell.
+	// +kubebuilder:validation:MinItems=1
+	Command []string `json:"command"`
+
+	// Args are the arguments of the command.
+	// +optional
+	Args []string `json:"args,omitempty"`
+}
+
+// MemberAddressingMode defines how the members of the replica set are addressed.
+type MemberAddressingMode string
+
+const (
+	// ServiceFQDNAddressing addresses the members by "<pod>.<service>.<namespace>.svc.<cluster domain>",
+	// which is resolved through the headless Service.
+	ServiceFQDNAddressing MemberAddressingMode = "ServiceFQDN"
+	// PodIPAddressing addresses the members by the IP addresses of their pods.
+	PodIPAddressing MemberAddressingMode = "PodIP"
+)
+
+// MemberAddressingConfiguration holds the settings of the addresses of the members.
+type MemberAddressingConfiguration struct {
+	// Mode defines how the members are addressed, defaults to "ServiceFQDN". With "PodIP" the members
+	// are addressed by the IP addresses of their pods, which change when the pods are restarted. A restarted
+	// member is unreachable until the operator has updated its address, and the replica set can't recover
+	// on its own if a majority of the members are restarted at once. It is only meant for networks in which
+	// the hostnames of the pods can't be resolved, and can't be combined with TLS.
+	// The mode can't be changed once the replica set has been deployed.
+	// +kubebuilder:validation:Enum=ServiceFQDN;PodIP
+	// +optional
+	Mode MemberAddressingMode `json:"mode,omitempty"`
+
+	// ClusterDomain is the DNS domain of the cluster used in the hostnames of the members and in the
+	// connection strings, e.g. for clusters which don't use "cluster.local". It defaults to the
+	// CLUSTER_DNS_NAME environment variable of the operator, or "cluster.local".
+	// It can't be changed once the replica set has been deployed.
+	// +optional
+	ClusterDomain string `json:"clusterDomain,omitempty"`
+}
+
+// UpdateStrategyConfiguration holds the settings of the rolling update of the pods.
+type UpdateStrategyConfiguration struct {
+	// RollingUpdate configures the rolling update of the pods
+	// +optional
+	RollingUpdate *RollingUpdateConfiguration `json:"rollingUpdate,omitempty"`
+
+	// AutomaticRollback rolls back a change of the MongoDB version which doesn't complete in time,
+	// e.g. because the pods of the new version are crash-looping.
+	// +optional
+	AutomaticRollback *AutomaticRollbackConfiguration `json:"automaticRollback,omitempty"`
+}
+
+// AutomaticRollbackConfiguration holds the settings of the automatic rollback of version changes.
+type AutomaticRollbackConfiguration struct {
+	// ProgressDeadlineMinutes is the number of minutes after which a version change which hasn't
+	// completed is rolled back to the last version which was deployed successfully. Defaults to 30.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ProgressDeadlineMinutes int `json:"progressDeadlineMinutes,omitempty"`
+}
+
+// RollingUpdateConfiguration holds the settings of the RollingUpdate strategy of the StatefulSet.
+type RollingUpdateConfiguration struct {
+	// Partition is the ordinal at which the pods are updated, pods with a lower ordinal
+	// keep running the previous revision. It can be used to update the pods with the highest
+	// ordinals first, e.g. for canary rollouts. The partition is ignored while the
+	// MongoDB version is being changed.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Partition *int32 `json:"partition,omitempty"`
+}
+
+// ShutdownConfiguration holds the settings of the shutdown of the mongod processes.
+type ShutdownConfiguration struct {
+	// StepDownPrimary steps down the primary in a preStop hook of the mongod container, so that a
+	// new primary is elected before the pod is terminated, e.g. during rolling updates.
+	// +optional
+	StepDownPrimary bool `json:"stepDownPrimary,omitempty"`
+
+	// StepDownTimeoutSeconds is the number of seconds the primary waits for an electable secondary
+	// to catch up before it steps down. The termination grace period of the pods is extended by it.
+	// Defaults to 10
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	StepDownTimeoutSeconds int `json:"stepDownTimeoutSeconds,omitempty"`
+}
+
+// StorageConfiguration holds the storage settings of the deployment.
+type StorageConfiguration struct {
+	// DataPath is the absolute path the data volume is mounted at, which is used
+	// as the dbPath of each mongod. Defaults to "/data"
+	// +optional
+	DataPath string `json:"dataPath,omitempty"`
+
+	// HostPath is an absolute path on the nodes in which the data of each member is stored, in a
+	// sub directory named after the pod, instead of in a PersistentVolumeClaim. The directory must be
+	// writable by the mongod user.
+	// This is only meant for development and test clusters without a dynamic volume provisioner, e.g.
+	// single node kind or minikube clusters. It must not be used on clusters with multiple nodes,
+	// as a pod that is rescheduled on a different node loses its data.
+	// +optional
+	HostPath string `json:"hostPath,omitempty"`
+
+	// AccessMode is the access mode of the PersistentVolumeClaims of the data and the logs volumes,
+	// defaults to "ReadWriteOnce". "ReadWriteOncePod" requires Kubernetes 1.22 or later and a CSI driver
+	// supporting it. The volume claim templates of a StatefulSet are immutable, changing the access mode
+	// of an existing deployment requires recreating the StatefulSet and only applies to new claims.
+	// +kubebuilder:validation:Enum=ReadWriteOnce;ReadWriteOncePod;ReadWriteMany
+	// +optional
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+
+	// VolumeClaimLabels are added to the PersistentVolumeClaims of the data and the logs volumes, e.g.
+	// for cost reporting or backup tools which select the claims by label. The volume claim templates
+	// of a StatefulSet are immutable, changing them on an existing deployment requires recreating the
+	// StatefulSet and only applies to new claims.
+	// +optional
+	VolumeClaimLabels map[string]string `json:"volumeClaimLabels,omitempty"`
+
+	// VolumeClaimAnnotations are added to the PersistentVolumeClaims of the data and the logs volumes.
+	// Like the labels, changing them on an existing deployment requires recreating the StatefulSet.
+	// +optional
+	VolumeClaimAnnotations map[string]string `json:"volumeClaimAnnotations,omitempty"`
+
+	// JournalCommitIntervalMs is the maximum number of milliseconds between journal operations
+	// of each mongod, which is rendered as "storage.journal.commitIntervalMs". The default of
+	// mongod is used if it is not set.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=500
+	// +optional
+	JournalCommitIntervalMs int `json:"journalCommitIntervalMs,omitempty"`
+
+	// SyncPeriodSecs is the number of seconds between flushes of the data to disk of each mongod,
+	// which is rendered as "storage.syncPeriodSecs". The default of mongod is used if it is not set.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=31536000
+	// +optional
+	SyncPeriodSecs int `json:"syncPeriodSecs,omitempty"`
+}
+
+// ServiceConfiguration holds the settings of the headless Service of the replica set.
+type ServiceConfiguration struct {
+	// Annotations are added to the Service, e.g. for load balancers or service meshes.
+	// Annotations and labels which have been added to the Service by others are kept, and
+	// removing them from here doesn't remove them from the Service.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Labels are added to the Service.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// AgentConfiguration holds the settings of the MongoDB Agent.
+type AgentConfiguration struct {
+	// LogPath is the absolute path the logs volume is mounted at. The agent, the readiness probe
+	// and mongod write their log files into it. Defaults to "/var/log/mongodb-mms-automation"
+	// +optional
+	LogPath string `json:"logPath,omitempty"`
+
+	// MaxLogFileDurationHours is the number of hours after which the agent rotates its log file.
+	// The default of the agent is used if it is not set.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxLogFileDurationHours int `json:"maxLogFileDurationHours,omitempty"`
+
+	// MaxLogFileSizeMB is the size in megabytes after which the agent rotates its log file.
+	// The default of the agent is used if it is not set.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxLogFileSizeMB int `json:"maxLogFileSizeMB,omitempty"`
+
+	// MaxLogFiles is the number of rotated log files the agent keeps, older files are deleted.
+	// The default of the agent, which keeps all the files, is used if it is not set.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxLogFiles int `json:"maxLogFiles,omitempty"`
+
+	// DownloadBase is the absolute path of the directory the agent downloads the MongoDB binaries
+	// into, e.g. for custom images which provide the binaries in a different directory.
+	// Defaults to "/var/lib/mongodb-mms-automation"
+	// +optional
+	DownloadBase string `json:"downloadBase,omitempty"`
+
+	// LivenessProbe enables a liveness probe on the mongodb-agent container, which restarts the
+	// container once the agent stops serving its status port. The defaults are conservative so
+	// that the agent is not restarted during long running operations such as version upgrades.
+	// +optional
+	LivenessProbe *ProbeConfiguration `json:"livenessProbe,omitempty"`
+
+	// UpgradeReadinessInitialDelaySeconds is the initial delay of the readiness probe of the pods
+	// which are restarted by a version upgrade, as mongod may need more time to recover after the
+	// restart. The default delay of the readiness probe applies again once the upgrade has completed.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	UpgradeReadinessInitialDelaySeconds int `json:"upgradeReadinessInitialDelaySeconds,omitempty"`
+
+	// Mode configures whether the agent manages the mongod processes, which is the default, or only
+	// monitors mongod processes started by the user. In the MonitoringOnly mode the mongod container
+	// runs the command of its image, or the command configured through the StatefulSet override.
+	// +kubebuilder:validation:Enum=Automation;MonitoringOnly
+	// +optional
+	Mode AgentMode `json:"mode,omitempty"`
+
+	// DialTimeoutSeconds is the number of seconds the agent waits for a connection to a mongod to be
+	// established, from 1 to 600. The default of the agent is used if it is not set.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=600
+	// +optional
+	DialTimeoutSeconds int `json:"dialTimeoutSeconds,omitempty"`
+
+	// ServerSelectionTimeoutSeconds is the number of seconds the agent waits for a suitable mongod to
+	// become available for an operation, from 1 to 600. The default of the agent is used if it is not set.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=600
+	// +optional
+	ServerSelectionTimeoutSeconds int `json:"serverSelectionTimeoutSeconds,omitempty"`
+
+	// ImagePullPolicy is the pull policy of the images of the mongodb-agent container and of the init
+	// containers provided by the operator. Defaults to Always, IfNotPresent avoids pulling pinned images
+	// on every pod start.
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+}
+
+// AgentMode configures what the agent does with the mongod processes.
+type AgentMode string
+
+const (
+	// AgentModeAutomation lets the agent configure, start and upgrade the mongod processes.
+	AgentModeAutomation AgentMode = "Automation"
+	// AgentModeMonitoringOnly leaves the configuration and the lifecycle of the mongod processes
+	// to the user, the agent only monitors them.
+	AgentModeMonitoringOnly AgentMode = "MonitoringOnly"
+)
+
+// ProbeConfiguration holds the settings of a probe. The default of the probe is used for every
+// setting which is not set.
+type ProbeConfiguration struct {
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	InitialDelaySeconds int `json:"initialDelaySeconds,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	PeriodSeconds int `json:"periodSeconds,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	FailureThreshold int `json:"failureThreshold,omitempty"`
+}
+
+// SystemLogConfiguration holds the log verbosity settings of the deployment.
+type SystemLogConfiguration struct {
+	// Verbosity is the default verbosity level of all log components, from 0 to 5. Defaults to 0
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=5
+	// +optional
+	Verbosity int `json:"verbosity,omitempty"`
+
+	// Component holds the verbosity level of individual log components, from -1 to 5, keyed by
+	// the name of the component, e.g. "replication" or "storage.journal". A level of -1 means
+	// that the component inherits the verbosity of its parent.
+	// +optional
+	Component map[string]int `json:"component,omitempty"`
+}
+
+// DiagnosticsConfiguration holds the settings of the diagnostic data collected by the mongod processes.
+type DiagnosticsConfiguration struct {
+	// DisableFreeMonitoring turns off the free cloud monitoring of MongoDB, which sends monitoring data
+	// to MongoDB once enabled at runtime. It is configured in the process configuration, so that it stays
+	// off across restarts. Free monitoring is only available from MongoDB 4.0 up to 6.x, the setting is
+	// ignored for other versions.
+	// +optional
+	DisableFreeMonitoring bool `json:"disableFreeMonitoring,omitempty"`
+
+	// DisableDiagnosticDataCollection turns off the full time diagnostic data capture (FTDC), which
+	// stores diagnostic data in the diagnostic.data directory of the dbPath.
+	// +optional
+	DisableDiagnosticDataCollection bool `json:"disableDiagnosticDataCollection,omitempty"`
+
+	// DiagnosticDataCollectionPeriodMillis is the interval at which the diagnostic data is captured,
+	// defaults to 1000 milliseconds.
+	// +kubebuilder:validation:Minimum=100
+	// +optional
+	DiagnosticDataCollectionPeriodMillis int `json:"diagnosticDataCollectionPeriodMillis,omitempty"`
+}
+
+// NetConfiguration holds the network settings of the mongod processes.
+type NetConfiguration struct {
+	// MaxIncomingConnections is the maximum number of connections each mongod accepts, which is rendered
+	// as "net.maxIncomingConnections". The connections of the agents, of the other members and of the
+	// operator count towards the limit. The default of mongod is used if it is not set.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxIncomingConnections int `json:"maxIncomingConnections,omitempty"`
+}
+
+// OperationProfilingConfiguration holds the settings of the database profiler of the mongod processes.
+type OperationProfilingConfiguration struct {
+	// Mode is the operations the database profiler records, one of "off", "slowOp" or "all", which is
+	// rendered as "operationProfiling.mode". The default of mongod, "off", is used if it is not set.
+	// +kubebuilder:validation:Enum=off;slowOp;all
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// SlowOpThresholdMs is the number of milliseconds after which an operation is considered slow, which is
+	// rendered as "operationProfiling.slowOpThresholdMs". Slow operations are logged, and recorded by the
+	// profiler in the slowOp mode. The default of mongod, 100 milliseconds, is used if it is not set.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SlowOpThresholdMs int `json:"slowOpThresholdMs,omitempty"`
+}
+
+// MemberConfiguration holds the replica set settings of a single data-bearing member.
+type MemberConfiguration struct {
+	// Hidden hides the member from the clients. A hidden member has priority 0 and never becomes
+	// the primary.
+	// +optional
+	Hidden bool `json:"hidden,omitempty"`
+
+	// BuildIndexes configures whether the member builds indexes, defaults to true. Index builds can
+	// only be disabled on hidden members, e.g. dedicated backup members which never serve queries,
+	// and the setting can't be changed once the member has been added to the replica set.
+	// +optional
+	BuildIndexes *bool `json:"buildIndexes,omitempty"`
+
+	// Host overrides the "host:port" the member advertises in the replica set configuration, e.g. an
+	// externally-routable address. The other members replicate through this address, so it must be
+	// resolvable and routable from within the cluster, and the port must be the one mongod listens on.
+	// +optional
+	Host string `json:"host,omitempty"`
+}
+
+// BuildsIndexes returns whether the member builds indexes.
+func (c MemberConfiguration) BuildsIndexes() bool {
+	return c.BuildIndexes == nil || *c.BuildIndexes
 }
 
 // ReplicaSetHorizonConfiguration holds the split horizon DNS settings for
@@ -243,10 +758,73 @@ func (m *MongodConfiguration) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &m.Object)
 }
 
+// DeepCopy copies the configuration value by value. Unlike runtime.DeepCopyJSON, it accepts values
+// of types which are not produced by decoding JSON, e.g. ints when the configuration is built in code,
+// so that copying never panics. The copy holds values of the same types as the configuration.
 func (m *MongodConfiguration) DeepCopy() *MongodConfiguration {
-	return &MongodConfiguration{
-		Object: runtime.DeepCopyJSON(m.Object),
+	if m.Object == nil {
+		return &MongodConfiguration{}
 	}
+	return &MongodConfiguration{Object: deepCopyValue(m.Object).(map[string]interface{})}
+}
+
+// deepCopyValue returns a copy of the given value which shares no maps or slices with it. Values
+// of other kinds, e.g. scalars, are returned as they are.
+func deepCopyValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if value == nil {
+			return value
+		}
+		copied := make(map[string]interface{}, len(value))
+		for key, elem := range value {
+			copied[key] = deepCopyValue(elem)
+		}
+		return copied
+	case []interface{}:
+		if value == nil {
+			return value
+		}
+		copied := make([]interface{}, len(value))
+		for i, elem := range value {
+			copied[i] = deepCopyValue(elem)
+		}
+		return copied
+	}
+
+	// maps and slices of other types, e.g. []string
+	original := reflect.ValueOf(value)
+	switch original.Kind() {
+	case reflect.Map:
+		if original.IsNil() {
+			return value
+		}
+		copied := reflect.MakeMapWithSize(original.Type(), original.Len())
+		iter := original.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopyReflectValue(iter.Value(), original.Type().Elem()))
+		}
+		return copied.Interface()
+	case reflect.Slice:
+		if original.IsNil() {
+			return value
+		}
+		copied := reflect.MakeSlice(original.Type(), original.Len(), original.Len())
+		for i := 0; i < original.Len(); i++ {
+			copied.Index(i).Set(deepCopyReflectValue(original.Index(i), original.Type().Elem()))
+		}
+		return copied.Interface()
+	}
+	return value
+}
+
+// deepCopyReflectValue copies an element of a map or a slice whose elements are of the given type.
+func deepCopyReflectValue(value reflect.Value, elemType reflect.Type) reflect.Value {
+	// nil elements of interface types have no value to copy
+	if value.Kind() == reflect.Interface && value.IsNil() {
+		return reflect.Zero(elemType)
+	}
+	return reflect.ValueOf(deepCopyValue(value.Interface()))
 }
 
 type MongoDBUser struct {
@@ -268,6 +846,14 @@ type MongoDBUser struct {
 	ScramCredentialsSecretName string `json:"scramCredentialsSecretName"`
 }
 
+// GetDatabase returns the database the user is stored in, defaulting to "admin"
+func (m MongoDBUser) GetDatabase() string {
+	if m.DB == "" {
+		return defaultUserDatabase
+	}
+	return m.DB
+}
+
 func (m MongoDBUser) GetPasswordSecretKey() string {
 	if m.PasswordSecretRef.Key == "" {
 		return defaultPasswordKey
@@ -291,6 +877,24 @@ type SecretKeyReference struct {
 	Key string `json:"key"`
 }
 
+// ConfigMapKeyReference is a reference to a key in a ConfigMap
+type ConfigMapKeyReference struct {
+	// Name is the name of the ConfigMap
+	Name string `json:"name"`
+
+	// Key is the key in the ConfigMap. Defaults to "mongod.conf"
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// GetKey returns the key of the referenced ConfigMap entry, falling back to "mongod.conf".
+func (c ConfigMapKeyReference) GetKey() string {
+	if c.Key == "" {
+		return defaultMongodConfigFileKey
+	}
+	return c.Key
+}
+
 // Role is the database role this user should have
 type Role struct {
 	// DB is the database the role can act on
@@ -328,12 +932,19 @@ type TLS struct {
 	// The certificate is expected to be available under the key "ca.crt"
 	// +optional
 	CaConfigMap LocalObjectReference `json:"caConfigMapRef"`
+
+	// ClientCertificateMode configures if the agents require the members to present a client certificate
+	// for the connections between the members, "REQUIRED" is only supported if TLS is enabled. Defaults to "OPTIONAL".
+	// +kubebuilder:validation:Enum=OPTIONAL;REQUIRED
+	// +optional
+	ClientCertificateMode automationconfig.ClientCertificateMode `json:"clientCertificateMode,omitempty"`
 }
 
 // LocalObjectReference is a reference to another Kubernetes object by name.
 // TODO: Replace with a type from the K8s API. CoreV1 has an equivalent
-// 	"LocalObjectReference" type but it contains a TODO in its
-// 	description that we don't want in our CRD.
+//
+//	"LocalObjectReference" type but it contains a TODO in its
+//	description that we don't want in our CRD.
 type LocalObjectReference struct {
 	Name string `json:"name"`
 }
@@ -343,14 +954,26 @@ type Authentication struct {
 	Modes []AuthMode `json:"modes"`
 
 	// IgnoreUnknownUsers set to true will ensure any users added manually (not through the CRD)
-	// will not be removed.
-
-	// TODO: defaults will work once we update to v1 CRD.
-
+	// will not be removed. It is the inverse of the authoritativeSet setting of the automation
+	// config: set to false, the agents delete every database user which is not declared in the
+	// resource. Defaults to true.
 	// +optional
 	// +kubebuilder:default:=true
 	// +nullable
 	IgnoreUnknownUsers *bool `json:"ignoreUnknownUsers,omitempty"`
+
+	// KeyfileSecretRef is a reference to an existing secret containing the keyfile used for
+	// internal authentication between the members of the replica set. If not specified,
+	// the keyfile is generated. The key defaults to "keyfile".
+	// +optional
+	KeyfileSecretRef *SecretKeyReference `json:"keyfileSecretRef,omitempty"`
+
+	// AgentCredentialsManagedExternally disables the creation of the agent password Secret
+	// "<name>-agent-password" and, unless KeyfileSecretRef is set, the keyfile Secret "<name>-keyfile",
+	// e.g. when they are provided by an external secret manager. The Secrets are used as they are,
+	// the reconciliation fails until they exist.
+	// +optional
+	AgentCredentialsManagedExternally bool `json:"agentCredentialsManagedExternally,omitempty"`
 }
 
 // +kubebuilder:validation:Enum=SCRAM;SCRAM-SHA-256;SCRAM-SHA-1
@@ -377,9 +1000,106 @@ type MongoDBCommunityStatus struct {
 	CurrentStatefulSetReplicas int `json:"currentStatefulSetReplicas"`
 	CurrentMongoDBMembers      int `json:"currentMongoDBMembers"`
 
+	// ObservedGeneration is the generation of the resource that was last successfully reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Primary is the host of the primary member of the replica set, as last observed by the operator
+	// +optional
+	Primary string `json:"primary,omitempty"`
+
+	// Members holds the readiness and the role of each member, as last observed by the operator
+	// +optional
+	Members []MemberStatus `json:"members,omitempty"`
+
+	// Topology is a snapshot of the configuration of the deployment which was last rolled out successfully
+	// +optional
+	Topology *TopologyStatus `json:"topology,omitempty"`
+
+	// Conditions are the latest observations of the health of the replica set by the operator
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
 	Message string `json:"message,omitempty"`
 }
 
+// MemberStatus holds the observed state of a single member of the replica set.
+type MemberStatus struct {
+	// Name is the name of the pod of the member
+	Name string `json:"name"`
+
+	// Ready is true if the pod of the member is ready
+	Ready bool `json:"ready"`
+
+	// Message is the reason reported by the agent why the member is not ready, once the
+	// member has not been ready for a while
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Role is the current role of the member in the replica set, e.g. "primary", "secondary" or "recovering"
+	// +optional
+	Role string `json:"role,omitempty"`
+}
+
+// TopologyStatus is a snapshot of the effective configuration of the deployment, as it is deployed through the
+// automation config. It is refreshed by every reconciliation which rolls the deployment out successfully.
+type TopologyStatus struct {
+	// AutomationConfigVersion is the version of the deployed automation config
+	AutomationConfigVersion int `json:"automationConfigVersion"`
+
+	// Version is the MongoDB version of the members
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// FeatureCompatibilityVersion is the feature compatibility version of the members
+	// +optional
+	FeatureCompatibilityVersion string `json:"featureCompatibilityVersion,omitempty"`
+
+	// TLSMode is the TLS mode of the members, e.g. "requireTLS", or "disabled" if TLS is not enabled
+	TLSMode string `json:"tlsMode"`
+
+	// AuthenticationMechanisms are the authentication mechanisms enabled on the deployment
+	// +optional
+	AuthenticationMechanisms []string `json:"authenticationMechanisms,omitempty"`
+
+	// Members are the members of the replica set
+	// +optional
+	Members []TopologyMember `json:"members,omitempty"`
+
+	// LastVersionTransitionTime is the time at which the operator first observed the current MongoDB version
+	// of the members, i.e. the time at which the last version change completed
+	// +optional
+	LastVersionTransitionTime *metav1.Time `json:"lastVersionTransitionTime,omitempty"`
+
+	// LastFeatureCompatibilityVersionTransitionTime is the time at which the operator first observed the current
+	// feature compatibility version of the members
+	// +optional
+	LastFeatureCompatibilityVersionTransitionTime *metav1.Time `json:"lastFeatureCompatibilityVersionTransitionTime,omitempty"`
+}
+
+// TopologyMember is a member of the replica set, as it is configured in the automation config.
+type TopologyMember struct {
+	// Name is the name of the process of the member, which is the name of its pod
+	Name string `json:"name"`
+
+	// Host is the host and port the member is addressed by
+	Host string `json:"host"`
+
+	// Votes is the number of votes of the member
+	Votes int `json:"votes"`
+
+	// Priority is the priority of the member in elections
+	Priority int `json:"priority"`
+
+	// ArbiterOnly is true if the member is an arbiter
+	// +optional
+	ArbiterOnly bool `json:"arbiterOnly,omitempty"`
+
+	// Hidden is true if the member is hidden from the clients
+	// +optional
+	Hidden bool `json:"hidden,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 
@@ -387,7 +1107,8 @@ type MongoDBCommunityStatus struct {
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=mongodbcommunity,scope=Namespaced,shortName=mdbc,singular=mongodbcommunity
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Current state of the MongoDB deployment"
-// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.version",description="Version of MongoDB server"
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.topology.version",description="Version of MongoDB server"
+// +kubebuilder:printcolumn:name="Primary",type="string",JSONPath=".status.primary",description="Host of the primary member",priority=1
 type MongoDBCommunity struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -437,13 +1158,25 @@ func (m MongoDBCommunity) GetScramOptions() scram.Options {
 		}
 	}
 
-	return scram.Options{
+	opts := scram.Options{
 		AuthoritativeSet:   !ignoreUnknownUsers,
 		KeyFile:            scram.AutomationAgentKeyFilePathInContainer,
 		AutoAuthMechanisms: authMechanisms,
 		AgentName:          scram.AgentName,
 		AutoAuthMechanism:  autoAuthMechanism,
+
+		AgentCredentialsManagedExternally: m.Spec.Security.Authentication.AgentCredentialsManagedExternally,
+	}
+
+	if keyfileSecretRef := m.Spec.Security.Authentication.KeyfileSecretRef; keyfileSecretRef != nil {
+		opts.KeyfileSecret = types.NamespacedName{Name: keyfileSecretRef.Name, Namespace: m.Namespace}
+		opts.KeyfileSecretKey = scram.AgentKeyfileKey
+		if keyfileSecretRef.Key != "" {
+			opts.KeyfileSecretKey = keyfileSecretRef.Key
+		}
 	}
+
+	return opts
 }
 
 // GetScramUsers converts all of the users from the spec into users
@@ -460,7 +1193,7 @@ func (m MongoDBCommunity) GetScramUsers() []scram.User {
 		}
 		users[i] = scram.User{
 			Username:                   u.Name,
-			Database:                   u.DB,
+			Database:                   u.GetDatabase(),
 			Roles:                      roles,
 			PasswordSecretKey:          u.GetPasswordSecretKey(),
 			PasswordSecretName:         u.PasswordSecretRef.Name,
@@ -479,15 +1212,31 @@ func (m MongoDBCommunity) AutomationConfigMembersThisReconciliation() int {
 	})
 }
 
-// MongoURI returns a mongo uri which can be used to connect to this deployment
+// MongoURI returns a mongo uri which can be used to connect to this deployment.
+// The uri specifies the name of the replica set so drivers perform replica set aware connections.
 func (m MongoDBCommunity) MongoURI() string {
-	return fmt.Sprintf("mongodb://%s", strings.Join(m.Hosts(), ","))
+	return m.MongoURIWithHosts(m.Hosts())
+}
+
+// MongoURIWithHosts returns the same uri as MongoURI, which connects to the given hosts instead, e.g. the
+// pod IPs of the members.
+func (m MongoDBCommunity) MongoURIWithHosts(hosts []string) string {
+	return fmt.Sprintf("mongodb://%s/?replicaSet=%s", strings.Join(hosts, ","), url.QueryEscape(m.Name))
 }
 
 // MongoSRVURI returns a mongo srv uri which can be used to connect to this deployment
 func (m MongoDBCommunity) MongoSRVURI() string {
-	clusterDomain := "svc.cluster.local" // TODO: make this configurable
-	return fmt.Sprintf("mongodb+srv://%s.%s.%s", m.ServiceName(), m.Namespace, clusterDomain)
+	return fmt.Sprintf("mongodb+srv://%s/?replicaSet=%s", m.ServiceFQDN(), url.QueryEscape(m.Name))
+}
+
+// MongoTLSURI returns the same uri as MongoURI, which additionally requires a TLS connection
+func (m MongoDBCommunity) MongoTLSURI() string {
+	return m.MongoURI() + "&tls=true"
+}
+
+// MongoSRVTLSURI returns the same uri as MongoSRVURI, which additionally requires a TLS connection
+func (m MongoDBCommunity) MongoSRVTLSURI() string {
+	return m.MongoSRVURI() + "&tls=true"
 }
 
 // MongoAuthUserURI returns a mongo uri which can be used to connect to this deployment
@@ -504,26 +1253,72 @@ func (m MongoDBCommunity) MongoAuthUserURI(user scram.User, password string) str
 // MongoAuthUserSRVURI returns a mongo srv uri which can be used to connect to this deployment
 // and includes the authentication data for the user
 func (m MongoDBCommunity) MongoAuthUserSRVURI(user scram.User, password string) string {
-	clusterDomain := "svc.cluster.local" // TODO: make this configurable
-	return fmt.Sprintf("mongodb+srv://%s:%s@%s.%s.%s/%s?ssl=%t",
+	return fmt.Sprintf("mongodb+srv://%s:%s@%s/%s?ssl=%t",
 		url.QueryEscape(user.Username),
 		url.QueryEscape(password),
-		m.ServiceName(),
-		m.Namespace,
-		clusterDomain,
+		m.ServiceFQDN(),
 		user.Database,
 		m.Spec.Security.TLS.Enabled)
 }
 
+// MemberAddressingMode returns how the members of the replica set are addressed, ServiceFQDNAddressing by default.
+func (m MongoDBCommunity) MemberAddressingMode() MemberAddressingMode {
+	if m.Spec.MemberAddressing.Mode == "" {
+		return ServiceFQDNAddressing
+	}
+	return m.Spec.MemberAddressing.Mode
+}
+
+// MemberOptions returns the per member options of the data-bearing members in the automation config.
+func (m MongoDBCommunity) MemberOptions() []automationconfig.MemberOptions {
+	var options []automationconfig.MemberOptions
+	for _, c := range m.Spec.MemberConfig {
+		options = append(options, automationconfig.MemberOptions{
+			Hidden:       c.Hidden,
+			BuildIndexes: c.BuildIndexes,
+		})
+	}
+	return options
+}
+
+// ClusterDomain returns the DNS domain of the cluster: the memberAddressing.clusterDomain, the CLUSTER_DNS_NAME
+// environment variable of the operator, or "cluster.local".
+func (m MongoDBCommunity) ClusterDomain() string {
+	if m.Spec.MemberAddressing.ClusterDomain != "" {
+		return m.Spec.MemberAddressing.ClusterDomain
+	}
+	if clusterDomain := os.Getenv(ClusterDNSNameEnv); clusterDomain != "" {
+		return clusterDomain
+	}
+	return "cluster.local"
+}
+
+// ServiceFQDN returns the fully qualified domain name of the Service of the members, the members are addressed
+// by "<pod>.<service FQDN>", unless they are addressed by their pod IPs.
+func (m MongoDBCommunity) ServiceFQDN() string {
+	return fmt.Sprintf("%s.%s.svc.%s", m.ServiceName(), m.Namespace, m.ClusterDomain())
+}
+
+// Hosts returns the addresses of the data-bearing members by the fully qualified domain names of their pods. The
+// addresses the members are deployed with, e.g. their pod IPs, are found in the automation config.
 func (m MongoDBCommunity) Hosts() []string {
 	hosts := make([]string, m.Spec.Members)
-	clusterDomain := "svc.cluster.local" // TODO: make this configurable
 	for i := 0; i < m.Spec.Members; i++ {
-		hosts[i] = fmt.Sprintf("%s-%d.%s.%s.%s:%d", m.Name, i, m.ServiceName(), m.Namespace, clusterDomain, 27017)
+		hosts[i] = fmt.Sprintf("%s-%d.%s:%d", m.Name, i, m.ServiceFQDN(), m.GetMongodPort())
 	}
 	return hosts
 }
 
+// SecondaryServiceName returns the name of the Service which only routes to the secondary members
+func (m MongoDBCommunity) SecondaryServiceName() string {
+	return m.Name + "-secondary"
+}
+
+// AgentStatusServiceName returns the name of the Service which exposes the status port of the agents
+func (m MongoDBCommunity) AgentStatusServiceName() string {
+	return m.Name + "-agent-status"
+}
+
 // ServiceName returns the name of the Service that should be created for this resource
 func (m MongoDBCommunity) ServiceName() string {
 	serviceName := m.Spec.StatefulSetConfiguration.SpecWrapper.Spec.ServiceName
@@ -554,6 +1349,42 @@ func (m MongoDBCommunity) TLSOperatorSecretNamespacedName() types.NamespacedName
 	return types.NamespacedName{Name: m.Name + "-server-certificate-key", Namespace: m.Namespace}
 }
 
+// AdditionalMongodConfigMapNamespacedName will get the namespaced name of the ConfigMap containing
+// the additional mongod configuration file. As the ConfigMap will be mounted to our pods, it has to be
+// in the same namespace as the MongoDB resource.
+func (m MongoDBCommunity) AdditionalMongodConfigMapNamespacedName() types.NamespacedName {
+	if m.Spec.AdditionalMongodConfigMap == nil {
+		return types.NamespacedName{}
+	}
+	return types.NamespacedName{Name: m.Spec.AdditionalMongodConfigMap.Name, Namespace: m.Namespace}
+}
+
+// HasSeparateArbiters returns whether the arbiters are deployed in their own StatefulSet.
+func (m MongoDBCommunity) HasSeparateArbiters() bool {
+	return m.Spec.ArbiterStatefulSetConfiguration != nil
+}
+
+// ArbiterNamespacedName returns the NamespacedName of the StatefulSet the arbiters are deployed in,
+// when HasSeparateArbiters is true.
+func (m MongoDBCommunity) ArbiterNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Name + "-arb", Namespace: m.Namespace}
+}
+
+// ArbiterServiceName returns the name of the headless Service of the arbiter StatefulSet, when HasSeparateArbiters
+// is true. The arbiters have their own Service, as their pods don't carry the labels the StatefulSet of the
+// data-bearing members selects its pods by.
+func (m MongoDBCommunity) ArbiterServiceName() string {
+	if m.Spec.ArbiterStatefulSetConfiguration != nil && m.Spec.ArbiterStatefulSetConfiguration.SpecWrapper.Spec.ServiceName != "" {
+		return m.Spec.ArbiterStatefulSetConfiguration.SpecWrapper.Spec.ServiceName
+	}
+	return m.ArbiterNamespacedName().Name + "-svc"
+}
+
+// ArbiterServiceFQDN returns the fully qualified domain name of the headless Service of the arbiters.
+func (m MongoDBCommunity) ArbiterServiceFQDN() string {
+	return fmt.Sprintf("%s.%s.svc.%s", m.ArbiterServiceName(), m.Namespace, m.ClusterDomain())
+}
+
 func (m MongoDBCommunity) NamespacedName() types.NamespacedName {
 	return types.NamespacedName{Name: m.Name, Namespace: m.Namespace}
 }
@@ -581,6 +1412,50 @@ func (m *MongoDBCommunity) StatefulSetReplicasThisReconciliation() int {
 	return scale.ReplicasThisReconciliation(m)
 }
 
+// GetProtocolVersion returns the replica set protocol version the deployment should be configured with.
+func (m MongoDBCommunity) GetProtocolVersion() string {
+	if m.Spec.ProtocolVersion == "" {
+		return automationconfig.DefaultProtocolVersion
+	}
+	return m.Spec.ProtocolVersion
+}
+
+// GetWriteConcernMajorityJournalDefault returns whether w:majority writes wait for the journal of the members.
+func (m MongoDBCommunity) GetWriteConcernMajorityJournalDefault() bool {
+	return m.Spec.WriteConcernMajorityJournalDefault == nil || *m.Spec.WriteConcernMajorityJournalDefault
+}
+
+// GetStepDownTimeoutSeconds returns the number of seconds the primary waits for a secondary to catch up
+// before it steps down on shutdown.
+func (m MongoDBCommunity) GetStepDownTimeoutSeconds() int {
+	if m.Spec.Shutdown.StepDownTimeoutSeconds == 0 {
+		return 10
+	}
+	return m.Spec.Shutdown.StepDownTimeoutSeconds
+}
+
+// GetRollingUpdatePartition returns the partition of the RollingUpdate strategy, if one is configured.
+func (m MongoDBCommunity) GetRollingUpdatePartition() *int32 {
+	if m.Spec.UpdateStrategy.RollingUpdate == nil {
+		return nil
+	}
+	return m.Spec.UpdateStrategy.RollingUpdate.Partition
+}
+
+// IsAutomaticRollbackEnabled returns true if version changes which don't complete in time are rolled back.
+func (m MongoDBCommunity) IsAutomaticRollbackEnabled() bool {
+	return m.Spec.UpdateStrategy.AutomaticRollback != nil
+}
+
+// GetRollbackProgressDeadline returns the time after which a version change which hasn't completed is rolled back.
+func (m MongoDBCommunity) GetRollbackProgressDeadline() time.Duration {
+	minutes := 30
+	if m.Spec.UpdateStrategy.AutomaticRollback != nil && m.Spec.UpdateStrategy.AutomaticRollback.ProgressDeadlineMinutes > 0 {
+		minutes = m.Spec.UpdateStrategy.AutomaticRollback.ProgressDeadlineMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
 // GetUpdateStrategyType returns the type of RollingUpgradeStrategy that the
 // MongoDB StatefulSet should be configured with.
 func (m MongoDBCommunity) GetUpdateStrategyType() appsv1.StatefulSetUpdateStrategyType {
@@ -617,6 +1492,119 @@ func (m MongoDBCommunity) LogsVolumeName() string {
 	return "logs-volume"
 }
 
+// DataPath returns the path the data volume is mounted at, which is also the dbPath of the mongod processes.
+func (m MongoDBCommunity) DataPath() string {
+	if m.Spec.Storage.DataPath != "" {
+		return m.Spec.Storage.DataPath
+	}
+	return automationconfig.DefaultMongoDBDataDir
+}
+
+// GetStorageAccessMode returns the access mode of the PersistentVolumeClaims of the data and the logs volumes.
+func (m MongoDBCommunity) GetStorageAccessMode() corev1.PersistentVolumeAccessMode {
+	if m.Spec.Storage.AccessMode != "" {
+		return m.Spec.Storage.AccessMode
+	}
+	return corev1.ReadWriteOnce
+}
+
+// GetVolumeClaimLabels returns the labels of the PersistentVolumeClaims of the data and the logs volumes.
+func (m MongoDBCommunity) GetVolumeClaimLabels() map[string]string {
+	return m.Spec.Storage.VolumeClaimLabels
+}
+
+// GetVolumeClaimAnnotations returns the annotations of the PersistentVolumeClaims of the data and the logs volumes.
+func (m MongoDBCommunity) GetVolumeClaimAnnotations() map[string]string {
+	return m.Spec.Storage.VolumeClaimAnnotations
+}
+
+// LogsPath returns the path the logs volume is mounted at, which is also the log directory of the agent and the mongod processes.
+func (m MongoDBCommunity) LogsPath() string {
+	if m.Spec.Agent.LogPath != "" {
+		return m.Spec.Agent.LogPath
+	}
+	return automationconfig.DefaultAgentLogPath
+}
+
+// IsAgentMonitoringOnly returns true if the agent only monitors the mongod processes without managing them.
+func (m MongoDBCommunity) IsAgentMonitoringOnly() bool {
+	return m.Spec.Agent.Mode == AgentModeMonitoringOnly
+}
+
+// DownloadBase returns the directory the agent downloads the MongoDB binaries into.
+func (m MongoDBCommunity) DownloadBase() string {
+	if m.Spec.Agent.DownloadBase != "" {
+		return m.Spec.Agent.DownloadBase
+	}
+	return automationconfig.DefaultDownloadBase
+}
+
+// ClientCertificateMode returns the client certificate mode of the connections between the members,
+// which is always optional while TLS is disabled.
+func (m MongoDBCommunity) ClientCertificateMode() automationconfig.ClientCertificateMode {
+	if m.Spec.Security.TLS.Enabled && m.Spec.Security.TLS.ClientCertificateMode != "" {
+		return m.Spec.Security.TLS.ClientCertificateMode
+	}
+	return automationconfig.ClientCertificateModeOptional
+}
+
+// GetAgentMaxLogFileDurationHours returns the hours after which the agent rotates its log file, 0 keeps the default of the agent.
+func (m MongoDBCommunity) GetAgentMaxLogFileDurationHours() int {
+	return m.Spec.Agent.MaxLogFileDurationHours
+}
+
+// GetAgentMaxLogFileSizeMB returns the size in megabytes after which the agent rotates its log file, 0 keeps the default of the agent.
+func (m MongoDBCommunity) GetAgentMaxLogFileSizeMB() int {
+	return m.Spec.Agent.MaxLogFileSizeMB
+}
+
+// GetAgentMaxLogFiles returns the number of rotated log files the agent keeps, 0 keeps all of them.
+func (m MongoDBCommunity) GetAgentMaxLogFiles() int {
+	return m.Spec.Agent.MaxLogFiles
+}
+
+// GetAgentImagePullPolicy returns the pull policy of the images of the agent and of the init containers, which
+// defaults to Always.
+func (m MongoDBCommunity) GetAgentImagePullPolicy() corev1.PullPolicy {
+	if m.Spec.Agent.ImagePullPolicy != "" {
+		return m.Spec.Agent.ImagePullPolicy
+	}
+	return corev1.PullAlways
+}
+
+// AgentStartupParameters returns the startup parameters of the agent which are configured in the spec.
+func (m MongoDBCommunity) AgentStartupParameters() []agent.StartupParameter {
+	var parameters []agent.StartupParameter
+	if timeout := m.Spec.Agent.DialTimeoutSeconds; timeout > 0 {
+		parameters = append(parameters, agent.StartupParameter{Key: "dialTimeoutSeconds", Value: strconv.Itoa(timeout)})
+	}
+	if timeout := m.Spec.Agent.ServerSelectionTimeoutSeconds; timeout > 0 {
+		parameters = append(parameters, agent.StartupParameter{Key: "serverSelectionTimeoutSeconds", Value: strconv.Itoa(timeout)})
+	}
+	return parameters
+}
+
+// IsVersionUpgradeHookDisabled returns whether the version upgrade hook init container is left out of the pods.
+func (m MongoDBCommunity) IsVersionUpgradeHookDisabled() bool {
+	return m.Spec.DisableVersionUpgradeHook
+}
+
+// GetAdditionalMongodArgs returns the arguments which are passed to mongod in addition to its configuration file.
+func (m MongoDBCommunity) GetAdditionalMongodArgs() []string {
+	return m.Spec.AdditionalMongodArgs
+}
+
+// GetMongodPort returns the port mongod listens on, which can be changed with net.port in the additionalMongodConfig.
+func (m MongoDBCommunity) GetMongodPort() int {
+	switch port := objx.New(m.Spec.AdditionalMongodConfig.Object).Get("net.port").Data().(type) {
+	case int:
+		return port
+	case float64:
+		return int(port)
+	}
+	return automationconfig.DefaultDBPort
+}
+
 type automationConfigReplicasScaler struct {
 	current, desired int
 }
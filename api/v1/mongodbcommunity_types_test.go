@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,11 +10,34 @@ import (
 
 func TestMongoDB_MongoURI(t *testing.T) {
 	mdb := newReplicaSet(2, "my-rs", "my-namespace")
-	assert.Equal(t, mdb.MongoURI(), "mongodb://my-rs-0.my-rs-svc.my-namespace.svc.cluster.local:27017,my-rs-1.my-rs-svc.my-namespace.svc.cluster.local:27017")
+	assert.Equal(t, mdb.MongoURI(), "mongodb://my-rs-0.my-rs-svc.my-namespace.svc.cluster.local:27017,my-rs-1.my-rs-svc.my-namespace.svc.cluster.local:27017/?replicaSet=my-rs")
 	mdb = newReplicaSet(1, "my-single-rs", "my-single-namespace")
-	assert.Equal(t, mdb.MongoURI(), "mongodb://my-single-rs-0.my-single-rs-svc.my-single-namespace.svc.cluster.local:27017")
+	assert.Equal(t, mdb.MongoURI(), "mongodb://my-single-rs-0.my-single-rs-svc.my-single-namespace.svc.cluster.local:27017/?replicaSet=my-single-rs")
 	mdb = newReplicaSet(5, "my-big-rs", "my-big-namespace")
-	assert.Equal(t, mdb.MongoURI(), "mongodb://my-big-rs-0.my-big-rs-svc.my-big-namespace.svc.cluster.local:27017,my-big-rs-1.my-big-rs-svc.my-big-namespace.svc.cluster.local:27017,my-big-rs-2.my-big-rs-svc.my-big-namespace.svc.cluster.local:27017,my-big-rs-3.my-big-rs-svc.my-big-namespace.svc.cluster.local:27017,my-big-rs-4.my-big-rs-svc.my-big-namespace.svc.cluster.local:27017")
+	assert.Equal(t, mdb.MongoURI(), "mongodb://my-big-rs-0.my-big-rs-svc.my-big-namespace.svc.cluster.local:27017,my-big-rs-1.my-big-rs-svc.my-big-namespace.svc.cluster.local:27017,my-big-rs-2.my-big-rs-svc.my-big-namespace.svc.cluster.local:27017,my-big-rs-3.my-big-rs-svc.my-big-namespace.svc.cluster.local:27017,my-big-rs-4.my-big-rs-svc.my-big-namespace.svc.cluster.local:27017/?replicaSet=my-big-rs")
+}
+
+func TestMongoDB_MongoSRVURI(t *testing.T) {
+	mdb := newReplicaSet(2, "my-rs", "my-namespace")
+	assert.Equal(t, mdb.MongoSRVURI(), "mongodb+srv://my-rs-svc.my-namespace.svc.cluster.local/?replicaSet=my-rs")
+}
+
+func TestMongoDB_ClusterDomain(t *testing.T) {
+	mdb := newReplicaSet(1, "my-rs", "my-namespace")
+	assert.NoError(t, os.Setenv(ClusterDNSNameEnv, "operator.cluster"))
+	defer os.Unsetenv(ClusterDNSNameEnv)
+	assert.Equal(t, "mongodb://my-rs-0.my-rs-svc.my-namespace.svc.operator.cluster:27017/?replicaSet=my-rs", mdb.MongoURI())
+	assert.Equal(t, "mongodb+srv://my-rs-svc.my-namespace.svc.operator.cluster/?replicaSet=my-rs", mdb.MongoSRVURI())
+
+	mdb.Spec.MemberAddressing.ClusterDomain = "my.cluster"
+	assert.Equal(t, "mongodb://my-rs-0.my-rs-svc.my-namespace.svc.my.cluster:27017/?replicaSet=my-rs", mdb.MongoURI())
+	assert.Equal(t, "mongodb+srv://my-rs-svc.my-namespace.svc.my.cluster/?replicaSet=my-rs", mdb.MongoSRVURI())
+}
+
+func TestMongoDB_MongoTLSURI(t *testing.T) {
+	mdb := newReplicaSet(1, "my-rs", "my-namespace")
+	assert.Equal(t, mdb.MongoTLSURI(), "mongodb://my-rs-0.my-rs-svc.my-namespace.svc.cluster.local:27017/?replicaSet=my-rs&tls=true")
+	assert.Equal(t, mdb.MongoSRVTLSURI(), "mongodb+srv://my-rs-svc.my-namespace.svc.cluster.local/?replicaSet=my-rs&tls=true")
 }
 
 func TestGetScramCredentialsSecretName(t *testing.T) {
@@ -85,6 +109,33 @@ func TestGetScramCredentialsSecretName(t *testing.T) {
 
 }
 
+func TestGetScramOptions_KeyfileSecret(t *testing.T) {
+	mdb := newReplicaSet(3, "my-rs", "my-namespace")
+	mdb.Spec.Security.Authentication.Modes = []AuthMode{"SCRAM"}
+	assert.Empty(t, mdb.GetScramOptions().KeyfileSecret.Name)
+
+	mdb.Spec.Security.Authentication.KeyfileSecretRef = &SecretKeyReference{Name: "existing-keyfile"}
+	opts := mdb.GetScramOptions()
+	assert.Equal(t, "existing-keyfile", opts.KeyfileSecret.Name)
+	assert.Equal(t, "my-namespace", opts.KeyfileSecret.Namespace)
+	assert.Equal(t, "keyfile", opts.KeyfileSecretKey)
+
+	mdb.Spec.Security.Authentication.KeyfileSecretRef.Key = "my-key"
+	assert.Equal(t, "my-key", mdb.GetScramOptions().KeyfileSecretKey)
+}
+
+func TestGetScramUsers_Database(t *testing.T) {
+	mdb := newReplicaSet(3, "my-rs", "my-namespace")
+	mdb.Spec.Users = []MongoDBUser{
+		{Name: "default-user"},
+		{Name: "app-user", DB: "myapp"},
+	}
+
+	users := mdb.GetScramUsers()
+	assert.Equal(t, "admin", users[0].Database)
+	assert.Equal(t, "myapp", users[1].Database)
+}
+
 func newReplicaSet(members int, name, namespace string) MongoDBCommunity {
 	return MongoDBCommunity{
 		TypeMeta: metav1.TypeMeta{},
@@ -97,3 +148,33 @@ func newReplicaSet(members int, name, namespace string) MongoDBCommunity {
 		},
 	}
 }
+
+func TestMongodConfiguration_DeepCopy(t *testing.T) {
+	config := MongodConfiguration{Object: map[string]interface{}{
+		"net":     map[string]interface{}{"port": 1000},
+		"setName": "my-rs",
+		"storage": map[string]interface{}{
+			"dbPath":  "/data",
+			"engines": []interface{}{"wiredTiger", map[string]interface{}{"cacheSizeGB": 1.5}, nil},
+		},
+		"hosts":      []string{"host-0", "host-1"},
+		"parameters": map[string]int{"notablescan": 1},
+		"empty":      nil,
+	}}
+
+	copied := config.DeepCopy()
+	assert.Equal(t, config.Object, copied.Object)
+	assert.Equal(t, 1000, copied.Object["net"].(map[string]interface{})["port"], "the types of the values are kept")
+
+	copied.Object["net"].(map[string]interface{})["port"] = 2000
+	copied.Object["storage"].(map[string]interface{})["engines"].([]interface{})[1].(map[string]interface{})["cacheSizeGB"] = 2.5
+	copied.Object["hosts"].([]string)[0] = "other-host"
+	copied.Object["parameters"].(map[string]int)["notablescan"] = 0
+	assert.Equal(t, 1000, config.Object["net"].(map[string]interface{})["port"])
+	assert.Equal(t, 1.5, config.Object["storage"].(map[string]interface{})["engines"].([]interface{})[1].(map[string]interface{})["cacheSizeGB"])
+	assert.Equal(t, "host-0", config.Object["hosts"].([]string)[0])
+	assert.Equal(t, 1, config.Object["parameters"].(map[string]int)["notablescan"])
+
+	empty := MongodConfiguration{}
+	assert.Nil(t, empty.DeepCopy().Object)
+}
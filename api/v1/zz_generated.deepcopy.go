@@ -22,9 +22,31 @@ package v1
 
 import (
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentConfiguration) DeepCopyInto(out *AgentConfiguration) {
+	*out = *in
+	if in.LivenessProbe != nil {
+		in, out := &in.LivenessProbe, &out.LivenessProbe
+		*out = new(ProbeConfiguration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentConfiguration.
+func (in *AgentConfiguration) DeepCopy() *AgentConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Authentication) DeepCopyInto(out *Authentication) {
 	*out = *in
@@ -38,6 +60,11 @@ func (in *Authentication) DeepCopyInto(out *Authentication) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.KeyfileSecretRef != nil {
+		in, out := &in.KeyfileSecretRef, &out.KeyfileSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Authentication.
@@ -75,6 +102,61 @@ func (in *AuthenticationRestriction) DeepCopy() *AuthenticationRestriction {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutomaticRollbackConfiguration) DeepCopyInto(out *AutomaticRollbackConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutomaticRollbackConfiguration.
+func (in *AutomaticRollbackConfiguration) DeepCopy() *AutomaticRollbackConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(AutomaticRollbackConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommandConfiguration) DeepCopyInto(out *CommandConfiguration) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommandConfiguration.
+func (in *CommandConfiguration) DeepCopy() *CommandConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(CommandConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyReference) DeepCopyInto(out *ConfigMapKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyReference.
+func (in *ConfigMapKeyReference) DeepCopy() *ConfigMapKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CustomRole) DeepCopyInto(out *CustomRole) {
 	*out = *in
@@ -109,6 +191,21 @@ func (in *CustomRole) DeepCopy() *CustomRole {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiagnosticsConfiguration) DeepCopyInto(out *DiagnosticsConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiagnosticsConfiguration.
+func (in *DiagnosticsConfiguration) DeepCopy() *DiagnosticsConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(DiagnosticsConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LocalObjectReference) DeepCopyInto(out *LocalObjectReference) {
 	*out = *in
@@ -124,13 +221,63 @@ func (in *LocalObjectReference) DeepCopy() *LocalObjectReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemberAddressingConfiguration) DeepCopyInto(out *MemberAddressingConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemberAddressingConfiguration.
+func (in *MemberAddressingConfiguration) DeepCopy() *MemberAddressingConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(MemberAddressingConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemberConfiguration) DeepCopyInto(out *MemberConfiguration) {
+	*out = *in
+	if in.BuildIndexes != nil {
+		in, out := &in.BuildIndexes, &out.BuildIndexes
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemberConfiguration.
+func (in *MemberConfiguration) DeepCopy() *MemberConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(MemberConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemberStatus) DeepCopyInto(out *MemberStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemberStatus.
+func (in *MemberStatus) DeepCopy() *MemberStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MemberStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MongoDBCommunity) DeepCopyInto(out *MongoDBCommunity) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunity.
@@ -186,6 +333,11 @@ func (in *MongoDBCommunityList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MongoDBCommunitySpec) DeepCopyInto(out *MongoDBCommunitySpec) {
 	*out = *in
+	if in.WriteConcernMajorityJournalDefault != nil {
+		in, out := &in.WriteConcernMajorityJournalDefault, &out.WriteConcernMajorityJournalDefault
+		*out = new(bool)
+		**out = **in
+	}
 	if in.ReplicaSetHorizons != nil {
 		in, out := &in.ReplicaSetHorizons, &out.ReplicaSetHorizons
 		*out = make(ReplicaSetHorizonConfiguration, len(*in))
@@ -199,6 +351,19 @@ func (in *MongoDBCommunitySpec) DeepCopyInto(out *MongoDBCommunitySpec) {
 			}
 		}
 	}
+	out.MemberAddressing = in.MemberAddressing
+	if in.MemberConfig != nil {
+		in, out := &in.MemberConfig, &out.MemberConfig
+		*out = make([]MemberConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MembersToRemove != nil {
+		in, out := &in.MembersToRemove, &out.MembersToRemove
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	in.Security.DeepCopyInto(&out.Security)
 	if in.Users != nil {
 		in, out := &in.Users, &out.Users
@@ -208,7 +373,65 @@ func (in *MongoDBCommunitySpec) DeepCopyInto(out *MongoDBCommunitySpec) {
 		}
 	}
 	in.StatefulSetConfiguration.DeepCopyInto(&out.StatefulSetConfiguration)
+	if in.ArbiterStatefulSetConfiguration != nil {
+		in, out := &in.ArbiterStatefulSetConfiguration, &out.ArbiterStatefulSetConfiguration
+		*out = new(StatefulSetConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
 	in.AdditionalMongodConfig.DeepCopyInto(&out.AdditionalMongodConfig)
+	if in.AdditionalMongodConfigMap != nil {
+		in, out := &in.AdditionalMongodConfigMap, &out.AdditionalMongodConfigMap
+		*out = new(ConfigMapKeyReference)
+		**out = **in
+	}
+	if in.AdditionalMongodArgs != nil {
+		in, out := &in.AdditionalMongodArgs, &out.AdditionalMongodArgs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MongodCommand != nil {
+		in, out := &in.MongodCommand, &out.MongodCommand
+		*out = new(CommandConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MongodStartupProbe != nil {
+		in, out := &in.MongodStartupProbe, &out.MongodStartupProbe
+		*out = new(ProbeConfiguration)
+		**out = **in
+	}
+	in.Service.DeepCopyInto(&out.Service)
+	in.UpdateStrategy.DeepCopyInto(&out.UpdateStrategy)
+	out.Shutdown = in.Shutdown
+	in.Storage.DeepCopyInto(&out.Storage)
+	in.SystemLog.DeepCopyInto(&out.SystemLog)
+	out.Diagnostics = in.Diagnostics
+	out.Net = in.Net
+	out.OperationProfiling = in.OperationProfiling
+	in.Agent.DeepCopyInto(&out.Agent)
+	if in.AdditionalEnv != nil {
+		in, out := &in.AdditionalEnv, &out.AdditionalEnv
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AdditionalInitContainers != nil {
+		in, out := &in.AdditionalInitContainers, &out.AdditionalInitContainers
+		*out = make([]corev1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AgentCAConfigMap != nil {
+		in, out := &in.AgentCAConfigMap, &out.AgentCAConfigMap
+		*out = new(LocalObjectReference)
+		**out = **in
+	}
+	if in.ReadinessGates != nil {
+		in, out := &in.ReadinessGates, &out.ReadinessGates
+		*out = make([]corev1.PodReadinessGate, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunitySpec.
@@ -224,6 +447,23 @@ func (in *MongoDBCommunitySpec) DeepCopy() *MongoDBCommunitySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MongoDBCommunityStatus) DeepCopyInto(out *MongoDBCommunityStatus) {
 	*out = *in
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]MemberStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Topology != nil {
+		in, out := &in.Topology, &out.Topology
+		*out = new(TopologyStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunityStatus.
@@ -257,12 +497,42 @@ func (in *MongoDBUser) DeepCopy() *MongoDBUser {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetConfiguration) DeepCopyInto(out *NetConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetConfiguration.
+func (in *NetConfiguration) DeepCopy() *NetConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(NetConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MongodConfiguration) DeepCopyInto(out *MongodConfiguration) {
 	clone := in.DeepCopy()
 	*out = *clone
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperationProfilingConfiguration) DeepCopyInto(out *OperationProfilingConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationProfilingConfiguration.
+func (in *OperationProfilingConfiguration) DeepCopy() *OperationProfilingConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(OperationProfilingConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Privilege) DeepCopyInto(out *Privilege) {
 	*out = *in
@@ -284,6 +554,21 @@ func (in *Privilege) DeepCopy() *Privilege {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbeConfiguration) DeepCopyInto(out *ProbeConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeConfiguration.
+func (in *ProbeConfiguration) DeepCopy() *ProbeConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbeConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in ReplicaSetHorizonConfiguration) DeepCopyInto(out *ReplicaSetHorizonConfiguration) {
 	{
@@ -390,6 +675,50 @@ func (in *Security) DeepCopy() *Security {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceConfiguration) DeepCopyInto(out *ServiceConfiguration) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceConfiguration.
+func (in *ServiceConfiguration) DeepCopy() *ServiceConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShutdownConfiguration) DeepCopyInto(out *ShutdownConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShutdownConfiguration.
+func (in *ShutdownConfiguration) DeepCopy() *ShutdownConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ShutdownConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StatefulSetConfiguration) DeepCopyInto(out *StatefulSetConfiguration) {
 	*out = *in
@@ -412,6 +741,57 @@ func (in *StatefulSetSpecWrapper) DeepCopyInto(out *StatefulSetSpecWrapper) {
 	*out = *clone
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageConfiguration) DeepCopyInto(out *StorageConfiguration) {
+	*out = *in
+	if in.VolumeClaimLabels != nil {
+		in, out := &in.VolumeClaimLabels, &out.VolumeClaimLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.VolumeClaimAnnotations != nil {
+		in, out := &in.VolumeClaimAnnotations, &out.VolumeClaimAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageConfiguration.
+func (in *StorageConfiguration) DeepCopy() *StorageConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SystemLogConfiguration) DeepCopyInto(out *SystemLogConfiguration) {
+	*out = *in
+	if in.Component != nil {
+		in, out := &in.Component, &out.Component
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SystemLogConfiguration.
+func (in *SystemLogConfiguration) DeepCopy() *SystemLogConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(SystemLogConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TLS) DeepCopyInto(out *TLS) {
 	*out = *in
@@ -428,3 +808,96 @@ func (in *TLS) DeepCopy() *TLS {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyMember) DeepCopyInto(out *TopologyMember) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyMember.
+func (in *TopologyMember) DeepCopy() *TopologyMember {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyMember)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyStatus) DeepCopyInto(out *TopologyStatus) {
+	*out = *in
+	if in.AuthenticationMechanisms != nil {
+		in, out := &in.AuthenticationMechanisms, &out.AuthenticationMechanisms
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]TopologyMember, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastVersionTransitionTime != nil {
+		in, out := &in.LastVersionTransitionTime, &out.LastVersionTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastFeatureCompatibilityVersionTransitionTime != nil {
+		in, out := &in.LastFeatureCompatibilityVersionTransitionTime, &out.LastFeatureCompatibilityVersionTransitionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyStatus.
+func (in *TopologyStatus) DeepCopy() *TopologyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollingUpdateConfiguration) DeepCopyInto(out *RollingUpdateConfiguration) {
+	*out = *in
+	if in.Partition != nil {
+		in, out := &in.Partition, &out.Partition
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollingUpdateConfiguration.
+func (in *RollingUpdateConfiguration) DeepCopy() *RollingUpdateConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(RollingUpdateConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateStrategyConfiguration) DeepCopyInto(out *UpdateStrategyConfiguration) {
+	*out = *in
+	if in.RollingUpdate != nil {
+		in, out := &in.RollingUpdate, &out.RollingUpdate
+		*out = new(RollingUpdateConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AutomaticRollback != nil {
+		in, out := &in.AutomaticRollback, &out.AutomaticRollback
+		*out = new(AutomaticRollbackConfiguration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateStrategyConfiguration.
+func (in *UpdateStrategyConfiguration) DeepCopy() *UpdateStrategyConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateStrategyConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
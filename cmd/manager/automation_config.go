@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	automationConfigCommand = "automation-config"
+	defaultNamespace        = "default"
+)
+
+// runAutomationConfigCommand reads a MongoDBCommunity resource from the file given with -f and
+// writes the automation config the operator would generate for it to out. No cluster is required.
+func runAutomationConfigCommand(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet(automationConfigCommand, flag.ContinueOnError)
+	file := flags.String("f", "", "path to the MongoDBCommunity resource YAML file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("a resource file must be specified with -f")
+	}
+
+	data, err := ioutil.ReadFile(*file)
+	if err != nil {
+		return errors.Errorf("error reading file: %s", err)
+	}
+
+	mdb := mdbv1.MongoDBCommunity{}
+	if err := yaml.UnmarshalStrict(data, &mdb); err != nil {
+		return errors.Errorf("error parsing resource: %s", err)
+	}
+	if mdb.Namespace == "" {
+		mdb.Namespace = defaultNamespace
+	}
+
+	ac, err := controllers.BuildOfflineAutomationConfig(mdb)
+	if err != nil {
+		return err
+	}
+
+	acBytes, err := json.MarshalIndent(ac, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(acBytes))
+	return err
+}
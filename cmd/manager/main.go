@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
 	"github.com/mongodb/mongodb-kubernetes-operator/controllers"
@@ -22,6 +23,11 @@ var (
 
 const (
 	WatchNamespaceEnv = "WATCH_NAMESPACE"
+	SyncPeriodEnv     = "SYNC_PERIOD"
+
+	// defaultSyncPeriod is the interval in which all the resources are reconciled, even if no events
+	// have been received for them. Resources which are up to date are only checked and not reconciled.
+	defaultSyncPeriod = 10 * time.Minute
 )
 
 func init() {
@@ -49,12 +55,46 @@ func hasRequiredVariables(logger *zap.Logger, envVariables ...string) bool {
 	return allPresent
 }
 
+// syncPeriod returns the interval in which all the resources are reconciled, configured through
+// the SYNC_PERIOD environment variable as a duration, e.g. "10m".
+func syncPeriod() (time.Duration, error) {
+	value, ok := os.LookupEnv(SyncPeriodEnv)
+	if !ok || value == "" {
+		return defaultSyncPeriod, nil
+	}
+	period, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %s", SyncPeriodEnv, value, err)
+	}
+	if period <= 0 {
+		return 0, fmt.Errorf("%s must be greater than 0, got %s", SyncPeriodEnv, value)
+	}
+	return period, nil
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == automationConfigCommand {
+		if err := runAutomationConfigCommand(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == reconcileCommand {
+		if err := runReconcileCommand(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	log, err := configureLogger()
 	if err != nil {
 		log.Sugar().Fatalf("Failed to configure logger: %v", err)
 	}
 
+	log.Sugar().Infof("Operator version: %s", controllers.OperatorVersion)
+
 	if !hasRequiredVariables(log, construct.AgentImageEnv, construct.VersionUpgradeHookImageEnv, construct.ReadinessProbeImageEnv) {
 		os.Exit(1)
 	}
@@ -74,6 +114,12 @@ func main() {
 		log.Sugar().Infof("Watching namespace: %s", watchNamespace)
 	}
 
+	resyncPeriod, err := syncPeriod()
+	if err != nil {
+		log.Sugar().Fatal(err)
+	}
+	log.Sugar().Infof("Reconciling all resources every %s", resyncPeriod)
+
 	// Get a config to talk to the apiserver
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -82,7 +128,8 @@ func main() {
 
 	// Create a new Cmd to provide shared dependencies and start components
 	mgr, err := manager.New(cfg, manager.Options{
-		Namespace: watchNamespace,
+		Namespace:  watchNamespace,
+		SyncPeriod: &resyncPeriod,
 	})
 	if err != nil {
 		log.Sugar().Fatalf("Unable to create manager: %v", err)
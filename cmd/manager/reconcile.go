@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	reconcileCommand = "reconcile"
+
+	// defaultOperatorDeployment is the name of the operator Deployment in the manifests of the operator.
+	defaultOperatorDeployment = "mongodb-kubernetes-operator"
+)
+
+// runReconcileCommand runs a single reconciliation of the MongoDBCommunity resource given with -name and
+// -namespace against the cluster of the kubeconfig, and writes the objects it changed, the result and the
+// automation config version to out. The resource is reconciled with the same images and settings as the
+// operator, which are read from the environment variables of the operator.
+//
+// The resource is not locked against a running operator, so the command refuses to run unless the operator
+// Deployment is scaled down to 0, or -force is set. The state of the members is not fetched in the background,
+// as the members can't be reached from outside the cluster, so the primary, the roles of the members and the
+// Degraded condition are left as they are.
+func runReconcileCommand(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet(reconcileCommand, flag.ContinueOnError)
+	kubeconfig := flags.String("kubeconfig", "", "path to the kubeconfig file, the default loading rules apply if it is not set")
+	namespace := flags.String("namespace", defaultNamespace, "namespace of the MongoDBCommunity resource")
+	name := flags.String("name", "", "name of the MongoDBCommunity resource")
+	operatorNamespace := flags.String("operator-namespace", "", "namespace of the operator Deployment, defaults to the namespace of the resource")
+	operatorDeployment := flags.String("operator-deployment", defaultOperatorDeployment, "name of the operator Deployment, which must be scaled down to 0")
+	force := flags.Bool("force", false, "reconcile the resource even if the operator Deployment is running")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return errors.New("the name of the resource must be specified with -name")
+	}
+	if *operatorNamespace == "" {
+		*operatorNamespace = *namespace
+	}
+	if missing := missingVariables(construct.AgentImageEnv, construct.VersionUpgradeHookImageEnv, construct.ReadinessProbeImageEnv); len(missing) > 0 {
+		return errors.Errorf("required environment variables not found: %s", strings.Join(missing, ", "))
+	}
+
+	cfg, err := restConfig(*kubeconfig)
+	if err != nil {
+		return errors.Errorf("unable to get config: %s", err)
+	}
+	mgr, err := manager.New(cfg, manager.Options{
+		Scheme:    scheme,
+		Namespace: *namespace,
+		// the operator may be running on the same host
+		MetricsBindAddress: "0",
+	})
+	if err != nil {
+		return errors.Errorf("unable to create manager: %s", err)
+	}
+
+	operatorNsName := types.NamespacedName{Name: *operatorDeployment, Namespace: *operatorNamespace}
+	if err := checkOperatorScaledDown(context.Background(), mgr.GetAPIReader(), operatorNsName); err != nil {
+		if !*force {
+			return errors.Errorf("%s, scale it down to 0 or set -force", err)
+		}
+		fmt.Fprintf(out, "warning: %s\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	managerErr := make(chan error, 1)
+	go func() {
+		err := mgr.Start(ctx)
+		// stops waiting for the cache if the manager could not be started
+		cancel()
+		managerErr <- err
+	}()
+	if !mgr.GetCache().WaitForCacheSync(ctx) {
+		return errors.Errorf("unable to start manager: %s", <-managerErr)
+	}
+
+	nsName := types.NamespacedName{Name: *name, Namespace: *namespace}
+	mdb := mdbv1.MongoDBCommunity{}
+	if err := mgr.GetAPIReader().Get(ctx, nsName, &mdb); err != nil {
+		return errors.Errorf("error getting resource %s: %s", nsName, err)
+	}
+	versionBefore, err := automationConfigVersion(ctx, mgr.GetAPIReader(), mdb)
+	if err != nil {
+		return err
+	}
+
+	recorder := &recordingManager{Manager: mgr, out: out}
+	reconciler := controllers.NewReconciler(recorder)
+	reconciler.DisablePrimaryRefresh()
+	res, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: nsName})
+	if err != nil {
+		return errors.Errorf("error reconciling resource %s: %s", nsName, err)
+	}
+
+	if err := mgr.GetAPIReader().Get(ctx, nsName, &mdb); err != nil {
+		return errors.Errorf("error getting resource %s: %s", nsName, err)
+	}
+	versionAfter, err := automationConfigVersion(ctx, mgr.GetAPIReader(), mdb)
+	if err != nil {
+		return err
+	}
+
+	if recorder.changes == 0 {
+		fmt.Fprintln(out, "no objects changed")
+	}
+	fmt.Fprintf(out, "automation config version: %d -> %d\n", versionBefore, versionAfter)
+	fmt.Fprintf(out, "phase: %s\n", mdb.Status.Phase)
+	if mdb.Status.Message != "" {
+		fmt.Fprintf(out, "message: %s\n", mdb.Status.Message)
+	}
+	if res.RequeueAfter > 0 {
+		fmt.Fprintf(out, "the operator would reconcile the resource again after %s\n", res.RequeueAfter)
+	} else if res.Requeue {
+		fmt.Fprintln(out, "the operator would reconcile the resource again")
+	}
+	return nil
+}
+
+// checkOperatorScaledDown returns an error if the operator Deployment runs any pods, which would reconcile the
+// resource concurrently, as the resources are only locked within each process. A missing Deployment is ignored,
+// e.g. if the operator is not deployed in the cluster.
+func checkOperatorScaledDown(ctx context.Context, reader client.Reader, deployment types.NamespacedName) error {
+	operator := appsv1.Deployment{}
+	if err := reader.Get(ctx, deployment, &operator); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return nil
+		}
+		return errors.Errorf("unable to get the operator deployment %s: %s", deployment, err)
+	}
+	replicas := int32(1)
+	if operator.Spec.Replicas != nil {
+		replicas = *operator.Spec.Replicas
+	}
+	// pods which are still terminating could reconcile the resource as well.
+	if operator.Status.Replicas > replicas {
+		replicas = operator.Status.Replicas
+	}
+	if replicas > 0 {
+		return errors.Errorf("the operator deployment %s runs %d replicas, which reconcile the resource concurrently", deployment, replicas)
+	}
+	return nil
+}
+
+// missingVariables returns the environment variables which are not set.
+func missingVariables(envVariables ...string) []string {
+	var missing []string
+	for _, envVariable := range envVariables {
+		if _, envSpecified := os.LookupEnv(envVariable); !envSpecified {
+			missing = append(missing, envVariable)
+		}
+	}
+	return missing
+}
+
+// restConfig returns the config of the given kubeconfig file, or the config found with the default
+// loading rules if no file is given.
+func restConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig == "" {
+		return config.GetConfig()
+	}
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
+
+// automationConfigVersion returns the version of the deployed automation config of the resource, or 0 if
+// it has not been deployed yet. The secret is read from the API server, as the cache may not have observed
+// the latest changes of the reconciliation yet.
+func automationConfigVersion(ctx context.Context, reader client.Reader, mdb mdbv1.MongoDBCommunity) (int, error) {
+	acSecret := corev1.Secret{}
+	err := reader.Get(ctx, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace}, &acSecret)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return 0, nil
+		}
+		return 0, errors.Errorf("error getting automation config: %s", err)
+	}
+	ac, err := automationconfig.FromBytes(acSecret.Data[automationconfig.ConfigKey])
+	if err != nil {
+		return 0, errors.Errorf("error reading automation config: %s", err)
+	}
+	return ac.Version, nil
+}
+
+// recordingManager provides a client to the reconciler which writes each object it changes to out.
+type recordingManager struct {
+	manager.Manager
+	out     io.Writer
+	changes int
+}
+
+func (m *recordingManager) GetClient() client.Client {
+	return recordingClient{Client: m.Manager.GetClient(), manager: m}
+}
+
+// record writes the change of the object to out.
+func (m *recordingManager) record(action string, obj client.Object) {
+	kind := "object"
+	if gvk, err := apiutil.GVKForObject(obj, m.GetScheme()); err == nil {
+		kind = gvk.Kind
+	}
+	m.changes++
+	fmt.Fprintf(m.out, "%s %s %s\n", action, kind, client.ObjectKeyFromObject(obj))
+}
+
+// recordingClient records the objects which are changed through it. Updates which are accepted by the
+// API server without changing the object keep its resource version, and are not recorded.
+type recordingClient struct {
+	client.Client
+	manager *recordingManager
+}
+
+func (c recordingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := c.Client.Create(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.manager.record("created", obj)
+	return nil
+}
+
+func (c recordingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	resourceVersion := obj.GetResourceVersion()
+	if err := c.Client.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	if obj.GetResourceVersion() != resourceVersion {
+		c.manager.record("updated", obj)
+	}
+	return nil
+}
+
+func (c recordingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	resourceVersion := obj.GetResourceVersion()
+	if err := c.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	if obj.GetResourceVersion() != resourceVersion {
+		c.manager.record("patched", obj)
+	}
+	return nil
+}
+
+func (c recordingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.Client.Delete(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.manager.record("deleted", obj)
+	return nil
+}
+
+func (c recordingClient) Status() client.StatusWriter {
+	return recordingStatusWriter{StatusWriter: c.Client.Status(), manager: c.manager}
+}
+
+// recordingStatusWriter records the objects whose status is changed through it.
+type recordingStatusWriter struct {
+	client.StatusWriter
+	manager *recordingManager
+}
+
+func (w recordingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	resourceVersion := obj.GetResourceVersion()
+	if err := w.StatusWriter.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	if obj.GetResourceVersion() != resourceVersion {
+		w.manager.record("updated the status of", obj)
+	}
+	return nil
+}
+
+func (w recordingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	resourceVersion := obj.GetResourceVersion()
+	if err := w.StatusWriter.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	if obj.GetResourceVersion() != resourceVersion {
+		w.manager.record("patched the status of", obj)
+	}
+	return nil
+}
@@ -6,11 +6,13 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/readiness/config"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/readiness/headless"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/readiness/health"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/readiness/pod"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/contains"
 
 	"k8s.io/client-go/kubernetes"
@@ -47,23 +49,29 @@ func init() {
 // Additionally if the previous check hasn't returned 'true' the "deadlock" case is checked to make sure the Agent is
 // not waiting for the other members.
 func isPodReady(conf config.Config) (bool, error) {
+	ready, _, err := podReadiness(conf)
+	return ready, err
+}
+
+// podReadiness returns if the pod is ready, see isPodReady, and the reason why it is not ready.
+func podReadiness(conf config.Config) (bool, string, error) {
 	healthStatus, err := parseHealthStatus(conf.HealthStatusReader)
 	if err != nil {
 		logger.Errorf("There was problem parsing health status file: %s", err)
-		return false, err
+		return false, "", err
 	}
 
 	// The 'statuses' file can be empty only for OM Agents
 	if len(healthStatus.Healthiness) == 0 && !isHeadlessMode() {
 		logger.Info("'statuses' is empty. We assume there is no automation config for the agent yet.")
-		return true, nil
+		return true, "", nil
 	}
 
 	// If the agent has reached the goal state
 	inGoalState, err := isInGoalState(healthStatus, conf)
 	if err != nil {
 		logger.Errorf("There was problem checking the health status: %s", err)
-		return false, err
+		return false, "", err
 	}
 
 	inReadyState := isInReadyState(healthStatus)
@@ -73,15 +81,34 @@ func isPodReady(conf config.Config) (bool, error) {
 
 	if inGoalState && inReadyState {
 		logger.Info("Agent has reached goal state")
-		return true, nil
+		return true, "", nil
 	}
 
 	// Failback logic: the agent is not in goal state and got stuck in some steps
 	if !inGoalState && hasDeadlockedSteps(healthStatus) {
-		return true, nil
+		return true, "", nil
 	}
 
-	return false, nil
+	return false, notReadyReason(healthStatus, inGoalState, inReadyState), nil
+}
+
+// notReadyReason describes why the pod is not ready, including the step the agent is working on and its result.
+func notReadyReason(health health.Status, inGoalState, inReadyState bool) string {
+	var reasons []string
+	if !inGoalState {
+		reason := "the agent has not reached the goal state"
+		if step := findCurrentStep(health.ProcessPlans); step != nil {
+			reason += fmt.Sprintf(", current step: %s", step.Step)
+			if step.Result != "" {
+				reason += fmt.Sprintf(" (result: %s)", step.Result)
+			}
+		}
+		reasons = append(reasons, reason)
+	}
+	if !inReadyState {
+		reasons = append(reasons, "mongod is not up or not in a readable state")
+	}
+	return strings.Join(reasons, "; ")
 }
 
 // hasDeadlockedSteps returns true if the agent is stuck on waiting for the other agents
@@ -217,10 +244,16 @@ func main() {
 	}
 	logger = log.Sugar()
 
-	ready, err := isPodReady(config)
+	ready, reason, err := podReadiness(config)
 	if err != nil {
 		panic(err)
 	}
+	// the reason is recorded on the pod, so that the operator can report why the member is not ready.
+	if isHeadlessMode() {
+		if err := pod.PatchPodNotReadyReason(config.Namespace, reason, config.Hostname, config.ClientSet); err != nil {
+			logger.Errorf("Failed to record the reason the pod is not ready: %s", err)
+		}
+	}
 	if !ready {
 		os.Exit(1)
 	}
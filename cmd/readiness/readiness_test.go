@@ -52,6 +52,24 @@ func TestNotReadyWaitingForRsReady(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// TestNotReadyReason verifies that the reason the pod is not ready contains the step the agent is stuck in
+func TestNotReadyReason(t *testing.T) {
+	ready, reason, err := podReadiness(testConfig("testdata/health-status-pending.json"))
+	assert.False(t, ready)
+	assert.NoError(t, err)
+	assert.Equal(t, "the agent has not reached the goal state, current step: WaitAllRsMembersUp (result: wait)", reason)
+
+	ready, reason, err = podReadiness(testConfigWithMongoUp("testdata/health-status-ok.json", time.Hour*1))
+	assert.False(t, ready)
+	assert.NoError(t, err)
+	assert.Equal(t, "mongod is not up or not in a readable state", reason)
+
+	ready, reason, err = podReadiness(testConfig("testdata/health-status-ok.json"))
+	assert.True(t, ready)
+	assert.NoError(t, err)
+	assert.Empty(t, reason)
+}
+
 // TestNotReadyHealthFileHasNoPlans verifies that the readiness script doesn't panic if the health file has unexpected
 // data (there are no plans at all)
 func TestNotReadyHealthFileHasNoPlans(t *testing.T) {
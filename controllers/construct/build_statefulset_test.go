@@ -2,9 +2,11 @@ package construct
 
 import (
 	"os"
+	"path"
 	"reflect"
 	"testing"
 
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/container"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/resourcerequirements"
 
@@ -60,8 +62,94 @@ func TestMultipleCalls_DoNotCauseSideEffects(t *testing.T) {
 	})
 }
 
+func TestVersionUpgradeHook_CanBeDisabled(t *testing.T) {
+	mdb := newTestReplicaSet()
+	sts := &appsv1.StatefulSet{}
+	BuildMongoDBReplicaSetStatefulSetModificationFunction(&mdb, mdb)(sts)
+
+	assert.Len(t, sts.Spec.Template.Spec.InitContainers, 2)
+	mongodContainer := sts.Spec.Template.Spec.Containers[1]
+	assert.Contains(t, mongodContainer.Command[2], "/hooks/version-upgrade")
+
+	mdb.Spec.DisableVersionUpgradeHook = true
+	BuildMongoDBReplicaSetStatefulSetModificationFunction(&mdb, mdb)(sts)
+
+	assert.Len(t, sts.Spec.Template.Spec.InitContainers, 1)
+	assert.Equal(t, ReadinessProbeContainerName, sts.Spec.Template.Spec.InitContainers[0].Name)
+	mongodContainer = sts.Spec.Template.Spec.Containers[1]
+	assert.NotContains(t, mongodContainer.Command[2], "/hooks/version-upgrade")
+	assert.Contains(t, mongodContainer.Command[2], "exec mongod -f")
+}
+
+func TestAgentClusterFilePath_MatchesVolumeMount(t *testing.T) {
+	assertClusterFilePath := func(t *testing.T, expectedMountPath string) {
+		mdb := newTestReplicaSet()
+		sts := &appsv1.StatefulSet{}
+		BuildMongoDBReplicaSetStatefulSetModificationFunction(&mdb, mdb)(sts)
+
+		agentContainer := sts.Spec.Template.Spec.Containers[0]
+		mount := volumeMountByName(agentContainer.VolumeMounts, automationConfigVolumeName)
+		assert.NotNil(t, mount)
+		assert.Equal(t, expectedMountPath, mount.MountPath)
+		// the agent reads the key of the automation config Secret from the mounted volume
+		assert.Contains(t, agentContainer.Command[2], "-cluster="+path.Join(mount.MountPath, automationconfig.ConfigKey)+" ")
+	}
+
+	t.Run("Default mount path", func(t *testing.T) {
+		assertClusterFilePath(t, "/var/lib/automation/config")
+	})
+
+	t.Run("Custom mount path", func(t *testing.T) {
+		os.Setenv(AutomationConfigMountPathEnv, "/etc/mongodb-agent/config")
+		defer os.Unsetenv(AutomationConfigMountPathEnv)
+		assertClusterFilePath(t, "/etc/mongodb-agent/config")
+	})
+}
+
+func TestAgentHealthStatusFilePath_IsConsistent(t *testing.T) {
+	mdb := newTestReplicaSet()
+	sts := &appsv1.StatefulSet{}
+	BuildMongoDBReplicaSetStatefulSetModificationFunction(&mdb, mdb)(sts)
+
+	agentContainer := sts.Spec.Template.Spec.Containers[0]
+	mongodContainer := sts.Spec.Template.Spec.Containers[1]
+
+	agentPath := envValue(agentContainer.Env, agentHealthStatusFilePathEnv)
+	mongodPath := envValue(mongodContainer.Env, agentHealthStatusFilePathEnv)
+
+	// the readiness probe reads the file from the env var of the agent container, which must match the file the agent writes
+	assert.Contains(t, agentContainer.Command[2], "-healthCheckFilePath="+agentPath+" ")
+
+	agentMount := volumeMountByName(agentContainer.VolumeMounts, healthStatusVolumeName)
+	mongodMount := volumeMountByName(mongodContainer.VolumeMounts, healthStatusVolumeName)
+	assert.NotNil(t, agentMount)
+	assert.NotNil(t, mongodMount)
+
+	// both containers must reference the same file inside the shared volume
+	assert.Equal(t, path.Join(agentMount.MountPath, agentHealthStatusFileName), agentPath)
+	assert.Equal(t, path.Join(mongodMount.MountPath, agentHealthStatusFileName), mongodPath)
+}
+
+func envValue(envs []corev1.EnvVar, name string) string {
+	for _, e := range envs {
+		if e.Name == name {
+			return e.Value
+		}
+	}
+	return ""
+}
+
+func volumeMountByName(mounts []corev1.VolumeMount, name string) *corev1.VolumeMount {
+	for i := range mounts {
+		if mounts[i].Name == name {
+			return &mounts[i]
+		}
+	}
+	return nil
+}
+
 func TestMongod_Container(t *testing.T) {
-	c := container.New(mongodbContainer("4.2", []corev1.VolumeMount{}))
+	c := container.New(mongodbContainer("4.2", "/data", automationconfig.DefaultAgentLogPath, []corev1.VolumeMount{}, true, nil, 27017))
 
 	t.Run("Has correct Env vars", func(t *testing.T) {
 		assert.Len(t, c.Env, 1)
@@ -76,6 +164,86 @@ func TestMongod_Container(t *testing.T) {
 	t.Run("Resource requirements are correct", func(t *testing.T) {
 		assert.Equal(t, resourcerequirements.Defaults(), c.Resources)
 	})
+
+	t.Run("Startup probe is configured", func(t *testing.T) {
+		assert.NotNil(t, c.StartupProbe)
+		assert.Equal(t, probes.New(DefaultMongodStartup(27017)), *c.StartupProbe)
+		assert.Equal(t, int32(180), c.StartupProbe.FailureThreshold)
+		assert.Equal(t, int32(10), c.StartupProbe.PeriodSeconds)
+		assert.Equal(t, 27017, c.StartupProbe.TCPSocket.Port.IntValue())
+	})
+
+	t.Run("No additional arguments are passed by default", func(t *testing.T) {
+		assert.Nil(t, c.Args)
+		assert.Contains(t, c.Command[2], "exec mongod -f /data/automation-mongod.conf;")
+	})
+}
+
+func TestMongod_Container_AdditionalArgs(t *testing.T) {
+	c := container.New(mongodbContainer("4.2", "/data", automationconfig.DefaultAgentLogPath, []corev1.VolumeMount{}, true, []string{"--setParameter", "logLevel=1; rm -rf /"}, 27017))
+
+	// the arguments are passed as positional parameters and never interpreted by the shell
+	assert.Contains(t, c.Command[2], `exec mongod -f /data/automation-mongod.conf "$@";`)
+	assert.Equal(t, []string{"mongod", "--setParameter", "logLevel=1; rm -rf /"}, c.Args)
+}
+
+func TestBuildStatefulSet_DefaultAgentLogFile(t *testing.T) {
+	mdb := newTestReplicaSet()
+	sts := &appsv1.StatefulSet{}
+	BuildMongoDBReplicaSetStatefulSetModificationFunction(&mdb, mdb)(sts)
+
+	agentContainer := sts.Spec.Template.Spec.Containers[0]
+	assert.NotContains(t, agentContainer.Command[2], "-logFile")
+	assert.Equal(t, AutomationAgentCommand(), agentContainer.Command)
+}
+
+func TestAgentLogFlags_OnlyConfiguredFlags(t *testing.T) {
+	tests := map[string]struct {
+		configure     func(mdb *mdbv1.MongoDBCommunity)
+		expectedFlags []string
+	}{
+		"Nothing configured": {
+			configure: func(*mdbv1.MongoDBCommunity) {},
+		},
+		"Duration": {
+			configure:     func(mdb *mdbv1.MongoDBCommunity) { mdb.Spec.Agent.MaxLogFileDurationHours = 12 },
+			expectedFlags: []string{"-maxLogFileDurationHrs=12"},
+		},
+		"Size": {
+			configure:     func(mdb *mdbv1.MongoDBCommunity) { mdb.Spec.Agent.MaxLogFileSizeMB = 1 },
+			expectedFlags: []string{"-maxLogFileSize=1048576"},
+		},
+		"Files": {
+			configure:     func(mdb *mdbv1.MongoDBCommunity) { mdb.Spec.Agent.MaxLogFiles = 5 },
+			expectedFlags: []string{"-maxLogFiles=5"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mdb := newTestReplicaSet()
+			tc.configure(&mdb)
+			assert.Equal(t, tc.expectedFlags, AgentLogFlags(&mdb))
+		})
+	}
+}
+
+func TestBuildStatefulSet_AgentLogPath(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Agent.LogPath = "/var/log/mongodb"
+	mdb.Spec.Agent.MaxLogFileDurationHours = 12
+	mdb.Spec.Agent.MaxLogFileSizeMB = 100
+	mdb.Spec.Agent.MaxLogFiles = 5
+	sts := &appsv1.StatefulSet{}
+	BuildMongoDBReplicaSetStatefulSetModificationFunction(&mdb, mdb)(sts)
+
+	agentContainer := sts.Spec.Template.Spec.Containers[0]
+	assert.Contains(t, agentContainer.Command[2], "-logFile=/var/log/mongodb/automation-agent.log -maxLogFileDurationHrs=12 -maxLogFileSize=104857600 -maxLogFiles=5")
+	assert.Equal(t, "/var/log/mongodb/readiness.log", envValue(agentContainer.Env, readinessProbeLogFilePathEnv))
+	assert.Equal(t, "/var/log/mongodb", volumeMountByName(agentContainer.VolumeMounts, mdb.LogsVolumeName()).MountPath)
+
+	mongodContainer := sts.Spec.Template.Spec.Containers[1]
+	assert.Contains(t, mongodContainer.Command[2], "tail -F /var/log/mongodb/mongodb.log")
+	assert.Equal(t, "/var/log/mongodb", volumeMountByName(mongodContainer.VolumeMounts, mdb.LogsVolumeName()).MountPath)
 }
 
 func assertStatefulSetIsBuiltCorrectly(t *testing.T, mdb mdbv1.MongoDBCommunity, sts *appsv1.StatefulSet) {
@@ -85,7 +253,7 @@ func assertStatefulSetIsBuiltCorrectly(t *testing.T, mdb mdbv1.MongoDBCommunity,
 	assert.Equal(t, mdb.Name, sts.Name)
 	assert.Equal(t, mdb.Namespace, sts.Namespace)
 	assert.Equal(t, mongodbDatabaseServiceAccountName, sts.Spec.Template.Spec.ServiceAccountName)
-	assert.Len(t, sts.Spec.Template.Spec.Containers[0].Env, 4)
+	assert.Len(t, sts.Spec.Template.Spec.Containers[0].Env, 5)
 	assert.Len(t, sts.Spec.Template.Spec.Containers[1].Env, 1)
 
 	agentContainer := sts.Spec.Template.Spec.Containers[0]
@@ -3,6 +3,8 @@ package construct
 import (
 	"fmt"
 	"os"
+	"path"
+	"strconv"
 	"strings"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
@@ -16,6 +18,7 @@ import (
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/scale"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	corev1 "k8s.io/api/core/v1"
 )
@@ -28,9 +31,20 @@ const (
 	ReadinessProbeContainerName       = "mongodb-agent-readinessprobe"
 	readinessProbePath                = "/opt/scripts/readinessprobe"
 	agentHealthStatusFilePathEnv      = "AGENT_STATUS_FILEPATH"
-	clusterFilePath                   = "/var/lib/automation/config/cluster-config.json"
+	readinessProbeLogFilePathEnv      = "LOG_FILE_PATH"
+	agentLogFileName                  = "automation-agent.log"
+	readinessProbeLogFileName         = "readiness.log"
 	mongodbDatabaseServiceAccountName = "mongodb-database"
-	agentHealthStatusFilePathValue    = "/var/log/mongodb-mms-automation/healthstatus/agent-health-status.json"
+
+	// AgentStatusPort is the port the agent serves its status on.
+	AgentStatusPort = 5000
+
+	// the agent writes its health status into the healthstatus volume, which is mounted into both containers
+	// so that the readiness probe (agent container) and the version upgrade hook (mongod container) can read it.
+	healthStatusVolumeName      = "healthstatus"
+	agentHealthStatusFileName   = "agent-health-status.json"
+	agentHealthStatusMountPath  = "/var/log/mongodb-mms-automation/healthstatus"
+	mongodHealthStatusMountPath = "/healthstatus"
 
 	MongodbRepoUrl = "MONGODB_REPO_URL"
 
@@ -42,8 +56,15 @@ const (
 	VersionUpgradeHookImageEnv = "VERSION_UPGRADE_HOOK_IMAGE"
 	ReadinessProbeImageEnv     = "READINESS_PROBE_IMAGE"
 	ManagedSecurityContextEnv  = "MANAGED_SECURITY_CONTEXT"
+	// AutomationConfigMountPathEnv can be set on the operator to mount the automation config into the agent
+	// container at a different path than the default one, e.g. for unusual security contexts.
+	AutomationConfigMountPathEnv = "AUTOMATION_CONFIG_MOUNT_PATH"
+
+	automationConfigVolumeName       = "automation-config"
+	defaultAutomationConfigMountPath = "/var/lib/automation/config"
 
-	automationconfFilePath = "/data/automation-mongod.conf"
+	// automationconfFileName is the name of the mongod configuration file the agent writes into the dbPath.
+	automationconfFileName = "automation-mongod.conf"
 	keyfileFilePath        = "/var/lib/mongodb-mms-automation/authentication/keyfile"
 
 	automationAgentOptions = " -skipMongoStart -noDaemonize -useLocalMongoDbTools"
@@ -60,6 +81,10 @@ fi
 `
 )
 
+// VersionUpgradeHookCommand runs the version upgrade post-hook, a custom mongod command has to run it before
+// mongod is started to keep version upgrades safe.
+const VersionUpgradeHookCommand = "/hooks/version-upgrade"
+
 // MongoDBStatefulSetOwner is an interface which any resource which generates a MongoDB StatefulSet should implement.
 type MongoDBStatefulSetOwner interface {
 	// ServiceName returns the name of the K8S service the operator will create.
@@ -82,6 +107,30 @@ type MongoDBStatefulSetOwner interface {
 	DataVolumeName() string
 	// LogsVolumeName returns the name that the data volume should have
 	LogsVolumeName() string
+	// DataPath returns the path the data volume should be mounted at, it must match the dbPath of the processes in the automation config.
+	DataPath() string
+	// IsVersionUpgradeHookDisabled returns whether the version upgrade post-hook should not be run by the mongod container.
+	IsVersionUpgradeHookDisabled() bool
+	// GetAdditionalMongodArgs returns the command line arguments which are appended to the mongod command.
+	GetAdditionalMongodArgs() []string
+	// LogsPath returns the path the logs volume should be mounted at, it must match the log directory of the processes in the automation config.
+	LogsPath() string
+	// GetStorageAccessMode returns the access mode of the PersistentVolumeClaims of the data and the logs volumes.
+	GetStorageAccessMode() corev1.PersistentVolumeAccessMode
+	// GetVolumeClaimLabels returns the labels of the PersistentVolumeClaims of the data and the logs volumes.
+	GetVolumeClaimLabels() map[string]string
+	// GetVolumeClaimAnnotations returns the annotations of the PersistentVolumeClaims of the data and the logs volumes.
+	GetVolumeClaimAnnotations() map[string]string
+	// GetAgentMaxLogFileDurationHours returns the number of hours after which the agent rotates its log file, 0 keeps the agent default.
+	GetAgentMaxLogFileDurationHours() int
+	// GetAgentMaxLogFileSizeMB returns the size in megabytes after which the agent rotates its log file, 0 keeps the agent default.
+	GetAgentMaxLogFileSizeMB() int
+	// GetAgentMaxLogFiles returns the number of rotated log files the agent keeps, 0 keeps the agent default.
+	GetAgentMaxLogFiles() int
+	// GetAgentImagePullPolicy returns the pull policy of the images of the agent and of the init containers.
+	GetAgentImagePullPolicy() corev1.PullPolicy
+	// GetMongodPort returns the port mongod listens on.
+	GetMongodPort() int
 }
 
 // BuildMongoDBReplicaSetStatefulSetModificationFunction builds the parts of the replica set that are common between every resource that implements
@@ -95,9 +144,9 @@ func BuildMongoDBReplicaSetStatefulSetModificationFunction(mdb MongoDBStatefulSe
 	// the health status volume is required in both agent and mongod pods.
 	// the mongod requires it to determine if an upgrade is happening and needs to kill the pod
 	// to prevent agent deadlock
-	healthStatusVolume := statefulset.CreateVolumeFromEmptyDir("healthstatus")
-	agentHealthStatusVolumeMount := statefulset.CreateVolumeMount(healthStatusVolume.Name, "/var/log/mongodb-mms-automation/healthstatus")
-	mongodHealthStatusVolumeMount := statefulset.CreateVolumeMount(healthStatusVolume.Name, "/healthstatus")
+	healthStatusVolume := statefulset.CreateVolumeFromEmptyDir(healthStatusVolumeName)
+	agentHealthStatusVolumeMount := statefulset.CreateVolumeMount(healthStatusVolume.Name, agentHealthStatusMountPath)
+	mongodHealthStatusVolumeMount := statefulset.CreateVolumeMount(healthStatusVolume.Name, mongodHealthStatusMountPath)
 
 	// hooks volume is only required on the mongod pod.
 	hooksVolume := statefulset.CreateVolumeFromEmptyDir("hooks")
@@ -107,8 +156,8 @@ func BuildMongoDBReplicaSetStatefulSetModificationFunction(mdb MongoDBStatefulSe
 	scriptsVolume := statefulset.CreateVolumeFromEmptyDir("agent-scripts")
 	scriptsVolumeMount := statefulset.CreateVolumeMount(scriptsVolume.Name, "/opt/scripts", statefulset.WithReadOnly(false))
 
-	automationConfigVolume := statefulset.CreateVolumeFromSecret("automation-config", mdb.AutomationConfigSecretName())
-	automationConfigVolumeMount := statefulset.CreateVolumeMount(automationConfigVolume.Name, "/var/lib/automation/config", statefulset.WithReadOnly(true))
+	automationConfigVolume := statefulset.CreateVolumeFromSecret(automationConfigVolumeName, mdb.AutomationConfigSecretName())
+	automationConfigVolumeMount := statefulset.CreateVolumeMount(automationConfigVolume.Name, automationConfigMountPath(), statefulset.WithReadOnly(true))
 
 	keyFileNsName := mdb.GetAgentKeyfileSecretNamespacedName()
 	keyFileVolume := statefulset.CreateVolumeFromEmptyDir(keyFileNsName.Name)
@@ -117,24 +166,28 @@ func BuildMongoDBReplicaSetStatefulSetModificationFunction(mdb MongoDBStatefulSe
 
 	mongodbAgentVolumeMounts := []corev1.VolumeMount{agentHealthStatusVolumeMount, automationConfigVolumeMount, scriptsVolumeMount, keyFileVolumeVolumeMount}
 	mongodVolumeMounts := []corev1.VolumeMount{mongodHealthStatusVolumeMount, hooksVolumeMount, keyFileVolumeVolumeMountMongod}
+	claimMetadata := persistentvolumeclaim.Apply(
+		persistentvolumeclaim.WithLabels(mdb.GetVolumeClaimLabels()),
+		persistentvolumeclaim.WithAnnotations(mdb.GetVolumeClaimAnnotations()),
+	)
 	dataVolumeClaim := statefulset.NOOP()
 	logVolumeClaim := statefulset.NOOP()
 	singleModeVolumeClaim := func(s *appsv1.StatefulSet) {}
 	if mdb.HasSeparateDataAndLogsVolumes() {
-		logVolumeMount := statefulset.CreateVolumeMount(mdb.LogsVolumeName(), automationconfig.DefaultAgentLogPath)
-		dataVolumeMount := statefulset.CreateVolumeMount(mdb.DataVolumeName(), "/data")
-		dataVolumeClaim = statefulset.WithVolumeClaim(mdb.DataVolumeName(), dataPvc(mdb.DataVolumeName()))
-		logVolumeClaim = statefulset.WithVolumeClaim(mdb.LogsVolumeName(), logsPvc(mdb.LogsVolumeName()))
+		logVolumeMount := statefulset.CreateVolumeMount(mdb.LogsVolumeName(), mdb.LogsPath())
+		dataVolumeMount := statefulset.CreateVolumeMount(mdb.DataVolumeName(), mdb.DataPath())
+		dataVolumeClaim = statefulset.WithVolumeClaim(mdb.DataVolumeName(), dataPvc(mdb.DataVolumeName(), mdb.GetStorageAccessMode(), claimMetadata))
+		logVolumeClaim = statefulset.WithVolumeClaim(mdb.LogsVolumeName(), logsPvc(mdb.LogsVolumeName(), mdb.GetStorageAccessMode(), claimMetadata))
 		mongodbAgentVolumeMounts = append(mongodbAgentVolumeMounts, dataVolumeMount, logVolumeMount)
 		mongodVolumeMounts = append(mongodVolumeMounts, dataVolumeMount, logVolumeMount)
 	} else {
 		mounts := []corev1.VolumeMount{
-			statefulset.CreateVolumeMount(mdb.DataVolumeName(), "/data", statefulset.WithSubPath("data")),
-			statefulset.CreateVolumeMount(mdb.DataVolumeName(), automationconfig.DefaultAgentLogPath, statefulset.WithSubPath("logs")),
+			statefulset.CreateVolumeMount(mdb.DataVolumeName(), mdb.DataPath(), statefulset.WithSubPath("data")),
+			statefulset.CreateVolumeMount(mdb.DataVolumeName(), mdb.LogsPath(), statefulset.WithSubPath("logs")),
 		}
 		mongodbAgentVolumeMounts = append(mongodbAgentVolumeMounts, mounts...)
 		mongodVolumeMounts = append(mongodVolumeMounts, mounts...)
-		singleModeVolumeClaim = statefulset.WithVolumeClaim(mdb.DataVolumeName(), dataPvc(mdb.DataVolumeName()))
+		singleModeVolumeClaim = statefulset.WithVolumeClaim(mdb.DataVolumeName(), dataPvc(mdb.DataVolumeName(), mdb.GetStorageAccessMode(), claimMetadata))
 	}
 
 	podSecurityContext := podtemplatespec.NOOP()
@@ -143,6 +196,12 @@ func BuildMongoDBReplicaSetStatefulSetModificationFunction(mdb MongoDBStatefulSe
 		podSecurityContext = podtemplatespec.WithSecurityContext(podtemplatespec.DefaultPodSecurityContext())
 	}
 
+	versionUpgradeHook := podtemplatespec.WithInitContainer(versionUpgradeHookName, versionUpgradeHookInit([]corev1.VolumeMount{hooksVolumeMount}, mdb.GetAgentImagePullPolicy()))
+	if mdb.IsVersionUpgradeHookDisabled() {
+		// the init container needs to be removed explicitly in case it is already part of an existing StatefulSet.
+		versionUpgradeHook = podtemplatespec.WithoutInitContainer(versionUpgradeHookName)
+	}
+
 	return statefulset.Apply(
 		statefulset.WithName(mdb.GetName()),
 		statefulset.WithNamespace(mdb.GetNamespace()),
@@ -164,23 +223,75 @@ func BuildMongoDBReplicaSetStatefulSetModificationFunction(mdb MongoDBStatefulSe
 				podtemplatespec.WithVolume(scriptsVolume),
 				podtemplatespec.WithVolume(keyFileVolume),
 				podtemplatespec.WithServiceAccount(mongodbDatabaseServiceAccountName),
-				podtemplatespec.WithContainer(AgentName, mongodbAgentContainer(mdb.AutomationConfigSecretName(), mongodbAgentVolumeMounts)),
-				podtemplatespec.WithContainer(MongodbName, mongodbContainer(mdb.GetMongoDBVersion(), mongodVolumeMounts)),
-				podtemplatespec.WithInitContainer(versionUpgradeHookName, versionUpgradeHookInit([]corev1.VolumeMount{hooksVolumeMount})),
-				podtemplatespec.WithInitContainer(ReadinessProbeContainerName, readinessProbeInit([]corev1.VolumeMount{scriptsVolumeMount})),
+				podtemplatespec.WithContainer(AgentName, mongodbAgentContainer(mdb.AutomationConfigSecretName(), mdb.LogsPath(), AgentLogFlags(mdb), mongodbAgentVolumeMounts, mdb.GetAgentImagePullPolicy())),
+				podtemplatespec.WithContainer(MongodbName, mongodbContainer(mdb.GetMongoDBVersion(), mdb.DataPath(), mdb.LogsPath(), mongodVolumeMounts, !mdb.IsVersionUpgradeHookDisabled(), mdb.GetAdditionalMongodArgs(), mdb.GetMongodPort())),
+				versionUpgradeHook,
+				podtemplatespec.WithInitContainer(ReadinessProbeContainerName, readinessProbeInit([]corev1.VolumeMount{scriptsVolumeMount}, mdb.GetAgentImagePullPolicy())),
 			),
 		))
 }
 
+// IsOperatorInitContainer returns true if the init container with the given name is managed by the operator.
+func IsOperatorInitContainer(name string) bool {
+	return name == versionUpgradeHookName || name == ReadinessProbeContainerName
+}
+
+// agentHealthStatusFilePath returns the path of the agent health status file as seen from the agent container.
+func agentHealthStatusFilePath() string {
+	return path.Join(agentHealthStatusMountPath, agentHealthStatusFileName)
+}
+
+// mongodHealthStatusFilePath returns the path of the agent health status file as seen from the mongod container.
+func mongodHealthStatusFilePath() string {
+	return path.Join(mongodHealthStatusMountPath, agentHealthStatusFileName)
+}
+
+// automationConfigMountPath returns the path the automation config Secret is mounted at in the agent container.
+func automationConfigMountPath() string {
+	return envvar.GetEnvOrDefault(AutomationConfigMountPathEnv, defaultAutomationConfigMountPath)
+}
+
+// clusterFilePath returns the path of the automation config file the agent reads, which is the key of the
+// automation config Secret within its mount path.
+func clusterFilePath() string {
+	return path.Join(automationConfigMountPath(), automationconfig.ConfigKey)
+}
+
 func BaseAgentCommand() string {
-	return "agent/mongodb-agent -cluster=" + clusterFilePath + " -healthCheckFilePath=" + agentHealthStatusFilePathValue + " -serveStatusPort=5000"
+	return "agent/mongodb-agent -cluster=" + clusterFilePath() + " -healthCheckFilePath=" + agentHealthStatusFilePath() + " -serveStatusPort=" + strconv.Itoa(AgentStatusPort)
 }
 
-func AutomationAgentCommand() []string {
-	return []string{"/bin/bash", "-c", MongodbUserCommand + BaseAgentCommand() + automationAgentOptions}
+// AutomationAgentCommand returns the command of the mongodb-agent container, the additional
+// flags are appended to the agent options, e.g. "-httpsCAFile=/path/to/ca.crt".
+func AutomationAgentCommand(additionalFlags ...string) []string {
+	options := automationAgentOptions
+	for _, flag := range additionalFlags {
+		options += " " + flag
+	}
+	return []string{"/bin/bash", "-c", MongodbUserCommand + BaseAgentCommand() + options}
 }
 
-func mongodbAgentContainer(automationConfigSecretName string, volumeMounts []corev1.VolumeMount) container.Modification {
+// AgentLogFlags returns the flags of the mongodb-agent which configure its log file and log rotation. The log file
+// is only set if the logs are not in the default directory of the agent, so that the command of the agent stays the
+// same for the resources which don't configure it.
+func AgentLogFlags(mdb MongoDBStatefulSetOwner) []string {
+	var flags []string
+	if logsPath := mdb.LogsPath(); logsPath != automationconfig.DefaultAgentLogPath {
+		flags = append(flags, "-logFile="+path.Join(logsPath, agentLogFileName))
+	}
+	if hours := mdb.GetAgentMaxLogFileDurationHours(); hours > 0 {
+		flags = append(flags, fmt.Sprintf("-maxLogFileDurationHrs=%d", hours))
+	}
+	if sizeMB := mdb.GetAgentMaxLogFileSizeMB(); sizeMB > 0 {
+		flags = append(flags, fmt.Sprintf("-maxLogFileSize=%d", int64(sizeMB)*1024*1024))
+	}
+	if files := mdb.GetAgentMaxLogFiles(); files > 0 {
+		flags = append(flags, fmt.Sprintf("-maxLogFiles=%d", files))
+	}
+	return flags
+}
+
+func mongodbAgentContainer(automationConfigSecretName, logsPath string, agentFlags []string, volumeMounts []corev1.VolumeMount, pullPolicy corev1.PullPolicy) container.Modification {
 	securityContext := container.NOOP()
 	managedSecurityContext := envvar.ReadBool(ManagedSecurityContextEnv)
 	if !managedSecurityContext {
@@ -189,12 +300,12 @@ func mongodbAgentContainer(automationConfigSecretName string, volumeMounts []cor
 	return container.Apply(
 		container.WithName(AgentName),
 		container.WithImage(os.Getenv(AgentImageEnv)),
-		container.WithImagePullPolicy(corev1.PullAlways),
+		container.WithImagePullPolicy(pullPolicy),
 		container.WithReadinessProbe(DefaultReadiness()),
-		container.WithResourceRequirements(resourcerequirements.Defaults()),
+		container.WithResourceRequirements(resourcerequirements.AgentDefaults()),
 		container.WithVolumeMounts(volumeMounts),
 		securityContext,
-		container.WithCommand(AutomationAgentCommand()),
+		container.WithCommand(AutomationAgentCommand(agentFlags...)),
 		container.WithEnvs(
 			corev1.EnvVar{
 				Name:  headlessAgentEnv,
@@ -215,54 +326,95 @@ func mongodbAgentContainer(automationConfigSecretName string, volumeMounts []cor
 			},
 			corev1.EnvVar{
 				Name:  agentHealthStatusFilePathEnv,
-				Value: agentHealthStatusFilePathValue,
+				Value: agentHealthStatusFilePath(),
+			},
+			corev1.EnvVar{
+				Name:  readinessProbeLogFilePathEnv,
+				Value: path.Join(logsPath, readinessProbeLogFileName),
 			},
 		),
 	)
 }
 
-func versionUpgradeHookInit(volumeMount []corev1.VolumeMount) container.Modification {
+func versionUpgradeHookInit(volumeMount []corev1.VolumeMount, pullPolicy corev1.PullPolicy) container.Modification {
 	return container.Apply(
 		container.WithName(versionUpgradeHookName),
 		container.WithCommand([]string{"cp", "version-upgrade-hook", "/hooks/version-upgrade"}),
 		container.WithImage(os.Getenv(VersionUpgradeHookImageEnv)),
-		container.WithImagePullPolicy(corev1.PullAlways),
+		container.WithImagePullPolicy(pullPolicy),
+		container.WithResourceRequirements(resourcerequirements.VersionUpgradeHookDefaults()),
 		container.WithVolumeMounts(volumeMount),
 	)
 }
 
 func DefaultReadiness() probes.Modification {
 	return probes.Apply(
+		probes.Reset(),
 		probes.WithExecCommand([]string{readinessProbePath}),
 		probes.WithFailureThreshold(40),
 		probes.WithInitialDelaySeconds(5),
 	)
 }
 
-func dataPvc(dataVolumeName string) persistentvolumeclaim.Modification {
+// DefaultAgentLiveness returns the liveness probe of the mongodb-agent container. It checks that the
+// agent serves its status port and is conservative, so that the agent is not restarted while it
+// performs long running operations such as version upgrades.
+func DefaultAgentLiveness() probes.Modification {
+	return probes.Apply(
+		probes.Reset(),
+		probes.WithHandler(corev1.Handler{
+			TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(AgentStatusPort)},
+		}),
+		probes.WithInitialDelaySeconds(60),
+		probes.WithPeriodSeconds(30),
+		probes.WithTimeoutSeconds(5),
+		// 20 * 30s = 10 minutes before the container is restarted.
+		probes.WithFailureThreshold(20),
+	)
+}
+
+// DefaultMongodStartup returns the startup probe for the mongod container listening on the given port. It allows
+// mongod a generous amount of time to start, e.g. to perform WiredTiger recovery of a
+// large data set, before the kubelet considers the container as failed.
+func DefaultMongodStartup(port int) probes.Modification {
+	return probes.Apply(
+		probes.Reset(),
+		probes.WithHandler(corev1.Handler{
+			TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(port)},
+		}),
+		probes.WithInitialDelaySeconds(5),
+		probes.WithPeriodSeconds(10),
+		// 180 * 10s = 30 minutes before the container is restarted.
+		probes.WithFailureThreshold(180),
+	)
+}
+
+func dataPvc(dataVolumeName string, accessMode corev1.PersistentVolumeAccessMode, metadata persistentvolumeclaim.Modification) persistentvolumeclaim.Modification {
 	return persistentvolumeclaim.Apply(
+		metadata,
 		persistentvolumeclaim.WithName(dataVolumeName),
-		persistentvolumeclaim.WithAccessModes(corev1.ReadWriteOnce),
+		persistentvolumeclaim.WithAccessModes(accessMode),
 		persistentvolumeclaim.WithResourceRequests(resourcerequirements.BuildDefaultStorageRequirements()),
 	)
 }
 
-func logsPvc(logsVolumeName string) persistentvolumeclaim.Modification {
+func logsPvc(logsVolumeName string, accessMode corev1.PersistentVolumeAccessMode, metadata persistentvolumeclaim.Modification) persistentvolumeclaim.Modification {
 	return persistentvolumeclaim.Apply(
+		metadata,
 		persistentvolumeclaim.WithName(logsVolumeName),
-		persistentvolumeclaim.WithAccessModes(corev1.ReadWriteOnce),
+		persistentvolumeclaim.WithAccessModes(accessMode),
 		persistentvolumeclaim.WithResourceRequests(resourcerequirements.BuildStorageRequirements("2G")),
 	)
 }
 
 // readinessProbeInit returns a modification function which will add the readiness probe container.
 // this container will copy the readiness probe binary into the /opt/scripts directory.
-func readinessProbeInit(volumeMount []corev1.VolumeMount) container.Modification {
+func readinessProbeInit(volumeMount []corev1.VolumeMount, pullPolicy corev1.PullPolicy) container.Modification {
 	return container.Apply(
 		container.WithName(ReadinessProbeContainerName),
 		container.WithCommand([]string{"cp", "/probes/readinessprobe", "/opt/scripts/readinessprobe"}),
 		container.WithImage(os.Getenv(ReadinessProbeImageEnv)),
-		container.WithImagePullPolicy(corev1.PullAlways),
+		container.WithImagePullPolicy(pullPolicy),
 		container.WithVolumeMounts(volumeMount),
 	)
 }
@@ -276,27 +428,48 @@ func getMongoDBImage(version string) string {
 	return fmt.Sprintf("%s/%s:%s", repoUrl, mongoImageName, version)
 }
 
-func mongodbContainer(version string, volumeMounts []corev1.VolumeMount) container.Modification {
-	mongoDbCommand := fmt.Sprintf(`
+// MongodCommand returns the shell script the mongod container runs by default. It runs the version upgrade
+// post-hook, waits for the agent to write the configuration and the keyfile and starts mongod in the foreground.
+// Arguments for mongod can be appended to the script, which ends with the mongod command.
+func MongodCommand(dataPath, logsPath string, runVersionUpgradeHook bool) string {
+	// the agent writes the mongod configuration file into the dbPath of the process.
+	automationconfFilePath := path.Join(dataPath, automationconfFileName)
+
+	versionUpgradeHookCommand := ""
+	if runVersionUpgradeHook {
+		versionUpgradeHookCommand = `
 #run post-start hook to handle version changes
-/hooks/version-upgrade
+` + VersionUpgradeHookCommand + `
+`
+	}
 
+	return fmt.Sprintf(`%s
 # wait for config and keyfile to be created by the agent
  while ! [ -f %s -a -f %s ]; do sleep 3 ; done ; sleep 2 ;
 
 # with mongod configured to append logs, we need to provide them to stdout as
 # mongod does not write to stdout and a log file
-tail -F /var/log/mongodb-mms-automation/mongodb.log > /dev/stdout &
+tail -F %s > /dev/stdout &
 
 # start mongod with this configuration
-exec mongod -f %s;
+exec mongod -f %s`, versionUpgradeHookCommand, automationconfFilePath, keyfileFilePath, path.Join(logsPath, automationconfig.MongodLogFileName), automationconfFilePath)
+}
 
-`, automationconfFilePath, keyfileFilePath, automationconfFilePath)
+// mongodbContainer returns the mongod container. The additional arguments are passed to the shell as positional
+// parameters, which are appended to the mongod command with "$@", so that they are never interpreted by the shell.
+func mongodbContainer(version, dataPath, logsPath string, volumeMounts []corev1.VolumeMount, runVersionUpgradeHook bool, additionalArgs []string, port int) container.Modification {
+	mongodArgs := ""
+	var containerArgs []string
+	if len(additionalArgs) > 0 {
+		mongodArgs = ` "$@"`
+		// the first argument is $0, the name of the shell
+		containerArgs = append([]string{"mongod"}, additionalArgs...)
+	}
 
 	containerCommand := []string{
 		"/bin/sh",
 		"-c",
-		mongoDbCommand,
+		MongodCommand(dataPath, logsPath, runVersionUpgradeHook) + mongodArgs + ";\n\n",
 	}
 
 	securityContext := container.NOOP()
@@ -310,10 +483,12 @@ exec mongod -f %s;
 		container.WithImage(getMongoDBImage(version)),
 		container.WithResourceRequirements(resourcerequirements.Defaults()),
 		container.WithCommand(containerCommand),
+		container.WithArgs(containerArgs),
+		container.WithStartupProbe(DefaultMongodStartup(port)),
 		container.WithEnvs(
 			corev1.EnvVar{
 				Name:  agentHealthStatusFilePathEnv,
-				Value: "/healthstatus/agent-health-status.json",
+				Value: mongodHealthStatusFilePath(),
 			},
 		),
 		container.WithVolumeMounts(volumeMounts),
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"math"
+	"strings"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/statefulset"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	bytesPerGB = 1 << 30
+
+	// minCacheSizeGB is the smallest WiredTiger cache size mongod configures by default.
+	minCacheSizeGB = 0.25
+)
+
+// getWiredTigerCacheModification sizes the WiredTiger cache of the processes after the memory limit of their
+// mongod container, with the default formula of mongod: 50% of the limit minus 1GB, and at least 0.25GB. Otherwise
+// mongod may size the cache after the memory of the node, and be killed once it exceeds the limit of the container.
+// Processes without a memory limit are skipped, and a cache size configured in the additionalMongodConfig is
+// applied afterwards, which takes precedence.
+func getWiredTigerCacheModification(mdb mdbv1.MongoDBCommunity) automationconfig.Modification {
+	cacheSizeGB := wiredTigerCacheSizeGB(buildStatefulSetModificationFunction(mdb))
+	arbiterCacheSizeGB := cacheSizeGB
+	if mdb.HasSeparateArbiters() {
+		arbiterCacheSizeGB = wiredTigerCacheSizeGB(buildArbiterStatefulSetModificationFunction(mdb))
+	}
+	arbiterPrefix := mdb.ArbiterNamespacedName().Name + "-"
+
+	return func(ac *automationconfig.AutomationConfig) {
+		for i := range ac.Processes {
+			if mdb.HasSeparateArbiters() && strings.HasPrefix(ac.Processes[i].Name, arbiterPrefix) {
+				ac.Processes[i].SetWiredTigerCache(arbiterCacheSizeGB)
+				continue
+			}
+			ac.Processes[i].SetWiredTigerCache(cacheSizeGB)
+		}
+	}
+}
+
+// wiredTigerCacheSizeGB returns the WiredTiger cache size for the memory limit of the mongod container of the
+// given StatefulSet, or nil if it has none. The size is rounded down to two decimals.
+func wiredTigerCacheSizeGB(stsModification statefulset.Modification) *float32 {
+	sts := appsv1.StatefulSet{}
+	stsModification(&sts)
+
+	mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+	if mongodContainer == nil {
+		return nil
+	}
+	memoryLimit, ok := mongodContainer.Resources.Limits[corev1.ResourceMemory]
+	if !ok || memoryLimit.IsZero() {
+		return nil
+	}
+
+	cacheSizeGB := math.Floor((float64(memoryLimit.Value())/bytesPerGB-1)/2*100) / 100
+	if cacheSizeGB < minCacheSizeGB {
+		cacheSizeGB = minCacheSizeGB
+	}
+	size := float32(cacheSizeGB)
+	return &size
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"fmt"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/agent"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/versions"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// allowFCVDowngradeAnnotation must be set to "true" on the resource to allow lowering the featureCompatibilityVersion.
+	allowFCVDowngradeAnnotation = "mongodb.com/allow-fcv-downgrade"
+)
+
+// desiredFeatureCompatibilityVersion returns the featureCompatibilityVersion the processes should be configured with.
+func desiredFeatureCompatibilityVersion(mdb mdbv1.MongoDBCommunity) string {
+	if mdb.Spec.FeatureCompatibilityVersion != "" {
+		return mdb.Spec.FeatureCompatibilityVersion
+	}
+	return versions.CalculateFeatureCompatibilityVersion(mdb.Spec.Version)
+}
+
+// ensureFeatureCompatibilityVersionDowngrade guards against lowering the featureCompatibilityVersion of the deployment,
+// which can be data-destructive, unless it has been explicitly allowed with an annotation. If the MongoDB version is
+// changed at the same time, the featureCompatibilityVersion is lowered first on its own, and the returned boolean
+// indicates whether the agents have reached this state and the version change can proceed.
+func (r ReplicaSetReconciler) ensureFeatureCompatibilityVersionDowngrade(mdb mdbv1.MongoDBCommunity) (bool, error) {
+	acNsName := types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace}
+	currentAC, err := automationconfig.ReadFromSecret(r.client, acNsName)
+	if err != nil {
+		return false, fmt.Errorf("could not read existing automation config: %s", err)
+	}
+	if len(currentAC.Processes) == 0 {
+		return true, nil
+	}
+
+	currentFCV := currentAC.Processes[0].FeatureCompatibilityVersion
+	desiredFCV := desiredFeatureCompatibilityVersion(mdb)
+	if currentFCV == "" || desiredFCV == "" {
+		return true, nil
+	}
+
+	isDowngrade, err := versions.IsFeatureCompatibilityVersionDowngrade(currentFCV, desiredFCV)
+	if err != nil {
+		return false, err
+	}
+
+	downgradeAllowed := mdb.Annotations[allowFCVDowngradeAnnotation] == "true"
+	isChangingVersion := currentAC.Processes[0].Version != mdb.Spec.Version
+
+	if !isDowngrade {
+		if downgradeAllowed && isChangingVersion {
+			// the featureCompatibilityVersion may have been lowered in a previous reconciliation,
+			// it must have been applied before the version is changed.
+			return r.allAgentsReachedGoalState(mdb, currentAC.Version)
+		}
+		return true, nil
+	}
+
+	if !downgradeAllowed {
+		return false, fmt.Errorf(`featureCompatibilityVersion would be lowered from %s to %s, which can be data-destructive if features incompatible with %s are in use. `+
+			`Set the annotation %s: "true" on the resource to proceed`, currentFCV, desiredFCV, desiredFCV, allowFCVDowngradeAnnotation)
+	}
+
+	if !isChangingVersion {
+		// the featureCompatibilityVersion is changed on its own with the rest of the automation config.
+		return true, nil
+	}
+
+	r.log.Infof("Lowering featureCompatibilityVersion from %s to %s before changing the MongoDB version", currentFCV, desiredFCV)
+	for i := range currentAC.Processes {
+		currentAC.Processes[i].FeatureCompatibilityVersion = desiredFCV
+	}
+	currentAC.Version++
+
+	ac, err := automationconfig.EnsureSecret(r.client, acNsName, mdb.GetOwnerReferences(), currentAC)
+	if err != nil {
+		return false, fmt.Errorf("could not update the featureCompatibilityVersion in the automation config: %s", err)
+	}
+
+	return r.allAgentsReachedGoalState(mdb, ac.Version)
+}
+
+// allAgentsReachedGoalState returns whether the agents of all the pods have reached the given automation config version.
+func (r ReplicaSetReconciler) allAgentsReachedGoalState(mdb mdbv1.MongoDBCommunity, acVersion int) (bool, error) {
+	sts, err := r.client.GetStatefulSet(mdb.NamespacedName())
+	if err != nil {
+		return false, fmt.Errorf("failed to get StatefulSet: %s", err)
+	}
+	if isPreReadinessInitContainerStatefulSet(sts) {
+		return true, nil
+	}
+	return agent.AllReachedGoalState(sts, r.client, mdb.StatefulSetReplicasThisReconciliation(), acVersion, r.log)
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/replicaset"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// memberHealthChecksEnv enables the health checks which connect to the members of the replica sets with the
+	// credentials of the agent, their results are surfaced as conditions in the status of the resources.
+	memberHealthChecksEnv = "ENABLE_MEMBER_HEALTH_CHECKS"
+
+	configVersionsDivergeReason = "ConfigVersionsDiverge"
+	configVersionsAgreeReason   = "ConfigVersionsAgree"
+)
+
+// checkConfigVersions sets the Degraded condition of the resource after whether its members are on the same version
+// of the replica set config. Members on different versions, e.g. after a partial reconfig, may not agree on the
+// members of the replica set although their pods are ready. The members catch up with a reconfig within seconds,
+// so the condition is only set once the versions diverge in two consecutive refreshes. The condition is kept as it
+// is if no versions have been fetched.
+func (r ReplicaSetReconciler) checkConfigVersions(nsName types.NamespacedName, versions replicaset.ConfigVersions) error {
+	if len(versions) == 0 {
+		return nil
+	}
+	if !versions.Diverge() {
+		r.divergingConfigVersions.Delete(nsName)
+	} else if _, diverged := r.divergingConfigVersions.LoadOrStore(nsName, true); !diverged {
+		r.log.Debugf("The members of %s are on different replica set config versions, checking again with the next refresh", nsName)
+		return nil
+	}
+	condition := configVersionsCondition(versions)
+	return r.updateDegradedCondition(nsName, &condition)
+}
+
+// configVersionsCondition returns the Degraded condition describing the config versions of the members.
+func configVersionsCondition(versions replicaset.ConfigVersions) metav1.Condition {
+	hosts := make([]string, 0, len(versions))
+	for host := range versions {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	if !versions.Diverge() {
+		return metav1.Condition{
+			Type:    mdbv1.ConditionDegraded,
+			Status:  metav1.ConditionFalse,
+			Reason:  configVersionsAgreeReason,
+			Message: fmt.Sprintf("The members which can be reached are on replica set config version %d", versions[hosts[0]]),
+		}
+	}
+
+	memberVersions := make([]string, len(hosts))
+	for i, host := range hosts {
+		memberVersions[i] = fmt.Sprintf("%s: %d", host, versions[host])
+	}
+	return metav1.Condition{
+		Type:    mdbv1.ConditionDegraded,
+		Status:  metav1.ConditionTrue,
+		Reason:  configVersionsDivergeReason,
+		Message: fmt.Sprintf("The members are on different replica set config versions: %s", strings.Join(memberVersions, ", ")),
+	}
+}
+
+// updateDegradedCondition sets the Degraded condition in the status of the resource if it has changed,
+// the condition is removed if it is nil.
+func (r ReplicaSetReconciler) updateDegradedCondition(nsName types.NamespacedName, condition *metav1.Condition) error {
+	unlock := r.lockResource(nsName)
+	defer unlock()
+
+	mdb := mdbv1.MongoDBCommunity{}
+	if err := r.client.Get(context.TODO(), nsName, &mdb); err != nil {
+		return err
+	}
+
+	current := meta.FindStatusCondition(mdb.Status.Conditions, mdbv1.ConditionDegraded)
+	if condition == nil {
+		if current == nil {
+			return nil
+		}
+		meta.RemoveStatusCondition(&mdb.Status.Conditions, mdbv1.ConditionDegraded)
+		return r.client.Status().Update(context.TODO(), &mdb)
+	}
+
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason && current.Message == condition.Message {
+		return nil
+	}
+	condition.ObservedGeneration = mdb.Generation
+	meta.SetStatusCondition(&mdb.Status.Conditions, *condition)
+	return r.client.Status().Update(context.TODO(), &mdb)
+}
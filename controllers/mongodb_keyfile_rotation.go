@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/agent"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/versions"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// keyfileRotationPhase is the phase of the rotation of the keyfile of a deployment. mongod reads its keyfile on
+// startup, every phase is rolled out by restarting the members one by one. The keyfiles of two subsequent phases
+// are compatible, so that the members can always authenticate to each other during the rolling restarts.
+type keyfileRotationPhase string
+
+const (
+	// keyfileRotationAcceptNewKey is the phase in which the members accept the new key, while they still
+	// authenticate to the other members with the old one.
+	keyfileRotationAcceptNewKey keyfileRotationPhase = "AcceptNewKey"
+	// keyfileRotationUseNewKey is the phase in which the members authenticate to the other members with the
+	// new key, while they still accept the old one.
+	keyfileRotationUseNewKey keyfileRotationPhase = "UseNewKey"
+	// keyfileRotationCompleted is the phase in which the members only accept the new key.
+	keyfileRotationCompleted keyfileRotationPhase = "Completed"
+)
+
+// ensureKeyfileRotation advances the rotation of the keyfile once the keyfile secret has changed. The keyfile
+// in the automation config is not replaced at once, as the members which have not been restarted yet would
+// reject the new key: the new key is first added to the keyfile, then used by the members, and finally the old
+// key is removed. The next phase is only started once the previous one has been rolled out to all the members.
+func (r ReplicaSetReconciler) ensureKeyfileRotation(mdb *mdbv1.MongoDBCommunity) error {
+	// watch the keyfile secret managed by the operator so that its changes trigger a reconciliation.
+	r.secretWatcher.Watch(mdb.GetAgentKeyfileSecretNamespacedName(), mdb.NamespacedName())
+
+	if supported, err := versions.IsKeyfileRotationSupported(mdb.Spec.Version); err != nil || !supported || mdb.IsChangingVersion() {
+		// the keyfile is replaced at once, or the rotation continues once the version change has completed.
+		return err
+	}
+
+	keyfile, err := scram.ReadAgentKeyfile(r.client, mdb)
+	if err != nil || keyfile == "" {
+		return err
+	}
+	desiredKeys := scram.KeyfileKeys(keyfile)
+	if len(desiredKeys) == 0 {
+		return fmt.Errorf("the keyfile secret of the resource holds no key")
+	}
+	desiredKey := desiredKeys[0]
+
+	currentAC, err := automationconfig.ReadFromSecret(r.client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	if err != nil {
+		return fmt.Errorf("could not read existing automation config: %s", err)
+	}
+	currentKeys := scram.KeyfileKeys(currentAC.Auth.Key)
+	if len(currentKeys) == 0 {
+		// nothing has been deployed yet, the keyfile is used right away.
+		return nil
+	}
+
+	phase := keyfileRotationPhase(annotations.GetAnnotation(mdb, annotations.KeyfileRotationPhase))
+	var nextPhase keyfileRotationPhase
+	switch phase {
+	case keyfileRotationAcceptNewKey:
+		if len(currentKeys) != 2 || currentKeys[1] != desiredKey {
+			// the keyfile has changed again, the members must accept the latest key instead.
+			nextPhase = keyfileRotationAcceptNewKey
+		} else if rolledOut, err := r.isKeyfileRotationRolledOut(*mdb, currentAC); err != nil {
+			return err
+		} else if rolledOut {
+			nextPhase = keyfileRotationUseNewKey
+		}
+	case keyfileRotationUseNewKey:
+		if currentKeys[0] != desiredKey {
+			// the keyfile has changed again, the members keep using the key they use now.
+			nextPhase = keyfileRotationAcceptNewKey
+		} else if rolledOut, err := r.isKeyfileRotationRolledOut(*mdb, currentAC); err != nil {
+			return err
+		} else if rolledOut {
+			nextPhase = keyfileRotationCompleted
+		}
+	default:
+		if len(currentKeys) != 1 || currentKeys[0] != desiredKey {
+			nextPhase = keyfileRotationAcceptNewKey
+		}
+	}
+	if nextPhase == "" {
+		return nil
+	}
+
+	step := 1
+	if currentStep, err := strconv.Atoi(annotations.GetAnnotation(mdb, annotations.KeyfileRotationStep)); err == nil {
+		step = currentStep + 1
+	}
+	r.log.Infof("Rotating the keyfile, moving to phase %s", nextPhase)
+	return annotations.SetAnnotations(mdb, map[string]string{
+		annotations.KeyfileRotationPhase: string(nextPhase),
+		annotations.KeyfileRotationStep:  strconv.Itoa(step),
+	}, r.client)
+}
+
+// isKeyfileRotationRolledOut returns true if all the members have been restarted with the keyfile of the current
+// phase of the rotation, and their agents have reached the goal state of the current automation config.
+func (r ReplicaSetReconciler) isKeyfileRotationRolledOut(mdb mdbv1.MongoDBCommunity, currentAC automationconfig.AutomationConfig) (bool, error) {
+	sts, err := r.client.GetStatefulSet(mdb.NamespacedName())
+	if err != nil {
+		return false, fmt.Errorf("failed to get StatefulSet: %s", err)
+	}
+	if sts.Spec.Template.Annotations[annotations.KeyfileRotationStep] != annotations.GetAnnotation(&mdb, annotations.KeyfileRotationStep) {
+		return false, nil
+	}
+
+	ready, err := r.statefulSetsReady(mdb)
+	if err != nil || !ready {
+		return false, err
+	}
+	return agent.AllReachedGoalState(sts, r.client, mdb.StatefulSetReplicasThisReconciliation(), currentAC.Version, r.log)
+}
+
+// isRotatingKeyfile returns true if a rotation of the keyfile has not completed yet.
+func isRotatingKeyfile(mdb mdbv1.MongoDBCommunity) bool {
+	phase := keyfileRotationPhase(annotations.GetAnnotation(&mdb, annotations.KeyfileRotationPhase))
+	return phase == keyfileRotationAcceptNewKey || phase == keyfileRotationUseNewKey
+}
+
+// getKeyfileForRotation returns the contents of the keyfile for the current phase of the rotation of the keyfile,
+// given the contents of the keyfile which has been deployed and of the desired keyfile. A new key is only deployed
+// once its rotation has started, e.g. it is held back while the MongoDB version changes. It is only replaced at once
+// for the MongoDB versions which don't support keyfiles with multiple keys.
+func getKeyfileForRotation(mdb mdbv1.MongoDBCommunity, currentKeyfile, desiredKeyfile string) string {
+	currentKeys, desiredKeys := scram.KeyfileKeys(currentKeyfile), scram.KeyfileKeys(desiredKeyfile)
+	if len(currentKeys) == 0 || len(desiredKeys) == 0 {
+		return desiredKeyfile
+	}
+	desiredKey := desiredKeys[0]
+
+	switch keyfileRotationPhase(annotations.GetAnnotation(&mdb, annotations.KeyfileRotationPhase)) {
+	case keyfileRotationAcceptNewKey:
+		// the members keep authenticating with the key they use now.
+		if currentKeys[0] != desiredKey {
+			return scram.KeyfileContents(currentKeys[0], desiredKey)
+		}
+	case keyfileRotationUseNewKey:
+		for _, key := range currentKeys {
+			if key != desiredKey {
+				return scram.KeyfileContents(desiredKey, key)
+			}
+		}
+	}
+
+	for _, key := range currentKeys {
+		if key == desiredKey {
+			// the members already accept the desired key, e.g. once the rotation has completed.
+			return desiredKeyfile
+		}
+	}
+	if supported, err := versions.IsKeyfileRotationSupported(mdb.Spec.Version); err == nil && !supported && !mdb.IsChangingVersion() {
+		return desiredKeyfile
+	}
+	// the rotation has not started yet.
+	return currentKeyfile
+}
+
+// buildKeyfileRotationPodSpecModification propagates the step of the keyfile rotation into the pod template, so
+// that the pods are restarted one by one with the keyfile of every phase of the rotation.
+func buildKeyfileRotationPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	step := annotations.GetAnnotation(&mdb, annotations.KeyfileRotationStep)
+	if step == "" {
+		return podtemplatespec.NOOP()
+	}
+	return podtemplatespec.WithAnnotation(annotations.KeyfileRotationStep, step)
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"fmt"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/container"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// podIPEnv holds the IP address of the pod, the agent identifies its process by it when the members are
+// addressed by the IP addresses of their pods.
+const podIPEnv = "POD_IP"
+
+// getMemberAddressingModification returns a modification which replaces the hostnames of the processes with
+// the IP addresses of their pods, if the members are addressed by their pod IPs. A process whose pod has no IP
+// address yet keeps its hostname of the current automation config. The pods are watched, so that the automation
+// config is updated once their IP addresses change.
+func (r ReplicaSetReconciler) getMemberAddressingModification(mdb mdbv1.MongoDBCommunity, currentAC automationconfig.AutomationConfig) (automationconfig.Modification, error) {
+	if mdb.MemberAddressingMode() != mdbv1.PodIPAddressing {
+		return automationconfig.NOOP(), nil
+	}
+
+	podIPs := map[string]string{}
+	for _, podName := range memberPodNames(mdb) {
+		podNsName := types.NamespacedName{Name: podName, Namespace: mdb.Namespace}
+		r.podWatcher.Watch(podNsName, mdb.NamespacedName())
+
+		pod, err := r.client.GetPod(podNsName)
+		if err != nil {
+			if apiErrors.IsNotFound(err) {
+				continue
+			}
+			return automationconfig.NOOP(), fmt.Errorf("could not get pod %s: %s", podNsName, err)
+		}
+		if pod.Status.PodIP != "" {
+			podIPs[podName] = pod.Status.PodIP
+		}
+	}
+
+	currentHostnames := map[string]string{}
+	for _, process := range currentAC.Processes {
+		currentHostnames[process.Name] = process.HostName
+	}
+
+	return func(ac *automationconfig.AutomationConfig) {
+		for i := range ac.Processes {
+			if podIP, ok := podIPs[ac.Processes[i].Name]; ok {
+				ac.Processes[i].HostName = podIP
+			} else if hostname, ok := currentHostnames[ac.Processes[i].Name]; ok {
+				ac.Processes[i].HostName = hostname
+			}
+		}
+	}, nil
+}
+
+// processHosts returns the "<hostname>:<port>" address of each process in the automation config, keyed by the name
+// of the process, which is the name of its pod. The addresses reflect the cluster domain, the pod IPs and the hosts
+// the members advertise.
+func processHosts(ac automationconfig.AutomationConfig) map[string]string {
+	hosts := map[string]string{}
+	for _, process := range ac.Processes {
+		hosts[process.Name] = fmt.Sprintf("%s:%d", process.HostName, process.Port())
+	}
+	return hosts
+}
+
+// memberHosts returns the addresses of the data-bearing members in the automation config, or the hosts of the
+// resource if the automation config has not been deployed yet.
+func memberHosts(mdb mdbv1.MongoDBCommunity, ac automationconfig.AutomationConfig) []string {
+	if len(ac.ReplicaSets) == 0 {
+		return mdb.Hosts()
+	}
+	processes := processHosts(ac)
+	var hosts []string
+	for _, member := range ac.ReplicaSets[0].Members {
+		if host, ok := processes[member.Host]; ok && !member.ArbiterOnly {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// deployedMemberHosts returns the addresses of the data-bearing members in the deployed automation config.
+func (r ReplicaSetReconciler) deployedMemberHosts(mdb mdbv1.MongoDBCommunity) ([]string, error) {
+	ac, err := automationconfig.ReadFromSecret(r.client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	if err != nil {
+		return nil, fmt.Errorf("could not read the automation config: %s", err)
+	}
+	return memberHosts(mdb, ac), nil
+}
+
+// mongoURI returns the connection string of the members in the deployed automation config. The hosts of the
+// resource are used if the automation config could not be read.
+func (r ReplicaSetReconciler) mongoURI(mdb mdbv1.MongoDBCommunity) string {
+	hosts, err := r.deployedMemberHosts(mdb)
+	if err != nil {
+		r.log.Debugf("Could not get the hosts of the members: %s", err)
+		return mdb.MongoURI()
+	}
+	return mdb.MongoURIWithHosts(hosts)
+}
+
+// memberPodNames returns the names of the pods of the processes in the automation config, which are named after them.
+func memberPodNames(mdb mdbv1.MongoDBCommunity) []string {
+	var names []string
+	for i := 0; i < mdb.AutomationConfigMembersThisReconciliation(); i++ {
+		names = append(names, fmt.Sprintf("%s-%d", mdb.Name, i))
+	}
+	if mdb.HasSeparateArbiters() {
+		for i := 0; i < mdb.Spec.Arbiters; i++ {
+			names = append(names, fmt.Sprintf("%s-%d", mdb.ArbiterNamespacedName().Name, i))
+		}
+	}
+	return names
+}
+
+// buildMemberAddressingPodSpecModification publishes the IP address of the pod to the mongodb-agent container
+// through the downward API, if the members are addressed by their pod IPs.
+func buildMemberAddressingPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	if mdb.MemberAddressingMode() != mdbv1.PodIPAddressing {
+		return podtemplatespec.NOOP()
+	}
+
+	return podtemplatespec.WithContainer(construct.AgentName, container.WithEnvs(corev1.EnvVar{
+		Name: podIPEnv,
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				APIVersion: "v1",
+				FieldPath:  "status.podIP",
+			},
+		},
+	}))
+}
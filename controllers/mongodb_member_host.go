@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"go.uber.org/zap"
+)
+
+// memberHostOverrides returns the hostnames the members advertise instead of their own, by the names of their
+// pods. The port is not included, it is the port mongod listens on.
+func memberHostOverrides(mdb mdbv1.MongoDBCommunity) map[string]string {
+	hosts := map[string]string{}
+	for i, c := range mdb.Spec.MemberConfig {
+		if i >= mdb.Spec.Members || c.Host == "" {
+			continue
+		}
+		hostname, _, err := net.SplitHostPort(c.Host)
+		if err != nil {
+			// the host has been validated.
+			continue
+		}
+		hosts[fmt.Sprintf("%s-%d", mdb.Name, i)] = hostname
+	}
+	return hosts
+}
+
+// getMemberHostModification returns a modification which replaces the hostnames of the processes whose member
+// advertises a different host. The members of the replica set config keep referencing their process by its
+// name, the agents build the address of the member in the replica set from the hostname of its process. It is
+// applied after the member addressing, so that the configured hosts take precedence over the pod IPs.
+func getMemberHostModification(mdb mdbv1.MongoDBCommunity) automationconfig.Modification {
+	hosts := memberHostOverrides(mdb)
+	if len(hosts) == 0 {
+		return automationconfig.NOOP()
+	}
+
+	return func(ac *automationconfig.AutomationConfig) {
+		for i := range ac.Processes {
+			host, ok := hosts[ac.Processes[i].Name]
+			if !ok {
+				continue
+			}
+			if host != ac.Processes[i].HostName {
+				zap.S().Warnf("Member %s advertises the host %s instead of %s, the other members replicate through it, so it must be resolvable and routable from within the cluster",
+					ac.Processes[i].Name, host, ac.Processes[i].HostName)
+			}
+			ac.Processes[i].HostName = host
+		}
+	}
+}
+
+// overrideLocalHostFlag returns the flag of the agent which makes it manage the process with the host its member
+// advertises. The pods share the command of the agent, the flag is selected by the hostname of the pod, which
+// is the name of the pod. Pods without a configured host fall back to the IP address of their pod, if the members
+// are addressed by their pod IPs, and otherwise to the hostname of the agent.
+func overrideLocalHostFlag(mdb mdbv1.MongoDBCommunity, hosts map[string]string) string {
+	podNames := make([]string, 0, len(hosts))
+	for podName := range hosts {
+		podNames = append(podNames, podName)
+	}
+	sort.Strings(podNames)
+
+	cases := make([]string, 0, len(hosts)+1)
+	for _, podName := range podNames {
+		cases = append(cases, fmt.Sprintf("%s) echo -overrideLocalHost=%s ;;", podName, hosts[podName]))
+	}
+	if mdb.MemberAddressingMode() == mdbv1.PodIPAddressing {
+		cases = append(cases, "*) echo -overrideLocalHost=${"+podIPEnv+"} ;;")
+	}
+	return fmt.Sprintf(`$(case "$(hostname)" in %s esac)`, strings.Join(cases, " "))
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// getMemberRemovalModification returns a modification which removes the members of spec.membersToRemove from
+// the replica set and disables their processes, so that their agents shut them down. The pods of the members
+// can't be removed from the StatefulSet, which only removes its last pods, so they keep running without a process.
+func getMemberRemovalModification(mdb mdbv1.MongoDBCommunity, currentAC automationconfig.AutomationConfig) automationconfig.Modification {
+	toRemove := map[string]bool{}
+	for _, name := range membersToRemoveThisReconciliation(mdb, currentAC) {
+		toRemove[name] = true
+	}
+	if len(toRemove) == 0 {
+		return automationconfig.NOOP()
+	}
+
+	return func(ac *automationconfig.AutomationConfig) {
+		for i := range ac.Processes {
+			if toRemove[ac.Processes[i].Name] {
+				ac.Processes[i].Disabled = true
+			}
+		}
+		for i := range ac.ReplicaSets {
+			var members []automationconfig.ReplicaSetMember
+			for _, m := range ac.ReplicaSets[i].Members {
+				if !toRemove[m.Host] {
+					members = append(members, m)
+				}
+			}
+			ac.ReplicaSets[i].Members = members
+		}
+	}
+}
+
+// membersToRemoveThisReconciliation returns the members of spec.membersToRemove which are removed by the automation
+// config of this reconciliation: the members without an enabled process and the next one. As with scaling,
+// a single member is removed at a time.
+func membersToRemoveThisReconciliation(mdb mdbv1.MongoDBCommunity, currentAC automationconfig.AutomationConfig) []string {
+	enabled := enabledMembers(currentAC)
+	var members []string
+	removing := false
+	for _, name := range mdb.Spec.MembersToRemove {
+		if !enabled[name] {
+			members = append(members, name)
+		} else if !removing {
+			members = append(members, name)
+			removing = true
+		}
+	}
+	return members
+}
+
+// enabledMembers returns the names of the members whose processes are enabled in the automation config.
+func enabledMembers(ac automationconfig.AutomationConfig) map[string]bool {
+	enabled := map[string]bool{}
+	for _, p := range ac.Processes {
+		if !p.Disabled {
+			enabled[p.Name] = true
+		}
+	}
+	return enabled
+}
+
+// isRemovingMembers returns true if the deployed automation config still has enabled processes for members of
+// spec.membersToRemove, the removal then continues with the next reconciliation.
+func (r ReplicaSetReconciler) isRemovingMembers(mdb mdbv1.MongoDBCommunity) bool {
+	if len(mdb.Spec.MembersToRemove) == 0 {
+		return false
+	}
+	ac, err := automationconfig.ReadFromSecret(r.client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	if err != nil {
+		r.log.Debugf("Could not read the automation config to check the removal of members: %s", err)
+		return true
+	}
+	enabled := enabledMembers(ac)
+	for _, name := range mdb.Spec.MembersToRemove {
+		if enabled[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// holdScaleDownWhileRemovingMembers keeps the current number of members while members of spec.membersToRemove are
+// still being removed, and returns true if a scale down is held back. The StatefulSet removes its last pods when
+// it is scaled down, which would remove healthy members while the replica set is already losing the listed ones.
+// The scale down continues once the listed members have been removed.
+func (r ReplicaSetReconciler) holdScaleDownWhileRemovingMembers(mdb *mdbv1.MongoDBCommunity) bool {
+	if mdb.Spec.Members >= mdb.Status.CurrentMongoDBMembers || !r.isRemovingMembers(*mdb) {
+		return false
+	}
+	r.log.Infof("Holding back the scale down to %d members until the members of membersToRemove have been removed", mdb.Spec.Members)
+	mdb.Spec.Members = mdb.Status.CurrentMongoDBMembers
+	return true
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/agent"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// configConvergenceLag is the number of automation config versions the agent furthest behind is behind
+// the version deployed by the operator, for each resource.
+var configConvergenceLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "mongodb_agent_config_convergence_lag",
+	Help: "Number of automation config versions the agent furthest behind is behind the version deployed by the operator",
+}, []string{"namespace", "name"})
+
+func init() {
+	metrics.Registry.MustRegister(configConvergenceLag)
+}
+
+// updateConfigConvergenceLag sets the convergence lag of the resource, from the version of the automation
+// config and the versions the agents have reached, which the readiness probe publishes on their pods.
+// Agents which haven't published any version yet are behind by the whole version.
+func (r ReplicaSetReconciler) updateConfigConvergenceLag(mdb mdbv1.MongoDBCommunity) {
+	ac, err := automationconfig.ReadFromSecret(r.client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	if err != nil {
+		r.log.Debugf("Could not read the automation config to update the convergence lag: %s", err)
+		return
+	}
+
+	pods, err := r.listMemberPods(mdb)
+	if err != nil {
+		r.log.Debugf("Could not list the pods of the members to update the convergence lag: %s", err)
+		return
+	}
+
+	configConvergenceLag.WithLabelValues(mdb.Namespace, mdb.Name).Set(float64(convergenceLag(ac.Version, pods)))
+}
+
+// convergenceLag returns how many versions the agent furthest behind is behind the given automation config version.
+func convergenceLag(acVersion int, pods []corev1.Pod) int {
+	lag := 0
+	for _, pod := range pods {
+		achieved, _ := agent.AchievedConfigVersion(pod)
+		if acVersion-achieved > lag {
+			lag = acVersion - achieved
+		}
+	}
+	return lag
+}
+
+// deleteConfigConvergenceLag removes the convergence lag of a deleted resource.
+func deleteConfigConvergenceLag(nsName types.NamespacedName) {
+	configConvergenceLag.DeleteLabelValues(nsName.Namespace, nsName.Name)
+}
@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/agent"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/configmap"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/replicaset"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// primaryCacheTTL is how long the primary of a replica set is cached before it is fetched again.
+	primaryCacheTTL = 30 * time.Second
+
+	// primaryTimeout bounds the time spent connecting to the replica set to fetch its primary.
+	primaryTimeout = 10 * time.Second
+
+	// roleLabel is set on the pods of the data-bearing members to the current role of their member,
+	// when the secondary Service is enabled.
+	roleLabel     = "mongodb.com/role"
+	primaryRole   = "primary"
+	secondaryRole = "secondary"
+
+	// notReadyReasonThreshold is how long a pod has to be not ready before the reason reported by
+	// its agent is added to the status, so that members which are only restarting are not reported.
+	notReadyReasonThreshold = 2 * time.Minute
+)
+
+// refreshPrimary fetches the state of the members of the replica set in the background, unless the cached primary
+// is still fresh, records the primary in the status of the resource and labels the pods with the role of their member.
+// Failures are only logged, as neither is required to reconcile the resource.
+func (r ReplicaSetReconciler) refreshPrimary(mdb mdbv1.MongoDBCommunity) {
+	if r.primaryRefreshDisabled || !r.primaryCache.ShouldRefresh(mdb.NamespacedName()) {
+		return
+	}
+
+	opts, err := r.primaryConnectionOptions(mdb)
+	if err != nil {
+		r.log.Debugf("Could not build the options to connect to the replica set: %s", err)
+		return
+	}
+
+	log := r.log
+	go func() {
+		status, err := r.statusGetter.GetStatus(context.Background(), mdb.NamespacedName(), opts)
+		if err != nil {
+			log.Debugf("Could not get the state of the members of the replica set: %s", err)
+			return
+		}
+		states := status.States
+		r.primaryCache.Set(mdb.NamespacedName(), states.Primary())
+
+		if err := r.updateMemberRoles(mdb.NamespacedName(), states); err != nil {
+			log.Warnf("Could not update the roles of the members: %s", err)
+		}
+
+		if !r.memberHealthChecks {
+			r.divergingConfigVersions.Delete(mdb.NamespacedName())
+			if err := r.updateDegradedCondition(mdb.NamespacedName(), nil); err != nil {
+				log.Warnf("Could not remove the Degraded condition: %s", err)
+			}
+			return
+		}
+		if err := r.checkConfigVersions(mdb.NamespacedName(), status.ConfigVersions); err != nil {
+			log.Warnf("Could not check the replica set config versions of the members: %s", err)
+		}
+	}()
+}
+
+// updateMemberRoles sets the primary and the role of each member in the status of the resource, if they have
+// changed, and the role label of the pods if the secondary Service is enabled.
+func (r ReplicaSetReconciler) updateMemberRoles(nsName types.NamespacedName, states replicaset.MemberStates) error {
+	unlock := r.lockResource(nsName)
+	defer unlock()
+
+	mdb := mdbv1.MongoDBCommunity{}
+	if err := r.client.Get(context.TODO(), nsName, &mdb); err != nil {
+		return err
+	}
+	ac, err := automationconfig.ReadFromSecret(r.client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	if err != nil {
+		return fmt.Errorf("could not read the automation config: %s", err)
+	}
+	roles := memberRoles(states, ac)
+	if mdb.Spec.EnableSecondaryService {
+		if err := r.updateRoleLabels(mdb, roles); err != nil {
+			return err
+		}
+	}
+	rolesChanged := setMemberRoles(mdb.Status.Members, roles)
+	if mdb.Status.Primary == states.Primary() && !rolesChanged {
+		return nil
+	}
+	mdb.Status.Primary = states.Primary()
+	return r.client.Status().Update(context.TODO(), &mdb)
+}
+
+// memberRoles returns the role of the member of each pod, keyed by the name of the pod. The role of members
+// which are neither primary nor secondary is their state, e.g. "recovering". The members are matched to their
+// pods by the hosts of the processes in the automation config, as the members may be addressed by their pod IPs
+// or advertise different hosts. Members which are not in the automation config are ignored.
+func memberRoles(states replicaset.MemberStates, ac automationconfig.AutomationConfig) map[string]string {
+	podNames := map[string]string{}
+	for podName, host := range processHosts(ac) {
+		podNames[host] = podName
+	}
+
+	roles := map[string]string{}
+	for host, state := range states {
+		if podName, ok := podNames[host]; ok {
+			roles[podName] = strings.ToLower(state)
+		}
+	}
+	return roles
+}
+
+// setMemberRoles sets the role of each member and returns whether any of them has changed.
+func setMemberRoles(members []mdbv1.MemberStatus, roles map[string]string) bool {
+	changed := false
+	for i := range members {
+		if role := roles[members[i].Name]; members[i].Role != role {
+			members[i].Role = role
+			changed = true
+		}
+	}
+	return changed
+}
+
+// memberStatuses returns the readiness of the pod of each member, sorted by the name of the pods. Nil is returned if
+// the pods could not be listed, so that the members in the status are kept as they are.
+func (r ReplicaSetReconciler) memberStatuses(mdb mdbv1.MongoDBCommunity) []mdbv1.MemberStatus {
+	pods, err := r.listMemberPods(mdb)
+	if err != nil {
+		r.log.Debugf("Could not list the pods of the members: %s", err)
+		return nil
+	}
+	members := make([]mdbv1.MemberStatus, 0, len(pods))
+	for _, pod := range pods {
+		member := mdbv1.MemberStatus{Name: pod.Name, Ready: isPodReady(pod)}
+		if !member.Ready && notReadySince(pod) > notReadyReasonThreshold {
+			member.Message = agent.NotReadyReason(pod)
+		}
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+	return members
+}
+
+// listMemberPods returns the pods of the data-bearing members and of the arbiters, which are labeled with
+// the name of their own Service if they are deployed separately.
+func (r ReplicaSetReconciler) listMemberPods(mdb mdbv1.MongoDBCommunity) ([]corev1.Pod, error) {
+	appLabels := []string{mdb.ServiceName()}
+	if mdb.HasSeparateArbiters() {
+		appLabels = append(appLabels, mdb.ArbiterServiceName())
+	}
+
+	var pods []corev1.Pod
+	for _, app := range appLabels {
+		podList := corev1.PodList{}
+		if err := r.client.List(context.TODO(), &podList, k8sClient.InNamespace(mdb.Namespace), k8sClient.MatchingLabels{"app": app}); err != nil {
+			return nil, err
+		}
+		pods = append(pods, podList.Items...)
+	}
+	return pods, nil
+}
+
+// notReadySince returns how long the pod has not been ready, it is 0 if the pod is ready or has no Ready condition.
+func notReadySince(pod corev1.Pod) time.Duration {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status != corev1.ConditionTrue {
+			return time.Since(condition.LastTransitionTime.Time)
+		}
+	}
+	return 0
+}
+
+// notReadyMessage describes the members which are not ready and the reasons reported by their agents.
+func notReadyMessage(members []mdbv1.MemberStatus) string {
+	var reasons []string
+	for _, member := range members {
+		if member.Message != "" {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", member.Name, member.Message))
+		}
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", members not ready: %s", strings.Join(reasons, "; "))
+}
+
+// isPodReady returns true if the pod has the Ready condition.
+func isPodReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// updateRoleLabels labels the pods of the primary and the secondaries with their role. The label is removed from
+// the pods of members in any other state, e.g. recovering, so that they are not routed to by the secondary Service.
+func (r ReplicaSetReconciler) updateRoleLabels(mdb mdbv1.MongoDBCommunity, memberRoles map[string]string) error {
+	roles := map[string]string{}
+	for podName, role := range memberRoles {
+		if role == primaryRole || role == secondaryRole {
+			roles[podName] = role
+		}
+	}
+
+	pods := corev1.PodList{}
+	if err := r.client.List(context.TODO(), &pods, k8sClient.InNamespace(mdb.Namespace), k8sClient.MatchingLabels{"app": mdb.ServiceName()}); err != nil {
+		return fmt.Errorf("could not list pods: %s", err)
+	}
+	for i := range pods.Items {
+		pod := pods.Items[i]
+		role, hasRole := roles[pod.Name]
+		if pod.Labels[roleLabel] == role {
+			continue
+		}
+		if hasRole {
+			if pod.Labels == nil {
+				pod.Labels = map[string]string{}
+			}
+			pod.Labels[roleLabel] = role
+		} else {
+			delete(pod.Labels, roleLabel)
+		}
+		if err := r.client.Update(context.TODO(), &pod); err != nil {
+			return fmt.Errorf("could not update the role label of pod %s: %s", pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// primaryConnectionOptions returns the options to connect to the replica set with the credentials of the agent.
+func (r ReplicaSetReconciler) primaryConnectionOptions(mdb mdbv1.MongoDBCommunity) (replicaset.ConnectionOptions, error) {
+	password, err := secret.ReadKey(r.client, scram.AgentPasswordKey, mdb.GetAgentPasswordSecretNamespacedName())
+	if err != nil {
+		return replicaset.ConnectionOptions{}, fmt.Errorf("could not read the agent password: %s", err)
+	}
+
+	hosts, err := r.deployedMemberHosts(mdb)
+	if err != nil {
+		return replicaset.ConnectionOptions{}, err
+	}
+
+	opts := replicaset.ConnectionOptions{
+		Hosts:          hosts,
+		ReplicaSetName: mdb.Name,
+		Username:       scram.AgentName,
+		Password:       password,
+	}
+
+	if mdb.Spec.Security.TLS.Enabled {
+		ca, err := configmap.ReadKey(r.client, tlsCACertName, mdb.TLSConfigMapNamespacedName())
+		if err != nil {
+			return replicaset.ConnectionOptions{}, fmt.Errorf("could not read the CA certificate: %s", err)
+		}
+		opts.CACertificate = ca
+	}
+
+	return opts, nil
+}
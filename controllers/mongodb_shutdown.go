@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/container"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/lifecycle"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// defaultTerminationGracePeriodSeconds is the termination grace period of the pods in Kubernetes, the
+	// time mongod has to shut down after the preStop hook has completed.
+	defaultTerminationGracePeriodSeconds = 30
+
+	// minStepDownSeconds is the number of seconds the stepped down primary can't be elected again.
+	minStepDownSeconds = 60
+)
+
+// buildShutdownPodSpecModification adds a preStop hook to the mongod container which steps down the primary
+// before the pod is terminated, if it is enabled. The termination grace period is extended by the time the
+// primary waits for a secondary to catch up, so that mongod still has the default time to shut down.
+func buildShutdownPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	if !mdb.Spec.Shutdown.StepDownPrimary {
+		// the hook and the extended grace period are removed again once it is disabled, a grace period
+		// configured in the StatefulSet override is applied afterwards.
+		return podtemplatespec.Apply(
+			podtemplatespec.WithContainer(construct.MongodbName, func(c *corev1.Container) {
+				if c.Lifecycle != nil {
+					c.Lifecycle.PreStop = nil
+				}
+			}),
+			func(podTemplateSpec *corev1.PodTemplateSpec) {
+				podTemplateSpec.Spec.TerminationGracePeriodSeconds = nil
+			},
+		)
+	}
+
+	return podtemplatespec.Apply(
+		podtemplatespec.WithContainer(construct.MongodbName, container.WithLifecycle(lifecycle.WithPrestopCommand(stepDownCommand(mdb)))),
+		podtemplatespec.WithTerminationGracePeriodSeconds(defaultTerminationGracePeriodSeconds+mdb.GetStepDownTimeoutSeconds()),
+	)
+}
+
+// stepDownCommand returns the command which steps down the mongod process if it is the primary. The shell of
+// the mongod image is used, which is mongosh for newer images. The shell authenticates with the keyfile of
+// the deployment, and connects with TLS if it is enabled.
+func stepDownCommand(mdb mdbv1.MongoDBCommunity) []string {
+	timeout := mdb.GetStepDownTimeoutSeconds()
+	stepDownSeconds := minStepDownSeconds
+	if timeout > stepDownSeconds {
+		stepDownSeconds = timeout
+	}
+	stepDown := fmt.Sprintf("if (db.isMaster().ismaster) { db.adminCommand({replSetStepDown: %d, secondaryCatchUpPeriodSecs: %d}) }", stepDownSeconds, timeout)
+
+	mongoshOptions, mongoOptions := "", ""
+	if mdb.Spec.Security.TLS.Enabled {
+		// the certificates are not issued for localhost, the CA is still verified.
+		caFile := tlsCAMountPath + tlsCACertName
+		mongoshOptions = fmt.Sprintf(" --tls --tlsCAFile %s --tlsAllowInvalidHostnames", caFile)
+		mongoOptions = fmt.Sprintf(" --ssl --sslCAFile %s --sslAllowInvalidHostnames", caFile)
+	}
+
+	script := []string{
+		"if command -v mongosh >/dev/null 2>&1; then set -- mongosh" + mongoshOptions + "; else set -- mongo" + mongoOptions + "; fi",
+		// during a rotation the keyfile is a YAML sequence of keys, which can't contain "-", the first key is used.
+		fmt.Sprintf(`if [ -s %[1]s ]; then set -- "$@" --authenticationDatabase local --username __system --password "$(tr -d '[:space:]' < %[1]s | cut -d- -f2)"; fi`, scram.AutomationAgentKeyFilePathInContainer),
+		fmt.Sprintf(`"$@" --quiet --port %d --eval '%s'`, mdb.GetMongodPort(), stepDown),
+	}
+	return []string{"/bin/sh", "-c", strings.Join(script, "\n")}
+}
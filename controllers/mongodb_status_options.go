@@ -7,6 +7,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -112,6 +113,13 @@ func (o *optionBuilder) withStatefulSetReplicas(members int) *optionBuilder {
 	return o
 }
 
+func (o *optionBuilder) withObservedGeneration(generation int64) *optionBuilder {
+	o.options = append(o.options, observedGenerationOption{
+		generation: generation,
+	})
+	return o
+}
+
 func (o *optionBuilder) withMessage(severityLevel severity, msg string) *optionBuilder {
 	if apierrors.IsTransientMessage(msg) {
 		severityLevel = Debug
@@ -183,3 +191,89 @@ func (s statefulSetReplicasOption) ApplyOption(mdb *mdbv1.MongoDBCommunity) {
 func (s statefulSetReplicasOption) GetResult() (reconcile.Result, error) {
 	return result.OK()
 }
+
+type observedGenerationOption struct {
+	generation int64
+}
+
+func (o observedGenerationOption) ApplyOption(mdb *mdbv1.MongoDBCommunity) {
+	mdb.Status.ObservedGeneration = o.generation
+}
+
+func (o observedGenerationOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+func (o *optionBuilder) withMemberStatuses(members []mdbv1.MemberStatus) *optionBuilder {
+	o.options = append(o.options, memberStatusesOption{
+		members: members,
+	})
+	return o
+}
+
+type memberStatusesOption struct {
+	members []mdbv1.MemberStatus
+}
+
+// ApplyOption replaces the members in the status, their roles are kept as they are only known to the
+// background refresh of the member states. The members are kept as they are if they could not be read.
+func (m memberStatusesOption) ApplyOption(mdb *mdbv1.MongoDBCommunity) {
+	if m.members == nil {
+		return
+	}
+	roles := map[string]string{}
+	for _, member := range mdb.Status.Members {
+		roles[member.Name] = member.Role
+	}
+	members := make([]mdbv1.MemberStatus, len(m.members))
+	for i, member := range m.members {
+		member.Role = roles[member.Name]
+		members[i] = member
+	}
+	mdb.Status.Members = members
+}
+
+func (m memberStatusesOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+func (o *optionBuilder) withTopology(topology *mdbv1.TopologyStatus) *optionBuilder {
+	o.options = append(o.options, topologyOption{
+		topology: topology,
+		now:      metav1.Now(),
+	})
+	return o
+}
+
+type topologyOption struct {
+	topology *mdbv1.TopologyStatus
+	now      metav1.Time
+}
+
+// ApplyOption replaces the topology in the status. The transition times are carried over from the previous
+// topology, unless the version or the feature compatibility version have changed. The topology is kept as it is
+// if it could not be built.
+func (t topologyOption) ApplyOption(mdb *mdbv1.MongoDBCommunity) {
+	if t.topology == nil {
+		return
+	}
+	topology := t.topology.DeepCopy()
+	previous := mdb.Status.Topology
+	if previous == nil {
+		previous = &mdbv1.TopologyStatus{}
+	}
+
+	topology.LastVersionTransitionTime = previous.LastVersionTransitionTime
+	if previous.Version != topology.Version || topology.LastVersionTransitionTime == nil {
+		topology.LastVersionTransitionTime = t.now.DeepCopy()
+	}
+	topology.LastFeatureCompatibilityVersionTransitionTime = previous.LastFeatureCompatibilityVersionTransitionTime
+	if previous.FeatureCompatibilityVersion != topology.FeatureCompatibilityVersion || topology.LastFeatureCompatibilityVersionTransitionTime == nil {
+		topology.LastFeatureCompatibilityVersionTransitionTime = t.now.DeepCopy()
+	}
+	mdb.Status.Topology = topology
+}
+
+func (t topologyOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
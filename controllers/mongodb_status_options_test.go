@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"testing"
+	"time"
 
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -22,6 +23,14 @@ func TestMongoUriOption_ApplyOption(t *testing.T) {
 	assert.Equal(t, "my-uri", mdb.Status.MongoURI, "Status should be updated")
 }
 
+func TestOptionBuilder_ObservedGeneration(t *testing.T) {
+	mdb := newReplicaSet(3, "my-rs", "my-ns")
+
+	statusOptions().withObservedGeneration(4).GetOptions()[0].ApplyOption(&mdb)
+
+	assert.Equal(t, int64(4), mdb.Status.ObservedGeneration)
+}
+
 func TestOptionBuilder_RunningPhase(t *testing.T) {
 	mdb := newReplicaSet(3, "my-rs", "my-ns")
 
@@ -58,3 +67,28 @@ func newReplicaSet(members int, name, namespace string) mdbv1.MongoDBCommunity {
 		},
 	}
 }
+
+func TestTopologyOption_KeepsTransitionTimesOfUnchangedVersions(t *testing.T) {
+	mdb := newReplicaSet(3, "my-rs", "my-ns")
+	before := metav1.NewTime(metav1.Now().Add(-time.Hour))
+	mdb.Status.Topology = &mdbv1.TopologyStatus{
+		AutomationConfigVersion:     1,
+		Version:                     "4.2.6",
+		FeatureCompatibilityVersion: "4.2",
+		LastVersionTransitionTime:   &before,
+		LastFeatureCompatibilityVersionTransitionTime: &before,
+	}
+
+	now := metav1.Now()
+	topologyOption{
+		topology: &mdbv1.TopologyStatus{AutomationConfigVersion: 2, Version: "4.4.0", FeatureCompatibilityVersion: "4.2"},
+		now:      now,
+	}.ApplyOption(&mdb)
+
+	assert.Equal(t, 2, mdb.Status.Topology.AutomationConfigVersion)
+	assert.Equal(t, now, *mdb.Status.Topology.LastVersionTransitionTime, "the version has changed")
+	assert.Equal(t, before, *mdb.Status.Topology.LastFeatureCompatibilityVersionTransitionTime, "the feature compatibility version has not changed")
+
+	topologyOption{}.ApplyOption(&mdb)
+	assert.Equal(t, 2, mdb.Status.Topology.AutomationConfigVersion, "the topology should be kept if it could not be built")
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/statefulset"
 
+	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
@@ -28,6 +29,11 @@ const (
 	tlsOperatorSecretMountPath = "/var/lib/tls/server/" //nolint
 	tlsSecretCertName          = "tls.crt"              //nolint
 	tlsSecretKeyName           = "tls.key"
+
+	// tlsRetryInitialDelay and tlsRetryMaxDelay bound the interval, in seconds, at which the TLS
+	// resources are polled while they are not valid. Changes to them trigger a reconciliation anyway.
+	tlsRetryInitialDelay = 10
+	tlsRetryMaxDelay     = 300
 )
 
 // validateTLSConfig will check that the configured ConfigMap and Secret exist and that they have the correct fields.
@@ -38,6 +44,11 @@ func (r *ReplicaSetReconciler) validateTLSConfig(mdb mdbv1.MongoDBCommunity) (bo
 
 	r.log.Info("Ensuring TLS is correctly configured")
 
+	// Watch the CA ConfigMap and the certificate-key Secret so that their creation,
+	// updates and rotations trigger a reconciliation
+	r.configMapWatcher.Watch(mdb.TLSConfigMapNamespacedName(), mdb.NamespacedName())
+	r.secretWatcher.Watch(mdb.TLSSecretNamespacedName(), mdb.NamespacedName())
+
 	// Ensure CA ConfigMap exists
 	caData, err := configmap.ReadData(r.client, mdb.TLSConfigMapNamespacedName())
 	if err != nil {
@@ -76,13 +87,33 @@ func (r *ReplicaSetReconciler) validateTLSConfig(mdb mdbv1.MongoDBCommunity) (bo
 		return false, nil
 	}
 
-	// Watch certificate-key secret to handle rotations
-	r.secretWatcher.Watch(mdb.TLSSecretNamespacedName(), mdb.NamespacedName())
-
 	r.log.Infof("Successfully validated TLS config")
 	return true, nil
 }
 
+// nextTLSRetryDelay returns the number of seconds to wait before validating the TLS config of the resource again.
+// The delay doubles on each consecutive attempt, up to tlsRetryMaxDelay.
+func (r *ReplicaSetReconciler) nextTLSRetryDelay(mdb mdbv1.MongoDBCommunity) int {
+	attempts := 0
+	if value, ok := r.tlsRetries.Load(mdb.NamespacedName()); ok {
+		attempts = value.(int)
+	}
+	r.tlsRetries.Store(mdb.NamespacedName(), attempts+1)
+	return tlsRetryDelay(attempts)
+}
+
+// tlsRetryDelay returns the delay in seconds after the given number of previous attempts.
+func tlsRetryDelay(attempts int) int {
+	delay := tlsRetryInitialDelay
+	for i := 0; i < attempts && delay < tlsRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > tlsRetryMaxDelay {
+		return tlsRetryMaxDelay
+	}
+	return delay
+}
+
 // getTLSConfigModification creates a modification function which enables TLS in the automation config.
 // It will also ensure that the combined cert-key secret is created.
 func getTLSConfigModification(getUpdateCreator secret.GetUpdateCreator, mdb mdbv1.MongoDBCommunity) (automationconfig.Modification, error) {
@@ -176,29 +207,42 @@ func tlsConfigModification(mdb mdbv1.MongoDBCommunity, certKey string) automatio
 	}
 }
 
-// buildTLSPodSpecModification will add the TLS init container and volumes to the pod template if TLS is enabled.
+// buildTLSPodSpecModification adds the TLS volumes to the pod template and mounts them into the mongod and
+// the mongodb-agent containers if TLS is enabled.
 func buildTLSPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
-	if !mdb.Spec.Security.TLS.Enabled {
+	volumes, volumeMounts := tlsVolumes(mdb)
+	if len(volumes) == 0 {
 		return podtemplatespec.NOOP()
 	}
 
+	var modifications []podtemplatespec.Modification
+	for _, volume := range volumes {
+		modifications = append(modifications, podtemplatespec.WithVolume(volume))
+	}
+	return podtemplatespec.Apply(append(modifications,
+		podtemplatespec.WithVolumeMounts(construct.AgentName, volumeMounts...),
+		podtemplatespec.WithVolumeMounts(construct.MongodbName, volumeMounts...),
+	)...)
+}
+
+// tlsVolumes returns the volumes which the pods need if TLS is enabled, and their mounts, which are the same in
+// the mongod and the mongodb-agent containers. Nothing is returned if TLS is disabled.
+func tlsVolumes(mdb mdbv1.MongoDBCommunity) ([]corev1.Volume, []corev1.VolumeMount) {
+	if !mdb.Spec.Security.TLS.Enabled {
+		return nil, nil
+	}
+
 	// Configure a volume which mounts the CA certificate from a ConfigMap
 	// The certificate is used by both mongod and the agent
 	caVolume := statefulset.CreateVolumeFromConfigMap("tls-ca", mdb.Spec.Security.TLS.CaConfigMap.Name)
 	caVolumeMount := statefulset.CreateVolumeMount(caVolume.Name, tlsCAMountPath, statefulset.WithReadOnly(true))
 
-	// Configure a volume which mounts the secret holding the server key and certificate
+	// Configure a volume which mounts the secret created by the operator. MongoDB expects the key and the
+	// certificate in a single PEM file, the user provided secret stores them in separate fields, tls.crt
+	// and tls.key, so the operator combines them into the PEM file of its own secret.
 	// The same key-certificate pair is used for all servers
 	tlsSecretVolume := statefulset.CreateVolumeFromSecret("tls-secret", mdb.TLSOperatorSecretNamespacedName().Name)
 	tlsSecretVolumeMount := statefulset.CreateVolumeMount(tlsSecretVolume.Name, tlsOperatorSecretMountPath, statefulset.WithReadOnly(true))
 
-	// MongoDB expects both key and certificate to be provided in a single PEM file
-	// We are using a secret format where they are stored in separate fields, tls.crt and tls.key
-	// Because of this we need to use an init container which reads the two files mounted from the secret and combines them into one
-	return podtemplatespec.Apply(
-		podtemplatespec.WithVolume(caVolume),
-		podtemplatespec.WithVolume(tlsSecretVolume),
-		podtemplatespec.WithVolumeMounts(construct.AgentName, tlsSecretVolumeMount, caVolumeMount),
-		podtemplatespec.WithVolumeMounts(construct.MongodbName, tlsSecretVolumeMount, caVolumeMount),
-	)
+	return []corev1.Volume{caVolume, tlsSecretVolume}, []corev1.VolumeMount{tlsSecretVolumeMount, caVolumeMount}
 }
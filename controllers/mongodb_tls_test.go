@@ -3,19 +3,28 @@ package controllers
 import (
 	"context"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/validation"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
 	mdbClient "github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/configmap"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/container"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
 	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -79,6 +88,103 @@ func TestStatefulSet_IsCorrectlyConfiguredWithTLS(t *testing.T) {
 	assert.Contains(t, mongodbContainer.VolumeMounts, tlsCAVolumeMount)
 }
 
+func TestBuildStatefulSet_IsCorrectlyConfiguredWithTLS(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	sts := BuildStatefulSet(mdb)
+
+	podSpec := sts.Spec.Template.Spec
+	assert.Len(t, podSpec.Containers, 2)
+	assert.NotNil(t, podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template))
+	assert.NotNil(t, podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template))
+
+	volumeNames := make([]string, len(podSpec.Volumes))
+	for i, v := range podSpec.Volumes {
+		volumeNames[i] = v.Name
+	}
+	keyfileVolume := mdb.GetAgentKeyfileSecretNamespacedName().Name
+	assert.Subset(t, volumeNames, []string{"tls-ca", "tls-secret", keyfileVolume})
+
+	for _, c := range podSpec.Containers {
+		assert.Contains(t, c.VolumeMounts, corev1.VolumeMount{Name: "tls-secret", ReadOnly: true, MountPath: tlsOperatorSecretMountPath}, c.Name)
+		assert.Contains(t, c.VolumeMounts, corev1.VolumeMount{Name: "tls-ca", ReadOnly: true, MountPath: tlsCAMountPath}, c.Name)
+		assert.Contains(t, c.VolumeMounts, corev1.VolumeMount{Name: keyfileVolume, MountPath: "/var/lib/mongodb-mms-automation/authentication"}, c.Name)
+	}
+
+	t.Run("It is the StatefulSet which is deployed", func(t *testing.T) {
+		mgr := client.NewManager(&mdb)
+		err := createTLSSecretAndConfigMap(mgr.GetClient(), mdb)
+		assert.NoError(t, err)
+
+		r := NewReconciler(mgr)
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		deployed, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, deployed.Spec, sts.Spec)
+	})
+}
+
+func TestTLSVolumes(t *testing.T) {
+	permission := int32(416)
+	tests := []struct {
+		name                 string
+		mdb                  mdbv1.MongoDBCommunity
+		expectedVolumes      []corev1.Volume
+		expectedVolumeMounts []corev1.VolumeMount
+	}{
+		{
+			name: "TLS disabled",
+			mdb:  newTestReplicaSet(),
+		},
+		{
+			name: "TLS enabled",
+			mdb:  newTestReplicaSetWithTLS(),
+			expectedVolumes: []corev1.Volume{
+				{
+					Name: "tls-ca",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "caConfigMap"},
+						},
+					},
+				},
+				{
+					Name: "tls-secret",
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName:  "my-rs-server-certificate-key",
+							DefaultMode: &permission,
+						},
+					},
+				},
+			},
+			expectedVolumeMounts: []corev1.VolumeMount{
+				{Name: "tls-secret", ReadOnly: true, MountPath: "/var/lib/tls/server/"},
+				{Name: "tls-ca", ReadOnly: true, MountPath: "/var/lib/tls/ca/"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			volumes, volumeMounts := tlsVolumes(tt.mdb)
+			assert.Equal(t, tt.expectedVolumes, volumes)
+			assert.Equal(t, tt.expectedVolumeMounts, volumeMounts)
+
+			podTemplate := corev1.PodTemplateSpec{}
+			podtemplatespec.WithContainer(construct.AgentName, container.NOOP())(&podTemplate)
+			podtemplatespec.WithContainer(construct.MongodbName, container.NOOP())(&podTemplate)
+			buildTLSPodSpecModification(tt.mdb)(&podTemplate)
+
+			assert.Equal(t, tt.expectedVolumes, podTemplate.Spec.Volumes)
+			for _, c := range podTemplate.Spec.Containers {
+				assert.ElementsMatch(t, tt.expectedVolumeMounts, c.VolumeMounts, "unexpected volume mounts in container %s", c.Name)
+			}
+		})
+	}
+}
+
 func TestAutomationConfig_IsCorrectlyConfiguredWithTLS(t *testing.T) {
 	createAC := func(mdb mdbv1.MongoDBCommunity) automationconfig.AutomationConfig {
 		client := mdbClient.NewClient(client.NewManager(&mdb).GetClient())
@@ -145,6 +251,26 @@ func TestAutomationConfig_IsCorrectlyConfiguredWithTLS(t *testing.T) {
 			assert.True(t, process.Args26.Get("net.tls.allowConnectionsWithoutCertificates").MustBool())
 		}
 	})
+
+	t.Run("With TLS enabled and client certificates required", func(t *testing.T) {
+		mdb := newTestReplicaSetWithTLS()
+		mdb.Spec.Security.TLS.ClientCertificateMode = automationconfig.ClientCertificateModeRequired
+		ac := createAC(mdb)
+
+		assert.Equal(t, &automationconfig.TLS{
+			CAFilePath:            tlsCAMountPath + tlsCACertName,
+			ClientCertificateMode: automationconfig.ClientCertificateModeRequired,
+		}, ac.TLSConfig)
+	})
+
+	t.Run("With TLS disabled, client certificates are optional", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Security.TLS.ClientCertificateMode = automationconfig.ClientCertificateModeRequired
+		ac := createAC(mdb)
+
+		assert.Equal(t, automationconfig.ClientCertificateModeOptional, ac.TLSConfig.ClientCertificateMode)
+		assert.Error(t, validation.ValidateInitalSpec(mdb), "required client certificates without TLS should be rejected")
+	})
 }
 
 func TestTLSOperatorSecret(t *testing.T) {
@@ -196,6 +322,57 @@ func TestTLSOperatorSecret(t *testing.T) {
 	})
 }
 
+func TestTLSResources_AreWaitedFor(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second*10, res.RequeueAfter)
+
+	err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "Waiting for TLS resources")
+
+	t.Run("Polling backs off", func(t *testing.T) {
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		assert.Equal(t, time.Second*20, res.RequeueAfter)
+	})
+
+	t.Run("Creating the TLS resources triggers a reconciliation", func(t *testing.T) {
+		err := createTLSSecretAndConfigMap(mgr.GetClient(), mdb)
+		assert.NoError(t, err)
+
+		queue := controllertest.Queue{Interface: workqueue.New()}
+		r.secretWatcher.Create(event.CreateEvent{
+			Object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: mdb.Spec.Security.TLS.CertificateKeySecret.Name, Namespace: mdb.Namespace}},
+		}, queue)
+		r.configMapWatcher.Create(event.CreateEvent{
+			Object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: mdb.Spec.Security.TLS.CaConfigMap.Name, Namespace: mdb.Namespace}},
+		}, queue)
+		assert.Equal(t, 1, queue.Len())
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+	})
+
+	t.Run("Backoff is reset once the TLS resources are valid", func(t *testing.T) {
+		_, ok := r.tlsRetries.Load(mdb.NamespacedName())
+		assert.False(t, ok)
+	})
+}
+
+func TestTLSRetryDelay(t *testing.T) {
+	assert.Equal(t, 10, tlsRetryDelay(0))
+	assert.Equal(t, 20, tlsRetryDelay(1))
+	assert.Equal(t, 160, tlsRetryDelay(4))
+	assert.Equal(t, 300, tlsRetryDelay(5))
+	assert.Equal(t, 300, tlsRetryDelay(100))
+}
+
 func TestCombineCertificateAndKey(t *testing.T) {
 	tests := []struct {
 		Cert     string
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"fmt"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// topologyStatus returns the snapshot of the topology of the deployment, built from the automation config which
+// has been deployed. It returns nil if the automation config could not be read, the snapshot is kept as it is then.
+func (r ReplicaSetReconciler) topologyStatus(mdb mdbv1.MongoDBCommunity) *mdbv1.TopologyStatus {
+	ac, err := automationconfig.ReadFromSecret(r.client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	if err != nil {
+		r.log.Debugf("Could not read the automation config to build the topology: %s", err)
+		return nil
+	}
+	topology := buildTopologyStatus(ac)
+	return &topology
+}
+
+// buildTopologyStatus returns the snapshot of the topology of the deployment described by the automation config.
+// The transition times are not set, they depend on the previous snapshot.
+func buildTopologyStatus(ac automationconfig.AutomationConfig) mdbv1.TopologyStatus {
+	topology := mdbv1.TopologyStatus{
+		AutomationConfigVersion: ac.Version,
+		TLSMode:                 string(automationconfig.TLSModeDisabled),
+	}
+	if !ac.Auth.Disabled {
+		topology.AuthenticationMechanisms = ac.Auth.DeploymentAuthMechanisms
+	}
+
+	processes := map[string]automationconfig.Process{}
+	for _, p := range ac.Processes {
+		processes[p.Name] = p
+	}
+	if len(ac.Processes) > 0 {
+		p := ac.Processes[0]
+		topology.Version = p.Version
+		topology.FeatureCompatibilityVersion = p.FeatureCompatibilityVersion
+		if mode := p.Args26.Get("net.tls.mode").Str(); mode != "" {
+			topology.TLSMode = mode
+		}
+	}
+
+	for _, rs := range ac.ReplicaSets {
+		for _, m := range rs.Members {
+			// the members reference their process by its name.
+			p := processes[m.Host]
+			topology.Members = append(topology.Members, mdbv1.TopologyMember{
+				Name:        m.Host,
+				Host:        fmt.Sprintf("%s:%d", p.HostName, p.Port()),
+				Votes:       m.Votes,
+				Priority:    m.Priority,
+				ArbiterOnly: m.ArbiterOnly,
+				Hidden:      m.Hidden,
+			})
+		}
+	}
+	return topology
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"fmt"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// checkQuorumForVersionChange returns an empty string if the MongoDB version can be changed, or describes why the
+// change is held back otherwise. The members are restarted one at a time once the new version is deployed, the
+// remaining voting members must then still form a majority so that the replica set keeps a primary. The pods
+// which are not ready are counted as unavailable voting members, an already unhealthy member then holds back the
+// change until it has recovered. A member which is unhealthy because of the current version, e.g. a version change
+// to fix it, can only be recovered by skipping the check with the annotations.SkipUpgradeQuorumCheck annotation.
+func (r ReplicaSetReconciler) checkQuorumForVersionChange(mdb mdbv1.MongoDBCommunity) (string, error) {
+	if annotations.GetAnnotation(&mdb, annotations.SkipUpgradeQuorumCheck) == "true" {
+		r.log.Warnf("The quorum is not checked before the version change, as the %s annotation is set", annotations.SkipUpgradeQuorumCheck)
+		return "", nil
+	}
+
+	currentAC, err := automationconfig.ReadFromSecret(r.client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	if err != nil {
+		return "", fmt.Errorf("could not read existing automation config: %s", err)
+	}
+	if !acVersionDiffers(mdb, currentAC) || len(currentAC.ReplicaSets) == 0 {
+		return "", nil
+	}
+
+	votingMembers := 0
+	for _, m := range currentAC.ReplicaSets[0].Members {
+		votingMembers += m.Votes
+	}
+	notReady, err := r.notReadyPods(mdb)
+	if err != nil {
+		return "", err
+	}
+
+	majority := votingMembers/2 + 1
+	if votingMembers-1 < majority {
+		// replica sets with less than 3 voting members lose their primary when any member restarts
+		return "", nil
+	}
+	available := votingMembers - notReady
+	// the member which is restarted first is unavailable as well
+	if available-1 >= majority {
+		return "", nil
+	}
+	return fmt.Sprintf("Waiting for quorum before continuing the upgrade to version %s: %d of the %d voting members are available, "+
+		"%d must remain available while a member restarts", mdb.Spec.Version, available, votingMembers, majority), nil
+}
+
+// acVersionDiffers returns true if the MongoDB version of the spec differs from the version of a process in the
+// deployed automation config.
+func acVersionDiffers(mdb mdbv1.MongoDBCommunity, currentAC automationconfig.AutomationConfig) bool {
+	for _, p := range currentAC.Processes {
+		if p.Version != mdb.Spec.Version {
+			return true
+		}
+	}
+	return false
+}
+
+// notReadyPods returns the number of pods of the members and the arbiters which are not ready.
+func (r ReplicaSetReconciler) notReadyPods(mdb mdbv1.MongoDBCommunity) (int, error) {
+	stsNames := []types.NamespacedName{mdb.NamespacedName()}
+	if mdb.HasSeparateArbiters() {
+		stsNames = append(stsNames, mdb.ArbiterNamespacedName())
+	}
+
+	notReady := 0
+	for _, name := range stsNames {
+		sts, err := r.client.GetStatefulSet(name)
+		if err != nil {
+			if apiErrors.IsNotFound(err) {
+				continue
+			}
+			return 0, err
+		}
+		replicas := int(sts.Status.Replicas)
+		if sts.Spec.Replicas != nil && int(*sts.Spec.Replicas) > replicas {
+			replicas = int(*sts.Spec.Replicas)
+		}
+		if replicas > int(sts.Status.ReadyReplicas) {
+			notReady += replicas - int(sts.Status.ReadyReplicas)
+		}
+	}
+	return notReady, nil
+}
@@ -1,14 +1,93 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	// passwordSecretRetryIntervalEnv configures how long to wait before reconciling a resource again whose user
+	// password secrets don't exist yet, as a duration, e.g. "5s".
+	passwordSecretRetryIntervalEnv = "PASSWORD_SECRET_RETRY_INTERVAL"
+	defaultPasswordSecretRetry     = 5
+)
+
+// passwordSecretRetrySeconds returns the number of seconds to wait for the password secrets of the users,
+// the default is used if PASSWORD_SECRET_RETRY_INTERVAL is not a positive duration.
+func passwordSecretRetrySeconds() int {
+	value, ok := os.LookupEnv(passwordSecretRetryIntervalEnv)
+	if !ok || value == "" {
+		return defaultPasswordSecretRetry
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		zap.S().Warnf("Invalid %s %q, using the default of %d seconds", passwordSecretRetryIntervalEnv, value, defaultPasswordSecretRetry)
+		return defaultPasswordSecretRetry
+	}
+	if interval < time.Second {
+		return 1
+	}
+	return int(interval.Round(time.Second).Seconds())
+}
+
+// missingPasswordSecret returns the password secret of the first user which has neither a password secret nor
+// SCRAM credentials, e.g. if the secret is applied after the resource. The user can't be created until the secret
+// exists, so it is watched and its creation triggers a reconciliation. The returned name is empty if no secret is missing.
+func (r ReplicaSetReconciler) missingPasswordSecret(mdb mdbv1.MongoDBCommunity) (types.NamespacedName, string, error) {
+	for _, user := range mdb.GetScramUsers() {
+		secretNamespacedName := types.NamespacedName{Name: user.PasswordSecretName, Namespace: mdb.Namespace}
+		if _, err := r.client.GetSecret(secretNamespacedName); !apiErrors.IsNotFound(err) {
+			if err != nil {
+				return types.NamespacedName{}, "", err
+			}
+			continue
+		}
+
+		_, err := r.client.GetSecret(types.NamespacedName{Name: user.ScramCredentialsSecretName, Namespace: mdb.Namespace})
+		if err == nil {
+			continue
+		}
+		if !apiErrors.IsNotFound(err) {
+			return types.NamespacedName{}, "", err
+		}
+		r.secretWatcher.Watch(secretNamespacedName, mdb.NamespacedName())
+		return secretNamespacedName, user.Username, nil
+	}
+	return types.NamespacedName{}, "", nil
+}
+
+// validateUserPasswordSecrets checks that the password secret of each user, if it exists, contains a non-empty
+// password under the configured key. Missing password secrets are handled by ensureUserResources.
+func (r ReplicaSetReconciler) validateUserPasswordSecrets(mdb mdbv1.MongoDBCommunity) error {
+	for _, user := range mdb.GetScramUsers() {
+		secretNamespacedName := types.NamespacedName{Name: user.PasswordSecretName, Namespace: mdb.Namespace}
+		data, err := secret.ReadStringData(r.client, secretNamespacedName)
+		if err != nil {
+			if apiErrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		if pwd, ok := data[user.PasswordSecretKey]; !ok || pwd == "" {
+			return fmt.Errorf(`password secret %s of user "%s" should contain a password in the key "%s"`, secretNamespacedName, user.Username, user.PasswordSecretKey)
+		}
+	}
+	return nil
+}
+
 // ensureUserResources will check that the configured user password secrets can be found
 // and will start monitor them so that the reconcile process is triggered every time these secrets are updated
 func (r ReplicaSetReconciler) ensureUserResources(mdb mdbv1.MongoDBCommunity) error {
@@ -62,3 +141,34 @@ func (r ReplicaSetReconciler) updateConnectionStringSecrets(mdb mdbv1.MongoDBCom
 
 	return nil
 }
+
+// deleteOrphanedScramCredentialsSecrets deletes the SCRAM credentials secrets of the users which have been removed
+// from the resource. Only the secrets controlled by the resource are deleted, secrets created before the operator
+// set owner references on them are left as they are.
+func (r ReplicaSetReconciler) deleteOrphanedScramCredentialsSecrets(mdb mdbv1.MongoDBCommunity) error {
+	desiredSecretNames := map[string]bool{}
+	for _, user := range mdb.GetScramUsers() {
+		desiredSecretNames[user.ScramCredentialsSecretName] = true
+	}
+
+	secrets := corev1.SecretList{}
+	if err := r.client.List(context.TODO(), &secrets, k8sClient.InNamespace(mdb.Namespace)); err != nil {
+		return fmt.Errorf("could not list secrets: %s", err)
+	}
+	for _, s := range secrets.Items {
+		if !strings.HasSuffix(s.Name, "-scram-credentials") || desiredSecretNames[s.Name] || !isControlledBy(s.ObjectMeta, mdb) {
+			continue
+		}
+		r.log.Infof("Deleting the SCRAM credentials secret %s of a removed user", s.Name)
+		if err := r.client.DeleteSecret(types.NamespacedName{Name: s.Name, Namespace: s.Namespace}); err != nil && !apiErrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete secret %s: %s", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// isControlledBy returns true if the object has the given resource as its controller.
+func isControlledBy(object metav1.ObjectMeta, mdb mdbv1.MongoDBCommunity) bool {
+	controller := metav1.GetControllerOfNoCopy(&object)
+	return controller != nil && controller.Name == mdb.Name && controller.UID == mdb.UID
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/statefulset"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/result"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/status"
+	appsv1 "k8s.io/api/apps/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ensureVersionRollback rolls back a change of the MongoDB version which hasn't completed within the progress
+// deadline, if automatic rollbacks are enabled. While a version is rolled back, the version of the last successful
+// configuration is deployed instead, which is only changed in memory and never written to the Spec. The rollback is
+// lifted once a different version is configured, or automatic rollbacks are disabled. The returned string is the
+// version which has been rolled back, if any.
+func (r ReplicaSetReconciler) ensureVersionRollback(mdb *mdbv1.MongoDBCommunity) (string, error) {
+	rolledBackVersion := annotations.GetAnnotation(mdb, annotations.RolledBackMongoDBVersion)
+	if rolledBackVersion != "" && (rolledBackVersion != mdb.Spec.Version || !mdb.IsAutomaticRollbackEnabled()) {
+		r.log.Infof("Lifting the rollback of MongoDB version %s", rolledBackVersion)
+		if err := annotations.SetAnnotations(mdb, map[string]string{annotations.RolledBackMongoDBVersion: ""}, r.client); err != nil {
+			return "", fmt.Errorf("could not lift the rollback of MongoDB version %s: %s", rolledBackVersion, err)
+		}
+		rolledBackVersion = ""
+	}
+
+	if rolledBackVersion == "" {
+		rolledBack, err := r.trackVersionChange(mdb)
+		if err != nil || !rolledBack {
+			return "", err
+		}
+		rolledBackVersion = mdb.Spec.Version
+	}
+
+	lastSpec, err := lastSuccessfulSpec(*mdb)
+	if err != nil {
+		return "", fmt.Errorf("could not read the last successful configuration: %s", err)
+	}
+	if lastSpec == nil || lastSpec.Version == "" {
+		return "", fmt.Errorf("MongoDB version %s has been rolled back, but there is no last successful configuration to roll back to", rolledBackVersion)
+	}
+
+	mdb.Spec.Version = lastSpec.Version
+	return rolledBackVersion, nil
+}
+
+// trackVersionChange records when a change of the MongoDB version has started, and rolls it back if the StatefulSets
+// are still not ready after the progress deadline. The version change is rolled back by making it a version change
+// from the new version to the previous one, which is completed like any other. The returned boolean indicates
+// whether the version change has been rolled back.
+func (r ReplicaSetReconciler) trackVersionChange(mdb *mdbv1.MongoDBCommunity) (bool, error) {
+	startedAt := annotations.GetAnnotation(mdb, annotations.VersionChangeStartedAt)
+	if !mdb.IsAutomaticRollbackEnabled() || !mdb.IsChangingVersion() {
+		if startedAt == "" {
+			return false, nil
+		}
+		return false, annotations.SetAnnotations(mdb, map[string]string{annotations.VersionChangeStartedAt: ""}, r.client)
+	}
+
+	started, err := time.Parse(time.RFC3339, startedAt)
+	if err != nil {
+		// the version change has just started, or the annotation has been modified.
+		return false, annotations.SetAnnotations(mdb, map[string]string{annotations.VersionChangeStartedAt: time.Now().UTC().Format(time.RFC3339)}, r.client)
+	}
+
+	if time.Since(started) < mdb.GetRollbackProgressDeadline() {
+		return false, nil
+	}
+
+	ready, err := r.statefulSetsReady(*mdb)
+	if err != nil || ready {
+		// the version change is completed in this reconciliation.
+		return false, err
+	}
+
+	r.log.Warnf("MongoDB version %s has not become ready within %s, rolling back to version %s",
+		mdb.Spec.Version, mdb.GetRollbackProgressDeadline(), annotations.GetAnnotation(mdb, annotations.LastAppliedMongoDBVersion))
+	return true, annotations.SetAnnotations(mdb, map[string]string{
+		annotations.RolledBackMongoDBVersion:  mdb.Spec.Version,
+		annotations.LastAppliedMongoDBVersion: mdb.Spec.Version,
+		annotations.VersionChangeStartedAt:    "",
+	}, r.client)
+}
+
+// statefulSetsReady returns true if the StatefulSets of the members and the arbiters are ready.
+func (r ReplicaSetReconciler) statefulSetsReady(mdb mdbv1.MongoDBCommunity) (bool, error) {
+	sts, err := r.client.GetStatefulSet(mdb.NamespacedName())
+	if err != nil {
+		return false, fmt.Errorf("failed to get StatefulSet: %s", err)
+	}
+	if !statefulset.IsReady(sts, mdb.StatefulSetReplicasThisReconciliation()) {
+		return false, nil
+	}
+
+	if mdb.HasSeparateArbiters() {
+		arbiterSts, err := r.client.GetStatefulSet(mdb.ArbiterNamespacedName())
+		if err != nil {
+			return false, fmt.Errorf("failed to get arbiter StatefulSet: %s", err)
+		}
+		return statefulset.IsReady(arbiterSts, mdb.Spec.Arbiters), nil
+	}
+	return true, nil
+}
+
+// deleteRolledBackPods deletes the pods which are not ready and haven't been updated to the current revision of their
+// StatefulSet. The StatefulSets are updated with the OnDelete strategy while the version is rolled back, and pods which
+// crash on the version which has been rolled back would never be replaced, as their agents can't complete the version change.
+func (r ReplicaSetReconciler) deleteRolledBackPods(mdb mdbv1.MongoDBCommunity) error {
+	stsNames := []types.NamespacedName{mdb.NamespacedName()}
+	if mdb.HasSeparateArbiters() {
+		stsNames = append(stsNames, mdb.ArbiterNamespacedName())
+	}
+
+	for _, stsName := range stsNames {
+		sts, err := r.client.GetStatefulSet(stsName)
+		if err != nil {
+			return fmt.Errorf("failed to get StatefulSet %s: %s", stsName, err)
+		}
+		if sts.Status.UpdateRevision == "" || sts.Status.ObservedGeneration != sts.Generation || sts.Spec.Replicas == nil {
+			// the rolled back revision has not been observed by the StatefulSet controller yet.
+			continue
+		}
+
+		for i := 0; i < int(*sts.Spec.Replicas); i++ {
+			podNsName := types.NamespacedName{Name: fmt.Sprintf("%s-%d", sts.Name, i), Namespace: sts.Namespace}
+			pod, err := r.client.GetPod(podNsName)
+			if err != nil {
+				if apiErrors.IsNotFound(err) {
+					continue
+				}
+				return fmt.Errorf("could not get pod %s: %s", podNsName, err)
+			}
+			if isPodReady(pod) || pod.Labels[appsv1.ControllerRevisionHashLabelKey] == sts.Status.UpdateRevision {
+				continue
+			}
+
+			r.log.Infof("Deleting pod %s, which is not ready on the MongoDB version which has been rolled back", podNsName)
+			if err := r.client.Delete(context.TODO(), &pod); err != nil && !apiErrors.IsNotFound(err) {
+				return fmt.Errorf("could not delete pod %s: %s", podNsName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// completeVersionRollback saves the configuration the deployment has been rolled back to, and marks the resource as
+// Failed. The reconciliation is not retried, the version is only changed again once a different one is configured.
+func (r ReplicaSetReconciler) completeVersionRollback(mdb mdbv1.MongoDBCommunity, rolledBackVersion string, members []mdbv1.MemberStatus) (reconcile.Result, error) {
+	// the status update overwrites the version which has been rolled back to with the one of the Spec.
+	deployedMdb := mdb
+	_, err := status.Update(r.client.Status(), &mdb,
+		statusOptions().
+			withMongoURI(r.mongoURI(mdb)).
+			withMongoDBMembers(mdb.AutomationConfigMembersThisReconciliation()).
+			withStatefulSetReplicas(mdb.StatefulSetReplicasThisReconciliation()).
+			withMemberStatuses(members).
+			withMessage(Error, fmt.Sprintf("MongoDB version %s did not become ready within %s and has been rolled back to version %s, configure a different version to retry",
+				rolledBackVersion, mdb.GetRollbackProgressDeadline(), deployedMdb.Spec.Version)).
+			withFailedPhase(),
+	)
+	if err != nil {
+		r.log.Errorf("Error updating the status of the MongoDB resource: %s", err)
+		return result.Failed()
+	}
+
+	if err := r.updateLastSuccessfulConfiguration(deployedMdb); err != nil {
+		r.log.Errorf("Could not save current spec as an annotation: %s", err)
+	}
+	return result.OK()
+}
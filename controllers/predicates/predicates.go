@@ -4,12 +4,15 @@ import (
 	"reflect"
 
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 // OnlyOnSpecChange returns a set of predicates indicating
-// that reconciliations should only happen on changes to the Spec of the resource.
+// that reconciliations should only happen on changes to the Spec of the resource,
+// or to the annotations used to trigger a rolling restart and to freeze the automation config version.
 // any other changes won't trigger a reconciliation. This allows us to freely update the annotations
 // of the resource without triggering unintentional reconciliations.
 func OnlyOnSpecChange() predicate.Funcs {
@@ -18,7 +21,21 @@ func OnlyOnSpecChange() predicate.Funcs {
 			oldResource := e.ObjectOld.(*mdbv1.MongoDBCommunity)
 			newResource := e.ObjectNew.(*mdbv1.MongoDBCommunity)
 			specChanged := !reflect.DeepEqual(oldResource.Spec, newResource.Spec)
-			return specChanged
+			restartRequested := oldResource.Annotations[annotations.RestartedAt] != newResource.Annotations[annotations.RestartedAt]
+			freezeChanged := oldResource.Annotations[annotations.FreezeAutomationConfigVersion] != newResource.Annotations[annotations.FreezeAutomationConfigVersion]
+			return specChanged || restartRequested || freezeChanged
+		},
+	}
+}
+
+// OnlyOnPodIPChange returns a set of predicates indicating that updates of pods only trigger a
+// reconciliation if the IP address of the pod changed.
+func OnlyOnPodIPChange() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPod := e.ObjectOld.(*corev1.Pod)
+			newPod := e.ObjectNew.(*corev1.Pod)
+			return oldPod.Status.PodIP != newPod.Status.PodIP
 		},
 	}
 }
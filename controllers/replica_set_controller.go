@@ -5,6 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/controllers/predicates"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -13,6 +17,7 @@ import (
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/container"
 
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/envvar"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/functions"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/agent"
@@ -22,6 +27,7 @@ import (
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/scale"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/status"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/versions"
 
 	"github.com/pkg/errors"
 
@@ -34,32 +40,59 @@ import (
 	"github.com/mongodb/mongodb-kubernetes-operator/controllers/watch"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/configmap"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/probes"
 
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
 	kubernetesClient "github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/service"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/statefulset"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/replicaset"
 	"go.uber.org/zap"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
 )
 
 const (
-	clusterDNSName = "CLUSTER_DNS_NAME"
-
 	lastSuccessfulConfiguration = "mongodb.com/v1.lastSuccessfulConfiguration"
 	lastAppliedMongoDBVersion   = "mongodb.com/v1.lastAppliedMongoDBVersion"
+
+	additionalMongodConfigMountPath  = "/var/lib/mongod-config/"
+	additionalMongodConfigVolumeName = "additional-mongod-config"
+
+	// agentCAMountPath is where the CA bundle trusted by the agent for its outbound connections is mounted.
+	agentCAMountPath  = "/var/lib/mongodb-mms-automation/agent-ca/"
+	agentCAVolumeName = "agent-ca"
+
+	// podNameEnv holds the name of the pod, it is used to store the data of each member in its own hostPath sub directory
+	podNameEnv = "POD_NAME"
+
+	// frozenRetrySeconds is the delay before reconciling a resource again whose automation config changes are
+	// held back by a frozen version. Removing the annotation triggers a reconciliation as well.
+	frozenRetrySeconds = 60
+
+	// additionalEnvAnnotation records the names of the additional environment variables on the StatefulSets.
+	additionalEnvAnnotation = "mongodb.com/v1.additionalEnv"
+
+	// arbiterLabel is set on the pods of the arbiter StatefulSet, to distinguish them from the data-bearing members
+	arbiterLabel = "mongodb.com/arbiter"
 )
 
+// OperatorVersion is the version of the operator, which is set at build time with
+// -ldflags "-X github.com/mongodb/mongodb-kubernetes-operator/controllers.OperatorVersion=<version>".
+var OperatorVersion = "unknown"
+
 func init() {
 	logger, err := zap.NewDevelopment()
 	if err != nil {
@@ -71,21 +104,43 @@ func init() {
 func NewReconciler(mgr manager.Manager) *ReplicaSetReconciler {
 	mgrClient := mgr.GetClient()
 	secretWatcher := watch.New()
+	configMapWatcher := watch.New()
+	podWatcher := watch.New()
 
 	return &ReplicaSetReconciler{
-		client:        kubernetesClient.NewClient(mgrClient),
-		scheme:        mgr.GetScheme(),
-		log:           zap.S(),
-		secretWatcher: &secretWatcher,
+		client:                     kubernetesClient.NewClient(mgrClient),
+		scheme:                     mgr.GetScheme(),
+		log:                        zap.S(),
+		secretWatcher:              &secretWatcher,
+		configMapWatcher:           &configMapWatcher,
+		podWatcher:                 &podWatcher,
+		reconciledGenerations:      &sync.Map{},
+		tlsRetries:                 &sync.Map{},
+		resourceLocks:              &sync.Map{},
+		frozenVersions:             &sync.Map{},
+		divergingConfigVersions:    &sync.Map{},
+		statusGetter:               replicaset.NewStatusGetter(primaryTimeout),
+		primaryCache:               replicaset.NewPrimaryCache(primaryCacheTTL),
+		memberHealthChecks:         envvar.ReadBool(memberHealthChecksEnv),
+		passwordSecretRetrySeconds: passwordSecretRetrySeconds(),
 	}
 }
 
+// DisablePrimaryRefresh stops the reconciler from fetching the state of the members in the background, which
+// records the primary, the roles and the Degraded condition. It is used by single reconciliations from outside
+// the cluster, which can't connect to the members and exit before the refresh completes.
+func (r *ReplicaSetReconciler) DisablePrimaryRefresh() {
+	r.primaryRefreshDisabled = true
+}
+
 // SetupWithManager sets up the controller with the Manager and configures the necessary watches.
 func (r *ReplicaSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 3}).
 		For(&mdbv1.MongoDBCommunity{}, builder.WithPredicates(predicates.OnlyOnSpecChange())).
 		Watches(&source.Kind{Type: &corev1.Secret{}}, r.secretWatcher).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, r.configMapWatcher).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, r.podWatcher, builder.WithPredicates(predicates.OnlyOnPodIPChange())).
 		Complete(r)
 }
 
@@ -93,17 +148,73 @@ func (r *ReplicaSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 type ReplicaSetReconciler struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client        kubernetesClient.Client
-	scheme        *runtime.Scheme
-	log           *zap.SugaredLogger
-	secretWatcher *watch.ResourceWatcher
+	client           kubernetesClient.Client
+	scheme           *runtime.Scheme
+	log              *zap.SugaredLogger
+	secretWatcher    *watch.ResourceWatcher
+	configMapWatcher *watch.ResourceWatcher
+	podWatcher       *watch.ResourceWatcher
+
+	// reconciledGenerations holds the generation of each resource that has been successfully
+	// reconciled by this operator process.
+	reconciledGenerations *sync.Map
+
+	// tlsRetries holds the number of consecutive reconciliations of each resource which
+	// found its TLS resources not yet valid.
+	tlsRetries *sync.Map
+
+	// resourceLocks holds a *sync.Mutex for each resource, which serializes its reconciliations.
+	resourceLocks *sync.Map
+
+	// frozenVersions holds the frozen automation config version of each resource whose desired automation
+	// config differs from the deployed one while the version is frozen, see annotations.FreezeAutomationConfigVersion.
+	frozenVersions *sync.Map
+
+	// divergingConfigVersions holds the resources whose members were on different replica set config
+	// versions in the last refresh, see checkConfigVersions.
+	divergingConfigVersions *sync.Map
+
+	statusGetter replicaset.StatusGetter
+	primaryCache replicaset.PrimaryCache
+
+	// primaryRefreshDisabled stops the state of the members from being fetched in the background, see
+	// DisablePrimaryRefresh.
+	primaryRefreshDisabled bool
+
+	// memberHealthChecks enables the health checks which connect to the members, see memberHealthChecksEnv.
+	memberHealthChecks bool
+
+	// passwordSecretRetrySeconds is the delay before reconciling a resource again whose user password
+	// secrets don't exist yet, see passwordSecretRetryIntervalEnv.
+	passwordSecretRetrySeconds int
 }
 
 // +kubebuilder:rbac:groups=mongodbcommunity.mongodb.com,resources=mongodbcommunity,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=mongodbcommunity.mongodb.com,resources=mongodbcommunity/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=mongodbcommunity.mongodb.com,resources=mongodbcommunity/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update
+
+// forgetResource releases the state held for a resource which has been deleted. It is called while holding the lock
+// of the resource, a reconciliation already waiting for the lock still acquires it once it is released.
+func (r ReplicaSetReconciler) forgetResource(nsName types.NamespacedName) {
+	r.statusGetter.Forget(nsName)
+	r.primaryCache.Remove(nsName)
+	deleteConfigConvergenceLag(nsName)
+	r.reconciledGenerations.Delete(nsName)
+	r.tlsRetries.Delete(nsName)
+	r.frozenVersions.Delete(nsName)
+	r.divergingConfigVersions.Delete(nsName)
+	r.resourceLocks.Delete(nsName)
+}
+
+// lockResource acquires the lock of the given resource and returns the function releasing it.
+func (r ReplicaSetReconciler) lockResource(nsName types.NamespacedName) func() {
+	lock, _ := r.resourceLocks.LoadOrStore(nsName, &sync.Mutex{})
+	mutex := lock.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
 
 // Reconcile reads that state of the cluster for a MongoDB object and makes changes based on the state read
 // and what is in the MongoDB.Spec
@@ -111,6 +222,11 @@ type ReplicaSetReconciler struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	// The workqueue never hands out the same request to two workers at once, but reconciliations
+	// of the same resource are still serialized here so that each one reads the automation config
+	// written by the previous one, and its version is only bumped once per change.
+	unlock := r.lockResource(request.NamespacedName)
+	defer unlock()
 
 	// TODO: generalize preparation for resource
 	// Fetch the MongoDB instance
@@ -121,6 +237,7 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			r.forgetResource(request.NamespacedName)
 			return result.OK()
 		}
 		r.log.Errorf("Error reconciling MongoDB resource: %s", err)
@@ -129,6 +246,13 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 	}
 
 	r.log = zap.S().With("ReplicaSet", request.NamespacedName)
+
+	if r.isUpToDate(mdb) {
+		r.log.Debugf("MongoDB generation %d has already been reconciled and is ready, skipping reconciliation", mdb.Generation)
+		r.refreshPrimary(mdb)
+		return r.memberRolesResult(mdb)
+	}
+
 	r.log.Infof("Reconciling MongoDB")
 
 	r.log.Debug("Validating MongoDB.Spec")
@@ -139,6 +263,29 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 				withFailedPhase(),
 		)
 	}
+	for _, warning := range validation.Warnings(mdb) {
+		r.log.Warnf("MongoDB.Spec: %s", warning)
+	}
+
+	rolledBackVersion, err := r.ensureVersionRollback(&mdb)
+	if err != nil {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error rolling back the MongoDB version: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	// the spec only changes for this reconciliation, the status update keeps the spec of the resource.
+	holdingScaleDown := r.holdScaleDownWhileRemovingMembers(&mdb)
+
+	if err := r.ensureKeyfileRotation(&mdb); err != nil {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error rotating the keyfile: %s", err)).
+				withFailedPhase(),
+		)
+	}
 
 	r.log.Debug("Ensuring the service exists")
 	if err := r.ensureService(mdb); err != nil {
@@ -149,6 +296,22 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 		)
 	}
 
+	if err := r.ensureSecondaryService(mdb); err != nil {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error ensuring the secondary service: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	if err := r.ensureAgentStatusService(mdb); err != nil {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error ensuring the agent status service: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
 	isTLSValid, err := r.validateTLSConfig(mdb)
 	if err != nil {
 		return status.Update(r.client.Status(), &mdb,
@@ -159,12 +322,15 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 	}
 
 	if !isTLSValid {
+		retryAfter := r.nextTLSRetryDelay(mdb)
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
-				withMessage(Info, "TLS config is not yet valid, retrying in 10 seconds").
-				withPendingPhase(10),
+				withMessage(Info, fmt.Sprintf(`Waiting for TLS resources: ConfigMap "%s" with field "%s" and Secret "%s" with fields "%s" and "%s" must exist, retrying in %d seconds`,
+					mdb.TLSConfigMapNamespacedName(), tlsCACertName, mdb.TLSSecretNamespacedName(), tlsSecretCertName, tlsSecretKeyName, retryAfter)).
+				withPendingPhase(retryAfter),
 		)
 	}
+	r.tlsRetries.Delete(mdb.NamespacedName())
 
 	if err := r.ensureTLSResources(mdb); err != nil {
 		return status.Update(r.client.Status(), &mdb,
@@ -174,6 +340,30 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 		)
 	}
 
+	missingSecret, username, err := r.missingPasswordSecret(mdb)
+	if err != nil {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error reading the user password secrets: %s", err)).
+				withFailedPhase(),
+		)
+	}
+	if missingSecret.Name != "" {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Info, fmt.Sprintf(`Waiting for password secret %s of user "%s", retrying in %d seconds`, missingSecret, username, r.passwordSecretRetrySeconds)).
+				withPendingPhase(r.passwordSecretRetrySeconds),
+		)
+	}
+
+	if err := r.validateUserPasswordSecrets(mdb); err != nil {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Info, fmt.Sprintf("User password secret is not yet valid, retrying in 10 seconds: %s", err)).
+				withPendingPhase(10),
+		)
+	}
+
 	if err := r.ensureUserResources(mdb); err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
@@ -182,7 +372,44 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 		)
 	}
 
+	fcvReady, err := r.ensureFeatureCompatibilityVersionDowngrade(mdb)
+	if err != nil {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error changing the featureCompatibilityVersion: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	if !fcvReady {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Info, "Lowering the featureCompatibilityVersion before changing the MongoDB version, retrying in 10 seconds").
+				withPendingPhase(10),
+		)
+	}
+
+	if rolledBackVersion == "" {
+		waitingForQuorum, err := r.checkQuorumForVersionChange(mdb)
+		if err != nil {
+			return status.Update(r.client.Status(), &mdb,
+				statusOptions().
+					withMessage(Error, fmt.Sprintf("Error checking the quorum for the version change: %s", err)).
+					withFailedPhase(),
+			)
+		}
+		if waitingForQuorum != "" {
+			return status.Update(r.client.Status(), &mdb,
+				statusOptions().
+					withMessage(Info, waitingForQuorum+", retrying in 10 seconds").
+					withMemberStatuses(r.memberStatuses(mdb)).
+					withPendingPhase(10),
+			)
+		}
+	}
+
 	ready, err := r.deployMongoDBReplicaSet(mdb)
+	r.updateConfigConvergenceLag(mdb)
 	if err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
@@ -191,10 +418,19 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 		)
 	}
 
+	members := r.memberStatuses(mdb)
 	if !ready {
+		message := "ReplicaSet is not yet ready, retrying in 10 seconds"
+		if rolledBackVersion != "" {
+			if err := r.deleteRolledBackPods(mdb); err != nil {
+				r.log.Errorf("Could not delete the pods of the MongoDB version which has been rolled back: %s", err)
+			}
+			message = fmt.Sprintf("Rolling back MongoDB version %s to version %s, retrying in 10 seconds", rolledBackVersion, mdb.Spec.Version)
+		}
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
-				withMessage(Info, "ReplicaSet is not yet ready, retrying in 10 seconds").
+				withMemberStatuses(members).
+				withMessage(Info, message+notReadyMessage(members)).
 				withPendingPhase(10),
 		)
 	}
@@ -214,15 +450,62 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 			withMessage(Info, fmt.Sprintf("Performing scaling operation, currentMembers=%d, desiredMembers=%d",
 				mdb.CurrentReplicas(), mdb.DesiredReplicas())).
 			withStatefulSetReplicas(mdb.StatefulSetReplicasThisReconciliation()).
+			withMemberStatuses(members).
+			withPendingPhase(10),
+		)
+	}
+
+	// a held back scale down continues with the next reconciliation.
+	if r.isRemovingMembers(mdb) || holdingScaleDown {
+		message := "Removing the members of membersToRemove one at a time"
+		if holdingScaleDown {
+			message += ", scaling down is held back until they have been removed"
+		}
+		return status.Update(r.client.Status(), &mdb, statusOptions().
+			withMongoDBMembers(mdb.AutomationConfigMembersThisReconciliation()).
+			withMessage(Info, message+", retrying in 10 seconds").
+			withStatefulSetReplicas(mdb.StatefulSetReplicasThisReconciliation()).
+			withMemberStatuses(members).
 			withPendingPhase(10),
 		)
 	}
 
+	if rolledBackVersion != "" {
+		return r.completeVersionRollback(mdb, rolledBackVersion, members)
+	}
+
+	if isRotatingKeyfile(mdb) {
+		return status.Update(r.client.Status(), &mdb, statusOptions().
+			withMongoDBMembers(mdb.AutomationConfigMembersThisReconciliation()).
+			withMessage(Info, fmt.Sprintf("Rotating the keyfile, phase %s has been rolled out, retrying in 10 seconds",
+				annotations.GetAnnotation(&mdb, annotations.KeyfileRotationPhase))).
+			withStatefulSetReplicas(mdb.StatefulSetReplicasThisReconciliation()).
+			withMemberStatuses(members).
+			withPendingPhase(10),
+		)
+	}
+
+	// the resource stays pending while changes are held back, so that the reconciliation is not skipped
+	// once the version is unfrozen.
+	if frozenVersion, ok := r.frozenVersions.Load(mdb.NamespacedName()); ok {
+		return status.Update(r.client.Status(), &mdb, statusOptions().
+			withMongoDBMembers(mdb.AutomationConfigMembersThisReconciliation()).
+			withMessage(Info, fmt.Sprintf("The automation config version is frozen at %d by the %s annotation, the pending changes are applied once it is removed, retrying in %d seconds",
+				frozenVersion, annotations.FreezeAutomationConfigVersion, frozenRetrySeconds)).
+			withStatefulSetReplicas(mdb.StatefulSetReplicasThisReconciliation()).
+			withMemberStatuses(members).
+			withPendingPhase(frozenRetrySeconds),
+		)
+	}
+
 	res, err := status.Update(r.client.Status(), &mdb,
 		statusOptions().
-			withMongoURI(mdb.MongoURI()).
+			withObservedGeneration(mdb.Generation).
+			withMongoURI(r.mongoURI(mdb)).
 			withMongoDBMembers(mdb.AutomationConfigMembersThisReconciliation()).
 			withStatefulSetReplicas(mdb.StatefulSetReplicasThisReconciliation()).
+			withMemberStatuses(members).
+			withTopology(r.topologyStatus(mdb)).
 			withMessage(None, "").
 			withRunningPhase(),
 	)
@@ -235,17 +518,83 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 		r.log.Errorf("Could not update connection string secrets: %s", err)
 	}
 
+	if err := r.deleteOrphanedScramCredentialsSecrets(mdb); err != nil {
+		r.log.Errorf("Could not delete orphaned SCRAM credentials secrets: %s", err)
+	}
+
 	if err := r.updateLastSuccessfulConfiguration(mdb); err != nil {
 		r.log.Errorf("Could not save current spec as an annotation: %s", err)
 	}
 
+	r.refreshPrimary(mdb)
+
+	if mdb.Spec.Agent.UpgradeReadinessInitialDelaySeconds != 0 && mdb.IsChangingVersion() {
+		// the pods still have the extended readiness delay of the version change, it is removed by the next
+		// reconciliation, which is not skipped as the generation is only recorded below.
+		r.log.Info("Requeuing reconciliation to restore the readiness delay after the version change")
+		return result.Retry(1)
+	}
+
 	if res.RequeueAfter > 0 || res.Requeue {
 		r.log.Info("Requeuing reconciliation")
 		return res, nil
 	}
 
+	r.reconciledGenerations.Store(mdb.NamespacedName(), mdb.Generation)
+
 	r.log.Infof("Successfully finished reconciliation, MongoDB.Spec: %+v, MongoDB.Status: %+v", mdb.Spec, mdb.Status)
-	return res, err
+	return r.memberRolesResult(mdb)
+}
+
+// memberRolesResult returns the result of a successful reconciliation. If the secondary Service is enabled, the resource
+// is requeued so that the role labels of the pods keep up with elections, which don't trigger any reconciliation.
+// The same applies to the member health checks, if they are enabled.
+func (r ReplicaSetReconciler) memberRolesResult(mdb mdbv1.MongoDBCommunity) (reconcile.Result, error) {
+	if !mdb.Spec.EnableSecondaryService && !r.memberHealthChecks {
+		return result.OK()
+	}
+	return reconcile.Result{RequeueAfter: primaryCacheTTL}, nil
+}
+
+// isUpToDate returns whether the current generation of the resource has already been successfully reconciled
+// by this operator process and the owned StatefulSets are still ready, in which case the reconciliation can be
+// skipped. Reconciliations triggered by changes to watched Secrets, ConfigMaps and pods, or by a requested restart,
+// are never skipped.
+func (r ReplicaSetReconciler) isUpToDate(mdb mdbv1.MongoDBCommunity) bool {
+	// all the triggers need to be consumed, even if the first one was set.
+	triggeredBySecret := r.secretWatcher.ConsumeTrigger(mdb.NamespacedName())
+	triggeredByConfigMap := r.configMapWatcher.ConsumeTrigger(mdb.NamespacedName())
+	triggeredByPod := r.podWatcher.ConsumeTrigger(mdb.NamespacedName())
+	if triggeredBySecret || triggeredByConfigMap || triggeredByPod {
+		return false
+	}
+
+	if mdb.Generation == 0 || mdb.Status.Phase != mdbv1.Running || mdb.Status.ObservedGeneration != mdb.Generation {
+		return false
+	}
+
+	// after a restart of the operator, every resource is reconciled at least once
+	// so that changes to the operator configuration are applied.
+	if generation, ok := r.reconciledGenerations.Load(mdb.NamespacedName()); !ok || generation.(int64) != mdb.Generation {
+		return false
+	}
+
+	sts, err := r.client.GetStatefulSet(mdb.NamespacedName())
+	if err != nil || !statefulset.IsReady(sts, mdb.Spec.Members) {
+		return false
+	}
+	if restartedAt, ok := mdb.Annotations[annotations.RestartedAt]; ok && sts.Spec.Template.Annotations[annotations.RestartedAt] != restartedAt {
+		return false
+	}
+
+	if mdb.HasSeparateArbiters() {
+		arbiterSts, err := r.client.GetStatefulSet(mdb.ArbiterNamespacedName())
+		if err != nil || !statefulset.IsReady(arbiterSts, mdb.Spec.Arbiters) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // updateLastSuccessfulConfiguration annotates the MongoDBCommunity resource with the latest configuration
@@ -289,6 +638,10 @@ func (r *ReplicaSetReconciler) deployStatefulSet(mdb mdbv1.MongoDBCommunity) (bo
 		return false, errors.Errorf("error creating/updating StatefulSet: %s", err)
 	}
 
+	if err := r.ensureArbiterStatefulSet(mdb); err != nil {
+		return false, errors.Errorf("error creating/updating arbiter StatefulSet: %s", err)
+	}
+
 	currentSts, err := r.client.GetStatefulSet(mdb.NamespacedName())
 	if err != nil {
 		return false, errors.Errorf("error getting StatefulSet: %s", err)
@@ -298,6 +651,14 @@ func (r *ReplicaSetReconciler) deployStatefulSet(mdb mdbv1.MongoDBCommunity) (bo
 
 	isReady := statefulset.IsReady(currentSts, mdb.StatefulSetReplicasThisReconciliation())
 
+	if mdb.HasSeparateArbiters() {
+		arbiterSts, err := r.client.GetStatefulSet(mdb.ArbiterNamespacedName())
+		if err != nil {
+			return false, errors.Errorf("error getting arbiter StatefulSet: %s", err)
+		}
+		isReady = isReady && statefulset.IsReady(arbiterSts, mdb.Spec.Arbiters)
+	}
+
 	return isReady || currentSts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType, nil
 }
 
@@ -341,6 +702,13 @@ func (r *ReplicaSetReconciler) deployAutomationConfig(mdb mdbv1.MongoDBCommunity
 // shouldRunInOrder returns true if the order of execution of the AutomationConfig & StatefulSet
 // functions should be sequential or not. A value of false indicates they will run in reversed order.
 func (r *ReplicaSetReconciler) shouldRunInOrder(mdb mdbv1.MongoDBCommunity) bool {
+	// mongod reads the keyfile on startup, the keyfile of a rotation phase must be in the Automation Config before
+	// the members are restarted. The rotation is paused during version changes, and doesn't hold back scaling up.
+	if isRotatingKeyfile(mdb) && !mdb.IsChangingVersion() && !scale.IsScalingUp(mdb) {
+		r.log.Debug("Rotating the keyfile, the Automation Config must be updated first")
+		return true
+	}
+
 	// The only case when we push the StatefulSet first is when we are ensuring TLS for the already existing ReplicaSet
 	_, err := r.client.GetStatefulSet(mdb.NamespacedName())
 	if err == nil && mdb.Spec.Security.TLS.Enabled {
@@ -382,30 +750,121 @@ func (r *ReplicaSetReconciler) deployMongoDBReplicaSet(mdb mdbv1.MongoDBCommunit
 		})
 }
 
+// ensureService creates the headless Service, or updates it with the configured annotations and labels. Annotations
+// and labels which have been added to the Service by others are kept.
 func (r *ReplicaSetReconciler) ensureService(mdb mdbv1.MongoDBCommunity) error {
-	svc := buildService(mdb)
-	err := r.client.Create(context.TODO(), &svc)
-	if err != nil && apiErrors.IsAlreadyExists(err) {
-		r.log.Infof("The service already exists... moving forward: %s", err)
-		return nil
+	return service.CreateOrUpdateService(r.client, buildService(mdb))
+}
+
+// ensureArbiterStatefulSet creates or updates the StatefulSet of the arbiters and its headless Service if they
+// are deployed separately, and deletes them otherwise. The arbiters keep no data, so their StatefulSet is deleted
+// along with its pods and created again if a field which can't be changed has changed.
+func (r *ReplicaSetReconciler) ensureArbiterStatefulSet(mdb mdbv1.MongoDBCommunity) error {
+	arbiterNsName := mdb.ArbiterNamespacedName()
+	if !mdb.HasSeparateArbiters() {
+		if err := k8sClient.IgnoreNotFound(r.client.DeleteStatefulSet(arbiterNsName)); err != nil {
+			return err
+		}
+		return service.DeleteServiceIfItExists(r.client, types.NamespacedName{Name: mdb.ArbiterServiceName(), Namespace: mdb.Namespace})
+	}
+
+	if err := service.CreateOrUpdateService(r.client, buildArbiterService(mdb)); err != nil {
+		return errors.Errorf("error creating/updating arbiter Service: %s", err)
 	}
+
+	set := appsv1.StatefulSet{}
+	err := r.client.Get(context.TODO(), arbiterNsName, &set)
+	alreadyExists := err == nil
+	if err = k8sClient.IgnoreNotFound(err); err != nil {
+		return errors.Errorf("error getting arbiter StatefulSet: %s", err)
+	}
+	existing := set.DeepCopy()
+	buildArbiterStatefulSetModificationFunction(mdb)(&set)
+
+	if alreadyExists {
+		if changedFields := statefulset.ChangedImmutableFields(*existing, set); len(changedFields) > 0 {
+			if existing.DeletionTimestamp == nil {
+				r.log.Infof("Recreating the arbiter StatefulSet %s to change the fields %s", arbiterNsName, strings.Join(changedFields, ", "))
+				if err := k8sClient.IgnoreNotFound(r.client.DeleteStatefulSet(arbiterNsName)); err != nil {
+					return errors.Errorf("error deleting arbiter StatefulSet: %s", err)
+				}
+			}
+			desired := appsv1.StatefulSet{}
+			buildArbiterStatefulSetModificationFunction(mdb)(&desired)
+			if err := r.client.CreateStatefulSet(desired); err != nil {
+				if apiErrors.IsAlreadyExists(err) {
+					return errors.Errorf("waiting for the arbiter StatefulSet %s to be deleted before it is recreated", arbiterNsName)
+				}
+				return errors.Errorf("error recreating arbiter StatefulSet: %s", err)
+			}
+			return nil
+		}
+	}
+
+	_, err = statefulset.CreateOrUpdate(r.client, set)
 	return err
 }
 
 func (r *ReplicaSetReconciler) createOrUpdateStatefulSet(mdb mdbv1.MongoDBCommunity) error {
 	set := appsv1.StatefulSet{}
 	err := r.client.Get(context.TODO(), mdb.NamespacedName(), &set)
+	alreadyExists := err == nil
 	err = k8sClient.IgnoreNotFound(err)
 	if err != nil {
 		return errors.Errorf("error getting StatefulSet: %s", err)
 	}
+	existing := set.DeepCopy()
 	buildStatefulSetModificationFunction(mdb)(&set)
+
+	// some fields can't be changed on an existing StatefulSet, an update would be rejected by the apiserver.
+	if alreadyExists {
+		if changedFields := statefulset.ChangedImmutableFields(*existing, set); len(changedFields) > 0 {
+			if mdb.Annotations[annotations.AllowStatefulSetRecreate] != "true" {
+				return errors.Errorf(`the StatefulSet fields %s can't be changed on an existing StatefulSet, the StatefulSet must be deleted and recreated to apply them, `+
+					`the operator recreates it if the "%s" annotation is set to "true"`, strings.Join(changedFields, ", "), annotations.AllowStatefulSetRecreate)
+			}
+			return r.recreateStatefulSet(*existing, set, changedFields)
+		}
+	}
+
 	if _, err = statefulset.CreateOrUpdate(r.client, set); err != nil {
+		if statefulset.IsImmutableFieldsError(err) {
+			return errors.Errorf("an immutable field of the StatefulSet has been changed, the StatefulSet must be deleted and recreated to apply it: %s", err)
+		}
 		return errors.Errorf("error creating/updating StatefulSet: %s", err)
 	}
 	return nil
 }
 
+// recreateStatefulSet deletes the existing StatefulSet while keeping its pods, and creates the desired StatefulSet
+// which adopts them. The pods are only updated to the new pod template by the next rollout. The deletion completes
+// asynchronously, in which case an error is returned and the StatefulSet is created by a later reconciliation.
+func (r *ReplicaSetReconciler) recreateStatefulSet(existing, desired appsv1.StatefulSet, changedFields []string) error {
+	if existing.DeletionTimestamp == nil {
+		r.log.Infof("Recreating StatefulSet %s/%s to change the fields %s, the pods are kept", existing.Namespace, existing.Name, strings.Join(changedFields, ", "))
+		if err := r.client.Delete(context.TODO(), &existing, k8sClient.PropagationPolicy(metav1.DeletePropagationOrphan)); k8sClient.IgnoreNotFound(err) != nil {
+			return errors.Errorf("error deleting StatefulSet: %s", err)
+		}
+	}
+
+	// the desired StatefulSet was built on top of the existing one, only the metadata managed by the operator is kept.
+	desired.ObjectMeta = metav1.ObjectMeta{
+		Name:            desired.Name,
+		Namespace:       desired.Namespace,
+		Labels:          desired.Labels,
+		Annotations:     desired.Annotations,
+		OwnerReferences: desired.OwnerReferences,
+	}
+	desired.Status = appsv1.StatefulSetStatus{}
+	if err := r.client.CreateStatefulSet(desired); err != nil {
+		if apiErrors.IsAlreadyExists(err) {
+			return errors.Errorf("waiting for StatefulSet %s/%s to be deleted before it is recreated", desired.Namespace, desired.Name)
+		}
+		return errors.Errorf("error recreating StatefulSet: %s", err)
+	}
+	return nil
+}
+
 // ensureAutomationConfig makes sure the AutomationConfig secret has been successfully created. The automation config
 // that was updated/created is returned.
 func (r ReplicaSetReconciler) ensureAutomationConfig(mdb mdbv1.MongoDBCommunity) (automationconfig.AutomationConfig, error) {
@@ -414,9 +873,33 @@ func (r ReplicaSetReconciler) ensureAutomationConfig(mdb mdbv1.MongoDBCommunity)
 		return automationconfig.AutomationConfig{}, errors.Errorf("could not build automation config: %s", err)
 	}
 
+	acSecretNsName := types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace}
+	// watch the automation config secret so that external edits trigger a reconciliation which overwrites them.
+	r.secretWatcher.Watch(acSecretNsName, mdb.NamespacedName())
+
+	r.frozenVersions.Delete(mdb.NamespacedName())
+	if mdb.Annotations[annotations.FreezeAutomationConfigVersion] == "true" {
+		currentAC, err := automationconfig.ReadFromSecret(r.client, acSecretNsName)
+		if err != nil {
+			return automationconfig.AutomationConfig{}, errors.Errorf("could not read existing automation config: %s", err)
+		}
+		// nothing has been deployed yet, there is no version to freeze.
+		if currentAC.Version > 0 {
+			areEqual, err := automationconfig.AreEqual(ac, currentAC)
+			if err != nil {
+				return automationconfig.AutomationConfig{}, err
+			}
+			if !areEqual {
+				r.log.Infof("The AutomationConfig version is frozen at %d by the %s annotation, pending changes will not be applied", currentAC.Version, annotations.FreezeAutomationConfigVersion)
+				r.frozenVersions.Store(mdb.NamespacedName(), currentAC.Version)
+			}
+			return currentAC, nil
+		}
+	}
+
 	return automationconfig.EnsureSecret(
 		r.client,
-		types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace},
+		acSecretNsName,
 		mdb.GetOwnerReferences(),
 		ac,
 	)
@@ -424,23 +907,102 @@ func (r ReplicaSetReconciler) ensureAutomationConfig(mdb mdbv1.MongoDBCommunity)
 }
 
 func buildAutomationConfig(mdb mdbv1.MongoDBCommunity, auth automationconfig.Auth, currentAc automationconfig.AutomationConfig, modifications ...automationconfig.Modification) (automationconfig.AutomationConfig, error) {
-	domain := getDomain(mdb.ServiceName(), mdb.Namespace, os.Getenv(clusterDNSName))
 	zap.S().Debugw("AutomationConfigMembersThisReconciliation", "mdb.AutomationConfigMembersThisReconciliation()", mdb.AutomationConfigMembersThisReconciliation())
 
+	// arbiters are either the first members of the StatefulSet, or deployed in their own StatefulSet
+	arbiters, separateArbiters := mdb.Spec.Arbiters, 0
+	if mdb.HasSeparateArbiters() {
+		arbiters, separateArbiters = 0, mdb.Spec.Arbiters
+	}
+
 	return automationconfig.NewBuilder().
 		SetTopology(automationconfig.ReplicaSetTopology).
 		SetName(mdb.Name).
-		SetDomain(domain).
+		SetDomain(mdb.ServiceFQDN()).
 		SetMembers(mdb.AutomationConfigMembersThisReconciliation()).
-		SetArbiters(mdb.Spec.Arbiters).
+		SetArbiters(arbiters).
+		SetArbiterMembers(mdb.ArbiterNamespacedName().Name, mdb.ArbiterServiceFQDN(), separateArbiters).
 		SetReplicaSetHorizons(mdb.Spec.ReplicaSetHorizons).
+		SetMemberOptions(mdb.MemberOptions()).
 		SetPreviousAutomationConfig(currentAc).
 		SetMongoDBVersion(mdb.Spec.Version).
 		SetFCV(mdb.Spec.FeatureCompatibilityVersion).
-		SetOptions(automationconfig.Options{DownloadBase: "/var/lib/mongodb-mms-automation"}).
+		SetProtocolVersion(mdb.GetProtocolVersion()).
+		SetWriteConcernMajorityJournalDefault(mdb.Spec.WriteConcernMajorityJournalDefault).
+		SetDataDir(mdb.DataPath()).
+		SetLogDir(mdb.LogsPath()).
+		SetDownloadBase(mdb.DownloadBase()).
+		SetClientCertificateMode(mdb.ClientCertificateMode()).
 		SetAuth(auth).
+		AddModifications(getSystemLogModification(mdb)).
+		AddModifications(getStorageModification(mdb)).
+		AddModifications(getNetModification(mdb)).
+		AddModifications(getOperationProfilingModification(mdb)).
+		AddModifications(getAgentModeModification(mdb)).
+		AddModifications(getWiredTigerCacheModification(mdb)).
+		AddModifications(getDiagnosticsModification(mdb)).
 		AddModifications(getMongodConfigModification(mdb)).
 		AddModifications(modifications...).
+		AddModifications(getMemberHostModification(mdb)).
+		Build()
+}
+
+// ensureSecondaryService creates the Service routing to the secondaries if it is enabled, and deletes it otherwise.
+func (r *ReplicaSetReconciler) ensureSecondaryService(mdb mdbv1.MongoDBCommunity) error {
+	if !mdb.Spec.EnableSecondaryService {
+		return service.DeleteServiceIfItExists(r.client, types.NamespacedName{Name: mdb.SecondaryServiceName(), Namespace: mdb.Namespace})
+	}
+	return service.CreateOrUpdateService(r.client, buildSecondaryService(mdb))
+}
+
+// ensureAgentStatusService creates the Service exposing the status port of the agents if it is enabled, and deletes it otherwise.
+func (r *ReplicaSetReconciler) ensureAgentStatusService(mdb mdbv1.MongoDBCommunity) error {
+	if !mdb.Spec.EnableAgentStatusService {
+		return service.DeleteServiceIfItExists(r.client, types.NamespacedName{Name: mdb.AgentStatusServiceName(), Namespace: mdb.Namespace})
+	}
+	return service.CreateOrUpdateService(r.client, buildAgentStatusService(mdb))
+}
+
+// withOperatorVersion returns a copy of the given annotations, with the annotation of the version of the operator
+// which reconciles the object.
+func withOperatorVersion(objectAnnotations map[string]string) map[string]string {
+	versioned := map[string]string{}
+	for k, v := range objectAnnotations {
+		versioned[k] = v
+	}
+	versioned[annotations.ManagedByVersion] = OperatorVersion
+	return versioned
+}
+
+// buildSecondaryService creates a Service which only selects the pods labeled with the secondary role.
+func buildSecondaryService(mdb mdbv1.MongoDBCommunity) corev1.Service {
+	return service.Builder().
+		SetName(mdb.SecondaryServiceName()).
+		SetNamespace(mdb.Namespace).
+		SetAnnotations(withOperatorVersion(nil)).
+		SetSelector(map[string]string{"app": mdb.ServiceName(), roleLabel: secondaryRole}).
+		SetServiceType(corev1.ServiceTypeClusterIP).
+		SetPort(27017).
+		SetPortName("mongodb").
+		SetSessionAffinity(mdb.Spec.SecondaryServiceSessionAffinity).
+		SetOwnerReferences(mdb.GetOwnerReferences()).
+		Build()
+}
+
+// buildAgentStatusService creates a headless Service which exposes the status port of the agents of all the pods,
+// including the pods which are not ready, as those are usually the ones to debug.
+func buildAgentStatusService(mdb mdbv1.MongoDBCommunity) corev1.Service {
+	return service.Builder().
+		SetName(mdb.AgentStatusServiceName()).
+		SetNamespace(mdb.Namespace).
+		SetAnnotations(withOperatorVersion(nil)).
+		SetSelector(map[string]string{"app": mdb.ServiceName()}).
+		SetServiceType(corev1.ServiceTypeClusterIP).
+		SetClusterIP("None").
+		SetPort(construct.AgentStatusPort).
+		SetPortName("agent-status").
+		SetPublishNotReadyAddresses(true).
+		SetOwnerReferences(mdb.GetOwnerReferences()).
 		Build()
 }
 
@@ -454,6 +1016,8 @@ func buildService(mdb mdbv1.MongoDBCommunity) corev1.Service {
 	return service.Builder().
 		SetName(mdb.ServiceName()).
 		SetNamespace(mdb.Namespace).
+		SetAnnotations(withOperatorVersion(mdb.Spec.Service.Annotations)).
+		SetLabels(mdb.Spec.Service.Labels).
 		SetSelector(label).
 		SetServiceType(corev1.ServiceTypeClusterIP).
 		SetClusterIP("None").
@@ -464,23 +1028,59 @@ func buildService(mdb mdbv1.MongoDBCommunity) corev1.Service {
 		Build()
 }
 
+// arbiterLabels returns the labels of the pods of the arbiter StatefulSet.
+func arbiterLabels(mdb mdbv1.MongoDBCommunity) map[string]string {
+	return map[string]string{
+		"app":        mdb.ArbiterServiceName(),
+		arbiterLabel: "true",
+	}
+}
+
+// buildArbiterService creates the headless Service of the arbiter StatefulSet, which provides the hostnames of
+// the arbiters as buildService does for the data-bearing members.
+func buildArbiterService(mdb mdbv1.MongoDBCommunity) corev1.Service {
+	return service.Builder().
+		SetName(mdb.ArbiterServiceName()).
+		SetNamespace(mdb.Namespace).
+		SetAnnotations(withOperatorVersion(nil)).
+		SetSelector(arbiterLabels(mdb)).
+		SetServiceType(corev1.ServiceTypeClusterIP).
+		SetClusterIP("None").
+		SetPort(27017).
+		SetPortName("mongodb").
+		SetPublishNotReadyAddresses(true).
+		SetOwnerReferences(mdb.GetOwnerReferences()).
+		Build()
+}
+
 // validateSpec checks if the MongoDB resource Spec is valid.
 // If there has not yet been a successful configuration, the function runs the intial Spec validations. Otherwise
 // it checks that the attempted Spec is valid in relation to the Spec that resulted from that last successful configuration.
 func (r ReplicaSetReconciler) validateSpec(mdb mdbv1.MongoDBCommunity) error {
-	lastSuccessfulConfigurationSaved, ok := mdb.Annotations[lastSuccessfulConfiguration]
-	if !ok {
+	lastSpec, err := lastSuccessfulSpec(mdb)
+	if err != nil {
+		return err
+	}
+	if lastSpec == nil {
 		// First version of Spec
 		return validation.ValidateInitalSpec(mdb)
 	}
 
-	lastSpec := mdbv1.MongoDBCommunitySpec{}
-	err := json.Unmarshal([]byte(lastSuccessfulConfigurationSaved), &lastSpec)
-	if err != nil {
-		return err
+	return validation.ValidateUpdate(mdb, *lastSpec)
+}
+
+// lastSuccessfulSpec returns the Spec of the last successful configuration, or nil if there has not been one yet.
+func lastSuccessfulSpec(mdb mdbv1.MongoDBCommunity) (*mdbv1.MongoDBCommunitySpec, error) {
+	lastSuccessfulConfigurationSaved, ok := mdb.Annotations[lastSuccessfulConfiguration]
+	if !ok {
+		return nil, nil
 	}
 
-	return validation.ValidateUpdate(mdb, lastSpec)
+	lastSpec := mdbv1.MongoDBCommunitySpec{}
+	if err := json.Unmarshal([]byte(lastSuccessfulConfigurationSaved), &lastSpec); err != nil {
+		return nil, err
+	}
+	return &lastSpec, nil
 }
 
 func getCustomRolesModification(mdb mdbv1.MongoDBCommunity) (automationconfig.Modification, error) {
@@ -510,10 +1110,31 @@ func (r ReplicaSetReconciler) buildAutomationConfig(mdb mdbv1.MongoDBCommunity)
 		return automationconfig.AutomationConfig{}, errors.Errorf("could not read existing automation config: %s", err)
 	}
 
+	mongodConfigMapModification, err := r.getMongodConfigMapModification(mdb)
+	if err != nil {
+		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure additional mongod config from ConfigMap: %s", err)
+	}
+
+	memberAddressingModification, err := r.getMemberAddressingModification(mdb, currentAC)
+	if err != nil {
+		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure the addresses of the members: %s", err)
+	}
+
+	// watch the existing keyfile secret so that changes to it trigger a reconciliation.
+	if keyfileSecret := mdb.GetScramOptions().KeyfileSecret; keyfileSecret.Name != "" {
+		r.secretWatcher.Watch(keyfileSecret, mdb.NamespacedName())
+	}
+	// watch the externally managed agent credentials so that their creation triggers a reconciliation.
+	if mdb.GetScramOptions().AgentCredentialsManagedExternally {
+		r.secretWatcher.Watch(mdb.GetAgentPasswordSecretNamespacedName(), mdb.NamespacedName())
+		r.secretWatcher.Watch(mdb.GetAgentKeyfileSecretNamespacedName(), mdb.NamespacedName())
+	}
+
 	auth := automationconfig.Auth{}
 	if err := scram.Enable(&auth, r.client, mdb); err != nil {
 		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure scram authentication: %s", err)
 	}
+	auth.Key = getKeyfileForRotation(mdb, currentAC.Auth.Key, auth.Key)
 
 	return buildAutomationConfig(
 		mdb,
@@ -521,9 +1142,138 @@ func (r ReplicaSetReconciler) buildAutomationConfig(mdb mdbv1.MongoDBCommunity)
 		currentAC,
 		tlsModification,
 		customRolesModification,
+		mongodConfigMapModification,
+		memberAddressingModification,
+		getMemberRemovalModification(mdb, currentAC),
 	)
 }
 
+// BuildOfflineAutomationConfig validates the given resource and builds the automation config the operator
+// would generate for it, without reading from or writing to a cluster. Authentication and TLS are not configured
+// as they depend on Secrets and ConfigMaps, and the desired number of members is used regardless of the status.
+func BuildOfflineAutomationConfig(mdb mdbv1.MongoDBCommunity) (automationconfig.AutomationConfig, error) {
+	if err := validation.ValidateInitalSpec(mdb); err != nil {
+		return automationconfig.AutomationConfig{}, errors.Errorf("invalid spec: %s", err)
+	}
+
+	customRolesModification, err := getCustomRolesModification(mdb)
+	if err != nil {
+		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure custom roles: %s", err)
+	}
+
+	mdb.Status = mdbv1.MongoDBCommunityStatus{}
+	return buildAutomationConfig(
+		mdb,
+		automationconfig.DisabledAuth(),
+		automationconfig.AutomationConfig{},
+		customRolesModification,
+		getMemberRemovalModification(mdb, automationconfig.AutomationConfig{}),
+	)
+}
+
+// getMongodConfigMapModification reads the mongod configuration file from the ConfigMap referenced
+// in the CRD and returns a modification which merges it into the configuration set up by the operator.
+// Settings which have already been configured, either by the operator or through AdditionalMongodConfig,
+// are never overridden by the contents of the file.
+func (r ReplicaSetReconciler) getMongodConfigMapModification(mdb mdbv1.MongoDBCommunity) (automationconfig.Modification, error) {
+	if mdb.Spec.AdditionalMongodConfigMap == nil {
+		return automationconfig.NOOP(), nil
+	}
+
+	configMapNsName := mdb.AdditionalMongodConfigMapNamespacedName()
+	contents, err := configmap.ReadKey(r.client, mdb.Spec.AdditionalMongodConfigMap.GetKey(), configMapNsName)
+	if err != nil {
+		return automationconfig.NOOP(), err
+	}
+
+	// Watch the ConfigMap so that changes to the file are propagated to the automation config
+	r.configMapWatcher.Watch(configMapNsName, mdb.NamespacedName())
+
+	mongodConfig := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(contents), &mongodConfig); err != nil {
+		return automationconfig.NOOP(), errors.Errorf("could not parse mongod config file from ConfigMap %s: %s", configMapNsName, err)
+	}
+
+	return func(ac *automationconfig.AutomationConfig) {
+		for i := range ac.Processes {
+			// Without the override option, mergo only adds the keys which are not yet present
+			_ = mergo.Merge(&ac.Processes[i].Args26, objx.New(mongodConfig))
+		}
+	}, nil
+}
+
+// getSystemLogModification configures the log verbosity of every process.
+func getSystemLogModification(mdb mdbv1.MongoDBCommunity) automationconfig.Modification {
+	return func(ac *automationconfig.AutomationConfig) {
+		for i := range ac.Processes {
+			ac.Processes[i].SetLogVerbosity(mdb.Spec.SystemLog.Verbosity, mdb.Spec.SystemLog.Component)
+		}
+	}
+}
+
+// getStorageModification configures the journal commit interval and the sync period of every process.
+func getStorageModification(mdb mdbv1.MongoDBCommunity) automationconfig.Modification {
+	return func(ac *automationconfig.AutomationConfig) {
+		for i := range ac.Processes {
+			ac.Processes[i].
+				SetJournalCommitInterval(mdb.Spec.Storage.JournalCommitIntervalMs).
+				SetSyncPeriod(mdb.Spec.Storage.SyncPeriodSecs)
+		}
+	}
+}
+
+// getNetModification configures the maximum number of incoming connections of every process.
+func getNetModification(mdb mdbv1.MongoDBCommunity) automationconfig.Modification {
+	return func(ac *automationconfig.AutomationConfig) {
+		for i := range ac.Processes {
+			ac.Processes[i].SetMaxIncomingConnections(mdb.Spec.Net.MaxIncomingConnections)
+		}
+	}
+}
+
+// getOperationProfilingModification configures the profiler mode and the slow operation threshold of every process.
+func getOperationProfilingModification(mdb mdbv1.MongoDBCommunity) automationconfig.Modification {
+	profiling := mdb.Spec.OperationProfiling
+	return func(ac *automationconfig.AutomationConfig) {
+		for i := range ac.Processes {
+			ac.Processes[i].SetOperationProfiling(profiling.Mode, profiling.SlowOpThresholdMs)
+		}
+	}
+}
+
+// getDiagnosticsModification turns off the free monitoring and the diagnostic data capture of every process if
+// they are disabled, and sets the interval of the diagnostic data capture. Free monitoring is only turned off for
+// the MongoDB versions which have it, as other versions don't accept its setting.
+func getDiagnosticsModification(mdb mdbv1.MongoDBCommunity) automationconfig.Modification {
+	diagnostics := mdb.Spec.Diagnostics
+	// the version has been validated.
+	freeMonitoringSupported, _ := versions.IsFreeMonitoringSupported(mdb.Spec.Version)
+	return func(ac *automationconfig.AutomationConfig) {
+		for i := range ac.Processes {
+			if diagnostics.DisableFreeMonitoring && freeMonitoringSupported {
+				ac.Processes[i].DisableFreeMonitoring()
+			}
+			if diagnostics.DisableDiagnosticDataCollection {
+				ac.Processes[i].DisableDiagnosticDataCollection()
+			}
+			ac.Processes[i].SetDiagnosticDataCollectionPeriod(diagnostics.DiagnosticDataCollectionPeriodMillis)
+		}
+	}
+}
+
+// getAgentModeModification configures the agent to only monitor the processes in the MonitoringOnly mode,
+// their configuration and lifecycle are then left to the user.
+func getAgentModeModification(mdb mdbv1.MongoDBCommunity) automationconfig.Modification {
+	if !mdb.IsAgentMonitoringOnly() {
+		return automationconfig.NOOP()
+	}
+	return func(ac *automationconfig.AutomationConfig) {
+		for i := range ac.Processes {
+			ac.Processes[i].ManualMode = true
+		}
+	}
+}
+
 // getMongodConfigModification will merge the additional configuration in the CRD
 // into the configuration set up by the operator.
 func getMongodConfigModification(mdb mdbv1.MongoDBCommunity) automationconfig.Modification {
@@ -536,34 +1286,328 @@ func getMongodConfigModification(mdb mdbv1.MongoDBCommunity) automationconfig.Mo
 	}
 }
 
-// buildStatefulSet takes a MongoDB resource and converts it into
-// the corresponding stateful set
-func buildStatefulSet(mdb mdbv1.MongoDBCommunity) (appsv1.StatefulSet, error) {
+// BuildStatefulSet returns the StatefulSet of the members of the given resource as the operator deploys it,
+// including the TLS and keyfile volumes and the StatefulSet override. It doesn't need a client, so that the
+// intended StatefulSet can be diffed with a deployed one in dry runs. The status and the fields which are
+// set by the apiserver are not set.
+func BuildStatefulSet(mdb mdbv1.MongoDBCommunity) appsv1.StatefulSet {
 	sts := appsv1.StatefulSet{}
 	buildStatefulSetModificationFunction(mdb)(&sts)
-	return sts, nil
+	return sts
 }
 
 func buildStatefulSetModificationFunction(mdb mdbv1.MongoDBCommunity) statefulset.Modification {
+	return statefulset.Apply(
+		buildMongodStatefulSetModificationFunction(mdb),
+		buildHostPathStorageModification(mdb),
+		statefulset.WithRollingUpdatePartition(mdb.GetRollingUpdatePartition()),
+		statefulset.WithCustomSpecs(mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec),
+	)
+}
+
+// buildHostPathStorageModification replaces the data volume claim with a hostPath volume when it is configured.
+// The members share the host directory, each of them stores its data in a sub directory named after the pod.
+func buildHostPathStorageModification(mdb mdbv1.MongoDBCommunity) statefulset.Modification {
+	if mdb.Spec.Storage.HostPath == "" {
+		return statefulset.NOOP()
+	}
+
+	dataVolumeMount := statefulset.CreateVolumeMount(mdb.DataVolumeName(), mdb.DataPath(), statefulset.WithSubPathExpr("$("+podNameEnv+")"))
+	podNameEnvVar := corev1.EnvVar{
+		Name: podNameEnv,
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				APIVersion: "v1",
+				FieldPath:  "metadata.name",
+			},
+		},
+	}
+
+	return statefulset.Apply(
+		statefulset.WithoutVolumeClaim(mdb.DataVolumeName()),
+		statefulset.WithPodSpecTemplate(
+			podtemplatespec.Apply(
+				podtemplatespec.WithVolume(statefulset.CreateVolumeFromHostPath(mdb.DataVolumeName(), mdb.Spec.Storage.HostPath)),
+				podtemplatespec.WithContainer(construct.AgentName, container.WithEnvs(podNameEnvVar)),
+				podtemplatespec.WithContainer(construct.MongodbName, container.WithEnvs(podNameEnvVar)),
+				podtemplatespec.WithVolumeMounts(construct.AgentName, dataVolumeMount),
+				podtemplatespec.WithVolumeMounts(construct.MongodbName, dataVolumeMount),
+			),
+		),
+	)
+}
+
+// buildArbiterStatefulSetModificationFunction builds the StatefulSet of the arbiters when they are deployed
+// separately. The arbiters run the same containers as the data-bearing members, but store their data and
+// logs in emptyDir volumes and only the arbiter StatefulSet override is applied. Their pods are labeled
+// with the name of their own headless Service, so that the StatefulSet of the data-bearing members doesn't
+// select them.
+func buildArbiterStatefulSetModificationFunction(mdb mdbv1.MongoDBCommunity) statefulset.Modification {
+	labels := arbiterLabels(mdb)
+
+	volumes := podtemplatespec.WithVolume(statefulset.CreateVolumeFromEmptyDir(mdb.DataVolumeName()))
+	if mdb.HasSeparateDataAndLogsVolumes() {
+		volumes = podtemplatespec.Apply(
+			volumes,
+			podtemplatespec.WithVolume(statefulset.CreateVolumeFromEmptyDir(mdb.LogsVolumeName())),
+		)
+	}
+
+	return statefulset.Apply(
+		buildMongodStatefulSetModificationFunction(mdb),
+		statefulset.WithName(mdb.ArbiterNamespacedName().Name),
+		statefulset.WithServiceName(mdb.ArbiterServiceName()),
+		statefulset.WithReplicas(mdb.Spec.Arbiters),
+		statefulset.WithLabels(labels),
+		statefulset.WithMatchLabels(labels),
+		statefulset.WithoutVolumeClaims(),
+		statefulset.WithPodSpecTemplate(
+			podtemplatespec.Apply(
+				podtemplatespec.WithPodLabels(labels),
+				volumes,
+			),
+		),
+		statefulset.WithCustomSpecs(mdb.Spec.ArbiterStatefulSetConfiguration.SpecWrapper.Spec),
+	)
+}
+
+// buildMongodStatefulSetModificationFunction builds the parts of the StatefulSet which are shared between
+// the data-bearing members and the arbiters.
+func buildMongodStatefulSetModificationFunction(mdb mdbv1.MongoDBCommunity) statefulset.Modification {
 	commonModification := construct.BuildMongoDBReplicaSetStatefulSetModificationFunction(&mdb, mdb)
 	return statefulset.Apply(
+		// the additional environment variables are applied first, so that the environment
+		// variables configured by the operator take precedence.
+		buildAdditionalEnvStatefulSetModification(mdb),
 		commonModification,
+		statefulset.WithPodSpecTemplate(buildAgentModePodSpecModification(mdb)),
+		statefulset.WithPodSpecTemplate(buildMongodCommandPodSpecModification(mdb)),
+		statefulset.WithPodSpecTemplate(buildAdditionalInitContainersPodSpecModification(mdb)),
 		statefulset.WithOwnerReference(mdb.GetOwnerReferences()),
+		statefulset.WithAnnotations(withOperatorVersion(nil)),
 		statefulset.WithPodSpecTemplate(
 			podtemplatespec.Apply(
 				buildTLSPodSpecModification(mdb),
+				buildMongodConfigMapPodSpecModification(mdb),
+				buildAgentCAPodSpecModification(mdb),
+				buildAgentCommandPodSpecModification(mdb),
+				buildMemberAddressingPodSpecModification(mdb),
+				buildAgentLivenessPodSpecModification(mdb),
+				buildMongodStartupPodSpecModification(mdb),
+				buildRestartPodSpecModification(mdb),
+				buildKeyfileRotationPodSpecModification(mdb),
+				buildUpgradeReadinessPodSpecModification(mdb),
+				buildShutdownPodSpecModification(mdb),
+				podtemplatespec.WithReadinessGates(mdb.Spec.ReadinessGates),
 			),
 		),
+	)
+}
 
-		statefulset.WithCustomSpecs(mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec),
+// buildRestartPodSpecModification propagates the restartedAt annotation of the resource into the pod template,
+// so that changing it triggers a rolling restart of the pods. While the version is being changed the
+// annotation is not propagated, the pods are restarted by the version upgrade with the OnDelete strategy
+// and the restart will be performed once the upgrade has completed.
+func buildRestartPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	restartedAt, ok := mdb.Annotations[annotations.RestartedAt]
+	if !ok || mdb.IsChangingVersion() {
+		return podtemplatespec.NOOP()
+	}
+	return podtemplatespec.WithAnnotation(annotations.RestartedAt, restartedAt)
+}
+
+// buildUpgradeReadinessPodSpecModification extends the initial delay of the readiness probe of the mongodb-agent
+// container while the version is being changed. The pods are restarted by the upgrade with the OnDelete strategy
+// and pick up the extended delay then. The reconciliation which completes the upgrade is requeued, and the next
+// one restores the default delay with a rolling restart of the pods.
+func buildUpgradeReadinessPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	initialDelay := mdb.Spec.Agent.UpgradeReadinessInitialDelaySeconds
+	if initialDelay == 0 || !mdb.IsChangingVersion() {
+		return podtemplatespec.NOOP()
+	}
+	return podtemplatespec.WithContainer(construct.AgentName, container.WithReadinessProbe(probes.WithInitialDelaySeconds(initialDelay)))
+}
+
+// buildAdditionalEnvStatefulSetModification adds the additional environment variables to the mongod and the
+// mongodb-agent containers. The names of the additional environment variables are recorded in an annotation of the
+// StatefulSet, so that the variables which are no longer configured are removed from the containers.
+func buildAdditionalEnvStatefulSetModification(mdb mdbv1.MongoDBCommunity) statefulset.Modification {
+	return func(sts *appsv1.StatefulSet) {
+		configured := map[string]bool{}
+		var names []string
+		for _, env := range mdb.Spec.AdditionalEnv {
+			configured[env.Name] = true
+			names = append(names, env.Name)
+		}
+
+		var removed []string
+		for _, name := range strings.Split(sts.Annotations[additionalEnvAnnotation], ",") {
+			if name != "" && !configured[name] {
+				removed = append(removed, name)
+			}
+		}
+		for _, containerName := range []string{construct.AgentName, construct.MongodbName} {
+			if c := podtemplatespec.FindContainerByName(containerName, &sts.Spec.Template); c != nil {
+				c.Env = envvar.Remove(c.Env, removed...)
+			}
+		}
+
+		if len(names) == 0 {
+			delete(sts.Annotations, additionalEnvAnnotation)
+			return
+		}
+		sort.Strings(names)
+		statefulset.Apply(
+			statefulset.WithAnnotations(map[string]string{additionalEnvAnnotation: strings.Join(names, ",")}),
+			statefulset.WithPodSpecTemplate(podtemplatespec.Apply(
+				podtemplatespec.WithContainer(construct.AgentName, container.WithEnvs(mdb.Spec.AdditionalEnv...)),
+				podtemplatespec.WithContainer(construct.MongodbName, container.WithEnvs(mdb.Spec.AdditionalEnv...)),
+			)),
+		)(sts)
+	}
+}
+
+// buildAgentModePodSpecModification removes the command of the mongod container in the MonitoringOnly mode, as it waits
+// for the configuration written by the agent. The mongod container then runs the command of its image, or the command
+// configured through the StatefulSet override.
+func buildAgentModePodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	if !mdb.IsAgentMonitoringOnly() {
+		return podtemplatespec.NOOP()
+	}
+	return podtemplatespec.WithContainer(construct.MongodbName, func(c *corev1.Container) {
+		c.Command = nil
+		c.Args = nil
+	})
+}
+
+// buildMongodCommandPodSpecModification replaces the command and the arguments of the mongod container
+// with the ones configured in the resource.
+func buildMongodCommandPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	if mdb.Spec.MongodCommand == nil {
+		return podtemplatespec.NOOP()
+	}
+	return podtemplatespec.WithContainer(construct.MongodbName, container.Apply(
+		container.WithCommand(mdb.Spec.MongodCommand.Command),
+		container.WithArgs(mdb.Spec.MongodCommand.Args),
+	))
+}
+
+// buildAdditionalInitContainersPodSpecModification adds the additional init containers after the init containers of
+// the operator, in the order they are configured. Init containers which are no longer configured are removed.
+func buildAdditionalInitContainersPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		var initContainers []corev1.Container
+		for _, c := range podTemplateSpec.Spec.InitContainers {
+			if construct.IsOperatorInitContainer(c.Name) {
+				initContainers = append(initContainers, c)
+			}
+		}
+		for _, c := range mdb.Spec.AdditionalInitContainers {
+			initContainers = append(initContainers, *c.DeepCopy())
+		}
+		podTemplateSpec.Spec.InitContainers = initContainers
+	}
+}
+
+// buildMongodConfigMapPodSpecModification will mount the ConfigMap containing the additional mongod
+// configuration file into the mongod container, if one has been specified. The volume is removed again
+// once no ConfigMap is referenced.
+func buildMongodConfigMapPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	if mdb.Spec.AdditionalMongodConfigMap == nil {
+		return podtemplatespec.WithoutVolume(additionalMongodConfigVolumeName)
+	}
+
+	mongodConfigVolume := statefulset.CreateVolumeFromConfigMap(additionalMongodConfigVolumeName, mdb.Spec.AdditionalMongodConfigMap.Name)
+	mongodConfigVolumeMount := statefulset.CreateVolumeMount(mongodConfigVolume.Name, additionalMongodConfigMountPath, statefulset.WithReadOnly(true))
+
+	return podtemplatespec.Apply(
+		// the volume of the existing StatefulSet is replaced, as it may reference a previous ConfigMap.
+		podtemplatespec.WithoutVolume(additionalMongodConfigVolumeName),
+		podtemplatespec.WithVolume(mongodConfigVolume),
+		podtemplatespec.WithVolumeMounts(construct.MongodbName, mongodConfigVolumeMount),
+	)
+}
+
+// buildAgentCAPodSpecModification will mount the ConfigMap containing the CA bundle for the outbound
+// connections of the agent into the mongodb-agent container, and configure the agent to trust it.
+// The volume is removed again once no ConfigMap is referenced.
+func buildAgentCAPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	if mdb.Spec.AgentCAConfigMap == nil {
+		return podtemplatespec.WithoutVolume(agentCAVolumeName)
+	}
+
+	agentCAVolume := statefulset.CreateVolumeFromConfigMap(agentCAVolumeName, mdb.Spec.AgentCAConfigMap.Name)
+	agentCAVolumeMount := statefulset.CreateVolumeMount(agentCAVolume.Name, agentCAMountPath, statefulset.WithReadOnly(true))
+
+	return podtemplatespec.Apply(
+		// the volume of the existing StatefulSet is replaced, as it may reference a previous ConfigMap.
+		podtemplatespec.WithoutVolume(agentCAVolumeName),
+		podtemplatespec.WithVolume(agentCAVolume),
+		podtemplatespec.WithVolumeMounts(construct.AgentName, agentCAVolumeMount),
 	)
 }
 
-func getDomain(service, namespace, clusterName string) string {
-	if clusterName == "" {
-		clusterName = "cluster.local"
+// buildAgentCommandPodSpecModification appends the flags of the mongodb-agent which depend on the resource
+// to the command of the mongodb-agent container.
+func buildAgentCommandPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	var flags []string
+	if mdb.Spec.AgentCAConfigMap != nil {
+		flags = append(flags, "-httpsCAFile="+path.Join(agentCAMountPath, tlsCACertName))
+	}
+	flags = append(flags, agent.StartupParametersToFlags(mdb.AgentStartupParameters()...)...)
+	if hosts := memberHostOverrides(mdb); len(hosts) > 0 {
+		// the agent manages the process whose hostname is the host its member advertises.
+		flags = append(flags, overrideLocalHostFlag(mdb, hosts))
+	} else if mdb.MemberAddressingMode() == mdbv1.PodIPAddressing {
+		// the agent manages the process whose hostname is the IP address of its pod.
+		flags = append(flags, "-overrideLocalHost=${"+podIPEnv+"}")
+	}
+	if len(flags) == 0 {
+		return podtemplatespec.NOOP()
+	}
+	return podtemplatespec.WithContainer(construct.AgentName, container.WithCommand(construct.AutomationAgentCommand(append(construct.AgentLogFlags(&mdb), flags...)...)))
+}
+
+// buildAgentLivenessPodSpecModification configures the liveness probe of the mongodb-agent container if it
+// has been enabled, the configured settings are applied on top of the defaults. The probe is removed
+// again once it is disabled.
+func buildAgentLivenessPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	livenessProbe := mdb.Spec.Agent.LivenessProbe
+	if livenessProbe == nil {
+		return podtemplatespec.WithContainer(construct.AgentName, func(c *corev1.Container) {
+			c.LivenessProbe = nil
+		})
+	}
+
+	return podtemplatespec.WithContainer(construct.AgentName, container.WithLivenessProbe(withProbeConfiguration(construct.DefaultAgentLiveness(), *livenessProbe)))
+}
+
+// buildMongodStartupPodSpecModification applies the configured settings of the startup probe of the mongod
+// container on top of the defaults.
+func buildMongodStartupPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	startupProbe := mdb.Spec.MongodStartupProbe
+	if startupProbe == nil {
+		return podtemplatespec.NOOP()
+	}
+	return podtemplatespec.WithContainer(construct.MongodbName, container.WithStartupProbe(withProbeConfiguration(construct.DefaultMongodStartup(mdb.GetMongodPort()), *startupProbe)))
+}
+
+// withProbeConfiguration applies the settings of the probe configuration which are set on top of the given defaults.
+func withProbeConfiguration(defaults probes.Modification, config mdbv1.ProbeConfiguration) probes.Modification {
+	modifications := []probes.Modification{defaults}
+	if config.InitialDelaySeconds > 0 {
+		modifications = append(modifications, probes.WithInitialDelaySeconds(config.InitialDelaySeconds))
+	}
+	if config.PeriodSeconds > 0 {
+		modifications = append(modifications, probes.WithPeriodSeconds(config.PeriodSeconds))
+	}
+	if config.TimeoutSeconds > 0 {
+		modifications = append(modifications, probes.WithTimeoutSeconds(config.TimeoutSeconds))
+	}
+	if config.FailureThreshold > 0 {
+		modifications = append(modifications, probes.WithFailureThreshold(config.FailureThreshold))
 	}
-	return fmt.Sprintf("%s.%s.svc.%s", service, namespace, clusterName)
+	return probes.Apply(modifications...)
 }
 
 // isPreReadinessInitContainerStatefulSet determines if the existing StatefulSet has been configured with the readiness probe init container.
@@ -7,9 +7,15 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/yaml"
 
@@ -21,9 +27,14 @@ import (
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/configmap"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/predicates"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/validation"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/container"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/probes"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
@@ -31,11 +42,20 @@ import (
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/resourcerequirements"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/replicaset"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -155,7 +175,8 @@ func TestStatefulSet_IsCorrectlyConfigured(t *testing.T) {
 	assert.Equal(t, construct.MongodbName, mongodbContainer.Name)
 	assert.Equal(t, "repo/mongo:4.2.2", mongodbContainer.Image)
 
-	assert.Equal(t, resourcerequirements.Defaults(), agentContainer.Resources)
+	assert.Equal(t, resourcerequirements.AgentDefaults(), agentContainer.Resources)
+	assert.Equal(t, resourcerequirements.Defaults(), mongodbContainer.Resources)
 
 	acVolume, err := getVolumeByName(sts, "automation-config")
 	assert.NoError(t, err)
@@ -194,6 +215,11 @@ func TestChangingVersion_ResultsInRollingUpdateStrategyType(t *testing.T) {
 
 	_ = mgrClient.Update(context.TODO(), &mdb)
 
+	// the new version is deployed while all the members are ready
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	_ = mgrClient.Get(context.TODO(), mdb.NamespacedName(), &sts)
+
 	// agents start the upgrade, they are not all ready
 	sts.Status.UpdatedReplicas = 1
 	sts.Status.ReadyReplicas = 2
@@ -201,7 +227,12 @@ func TestChangingVersion_ResultsInRollingUpdateStrategyType(t *testing.T) {
 	assert.NoError(t, err)
 	_ = mgrClient.Get(context.TODO(), mdb.NamespacedName(), &sts)
 
-	// reconcilliation is successful
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mdb.Namespace, Name: mdb.Name}})
+	assert.NoError(t, err)
+	assert.True(t, res.Requeue || res.RequeueAfter > 0, "the reconciliation waits for the members to be ready")
+
+	// reconcilliation is successful once the agents have completed the upgrade
+	makeStatefulSetReady(t, mgrClient, mdb)
 	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mdb.Namespace, Name: mdb.Name}})
 	assertReconciliationSuccessful(t, res, err)
 
@@ -213,21 +244,92 @@ func TestChangingVersion_ResultsInRollingUpdateStrategyType(t *testing.T) {
 		"The StatefulSet should have be re-configured to use RollingUpdates after it reached the ready state")
 }
 
+func TestChangingVersion_WaitsForQuorum(t *testing.T) {
+	changeVersion := func(t *testing.T, mdb mdbv1.MongoDBCommunity, readyReplicas int) (*ReplicaSetReconciler, client.Client) {
+		mgr := client.NewManager(&mdb)
+		r := NewReconciler(mgr)
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		setStatefulSetReadyReplicas(t, mgr.GetClient(), mdb, readyReplicas)
+		err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Version = "4.4.0"
+		err = mgr.GetClient().Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+		return r, mgr.Client
+	}
+	deployedVersion := func(t *testing.T, c client.Client, mdb mdbv1.MongoDBCommunity) string {
+		ac, err := automationconfig.ReadFromSecret(c, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		return ac.Processes[0].Version
+	}
+
+	t.Run("The version is not changed while a member is unavailable", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		r, c := changeVersion(t, mdb, 2)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		assert.Equal(t, 10*time.Second, res.RequeueAfter)
+
+		err = c.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+		assert.Equal(t, "Waiting for quorum before continuing the upgrade to version 4.4.0: 2 of the 3 voting members are available, "+
+			"2 must remain available while a member restarts, retrying in 10 seconds", mdb.Status.Message)
+		assert.Equal(t, "4.2.2", deployedVersion(t, c, mdb))
+
+		makeStatefulSetReady(t, c, mdb)
+		res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		assert.Equal(t, "4.4.0", deployedVersion(t, c, mdb))
+	})
+
+	t.Run("The version is changed while a member of a larger replica set is unavailable", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Members = 5
+		r, c := changeVersion(t, mdb, 4)
+
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		assert.Equal(t, "4.4.0", deployedVersion(t, c, mdb))
+	})
+
+	t.Run("The check can be skipped with an annotation", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Annotations[annotations.SkipUpgradeQuorumCheck] = "true"
+		r, c := changeVersion(t, mdb, 2)
+
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		assert.Equal(t, "4.4.0", deployedVersion(t, c, mdb))
+	})
+
+	t.Run("Replica sets without a quorum to keep are not held back", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Members = 1
+		r, c := changeVersion(t, mdb, 0)
+
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		assert.Equal(t, "4.4.0", deployedVersion(t, c, mdb))
+	})
+}
+
 func TestBuildStatefulSet_ConfiguresUpdateStrategyCorrectly(t *testing.T) {
 	t.Run("On No Version Change, Same Version", func(t *testing.T) {
 		mdb := newTestReplicaSet()
 		mdb.Spec.Version = "4.0.0"
 		mdb.Annotations[annotations.LastAppliedMongoDBVersion] = "4.0.0"
-		sts, err := buildStatefulSet(mdb)
-		assert.NoError(t, err)
+		sts := BuildStatefulSet(mdb)
 		assert.Equal(t, appsv1.RollingUpdateStatefulSetStrategyType, sts.Spec.UpdateStrategy.Type)
 	})
 	t.Run("On No Version Change, First Version", func(t *testing.T) {
 		mdb := newTestReplicaSet()
 		mdb.Spec.Version = "4.0.0"
 		delete(mdb.Annotations, annotations.LastAppliedMongoDBVersion)
-		sts, err := buildStatefulSet(mdb)
-		assert.NoError(t, err)
+		sts := BuildStatefulSet(mdb)
 		assert.Equal(t, appsv1.RollingUpdateStatefulSetStrategyType, sts.Spec.UpdateStrategy.Type)
 	})
 	t.Run("On Version Change", func(t *testing.T) {
@@ -243,10 +345,167 @@ func TestBuildStatefulSet_ConfiguresUpdateStrategyCorrectly(t *testing.T) {
 		assert.NoError(t, err)
 
 		mdb.Annotations[annotations.LastAppliedMongoDBVersion] = string(bytes)
-		sts, err := buildStatefulSet(mdb)
+		sts := BuildStatefulSet(mdb)
+
+		assert.Equal(t, appsv1.OnDeleteStatefulSetStrategyType, sts.Spec.UpdateStrategy.Type)
+	})
+}
+
+func TestReconcile_ForgetsDeletedResources(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	r.tlsRetries.Store(mdb.NamespacedName(), 1)
+	r.frozenVersions.Store(mdb.NamespacedName(), 1)
+	r.divergingConfigVersions.Store(mdb.NamespacedName(), true)
+
+	err = mgr.Client.Delete(context.TODO(), &mdb)
+	assert.NoError(t, err)
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	for name, state := range map[string]*sync.Map{
+		"reconciledGenerations":   r.reconciledGenerations,
+		"tlsRetries":              r.tlsRetries,
+		"resourceLocks":           r.resourceLocks,
+		"frozenVersions":          r.frozenVersions,
+		"divergingConfigVersions": r.divergingConfigVersions,
+	} {
+		_, ok := state.Load(mdb.NamespacedName())
+		assert.False(t, ok, "%s should not hold the deleted resource", name)
+	}
+	_, fresh := r.primaryCache.Get(mdb.NamespacedName())
+	assert.False(t, fresh)
+}
+
+func TestReconcile_SkipsAlreadyReconciledGeneration(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Generation = 1
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
 
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), mdb.Status.ObservedGeneration)
+
+	// modify the StatefulSet to detect whether it is updated by the next reconciliation
+	markStatefulSet := func() {
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		sts.Spec.Template.Labels["marker"] = "true"
+		_, err = mgr.Client.UpdateStatefulSet(sts)
+		assert.NoError(t, err)
+	}
+	isMarked := func() bool {
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		return sts.Spec.Template.Labels["marker"] == "true"
+	}
+
+	markStatefulSet()
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	assert.True(t, isMarked(), "The reconciliation should have been skipped")
+
+	t.Run("A change to a watched Secret is reconciled", func(t *testing.T) {
+		secretNsName := types.NamespacedName{Name: "watched-secret", Namespace: mdb.Namespace}
+		r.secretWatcher.Watch(secretNsName, mdb.NamespacedName())
+		r.secretWatcher.Update(event.UpdateEvent{
+			ObjectOld: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: secretNsName.Name, Namespace: secretNsName.Namespace}},
+		}, controllertest.Queue{Interface: workqueue.New()})
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		assert.False(t, isMarked())
+	})
+
+	t.Run("A changed IP address of a watched pod is reconciled", func(t *testing.T) {
+		markStatefulSet()
+		podNsName := types.NamespacedName{Name: mdb.Name + "-0", Namespace: mdb.Namespace}
+		r.podWatcher.Watch(podNsName, mdb.NamespacedName())
+		r.podWatcher.Update(event.UpdateEvent{
+			ObjectOld: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podNsName.Name, Namespace: podNsName.Namespace}},
+		}, controllertest.Queue{Interface: workqueue.New()})
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		assert.False(t, isMarked())
+	})
+
+	t.Run("A new generation is reconciled", func(t *testing.T) {
+		markStatefulSet()
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Generation = 2
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		assert.False(t, isMarked())
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
 		assert.NoError(t, err)
+		assert.Equal(t, int64(2), mdb.Status.ObservedGeneration)
+	})
+
+	t.Run("The first reconciliation after an operator restart is not skipped", func(t *testing.T) {
+		markStatefulSet()
+		res, err := NewReconciler(mgr).Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		assert.False(t, isMarked())
+	})
+}
+
+func TestBuildStatefulSet_RollingUpdatePartition(t *testing.T) {
+	partition := int32(2)
+	mdb := newTestReplicaSet()
+	mdb.Spec.UpdateStrategy.RollingUpdate = &mdbv1.RollingUpdateConfiguration{Partition: &partition}
+
+	sts := BuildStatefulSet(mdb)
+	assert.Equal(t, appsv1.RollingUpdateStatefulSetStrategyType, sts.Spec.UpdateStrategy.Type)
+	assert.Equal(t, int32(2), *sts.Spec.UpdateStrategy.RollingUpdate.Partition)
+
+	t.Run("The partition is ignored during a version change", func(t *testing.T) {
+		mdb.Spec.Version = "4.4.0"
+		mdb.Annotations[annotations.LastAppliedMongoDBVersion] = "4.2.2"
+		buildStatefulSetModificationFunction(mdb)(&sts)
+
+		assert.Equal(t, appsv1.OnDeleteStatefulSetStrategyType, sts.Spec.UpdateStrategy.Type)
+		assert.Nil(t, sts.Spec.UpdateStrategy.RollingUpdate)
+	})
+}
+
+func TestBuildStatefulSet_RestartedAtAnnotation(t *testing.T) {
+	t.Run("Is not set on the pod template by default", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		sts := BuildStatefulSet(mdb)
+		assert.NotContains(t, sts.Spec.Template.Annotations, annotations.RestartedAt)
+	})
+	t.Run("Is propagated to the pod template", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Annotations[annotations.RestartedAt] = "2021-07-01T10:00:00Z"
+		sts := BuildStatefulSet(mdb)
+		assert.Equal(t, "2021-07-01T10:00:00Z", sts.Spec.Template.Annotations[annotations.RestartedAt])
+	})
+	t.Run("Is not changed during a version change", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Annotations[annotations.RestartedAt] = "2021-07-01T10:00:00Z"
+		sts := BuildStatefulSet(mdb)
+
+		mdb.Spec.Version = "4.4.0"
+		mdb.Annotations[annotations.LastAppliedMongoDBVersion] = "4.2.2"
+		mdb.Annotations[annotations.RestartedAt] = "2021-07-02T10:00:00Z"
+		buildStatefulSetModificationFunction(mdb)(&sts)
+
 		assert.Equal(t, appsv1.OnDeleteStatefulSetStrategyType, sts.Spec.UpdateStrategy.Type)
+		assert.Equal(t, "2021-07-01T10:00:00Z", sts.Spec.Template.Annotations[annotations.RestartedAt])
 	})
 }
 
@@ -314,6 +573,40 @@ func TestAutomationConfig_versionIsNotBumpedWithNoChanges(t *testing.T) {
 	assert.Equal(t, currentAc.Version, 1)
 }
 
+func TestAutomationConfig_ExternalEditsAreOverwritten(t *testing.T) {
+	mdb := newTestReplicaSet()
+
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mdb.Namespace, Name: mdb.Name}})
+	assertReconciliationSuccessful(t, res, err)
+
+	acSecretNsName := types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace}
+	acSecret, err := mgr.Client.GetSecret(acSecretNsName)
+	assert.NoError(t, err)
+	assert.False(t, automationconfig.HasBeenEditedExternally(acSecret))
+
+	ac, err := automationconfig.FromBytes(acSecret.Data[automationconfig.ConfigKey])
+	assert.NoError(t, err)
+	ac.Options.DownloadBase = "/tmp/edited"
+	acBytes, err := json.Marshal(ac)
+	assert.NoError(t, err)
+	acSecret.Data[automationconfig.ConfigKey] = acBytes
+	assert.NoError(t, mgr.Client.UpdateSecret(acSecret))
+	assert.True(t, automationconfig.HasBeenEditedExternally(acSecret))
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mdb.Namespace, Name: mdb.Name}})
+	assertReconciliationSuccessful(t, res, err)
+
+	acSecret, err = mgr.Client.GetSecret(acSecretNsName)
+	assert.NoError(t, err)
+	assert.False(t, automationconfig.HasBeenEditedExternally(acSecret))
+
+	currentAc, err := automationconfig.FromBytes(acSecret.Data[automationconfig.ConfigKey])
+	assert.NoError(t, err)
+	assert.Equal(t, automationconfig.DefaultDownloadBase, currentAc.Options.DownloadBase, "the external edit should have been overwritten")
+}
+
 func TestAutomationConfigFCVIsNotIncreasedWhenUpgradingMinorVersion(t *testing.T) {
 	mdb := newTestReplicaSet()
 	mgr := client.NewManager(&mdb)
@@ -371,6 +664,74 @@ func TestAutomationConfig_CustomMongodConfig(t *testing.T) {
 	}
 }
 
+func TestAutomationConfig_MongodConfigFromConfigMap(t *testing.T) {
+	mdb := newTestReplicaSet()
+
+	mongodConfig := objx.New(map[string]interface{}{})
+	mongodConfig.Set("storage.other", "inline-value")
+	mdb.Spec.AdditionalMongodConfig.Object = mongodConfig
+	mdb.Spec.AdditionalMongodConfigMap = &mdbv1.ConfigMapKeyReference{Name: "mongod-config"}
+
+	mgr := client.NewManager(&mdb)
+	err := mgr.Client.CreateConfigMap(configmap.Builder().
+		SetName("mongod-config").
+		SetNamespace(mdb.Namespace).
+		SetField("mongod.conf", "net:\n  port: 1000\n  maxIncomingConnections: 100\nstorage:\n  other: file-value\n").
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+
+	for _, p := range currentAc.Processes {
+		// Settings from the file are added
+		assert.Equal(t, float64(100), p.Args26.Get("net.maxIncomingConnections").Data())
+
+		// Operator and inline settings take precedence
+		assert.Equal(t, float64(27017), p.Args26.Get("net.port").Data())
+		assert.Equal(t, "inline-value", p.Args26.Get("storage.other").Data())
+	}
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+
+	volume, err := getVolumeByName(sts, "additional-mongod-config")
+	assert.NoError(t, err)
+	assert.Equal(t, "mongod-config", volume.ConfigMap.Name)
+	mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+	assert.NotNil(t, mongodContainer)
+	assert.Contains(t, mongodContainer.VolumeMounts, corev1.VolumeMount{
+		Name:      "additional-mongod-config",
+		ReadOnly:  true,
+		MountPath: additionalMongodConfigMountPath,
+	})
+
+	t.Run("The volume is removed once no ConfigMap is referenced", func(t *testing.T) {
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.AdditionalMongodConfigMap = nil
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		_, err = getVolumeByName(sts, "additional-mongod-config")
+		assert.Error(t, err)
+		mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+		for _, mount := range mongodContainer.VolumeMounts {
+			assert.NotEqual(t, "additional-mongod-config", mount.Name)
+		}
+	})
+}
+
 func TestExistingPasswordAndKeyfile_AreUsedWhenTheSecretExists(t *testing.T) {
 	mdb := newScramReplicaSet()
 	mgr := client.NewManager(&mdb)
@@ -533,6 +894,28 @@ func TestIgnoreUnknownUsers(t *testing.T) {
 	})
 }
 
+func TestIgnoreUnknownUsers_TogglesAuthoritativeSet(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	for _, ignoreUnknownUsers := range []bool{true, false, true} {
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		ignore := ignoreUnknownUsers
+		mdb.Spec.Security.Authentication.IgnoreUnknownUsers = &ignore
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, !ignoreUnknownUsers, ac.Auth.AuthoritativeSet, "ignoreUnknownUsers: %t", ignoreUnknownUsers)
+	}
+}
+
 func TestAnnotationsAreAppliedToResource(t *testing.T) {
 	mdb := newTestReplicaSet()
 
@@ -667,66 +1050,780 @@ func TestCustomTaintsAndTolerations_Configuration(t *testing.T) {
 	assert.Equal(t, corev1.TaintEffectNoExecute, sts.Spec.Template.Spec.Tolerations[1].Effect)
 }
 
-func performReconciliationAndGetStatefulSet(t *testing.T, filePath string) appsv1.StatefulSet {
-	mdb, err := loadTestFixture(filePath)
-	assert.NoError(t, err)
+func TestUserPasswordSecret_Missing_IsWaitedFor(t *testing.T) {
+	mdb := newScramReplicaSet(mdbv1.MongoDBUser{
+		Name: "testuser",
+		PasswordSecretRef: mdbv1.SecretKeyReference{
+			Name: "password-secret",
+			Key:  "password",
+		},
+		ScramCredentialsSecretName: "scram-credentials",
+	})
 	mgr := client.NewManager(&mdb)
-	assert.NoError(t, generatePasswordsForAllUsers(mdb, mgr.Client))
 	r := NewReconciler(mgr)
+
 	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
-	assertReconciliationSuccessful(t, res, err)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second*5, res.RequeueAfter)
 
-	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
 	assert.NoError(t, err)
-	return sts
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+	assert.Equal(t, `Waiting for password secret my-ns/password-secret of user "testuser", retrying in 5 seconds`, mdb.Status.Message)
+
+	t.Run("Creating the secret triggers a reconciliation", func(t *testing.T) {
+		err := mgr.Client.CreateSecret(secret.Builder().
+			SetName("password-secret").
+			SetNamespace(mdb.Namespace).
+			SetField("password", "GAGTQK2ccRRaxJFudI5y").
+			Build(),
+		)
+		assert.NoError(t, err)
+
+		queue := controllertest.Queue{Interface: workqueue.New()}
+		r.secretWatcher.Create(event.CreateEvent{
+			Object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "password-secret", Namespace: mdb.Namespace}},
+		}, queue)
+		assert.Equal(t, 1, queue.Len())
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+	})
 }
 
-func generatePasswordsForAllUsers(mdb mdbv1.MongoDBCommunity, c client.Client) error {
-	for _, user := range mdb.Spec.Users {
+func TestPasswordSecretRetrySeconds(t *testing.T) {
+	defer os.Unsetenv(passwordSecretRetryIntervalEnv)
+
+	for value, expected := range map[string]int{
+		"":        5,
+		"30s":     30,
+		"2m":      120,
+		"100ms":   1,
+		"invalid": 5,
+		"-10s":    5,
+	} {
+		_ = os.Setenv(passwordSecretRetryIntervalEnv, value)
+		assert.Equal(t, expected, passwordSecretRetrySeconds(), value)
+	}
+}
 
-		key := "password"
-		if user.PasswordSecretRef.Key != "" {
-			key = user.PasswordSecretRef.Key
-		}
+func TestUserPasswordSecret_WithMissingKey_IsPending(t *testing.T) {
+	mdb := newScramReplicaSet(mdbv1.MongoDBUser{
+		Name: "testuser",
+		PasswordSecretRef: mdbv1.SecretKeyReference{
+			Name: "password-secret",
+			Key:  "my-password",
+		},
+		ScramCredentialsSecretName: "scram-credentials",
+	})
+	mgr := client.NewManager(&mdb)
+	err := mgr.Client.CreateSecret(secret.Builder().
+		SetName("password-secret").
+		SetNamespace(mdb.Namespace).
+		SetField("password", "GAGTQK2ccRRaxJFudI5y").
+		Build(),
+	)
+	assert.NoError(t, err)
 
-		passwordSecret := secret.Builder().
-			SetName(user.PasswordSecretRef.Name).
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second*10, res.RequeueAfter)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, `"my-password"`)
+
+	t.Run("Reconciliation succeeds once the key is present", func(t *testing.T) {
+		err := mgr.Client.UpdateSecret(secret.Builder().
+			SetName("password-secret").
 			SetNamespace(mdb.Namespace).
-			SetField(key, "GAGTQK2ccRRaxJFudI5y").
-			Build()
+			SetField("my-password", "GAGTQK2ccRRaxJFudI5y").
+			Build(),
+		)
+		assert.NoError(t, err)
 
-		if err := c.CreateSecret(passwordSecret); err != nil {
-			return err
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+	})
+}
+
+func TestScramCredentialsSecrets_OfRemovedUsers_AreDeleted(t *testing.T) {
+	newUser := func(name string) mdbv1.MongoDBUser {
+		return mdbv1.MongoDBUser{
+			Name: name,
+			DB:   "admin",
+			PasswordSecretRef: mdbv1.SecretKeyReference{
+				Name: name + "-password",
+			},
+			Roles:                      []mdbv1.Role{{Name: "readWrite", DB: "admin"}},
+			ScramCredentialsSecretName: name,
 		}
 	}
+	mdb := newScramReplicaSet(newUser("user-a"), newUser("user-b"))
+	mgr := client.NewManager(&mdb)
+	for _, user := range mdb.Spec.Users {
+		err := mgr.Client.CreateSecret(secret.Builder().
+			SetName(user.PasswordSecretRef.Name).
+			SetNamespace(mdb.Namespace).
+			SetField("password", "GAGTQK2ccRRaxJFudI5y").
+			Build(),
+		)
+		assert.NoError(t, err)
+	}
+	// a secret which is not controlled by the resource is never deleted.
+	err := mgr.Client.CreateSecret(secret.Builder().
+		SetName("other-scram-credentials").
+		SetNamespace(mdb.Namespace).
+		SetField("sha-1-salt", "salt").
+		Build(),
+	)
+	assert.NoError(t, err)
 
-	return nil
-}
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
 
-func assertReconciliationSuccessful(t *testing.T, result reconcile.Result, err error) {
+	for _, name := range []string{"user-a-scram-credentials", "user-b-scram-credentials"} {
+		s, err := mgr.Client.GetSecret(types.NamespacedName{Name: name, Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, mdb.GetOwnerReferences(), s.OwnerReferences)
+	}
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mdb.Spec.Users = mdb.Spec.Users[:1]
+	err = mgr.Client.Update(context.TODO(), &mdb)
 	assert.NoError(t, err)
-	assert.Equal(t, false, result.Requeue)
-	assert.Equal(t, time.Duration(0), result.RequeueAfter)
-}
 
-// makeStatefulSetReady updates the StatefulSet corresponding to the
-// provided MongoDB resource to mark it as ready for the case of `statefulset.IsReady`
-func makeStatefulSetReady(t *testing.T, c k8sClient.Client, mdb mdbv1.MongoDBCommunity) {
-	setStatefulSetReadyReplicas(t, c, mdb, mdb.StatefulSetReplicasThisReconciliation())
-}
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
 
-func setStatefulSetReadyReplicas(t *testing.T, c k8sClient.Client, mdb mdbv1.MongoDBCommunity, readyReplicas int) {
-	sts := appsv1.StatefulSet{}
-	err := c.Get(context.TODO(), mdb.NamespacedName(), &sts)
+	_, err = mgr.Client.GetSecret(types.NamespacedName{Name: "user-a-scram-credentials", Namespace: mdb.Namespace})
 	assert.NoError(t, err)
-	sts.Status.ReadyReplicas = int32(readyReplicas)
-	sts.Status.UpdatedReplicas = int32(mdb.StatefulSetReplicasThisReconciliation())
-	err = c.Update(context.TODO(), &sts)
+	_, err = mgr.Client.GetSecret(types.NamespacedName{Name: "user-b-scram-credentials", Namespace: mdb.Namespace})
+	assert.True(t, apiErrors.IsNotFound(err), "the credentials of the removed user should have been deleted")
+	_, err = mgr.Client.GetSecret(types.NamespacedName{Name: "other-scram-credentials", Namespace: mdb.Namespace})
 	assert.NoError(t, err)
 }
 
-// loadTestFixture will create a MongoDB resource from a given fixture
-func loadTestFixture(yamlFileName string) (mdbv1.MongoDBCommunity, error) {
+func TestUserPasswordSecret_WithEmptyPassword_IsPending(t *testing.T) {
+	mdb := newScramReplicaSet(mdbv1.MongoDBUser{
+		Name: "testuser",
+		PasswordSecretRef: mdbv1.SecretKeyReference{
+			Name: "password-secret",
+		},
+		ScramCredentialsSecretName: "scram-credentials",
+	})
+	mgr := client.NewManager(&mdb)
+	err := mgr.Client.CreateSecret(secret.Builder().
+		SetName("password-secret").
+		SetNamespace(mdb.Namespace).
+		SetField("password", "").
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	r := NewReconciler(mgr)
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, `"password"`)
+}
+
+func TestAdditionalEnv(t *testing.T) {
+	mdb, err := loadTestFixture("additional_env.yaml")
+	assert.NoError(t, err)
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+
+	for _, name := range []string{construct.AgentName, construct.MongodbName} {
+		c := podtemplatespec.FindContainerByName(name, &sts.Spec.Template)
+		assert.NotNil(t, c)
+		assert.Contains(t, c.Env, corev1.EnvVar{Name: "HTTP_PROXY", Value: "http://proxy.example.com:3128"})
+
+		// environment variables managed by the operator take precedence
+		for _, env := range c.Env {
+			if env.Name == "AGENT_STATUS_FILEPATH" {
+				assert.NotEqual(t, "/should/not/be/used", env.Value)
+			}
+		}
+	}
+
+	// subsequent reconciliations produce the same, sorted environment variables
+	env := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template).Env
+	assert.True(t, sort.SliceIsSorted(env, func(i, j int) bool { return env[i].Name < env[j].Name }))
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err = mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, env, podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template).Env)
+
+	t.Run("Environment variables which are no longer configured are removed", func(t *testing.T) {
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.AdditionalEnv = nil
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.NotContains(t, sts.Annotations, additionalEnvAnnotation)
+		for _, name := range []string{construct.AgentName, construct.MongodbName} {
+			names := map[string]bool{}
+			for _, env := range podtemplatespec.FindContainerByName(name, &sts.Spec.Template).Env {
+				names[env.Name] = true
+			}
+			assert.False(t, names["HTTP_PROXY"], name)
+			// the environment variables managed by the operator are kept.
+			assert.True(t, names["AGENT_STATUS_FILEPATH"], name)
+		}
+	})
+}
+
+func TestContainerResources_CanBeOverridden(t *testing.T) {
+	mdb, err := loadTestFixture("container_resources.yaml")
+	assert.NoError(t, err)
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+
+	t.Run("Agent resources are merged with the agent defaults", func(t *testing.T) {
+		agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+		assert.NotNil(t, agentContainer)
+		assert.Equal(t, resource.MustParse("0.3"), *agentContainer.Resources.Limits.Cpu())
+		assert.Equal(t, resource.MustParse("300M"), *agentContainer.Resources.Limits.Memory())
+		assert.Equal(t, resourcerequirements.AgentDefaults().Requests, agentContainer.Resources.Requests)
+	})
+
+	t.Run("Mongod resources are overridden", func(t *testing.T) {
+		mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+		assert.NotNil(t, mongodContainer)
+		assert.Equal(t, resource.MustParse("2"), *mongodContainer.Resources.Limits.Cpu())
+		assert.Equal(t, resource.MustParse("2G"), *mongodContainer.Resources.Limits.Memory())
+		assert.Equal(t, resource.MustParse("1"), *mongodContainer.Resources.Requests.Cpu())
+		assert.Equal(t, resource.MustParse("1G"), *mongodContainer.Resources.Requests.Memory())
+	})
+
+	t.Run("Version upgrade hook resources are merged with its defaults", func(t *testing.T) {
+		var hookContainer *corev1.Container
+		for i := range sts.Spec.Template.Spec.InitContainers {
+			if sts.Spec.Template.Spec.InitContainers[i].Name == "mongod-posthook" {
+				hookContainer = &sts.Spec.Template.Spec.InitContainers[i]
+			}
+		}
+		assert.NotNil(t, hookContainer)
+		assert.Equal(t, corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("100M")}, hookContainer.Resources.Limits)
+		assert.Equal(t, resourcerequirements.VersionUpgradeHookDefaults().Requests, hookContainer.Resources.Requests)
+	})
+}
+
+func TestCustomDataPath(t *testing.T) {
+	mdb, err := loadTestFixture("custom_data_path.yaml")
+	assert.NoError(t, err)
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	for _, p := range currentAc.Processes {
+		assert.Equal(t, "/var/lib/mongodb/data", p.Args26.Get("storage.dbPath").Data())
+	}
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+
+	for _, c := range sts.Spec.Template.Spec.Containers {
+		found := false
+		for _, m := range c.VolumeMounts {
+			if m.Name == mdb.DataVolumeName() {
+				found = true
+				assert.Equal(t, "/var/lib/mongodb/data", m.MountPath)
+			}
+		}
+		assert.True(t, found, "container %s should mount the data volume", c.Name)
+	}
+
+	// the mongod container waits for the configuration file the agent writes into the dbPath
+	mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+	assert.NotNil(t, mongodContainer)
+	assert.Contains(t, mongodContainer.Command[2], "/var/lib/mongodb/data/automation-mongod.conf")
+}
+
+func TestCustomDataPath_MustBeAbsolute(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Storage.DataPath = "relative/data"
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "absolute path")
+}
+
+func TestUser_InReservedDatabase_IsRejected(t *testing.T) {
+	mdb := newScramReplicaSet(mdbv1.MongoDBUser{
+		Name: "external-user",
+		DB:   "$external",
+		PasswordSecretRef: mdbv1.SecretKeyReference{
+			Name: "password-secret",
+		},
+		ScramCredentialsSecretName: "scram-credentials",
+	})
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, `"$external"`)
+}
+
+func TestBuildOfflineAutomationConfig(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Status.CurrentMongoDBMembers = 1
+
+	ac, err := BuildOfflineAutomationConfig(mdb)
+	assert.NoError(t, err)
+	assert.Len(t, ac.Processes, 3, "The desired number of members should be used")
+	assert.True(t, ac.Auth.Disabled)
+	assert.Equal(t, mdb.Name, ac.ReplicaSets[0].Id)
+
+	t.Run("Invalid specs are rejected", func(t *testing.T) {
+		mdb.Spec.Arbiters = 3
+		_, err := BuildOfflineAutomationConfig(mdb)
+		assert.Error(t, err)
+	})
+}
+
+func TestStatefulSet_WithInjectedSidecar_IsReconciled(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	// simulate a mutating webhook injecting a sidecar and an annotation into the StatefulSet
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	sts.Spec.Template.Spec.Containers = append(sts.Spec.Template.Spec.Containers, corev1.Container{Name: "istio-proxy", Image: "istio/proxyv2"})
+	sts.Spec.Template.Annotations = map[string]string{"sidecar.istio.io/status": "injected"}
+	_, err = mgr.Client.UpdateStatefulSet(sts)
+	assert.NoError(t, err)
+	makeStatefulSetReady(t, mgr.Client, mdb)
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err = mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.NotNil(t, podtemplatespec.FindContainerByName("istio-proxy", &sts.Spec.Template), "The injected sidecar should not be removed")
+	assert.Equal(t, "injected", sts.Spec.Template.Annotations["sidecar.istio.io/status"])
+	assert.NotNil(t, podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template))
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+}
+
+func TestSeparateArbiters(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Arbiters = 2
+	mdb.Spec.ArbiterStatefulSetConfiguration = &mdbv1.StatefulSetConfiguration{
+		SpecWrapper: mdbv1.StatefulSetSpecWrapper{
+			Spec: appsv1.StatefulSetSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						NodeSelector: map[string]string{"pool": "arbiters"},
+					},
+				},
+			},
+		},
+	}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), *sts.Spec.Replicas)
+	assert.Empty(t, sts.Spec.Template.Spec.NodeSelector)
+
+	arbiterSts, err := mgr.Client.GetStatefulSet(mdb.ArbiterNamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, "my-rs-arb", arbiterSts.Name)
+	assert.Equal(t, int32(2), *arbiterSts.Spec.Replicas)
+	assert.Empty(t, arbiterSts.Spec.VolumeClaimTemplates)
+	assert.Equal(t, "arbiters", arbiterSts.Spec.Template.Spec.NodeSelector["pool"])
+	assert.Equal(t, "my-rs-arb-svc", arbiterSts.Spec.Template.Labels["app"])
+	assert.Equal(t, "my-rs-arb-svc", arbiterSts.Spec.ServiceName)
+	selector, err := metav1.LabelSelectorAsSelector(sts.Spec.Selector)
+	assert.NoError(t, err)
+	assert.False(t, selector.Matches(labels.Set(arbiterSts.Spec.Template.Labels)), "the StatefulSet of the members must not select the arbiters")
+
+	arbiterSvc, err := mgr.Client.GetService(types.NamespacedName{Name: "my-rs-arb-svc", Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, "None", arbiterSvc.Spec.ClusterIP)
+	assert.Equal(t, arbiterSts.Spec.Template.Labels, arbiterSvc.Spec.Selector)
+
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	members := ac.ReplicaSets[0].Members
+	assert.Len(t, members, 5)
+	for i := 0; i < 3; i++ {
+		assert.False(t, members[i].ArbiterOnly)
+	}
+	assert.True(t, members[3].ArbiterOnly)
+	assert.Equal(t, "my-rs-arb-0", members[3].Host)
+	assert.True(t, members[4].ArbiterOnly)
+	assert.Equal(t, "my-rs-arb-1.my-rs-arb-svc.my-ns.svc.cluster.local", ac.Processes[4].HostName)
+
+	t.Run("The arbiter StatefulSet is recreated when its selector changes", func(t *testing.T) {
+		arbiterSts, err := mgr.Client.GetStatefulSet(mdb.ArbiterNamespacedName())
+		assert.NoError(t, err)
+		arbiterSts.Spec.Selector.MatchLabels = map[string]string{"app": mdb.ServiceName(), arbiterLabel: "true"}
+		err = mgr.Client.Update(context.TODO(), &arbiterSts)
+		assert.NoError(t, err)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		arbiterSts, err = mgr.Client.GetStatefulSet(mdb.ArbiterNamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, "my-rs-arb-svc", arbiterSts.Spec.Selector.MatchLabels["app"])
+	})
+
+	t.Run("The pods of the arbiters are listed with the pods of the members", func(t *testing.T) {
+		for _, pod := range []corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "my-rs-0", Namespace: mdb.Namespace, Labels: map[string]string{"app": mdb.ServiceName()}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "my-rs-arb-0", Namespace: mdb.Namespace, Labels: arbiterLabels(mdb)}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "other-0", Namespace: mdb.Namespace, Labels: map[string]string{"app": "other-svc"}}},
+		} {
+			pod := pod
+			assert.NoError(t, mgr.Client.Create(context.TODO(), &pod))
+			defer func() {
+				assert.NoError(t, mgr.Client.Delete(context.TODO(), &pod))
+			}()
+		}
+
+		pods, err := r.listMemberPods(mdb)
+		assert.NoError(t, err)
+		var names []string
+		for _, pod := range pods {
+			names = append(names, pod.Name)
+		}
+		assert.ElementsMatch(t, []string{"my-rs-0", "my-rs-arb-0"}, names)
+	})
+
+	t.Run("Arbiters can be scaled independently of the data-bearing members", func(t *testing.T) {
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Arbiters = 1
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		arbiterSts, err := mgr.Client.GetStatefulSet(mdb.ArbiterNamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), *arbiterSts.Spec.Replicas)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), *sts.Spec.Replicas)
+
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Len(t, ac.ReplicaSets[0].Members, 4)
+		assert.True(t, ac.ReplicaSets[0].Members[3].ArbiterOnly)
+	})
+
+	t.Run("The arbiter StatefulSet is removed when no longer configured", func(t *testing.T) {
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Arbiters = 0
+		mdb.Spec.ArbiterStatefulSetConfiguration = nil
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		_, err = mgr.Client.GetStatefulSet(mdb.ArbiterNamespacedName())
+		assert.True(t, apiErrors.IsNotFound(err))
+		_, err = mgr.Client.GetService(types.NamespacedName{Name: "my-rs-arb-svc", Namespace: mdb.Namespace})
+		assert.True(t, apiErrors.IsNotFound(err))
+	})
+}
+
+func TestMongodStartupProbe_Override(t *testing.T) {
+	mdb, err := loadTestFixture("mongod_startup_probe.yaml")
+	assert.NoError(t, err)
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+
+	mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+	assert.NotNil(t, mongodContainer)
+	assert.NotNil(t, mongodContainer.StartupProbe)
+
+	// values from the spec override the defaults, the rest of the defaults are kept
+	assert.Equal(t, int32(360), mongodContainer.StartupProbe.FailureThreshold)
+	assert.Equal(t, int32(10), mongodContainer.StartupProbe.PeriodSeconds)
+	assert.NotNil(t, mongodContainer.StartupProbe.TCPSocket)
+}
+
+func TestMongodStartupProbe_Spec(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.MongodStartupProbe = &mdbv1.ProbeConfiguration{PeriodSeconds: 20, FailureThreshold: 360}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+	assert.NotNil(t, mongodContainer)
+	assert.NotNil(t, mongodContainer.StartupProbe)
+	assert.Equal(t, int32(20), mongodContainer.StartupProbe.PeriodSeconds)
+	assert.Equal(t, int32(360), mongodContainer.StartupProbe.FailureThreshold)
+	assert.Equal(t, int32(5), mongodContainer.StartupProbe.InitialDelaySeconds, "the settings which are not set keep the defaults")
+	assert.Equal(t, 27017, mongodContainer.StartupProbe.TCPSocket.Port.IntValue())
+
+	t.Run("The defaults are restored once the settings are removed", func(t *testing.T) {
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.MongodStartupProbe = nil
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+		assert.Equal(t, int32(10), mongodContainer.StartupProbe.PeriodSeconds)
+		assert.Equal(t, int32(180), mongodContainer.StartupProbe.FailureThreshold)
+	})
+}
+
+func TestRevisionHistoryLimit_Configuration(t *testing.T) {
+	mdb, err := loadTestFixture("revision_history_limit.yaml")
+	assert.NoError(t, err)
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.NotNil(t, sts.Spec.RevisionHistoryLimit)
+	assert.Equal(t, int32(2), *sts.Spec.RevisionHistoryLimit)
+
+	// subsequent reconciliations keep the configured value
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err = mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), *sts.Spec.RevisionHistoryLimit)
+}
+
+func TestPodManagementPolicy_Configuration(t *testing.T) {
+	sts := performReconciliationAndGetStatefulSet(t, "parallel_pod_management.yaml")
+	assert.Equal(t, appsv1.ParallelPodManagement, sts.Spec.PodManagementPolicy)
+}
+
+func TestPodManagementPolicy_CannotBeChanged(t *testing.T) {
+	mdb, err := loadTestFixture("parallel_pod_management.yaml")
+	assert.NoError(t, err)
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.PodManagementPolicy = appsv1.OrderedReadyPodManagement
+	err = mgr.GetClient().Update(context.TODO(), &mdb)
+	assert.NoError(t, err)
+
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, `podManagementPolicy ("Parallel" -> "OrderedReady")`)
+	assert.Contains(t, mdb.Status.Message, "the StatefulSet must be deleted and recreated")
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, appsv1.ParallelPodManagement, sts.Spec.PodManagementPolicy)
+}
+
+func TestPodManagementPolicy_StatefulSetIsRecreatedWithAnnotation(t *testing.T) {
+	mdb, err := loadTestFixture("parallel_pod_management.yaml")
+	assert.NoError(t, err)
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mdb.Annotations[annotations.AllowStatefulSetRecreate] = "true"
+	mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.PodManagementPolicy = appsv1.OrderedReadyPodManagement
+	err = mgr.GetClient().Update(context.TODO(), &mdb)
+	assert.NoError(t, err)
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, appsv1.OrderedReadyPodManagement, sts.Spec.PodManagementPolicy)
+	assert.Equal(t, mdb.GetOwnerReferences(), sts.OwnerReferences)
+}
+
+func TestVolumeClaimTemplates_CannotBeChanged(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: mdb.DataVolumeName()},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("50G")},
+				},
+			},
+		},
+	}
+	err = mgr.GetClient().Update(context.TODO(), &mdb)
+	assert.NoError(t, err)
+
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "volumeClaimTemplates[data-volume]")
+	assert.NotContains(t, mdb.Status.Message, "logs-volume")
+}
+
+func performReconciliationAndGetStatefulSet(t *testing.T, filePath string) appsv1.StatefulSet {
+	mdb, err := loadTestFixture(filePath)
+	assert.NoError(t, err)
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, generatePasswordsForAllUsers(mdb, mgr.Client))
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	return sts
+}
+
+func generatePasswordsForAllUsers(mdb mdbv1.MongoDBCommunity, c client.Client) error {
+	for _, user := range mdb.Spec.Users {
+
+		key := "password"
+		if user.PasswordSecretRef.Key != "" {
+			key = user.PasswordSecretRef.Key
+		}
+
+		passwordSecret := secret.Builder().
+			SetName(user.PasswordSecretRef.Name).
+			SetNamespace(mdb.Namespace).
+			SetField(key, "GAGTQK2ccRRaxJFudI5y").
+			Build()
+
+		if err := c.CreateSecret(passwordSecret); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func assertReconciliationSuccessful(t *testing.T, result reconcile.Result, err error) {
+	assert.NoError(t, err)
+	assert.Equal(t, false, result.Requeue)
+	assert.Equal(t, time.Duration(0), result.RequeueAfter)
+}
+
+// makeStatefulSetReady updates the StatefulSet corresponding to the
+// provided MongoDB resource to mark it as ready for the case of `statefulset.IsReady`
+func makeStatefulSetReady(t *testing.T, c k8sClient.Client, mdb mdbv1.MongoDBCommunity) {
+	setStatefulSetReadyReplicas(t, c, mdb, mdb.StatefulSetReplicasThisReconciliation())
+}
+
+func setStatefulSetReadyReplicas(t *testing.T, c k8sClient.Client, mdb mdbv1.MongoDBCommunity, readyReplicas int) {
+	sts := appsv1.StatefulSet{}
+	err := c.Get(context.TODO(), mdb.NamespacedName(), &sts)
+	assert.NoError(t, err)
+	sts.Status.ReadyReplicas = int32(readyReplicas)
+	sts.Status.UpdatedReplicas = int32(mdb.StatefulSetReplicasThisReconciliation())
+	err = c.Update(context.TODO(), &sts)
+	assert.NoError(t, err)
+}
+
+// loadTestFixture will create a MongoDB resource from a given fixture
+func loadTestFixture(yamlFileName string) (mdbv1.MongoDBCommunity, error) {
 	testPath := fmt.Sprintf("testdata/%s", yamlFileName)
 	mdb := mdbv1.MongoDBCommunity{}
 	data, err := ioutil.ReadFile(testPath)
@@ -734,19 +1831,2754 @@ func loadTestFixture(yamlFileName string) (mdbv1.MongoDBCommunity, error) {
 		return mdb, errors.Errorf("error reading file: %s", err)
 	}
 
-	if err := marshalRuntimeObjectFromYAMLBytes(data, &mdb); err != nil {
-		return mdb, errors.Errorf("error converting yaml bytes to service account: %s", err)
+	if err := marshalRuntimeObjectFromYAMLBytes(data, &mdb); err != nil {
+		return mdb, errors.Errorf("error converting yaml bytes to service account: %s", err)
+	}
+
+	return mdb, nil
+}
+
+// marshalRuntimeObjectFromYAMLBytes accepts the bytes of a yaml resource
+// and unmarshals them into the provided runtime Object
+func marshalRuntimeObjectFromYAMLBytes(bytes []byte, obj runtime.Object) error {
+	jsonBytes, err := yaml.YAMLToJSON(bytes)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonBytes, &obj)
+}
+
+func TestFeatureCompatibilityVersionDowngrade_RequiresAnnotation(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mdb.Spec.Version = "4.0.6"
+	err = mgr.Client.Update(context.TODO(), &mdb)
+	assert.NoError(t, err)
+
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, allowFCVDowngradeAnnotation)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, "4.2", currentAc.Processes[0].FeatureCompatibilityVersion)
+	assert.Equal(t, "4.2.2", currentAc.Processes[0].Version)
+
+	t.Run("The downgrade proceeds once it is allowed", func(t *testing.T) {
+		mdb.Annotations = map[string]string{allowFCVDowngradeAnnotation: "true"}
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		for _, p := range currentAc.Processes {
+			assert.Equal(t, "4.0", p.FeatureCompatibilityVersion)
+			assert.Equal(t, "4.0.6", p.Version)
+		}
+		// one version for the featureCompatibilityVersion change, and one for the version change
+		assert.Equal(t, 3, currentAc.Version)
+	})
+}
+
+func TestProtocolVersion_IsValidatedAgainstVersion(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.ProtocolVersion = "0"
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, `protocol version "0" is not supported`)
+}
+
+func TestConcurrentReconciliations_BumpAutomationConfigVersionOnce(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mongodConfig := objx.New(map[string]interface{}{})
+	mongodConfig.Set("storage.other", "value")
+	mdb.Spec.AdditionalMongodConfig.Object = mongodConfig
+	err = mgr.Client.Update(context.TODO(), &mdb)
+	assert.NoError(t, err)
+
+	// a spec change and an owned object change trigger two reconciliations at the same time
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+			assertReconciliationSuccessful(t, res, err)
+		}()
+	}
+	wg.Wait()
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, currentAc.Version)
+	assert.Equal(t, "value", currentAc.Processes[0].Args26.Get("storage.other").Data())
+}
+
+type mockedStatusGetter struct {
+	states   replicaset.MemberStates
+	versions replicaset.ConfigVersions
+}
+
+func (m mockedStatusGetter) GetStatus(_ context.Context, _ types.NamespacedName, _ replicaset.ConnectionOptions) (replicaset.Status, error) {
+	return replicaset.Status{States: m.states, ConfigVersions: m.versions}, nil
+}
+
+func (m mockedStatusGetter) Forget(types.NamespacedName) {}
+
+func TestPrimary_IsRecordedInStatus(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	r.statusGetter = mockedStatusGetter{states: replicaset.MemberStates{
+		mdb.Hosts()[0]: replicaset.SecondaryState,
+		mdb.Hosts()[1]: replicaset.PrimaryState,
+	}}
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	assert.Eventually(t, func() bool {
+		unlock := r.lockResource(mdb.NamespacedName())
+		defer unlock()
+
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		return err == nil && mdb.Status.Primary == mdb.Hosts()[1]
+	}, time.Second*5, time.Millisecond*10)
+
+	t.Run("The members are not connected to once the refresh is disabled", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mgr := client.NewManager(&mdb)
+		r := NewReconciler(mgr)
+		calls := int32(0)
+		r.statusGetter = countingStatusGetter{calls: &calls}
+		r.DisablePrimaryRefresh()
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		assert.Never(t, func() bool {
+			return atomic.LoadInt32(&calls) > 0
+		}, time.Millisecond*100, time.Millisecond*10)
+	})
+}
+
+// countingStatusGetter counts the connections to the members.
+type countingStatusGetter struct {
+	mockedStatusGetter
+	calls *int32
+}
+
+func (c countingStatusGetter) GetStatus(ctx context.Context, nsName types.NamespacedName, opts replicaset.ConnectionOptions) (replicaset.Status, error) {
+	atomic.AddInt32(c.calls, 1)
+	return c.mockedStatusGetter.GetStatus(ctx, nsName, opts)
+}
+
+func TestAgentCAConfigMap(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.AgentCAConfigMap = &mdbv1.LocalObjectReference{Name: "mirror-ca"}
+
+	sts := BuildStatefulSet(mdb)
+
+	agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+	assert.NotNil(t, agentContainer)
+	assert.Contains(t, agentContainer.Command[2], "-httpsCAFile=/var/lib/mongodb-mms-automation/agent-ca/ca.crt")
+
+	assert.Contains(t, agentContainer.VolumeMounts, corev1.VolumeMount{Name: "agent-ca", MountPath: agentCAMountPath, ReadOnly: true})
+
+	var caVolume *corev1.Volume
+	for i, v := range sts.Spec.Template.Spec.Volumes {
+		if v.Name == "agent-ca" {
+			caVolume = &sts.Spec.Template.Spec.Volumes[i]
+		}
+	}
+	assert.NotNil(t, caVolume)
+	assert.Equal(t, "mirror-ca", caVolume.ConfigMap.Name)
+
+	mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+	for _, mount := range mongodContainer.VolumeMounts {
+		assert.NotEqual(t, "agent-ca", mount.Name)
+	}
+
+	t.Run("The volume follows the referenced ConfigMap and is removed once unset", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.AgentCAConfigMap = &mdbv1.LocalObjectReference{Name: "mirror-ca"}
+		mgr := client.NewManager(&mdb)
+		r := NewReconciler(mgr)
+		reconcileWith := func(t *testing.T, ref *mdbv1.LocalObjectReference) appsv1.StatefulSet {
+			err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+			assert.NoError(t, err)
+			mdb.Spec.AgentCAConfigMap = ref
+			err = mgr.Client.Update(context.TODO(), &mdb)
+			assert.NoError(t, err)
+			res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+			assertReconciliationSuccessful(t, res, err)
+			sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+			assert.NoError(t, err)
+			return sts
+		}
+		agentCAVolumes := func(sts appsv1.StatefulSet) []corev1.Volume {
+			var volumes []corev1.Volume
+			for _, v := range sts.Spec.Template.Spec.Volumes {
+				if v.Name == "agent-ca" {
+					volumes = append(volumes, v)
+				}
+			}
+			return volumes
+		}
+
+		sts := reconcileWith(t, &mdbv1.LocalObjectReference{Name: "mirror-ca"})
+		assert.Len(t, agentCAVolumes(sts), 1)
+
+		sts = reconcileWith(t, &mdbv1.LocalObjectReference{Name: "other-ca"})
+		volumes := agentCAVolumes(sts)
+		assert.Len(t, volumes, 1)
+		assert.Equal(t, "other-ca", volumes[0].ConfigMap.Name)
+
+		sts = reconcileWith(t, nil)
+		assert.Empty(t, agentCAVolumes(sts))
+		agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+		for _, mount := range agentContainer.VolumeMounts {
+			assert.NotEqual(t, "agent-ca", mount.Name)
+		}
+		assert.NotContains(t, agentContainer.Command[2], "-httpsCAFile")
+	})
+}
+
+func TestAutomationConfig_SystemLogVerbosity(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	acNsName := types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace}
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, acNsName)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, currentAc.Version)
+	assert.False(t, currentAc.Processes[0].Args26.Has("systemLog.verbosity"))
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mdb.Spec.SystemLog = mdbv1.SystemLogConfiguration{Verbosity: 1, Component: map[string]int{"replication": 2}}
+	err = mgr.Client.Update(context.TODO(), &mdb)
+	assert.NoError(t, err)
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err = automationconfig.ReadFromSecret(mgr.Client, acNsName)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, currentAc.Version)
+	for _, p := range currentAc.Processes {
+		assert.Equal(t, float64(1), p.Args26.Get("systemLog.verbosity").Data())
+		assert.Equal(t, float64(2), p.Args26.Get("systemLog.component.replication.verbosity").Data())
+	}
+
+	t.Run("Invalid verbosity levels are rejected", func(t *testing.T) {
+		mdb.Spec.SystemLog.Component["replication"] = 6
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+		assert.Contains(t, mdb.Status.Message, `component "replication"`)
+	})
+}
+
+func TestAdditionalMongodArgs(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.AdditionalMongodArgs = []string{"--setParameter", "diagnosticDataCollectionEnabled=false"}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+	assert.Equal(t, []string{"mongod", "--setParameter", "diagnosticDataCollectionEnabled=false"}, mongodContainer.Args)
+
+	t.Run("Arguments are removed when they are no longer specified", func(t *testing.T) {
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.AdditionalMongodArgs = nil
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Empty(t, podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template).Args)
+	})
+
+	t.Run("The configuration file cannot be replaced", func(t *testing.T) {
+		mdb.Spec.AdditionalMongodArgs = []string{"--config=/etc/mongod.conf"}
+		err := mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+		assert.Contains(t, mdb.Status.Message, `"--config"`)
+	})
+}
+
+func TestFreezeAutomationConfigVersion(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	acNsName := types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace}
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	frozenAc, err := automationconfig.ReadFromSecret(mgr.Client, acNsName)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, frozenAc.Version)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mdb.Annotations = map[string]string{annotations.FreezeAutomationConfigVersion: "true"}
+	mongodConfig := objx.New(map[string]interface{}{})
+	mongodConfig.Set("storage.other", "value")
+	mdb.Spec.AdditionalMongodConfig.Object = mongodConfig
+	err = mgr.Client.Update(context.TODO(), &mdb)
+	assert.NoError(t, err)
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(frozenRetrySeconds)*time.Second, res.RequeueAfter)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, acNsName)
+	assert.NoError(t, err)
+	areEqual, err := automationconfig.AreEqual(frozenAc, currentAc)
+	assert.NoError(t, err)
+	assert.True(t, areEqual, "the AutomationConfig should not change while frozen")
+	assert.Equal(t, 1, currentAc.Version)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "The automation config version is frozen at 1")
+
+	t.Run("Pending changes are applied once unfrozen", func(t *testing.T) {
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		delete(mdb.Annotations, annotations.FreezeAutomationConfigVersion)
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		currentAc, err := automationconfig.ReadFromSecret(mgr.Client, acNsName)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, currentAc.Version)
+		assert.Equal(t, "value", currentAc.Processes[0].Args26.Get("storage.other").Data())
+	})
+}
+
+func TestFreezeAutomationConfigVersion_UnfreezingIsNotSkipped(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Generation = 1
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	acNsName := types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace}
+	onSpecChange := predicates.OnlyOnSpecChange()
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.True(t, r.isUpToDate(mdb), "the reconciled generation should be skipped")
+
+	// the version is frozen together with a change of the spec.
+	frozen := mdb.DeepCopy()
+	frozen.Annotations = map[string]string{annotations.FreezeAutomationConfigVersion: "true"}
+	frozen.Spec.AdditionalMongodConfig.Object = map[string]interface{}{"storage": map[string]interface{}{"other": "value"}}
+	frozen.Generation = 2
+	assert.True(t, onSpecChange.Update(event.UpdateEvent{ObjectOld: &mdb, ObjectNew: frozen}))
+	err = mgr.Client.Update(context.TODO(), frozen)
+	assert.NoError(t, err)
+
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), frozen)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Pending, frozen.Status.Phase)
+	assert.NotEqual(t, int64(2), frozen.Status.ObservedGeneration, "the frozen generation has not been published")
+
+	// removing the annotation doesn't change the generation.
+	unfrozen := frozen.DeepCopy()
+	delete(unfrozen.Annotations, annotations.FreezeAutomationConfigVersion)
+	assert.True(t, onSpecChange.Update(event.UpdateEvent{ObjectOld: frozen, ObjectNew: unfrozen}), "unfreezing should trigger a reconciliation")
+	err = mgr.Client.Update(context.TODO(), unfrozen)
+	assert.NoError(t, err)
+	assert.False(t, r.isUpToDate(*unfrozen), "the reconciliation after unfreezing should not be skipped")
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, acNsName)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, currentAc.Version)
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+	assert.Equal(t, int64(2), mdb.Status.ObservedGeneration)
+}
+
+func TestValidation_ReportsAllProblems(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.FeatureCompatibilityVersion = "4.4"
+	mdb.Spec.Security.TLS.Enabled = true
+	mdb.Spec.Users = []mdbv1.MongoDBUser{{Name: "my-user", ScramCredentialsSecretName: "scram-credentials"}}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, `user "my-user" has no passwordSecretRef`)
+	assert.Contains(t, mdb.Status.Message, "TLS is enabled but no certificateKeySecretRef is configured")
+	assert.Contains(t, mdb.Status.Message, `featureCompatibilityVersion "4.4" is not compatible with MongoDB version 4.2.2`)
+
+	t.Run("Version dependent checks are skipped for an invalid version", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Version = "latest"
+		mdb.Spec.FeatureCompatibilityVersion = "4.4"
+		mdb.Spec.Members = 0
+		errs := validation.Validate(mdb)
+		assert.Len(t, errs, 3)
+		assert.Contains(t, fmt.Sprint(errs), "the number of members must be greater than 0")
+		assert.Contains(t, fmt.Sprint(errs), `invalid MongoDB version "latest"`)
+	})
+}
+
+func TestMongodCommand(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.MongodCommand = &mdbv1.CommandConfiguration{
+		Command: []string{"/opt/wrapper/run.sh"},
+		Args:    []string{"--", "mongod"},
+	}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	mongod := func() corev1.Container {
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		c := container.GetByName(construct.MongodbName, sts.Spec.Template.Spec.Containers)
+		assert.NotNil(t, c)
+		return *c
+	}
+	assert.Equal(t, []string{"/opt/wrapper/run.sh"}, mongod().Command)
+	assert.Equal(t, []string{"--", "mongod"}, mongod().Args)
+
+	t.Run("The default command is restored once the custom command is removed", func(t *testing.T) {
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.MongodCommand = nil
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		assert.Equal(t, "/bin/sh", mongod().Command[0])
+		assert.Contains(t, mongod().Command[2], construct.MongodCommand(mdb.DataPath(), mdb.LogsPath(), true))
+		assert.Empty(t, mongod().Args)
+	})
+
+	t.Run("A custom command cannot be combined with additional mongod arguments", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.MongodCommand = &mdbv1.CommandConfiguration{Command: []string{"/opt/wrapper/run.sh"}}
+		mdb.Spec.AdditionalMongodArgs = []string{"--quiet"}
+		assert.Error(t, validation.ValidateInitalSpec(mdb))
+	})
+}
+
+func TestClusterAuthMode_IsValidatedAgainstTLS(t *testing.T) {
+	withClusterAuthMode := func(mdb mdbv1.MongoDBCommunity, mode string) mdbv1.MongoDBCommunity {
+		mongodConfig := objx.New(map[string]interface{}{})
+		mongodConfig.Set("security.clusterAuthMode", mode)
+		mdb.Spec.AdditionalMongodConfig.Object = mongodConfig
+		return mdb
+	}
+
+	t.Run("X.509 requires TLS", func(t *testing.T) {
+		for _, mode := range []string{"sendKeyFile", "sendX509", "x509"} {
+			assert.Error(t, validation.ValidateInitalSpec(withClusterAuthMode(newTestReplicaSet(), mode)), mode)
+			assert.NoError(t, validation.ValidateInitalSpec(withClusterAuthMode(newTestReplicaSetWithTLS(), mode)), mode)
+		}
+		assert.NoError(t, validation.ValidateInitalSpec(withClusterAuthMode(newTestReplicaSet(), "keyFile")))
+	})
+
+	t.Run("X.509 only can't be combined with optional TLS", func(t *testing.T) {
+		mdb := newTestReplicaSetWithTLS()
+		mdb.Spec.Security.TLS.Optional = true
+		assert.EqualError(t, validation.ValidateInitalSpec(withClusterAuthMode(mdb, "x509")),
+			"security.clusterAuthMode x509 can't be combined with optional TLS, the members only authenticate with X.509 certificates which require TLS, set security.tls.optional to false")
+		assert.NoError(t, validation.ValidateInitalSpec(withClusterAuthMode(mdb, "sendX509")), "the members still accept the keyfile while switching to X.509")
+	})
+
+	t.Run("Unknown mode is rejected", func(t *testing.T) {
+		assert.Error(t, validation.ValidateInitalSpec(withClusterAuthMode(newTestReplicaSetWithTLS(), "X509")))
+	})
+}
+
+func TestNetConfig_IsRenderedWithTheOperatorPort(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mongodConfig := objx.New(map[string]interface{}{})
+	mongodConfig.Set("net.bindIp", "0.0.0.0,::")
+	mongodConfig.Set("net.ipv6", true)
+	mdb.Spec.AdditionalMongodConfig.Object = mongodConfig
+	assert.NoError(t, validation.ValidateInitalSpec(mdb))
+
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+
+	for _, p := range currentAc.Processes {
+		assert.Equal(t, "0.0.0.0,::", p.Args26.Get("net.bindIp").Data())
+		assert.Equal(t, true, p.Args26.Get("net.ipv6").Data())
+		assert.Equal(t, float64(27017), p.Args26.Get("net.port").Data())
+	}
+}
+
+func TestNetConfig_IsValidated(t *testing.T) {
+	withNetConfig := func(settings map[string]interface{}) mdbv1.MongoDBCommunity {
+		mdb := newTestReplicaSet()
+		mongodConfig := objx.New(map[string]interface{}{})
+		for k, v := range settings {
+			mongodConfig.Set(k, v)
+		}
+		mdb.Spec.AdditionalMongodConfig.Object = mongodConfig
+		return mdb
+	}
+
+	t.Run("Valid settings", func(t *testing.T) {
+		assert.NoError(t, validation.ValidateInitalSpec(withNetConfig(map[string]interface{}{"net.bindIp": "0.0.0.0"})))
+		assert.NoError(t, validation.ValidateInitalSpec(withNetConfig(map[string]interface{}{"net.bindIp": "localhost, 10.0.0.4"})))
+		assert.NoError(t, validation.ValidateInitalSpec(withNetConfig(map[string]interface{}{"net.bindIp": "::,0.0.0.0", "net.ipv6": true})))
+		assert.NoError(t, validation.ValidateInitalSpec(withNetConfig(map[string]interface{}{"net.bindIpAll": true, "net.ipv6": true})))
+	})
+
+	t.Run("Binding only to the loopback interface is rejected", func(t *testing.T) {
+		for _, bindIP := range []string{"localhost", "127.0.0.1", "localhost,127.0.0.1"} {
+			assert.EqualError(t, validation.ValidateInitalSpec(withNetConfig(map[string]interface{}{"net.bindIp": bindIP})),
+				fmt.Sprintf("net.bindIp %q only binds to the loopback interface, the members of the replica set could not reach each other", bindIP))
+		}
+		assert.Error(t, validation.ValidateInitalSpec(withNetConfig(map[string]interface{}{"net.bindIp": "::1", "net.ipv6": true})))
+	})
+
+	t.Run("IPv6 addresses require net.ipv6", func(t *testing.T) {
+		assert.EqualError(t, validation.ValidateInitalSpec(withNetConfig(map[string]interface{}{"net.bindIp": "0.0.0.0,::"})),
+			"net.bindIp address :: is an IPv6 address, which requires net.ipv6 to be true")
+	})
+
+	t.Run("Invalid settings", func(t *testing.T) {
+		assert.Error(t, validation.ValidateInitalSpec(withNetConfig(map[string]interface{}{"net.ipv6": "true"})))
+		assert.Error(t, validation.ValidateInitalSpec(withNetConfig(map[string]interface{}{"net.bindIp": "0.0.0.0,"})))
+		assert.Error(t, validation.ValidateInitalSpec(withNetConfig(map[string]interface{}{"net.bindIp": "0.0.0.0", "net.bindIpAll": true})))
+	})
+}
+
+func TestReplicaSetHorizons_AreValidated(t *testing.T) {
+	withHorizons := func(horizons ...automationconfig.ReplicaSetHorizons) mdbv1.MongoDBCommunity {
+		mdb := newTestReplicaSet()
+		mdb.Spec.ReplicaSetHorizons = horizons
+		return mdb
+	}
+	validHorizons := func() []automationconfig.ReplicaSetHorizons {
+		return []automationconfig.ReplicaSetHorizons{
+			{"external": "rs-0.example.com:31181", "internal": "10.0.0.1:27017"},
+			{"external": "rs-1.example.com:31182", "internal": "10.0.0.2:27017"},
+			{"external": "rs-2.example.com:31183", "internal": "10.0.0.3:27017"},
+		}
+	}
+
+	t.Run("Valid horizons", func(t *testing.T) {
+		assert.NoError(t, validation.ValidateInitalSpec(withHorizons(validHorizons()...)))
+		assert.NoError(t, validation.ValidateInitalSpec(withHorizons()))
+	})
+
+	t.Run("Every member requires horizons", func(t *testing.T) {
+		assert.EqualError(t, validation.ValidateInitalSpec(withHorizons(validHorizons()[:2]...)),
+			"replicaSetHorizons has 2 entries, one is required for each of the 3 members")
+	})
+
+	t.Run("Mismatched horizon names are rejected", func(t *testing.T) {
+		horizons := validHorizons()
+		horizons[1] = automationconfig.ReplicaSetHorizons{"external": "rs-1.example.com:31182", "other": "10.0.0.2:27017"}
+		horizons[2] = automationconfig.ReplicaSetHorizons{"external": "rs-2.example.com:31183"}
+		err := validation.ValidateInitalSpec(withHorizons(horizons...))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "replicaSetHorizons[1] must declare the same horizons as replicaSetHorizons[0]: missing internal; unexpected other")
+		assert.Contains(t, err.Error(), "replicaSetHorizons[2] must declare the same horizons as replicaSetHorizons[0]: missing internal")
+	})
+
+	t.Run("Invalid addresses are rejected", func(t *testing.T) {
+		horizons := validHorizons()
+		horizons[0]["external"] = "rs-0.example.com"
+		horizons[1]["external"] = "rs-1.example.com:0"
+		horizons[2]["external"] = "rs_2:31183"
+		err := validation.ValidateInitalSpec(withHorizons(horizons...))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `replicaSetHorizons[0] horizon "external": "rs-0.example.com" must have the format host:port`)
+		assert.Contains(t, err.Error(), `replicaSetHorizons[1] horizon "external": "rs-1.example.com:0" must have a port between 1 and 65535`)
+		assert.Contains(t, err.Error(), `replicaSetHorizons[2] horizon "external": "rs_2:31183" is neither a valid hostname nor an IP address`)
+	})
+
+	t.Run("Members can't share an address in a horizon", func(t *testing.T) {
+		horizons := validHorizons()
+		horizons[2]["internal"] = horizons[0]["internal"]
+		assert.EqualError(t, validation.ValidateInitalSpec(withHorizons(horizons...)),
+			`replicaSetHorizons[0] and replicaSetHorizons[2] have the same address 10.0.0.1:27017 in horizon "internal"`)
+	})
+
+	t.Run("Problems are reported in the status before the horizons are deployed", func(t *testing.T) {
+		horizons := validHorizons()
+		horizons[1] = automationconfig.ReplicaSetHorizons{"external": "rs-1.example.com"}
+		mdb := withHorizons(horizons...)
+		mgr := client.NewManager(&mdb)
+		r := NewReconciler(mgr)
+
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+		assert.Contains(t, mdb.Status.Message, "replicaSetHorizons[1] must declare the same horizons as replicaSetHorizons[0]: missing internal")
+		assert.Contains(t, mdb.Status.Message, `"rs-1.example.com" must have the format host:port`)
+	})
+}
+
+func TestMemberAddressing_PodIP(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.MemberAddressing.Mode = mdbv1.PodIPAddressing
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	agentContainer := container.GetByName(construct.AgentName, sts.Spec.Template.Spec.Containers)
+	assert.NotNil(t, agentContainer)
+	assert.Contains(t, agentContainer.Command[2], "-overrideLocalHost=${POD_IP}")
+	assert.Contains(t, agentContainer.Env, corev1.EnvVar{
+		Name:      podIPEnv,
+		ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "status.podIP"}},
+	})
+
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	for _, process := range ac.Processes {
+		assert.Equal(t, fmt.Sprintf("%s.%s", process.Name, mdb.ServiceFQDN()), process.HostName, "the hostname should be kept until the pod has an IP address")
+	}
+
+	t.Run("The hostnames are replaced with the IP addresses of the pods", func(t *testing.T) {
+		for i := 0; i < mdb.Spec.Members-1; i++ {
+			pod := corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", mdb.Name, i), Namespace: mdb.Namespace},
+				Status:     corev1.PodStatus{PodIP: fmt.Sprintf("10.0.0.%d", i)},
+			}
+			assert.NoError(t, mgr.Client.Create(context.TODO(), &pod))
+		}
+
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, "10.0.0.0", ac.Processes[0].HostName)
+		assert.Equal(t, "10.0.0.1", ac.Processes[1].HostName)
+		assert.Equal(t, fmt.Sprintf("%s-2.%s", mdb.Name, mdb.ServiceFQDN()), ac.Processes[2].HostName)
+
+		// the connection string is updated once the agents have reached the automation config, the last
+		// member has no IP address yet.
+		lastPod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-2", mdb.Name), Namespace: mdb.Namespace}}
+		assert.NoError(t, mgr.Client.Create(context.TODO(), &lastPod))
+		for i := 0; i < mdb.Spec.Members; i++ {
+			pod := corev1.Pod{}
+			err := mgr.Client.Get(context.TODO(), types.NamespacedName{Name: fmt.Sprintf("%s-%d", mdb.Name, i), Namespace: mdb.Namespace}, &pod)
+			assert.NoError(t, err)
+			pod.Annotations = map[string]string{"agent.mongodb.com/version": strconv.Itoa(ac.Version)}
+			assert.NoError(t, mgr.Client.Update(context.TODO(), &pod))
+		}
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("mongodb://10.0.0.0:27017,10.0.0.1:27017,%s-2.%s:27017/?replicaSet=%s", mdb.Name, mdb.ServiceFQDN(), mdb.Name), mdb.Status.MongoURI)
+	})
+
+	t.Run("The IP address of the pods cannot be combined with TLS", func(t *testing.T) {
+		mdb := newTestReplicaSetWithTLS()
+		mdb.Spec.MemberAddressing.Mode = mdbv1.PodIPAddressing
+		assert.Error(t, validation.ValidateInitalSpec(mdb))
+	})
+
+	t.Run("The mode cannot be changed once the replica set has been deployed", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		assert.Error(t, validation.ValidateUpdate(mdb, mdbv1.MongoDBCommunitySpec{Members: 3, MemberAddressing: mdbv1.MemberAddressingConfiguration{Mode: mdbv1.PodIPAddressing}}))
+		assert.NoError(t, validation.ValidateUpdate(mdb, mdbv1.MongoDBCommunitySpec{Members: 3, MemberAddressing: mdbv1.MemberAddressingConfiguration{Mode: mdbv1.ServiceFQDNAddressing}}))
+	})
+}
+
+func TestMemberAddressing_ClusterDomain(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.MemberAddressing.ClusterDomain = "my.cluster"
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%s-0.%s.%s.svc.my.cluster", mdb.Name, mdb.ServiceName(), mdb.Namespace), ac.Processes[0].HostName)
+	assert.Equal(t, fmt.Sprintf("%s-0.%s.%s.svc.my.cluster:27017", mdb.Name, mdb.ServiceName(), mdb.Namespace), mdb.Hosts()[0])
+
+	t.Run("The cluster domain defaults to the one of the operator", func(t *testing.T) {
+		assert.NoError(t, os.Setenv(mdbv1.ClusterDNSNameEnv, "operator.cluster"))
+		defer os.Unsetenv(mdbv1.ClusterDNSNameEnv)
+		mdb := newTestReplicaSet()
+		mgr := client.NewManager(&mdb)
+		r := NewReconciler(mgr)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%s-0.%s.%s.svc.operator.cluster", mdb.Name, mdb.ServiceName(), mdb.Namespace), ac.Processes[0].HostName)
+		assert.Equal(t, ac.Processes[0].HostName+":27017", mdb.Hosts()[0])
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, mdb.MongoURI(), mdb.Status.MongoURI)
+	})
+
+	t.Run("Invalid cluster domains are rejected", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.MemberAddressing.ClusterDomain = "my_cluster"
+		assert.Error(t, validation.ValidateInitalSpec(mdb))
+	})
+}
+
+func TestReadinessGates(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: "target-health.elbv2.k8s.aws/my-tgb"}}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, mdb.Spec.ReadinessGates, sts.Spec.Template.Spec.ReadinessGates)
+
+	t.Run("Removed readiness gates are removed from the StatefulSet", func(t *testing.T) {
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.ReadinessGates = nil
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Empty(t, sts.Spec.Template.Spec.ReadinessGates)
+	})
+}
+
+func TestAdditionalInitContainers(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.AdditionalInitContainers = []corev1.Container{
+		{
+			Name:         "restore-snapshot",
+			Image:        "restore:latest",
+			VolumeMounts: []corev1.VolumeMount{{Name: mdb.DataVolumeName(), MountPath: "/data"}},
+		},
+		{Name: "warm-cache", Image: "warm-cache:latest"},
+	}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	initContainerNames := func() []string {
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		var names []string
+		for _, c := range sts.Spec.Template.Spec.InitContainers {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"mongod-posthook", construct.ReadinessProbeContainerName, "restore-snapshot", "warm-cache"}, initContainerNames(),
+		"the additional init containers should run after the ones of the operator and in the configured order")
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	restore := container.GetByName("restore-snapshot", sts.Spec.Template.Spec.InitContainers)
+	assert.NotNil(t, restore)
+	assert.Equal(t, []corev1.VolumeMount{{Name: mdb.DataVolumeName(), MountPath: "/data"}}, restore.VolumeMounts)
+	assert.Equal(t, mdb.DataVolumeName(), sts.Spec.VolumeClaimTemplates[0].Name)
+
+	t.Run("Removed init containers are removed from the StatefulSet", func(t *testing.T) {
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.AdditionalInitContainers = mdb.Spec.AdditionalInitContainers[1:]
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"mongod-posthook", construct.ReadinessProbeContainerName, "warm-cache"}, initContainerNames())
+	})
+
+	t.Run("Init containers of the operator cannot be configured", func(t *testing.T) {
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.AdditionalInitContainers = []corev1.Container{{Name: construct.ReadinessProbeContainerName}}
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+		assert.Contains(t, mdb.Status.Message, "is managed by the operator")
+	})
+}
+
+func TestAgentMonitoringOnlyMode(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Agent.Mode = mdbv1.AgentModeMonitoringOnly
+	mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: construct.MongodbName, Command: []string{"/usr/local/bin/start-mongod.sh"}},
+	}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	for _, p := range currentAc.Processes {
+		assert.True(t, p.ManualMode)
+	}
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+	assert.Equal(t, []string{"/usr/local/bin/start-mongod.sh"}, mongodContainer.Command, "the command of the operator should be replaced")
+
+	t.Run("Processes are managed again in the Automation mode", func(t *testing.T) {
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Agent.Mode = mdbv1.AgentModeAutomation
+		mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.Template.Spec.Containers = nil
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		for _, p := range currentAc.Processes {
+			assert.False(t, p.ManualMode)
+		}
+	})
+}
+
+func TestStorageJournalCommitIntervalAndSyncPeriod(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Storage.JournalCommitIntervalMs = 50
+	mdb.Spec.Storage.SyncPeriodSecs = 30
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	for _, p := range currentAc.Processes {
+		assert.Equal(t, float64(50), p.Args26.Get("storage.journal.commitIntervalMs").Data())
+		assert.Equal(t, float64(30), p.Args26.Get("storage.syncPeriodSecs").Data())
+	}
+
+	t.Run("Commit interval above 500 milliseconds is rejected", func(t *testing.T) {
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Storage.JournalCommitIntervalMs = 501
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+		assert.Contains(t, mdb.Status.Message, "the journal commit interval must be 0 or between 1 and 500 milliseconds")
+	})
+}
+
+func TestConnectionLimitAndOperationProfiling(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Net.MaxIncomingConnections = 2000
+	mdb.Spec.OperationProfiling = mdbv1.OperationProfilingConfiguration{Mode: "slowOp", SlowOpThresholdMs: 250}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Len(t, currentAc.Processes, 3)
+	for _, p := range currentAc.Processes {
+		assert.Equal(t, float64(2000), p.Args26.Get("net.maxIncomingConnections").Data())
+		assert.Equal(t, float64(27017), p.Args26.Get("net.port").Data())
+		assert.Equal(t, "slowOp", p.Args26.Get("operationProfiling.mode").Data())
+		assert.Equal(t, float64(250), p.Args26.Get("operationProfiling.slowOpThresholdMs").Data())
+	}
+
+	t.Run("The defaults of mongod are kept", func(t *testing.T) {
+		ac, err := buildAutomationConfig(newTestReplicaSet(), automationconfig.Auth{}, automationconfig.AutomationConfig{})
+		assert.NoError(t, err)
+		for _, p := range ac.Processes {
+			assert.Nil(t, p.Args26.Get("net.maxIncomingConnections").Data())
+			assert.Nil(t, p.Args26.Get("operationProfiling").Data())
+		}
+	})
+
+	t.Run("Unknown profiling mode is rejected", func(t *testing.T) {
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.OperationProfiling.Mode = "slow"
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+		assert.Contains(t, mdb.Status.Message, "the operation profiling mode must be one of off, slowOp or all")
+	})
+
+	t.Run("Negative connection limit is rejected", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Net.MaxIncomingConnections = -1
+		err := validation.ValidateInitalSpec(mdb)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "the maximum number of incoming connections must be positive")
+	})
+
+	t.Run("Settings also in the additional mongod configuration are rejected", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Net.MaxIncomingConnections = 2000
+		mdb.Spec.AdditionalMongodConfig.Object = map[string]interface{}{"net": map[string]interface{}{"maxIncomingConnections": 100}}
+		err := validation.ValidateInitalSpec(mdb)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "net.maxIncomingConnections is configured with spec.net.maxIncomingConnections")
+
+		mdb = newTestReplicaSet()
+		mdb.Spec.OperationProfiling.SlowOpThresholdMs = 250
+		mdb.Spec.AdditionalMongodConfig.Object = map[string]interface{}{"operationProfiling": map[string]interface{}{"slowOpThresholdMs": 50}}
+		err = validation.ValidateInitalSpec(mdb)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "operationProfiling.slowOpThresholdMs is configured with spec.operationProfiling.slowOpThresholdMs")
+
+		mdb.Spec.AdditionalMongodConfig.Object = map[string]interface{}{"operationProfiling": map[string]interface{}{"mode": "all"}}
+		assert.NoError(t, validation.ValidateInitalSpec(mdb), "settings not set in the spec can still be configured")
+	})
+}
+
+func TestDiagnostics(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Diagnostics = mdbv1.DiagnosticsConfiguration{DisableFreeMonitoring: true, DisableDiagnosticDataCollection: true}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	for _, p := range currentAc.Processes {
+		assert.Equal(t, "off", p.Args26.Get("cloud.monitoring.free.state").Data())
+		assert.Equal(t, false, p.Args26.Get("setParameter.diagnosticDataCollectionEnabled").Data())
+		assert.Nil(t, p.Args26.Get("setParameter.diagnosticDataCollectionPeriodMillis").Data())
+	}
+
+	t.Run("The interval of the diagnostic data capture is configured", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Diagnostics.DiagnosticDataCollectionPeriodMillis = 5000
+		ac, err := buildAutomationConfig(mdb, automationconfig.Auth{}, automationconfig.AutomationConfig{})
+		assert.NoError(t, err)
+		for _, p := range ac.Processes {
+			assert.Equal(t, 5000, p.Args26.Get("setParameter.diagnosticDataCollectionPeriodMillis").Data())
+			assert.Nil(t, p.Args26.Get("setParameter.diagnosticDataCollectionEnabled").Data())
+			assert.Nil(t, p.Args26.Get("cloud").Data())
+		}
+	})
+
+	t.Run("Free monitoring is not configured on versions without it", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Version = "7.0.2"
+		mdb.Spec.Diagnostics.DisableFreeMonitoring = true
+		ac, err := buildAutomationConfig(mdb, automationconfig.Auth{}, automationconfig.AutomationConfig{})
+		assert.NoError(t, err)
+		for _, p := range ac.Processes {
+			assert.Nil(t, p.Args26.Get("cloud").Data())
+		}
+	})
+}
+
+func TestAgentTimeouts(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Agent.DialTimeoutSeconds = 20
+	mdb.Spec.Agent.ServerSelectionTimeoutSeconds = 5
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+	assert.Contains(t, agentContainer.Command[2], " -dialTimeoutSeconds=20 -serverSelectionTimeoutSeconds=5")
+
+	t.Run("The defaults of the agent are kept", func(t *testing.T) {
+		sts := BuildStatefulSet(newTestReplicaSet())
+		agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+		assert.NotContains(t, agentContainer.Command[2], "TimeoutSeconds")
+	})
+
+	t.Run("Timeouts out of range are rejected", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Agent.DialTimeoutSeconds = 601
+		assert.EqualError(t, validation.ValidateInitalSpec(mdb), "the agent dial timeout must be 0 or between 1 and 600 seconds, got 601")
+
+		mdb = newTestReplicaSet()
+		mdb.Spec.Agent.ServerSelectionTimeoutSeconds = -1
+		assert.EqualError(t, validation.ValidateInitalSpec(mdb), "the agent server selection timeout must be 0 or between 1 and 600 seconds, got -1")
+	})
+}
+
+func TestAgentDownloadBase(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Agent.DownloadBase = "/opt/mongodb/binaries"
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, "/opt/mongodb/binaries", currentAc.Options.DownloadBase)
+
+	t.Run("Relative download base is rejected", func(t *testing.T) {
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Agent.DownloadBase = "binaries"
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+		assert.Contains(t, mdb.Status.Message, "agent download base")
+	})
+}
+
+func TestAgentImagePullPolicy(t *testing.T) {
+	assertPullPolicy := func(t *testing.T, mdb mdbv1.MongoDBCommunity, pullPolicy corev1.PullPolicy) {
+		mgr := client.NewManager(&mdb)
+		r := NewReconciler(mgr)
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+		assert.NotNil(t, agentContainer)
+		assert.Equal(t, pullPolicy, agentContainer.ImagePullPolicy)
+		for _, c := range sts.Spec.Template.Spec.InitContainers {
+			assert.Equal(t, pullPolicy, c.ImagePullPolicy, "init container %s", c.Name)
+		}
+		mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+		assert.NotNil(t, mongodContainer)
+		assert.Empty(t, mongodContainer.ImagePullPolicy, "the pull policy of the mongod image should not be changed")
+	}
+
+	t.Run("Images are always pulled by default", func(t *testing.T) {
+		assertPullPolicy(t, newTestReplicaSet(), corev1.PullAlways)
+	})
+
+	t.Run("Pull policy can be configured", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Agent.ImagePullPolicy = corev1.PullIfNotPresent
+		assertPullPolicy(t, mdb, corev1.PullIfNotPresent)
+	})
+}
+
+func TestAgentLivenessProbe(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+	assert.NotNil(t, agentContainer)
+	assert.Nil(t, agentContainer.LivenessProbe, "the liveness probe should not be configured by default")
+
+	t.Run("Liveness probe is configured when enabled", func(t *testing.T) {
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Agent.LivenessProbe = &mdbv1.ProbeConfiguration{FailureThreshold: 30}
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+		assert.NotNil(t, agentContainer.LivenessProbe)
+		assert.Equal(t, intstr.FromInt(5000), agentContainer.LivenessProbe.TCPSocket.Port)
+		assert.Equal(t, int32(30), agentContainer.LivenessProbe.FailureThreshold)
+		assert.Equal(t, int32(60), agentContainer.LivenessProbe.InitialDelaySeconds)
+		assert.Equal(t, int32(30), agentContainer.LivenessProbe.PeriodSeconds)
+	})
+
+	t.Run("Liveness probe is removed when disabled", func(t *testing.T) {
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Agent.LivenessProbe = nil
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+		assert.Nil(t, agentContainer.LivenessProbe)
+	})
+}
+
+func TestUpgradeReadinessInitialDelay(t *testing.T) {
+	readinessInitialDelay := func(t *testing.T, mdb mdbv1.MongoDBCommunity) int32 {
+		sts := BuildStatefulSet(mdb)
+		agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+		assert.NotNil(t, agentContainer)
+		return agentContainer.ReadinessProbe.InitialDelaySeconds
+	}
+
+	t.Run("Default delay is used when not configured", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Version = "4.2.2"
+		mdb.Annotations[annotations.LastAppliedMongoDBVersion] = "4.0.0"
+		assert.Equal(t, int32(5), readinessInitialDelay(t, mdb))
+	})
+	t.Run("Extended delay is used while changing version", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Version = "4.2.2"
+		mdb.Annotations[annotations.LastAppliedMongoDBVersion] = "4.0.0"
+		mdb.Spec.Agent.UpgradeReadinessInitialDelaySeconds = 120
+		assert.Equal(t, int32(120), readinessInitialDelay(t, mdb))
+	})
+	t.Run("Default delay is restored after the version change", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Version = "4.2.2"
+		mdb.Annotations[annotations.LastAppliedMongoDBVersion] = "4.2.2"
+		mdb.Spec.Agent.UpgradeReadinessInitialDelaySeconds = 120
+		assert.Equal(t, int32(5), readinessInitialDelay(t, mdb))
+	})
+}
+
+func TestUpgradeReadinessInitialDelay_IsRestoredAfterTheUpgrade(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Generation = 1
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	readinessInitialDelay := func(t *testing.T) int32 {
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		return podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template).ReadinessProbe.InitialDelaySeconds
+	}
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mdb.Spec.Version = "4.4.0"
+	mdb.Spec.Agent.UpgradeReadinessInitialDelaySeconds = 120
+	mdb.Generation = 2
+	err = mgr.Client.Update(context.TODO(), &mdb)
+	assert.NoError(t, err)
+
+	// the reconciliation which completes the upgrade is requeued.
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.True(t, res.Requeue)
+	assert.Equal(t, int32(120), readinessInitialDelay(t))
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+	assert.Equal(t, "4.4.0", mdb.Annotations[annotations.LastAppliedMongoDBVersion])
+	assert.False(t, r.isUpToDate(mdb), "the requeued reconciliation should not be skipped")
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	assert.Equal(t, int32(5), readinessInitialDelay(t))
+}
+
+func TestAgentLogPath(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Agent.LogPath = "/var/log/mongodb"
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	for _, p := range currentAc.Processes {
+		assert.Equal(t, "/var/log/mongodb/mongodb.log", p.Args26.Get("systemLog.path").Data())
+	}
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+	assert.NotNil(t, agentContainer)
+	assert.Contains(t, agentContainer.Command[2], "-logFile=/var/log/mongodb/automation-agent.log")
+	assert.Contains(t, agentContainer.VolumeMounts, corev1.VolumeMount{Name: mdb.LogsVolumeName(), MountPath: "/var/log/mongodb"})
+
+	for _, logPath := range []string{"logs", "/data/logs"} {
+		t.Run(fmt.Sprintf("Invalid log path %s", logPath), func(t *testing.T) {
+			err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+			assert.NoError(t, err)
+			mdb.Spec.Agent.LogPath = logPath
+			err = mgr.Client.Update(context.TODO(), &mdb)
+			assert.NoError(t, err)
+
+			_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+			assert.NoError(t, err)
+
+			err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+			assert.NoError(t, err)
+			assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+			assert.Contains(t, mdb.Status.Message, "agent log path")
+		})
+	}
+}
+
+func TestStorageHostPath(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Storage.HostPath = "/mnt/mongodb"
+
+	sts := BuildStatefulSet(mdb)
+
+	assert.Len(t, sts.Spec.VolumeClaimTemplates, 1)
+	assert.Equal(t, mdb.LogsVolumeName(), sts.Spec.VolumeClaimTemplates[0].Name)
+
+	var dataVolume *corev1.Volume
+	for i := range sts.Spec.Template.Spec.Volumes {
+		if sts.Spec.Template.Spec.Volumes[i].Name == mdb.DataVolumeName() {
+			dataVolume = &sts.Spec.Template.Spec.Volumes[i]
+		}
+	}
+	assert.NotNil(t, dataVolume)
+	assert.Equal(t, "/mnt/mongodb", dataVolume.HostPath.Path)
+
+	for _, name := range []string{construct.AgentName, construct.MongodbName} {
+		c := podtemplatespec.FindContainerByName(name, &sts.Spec.Template)
+		assert.NotNil(t, c)
+		assert.Contains(t, c.VolumeMounts, corev1.VolumeMount{Name: mdb.DataVolumeName(), MountPath: mdb.DataPath(), SubPathExpr: "$(POD_NAME)"})
+		assert.NotContains(t, c.VolumeMounts, corev1.VolumeMount{Name: mdb.DataVolumeName(), MountPath: mdb.DataPath()})
+		found := false
+		for _, env := range c.Env {
+			if env.Name == podNameEnv {
+				found = true
+				assert.Equal(t, "metadata.name", env.ValueFrom.FieldRef.FieldPath)
+			}
+		}
+		assert.True(t, found, "the %s container must have the pod name env var", name)
+	}
+
+	t.Run("Host path must be absolute", func(t *testing.T) {
+		mdb.Spec.Storage.HostPath = "mnt/mongodb"
+		mgr := client.NewManager(&mdb)
+		r := NewReconciler(mgr)
+
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+		assert.Contains(t, mdb.Status.Message, "storage host path")
+	})
+}
+
+func TestStorageAccessMode(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Storage.AccessMode = "ReadWriteOncePod"
+
+	sts := BuildStatefulSet(mdb)
+
+	assert.Len(t, sts.Spec.VolumeClaimTemplates, 2)
+	for _, claim := range sts.Spec.VolumeClaimTemplates {
+		assert.Equal(t, []corev1.PersistentVolumeAccessMode{"ReadWriteOncePod"}, claim.Spec.AccessModes)
+	}
+
+	t.Run("Read only volumes are rejected", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Storage.AccessMode = corev1.ReadOnlyMany
+		assert.Error(t, validation.ValidateInitalSpec(mdb))
+	})
+
+	t.Run("The access mode cannot be combined with a host path", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Storage.AccessMode = corev1.ReadWriteMany
+		mdb.Spec.Storage.HostPath = "/mnt/mongodb"
+		assert.Error(t, validation.ValidateInitalSpec(mdb))
+	})
+}
+
+func TestVolumeClaimMetadata(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Storage.VolumeClaimLabels = map[string]string{"cost-center": "data"}
+	mdb.Spec.Storage.VolumeClaimAnnotations = map[string]string{"backup.example.com/schedule": "daily"}
+
+	sts := BuildStatefulSet(mdb)
+	assert.Len(t, sts.Spec.VolumeClaimTemplates, 2)
+	for _, claim := range sts.Spec.VolumeClaimTemplates {
+		assert.Equal(t, map[string]string{"cost-center": "data"}, claim.Labels)
+		assert.Equal(t, map[string]string{"backup.example.com/schedule": "daily"}, claim.Annotations)
+	}
+
+	t.Run("Changing the labels requires recreating the StatefulSet", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mgr := client.NewManager(&mdb)
+		r := NewReconciler(mgr)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Storage.VolumeClaimLabels = map[string]string{"cost-center": "data"}
+		err = mgr.GetClient().Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+		assert.Contains(t, mdb.Status.Message, fmt.Sprintf("volumeClaimTemplates[%s]", mdb.DataVolumeName()))
+
+		mdb.Annotations[annotations.AllowStatefulSetRecreate] = "true"
+		err = mgr.GetClient().Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"cost-center": "data"}, sts.Spec.VolumeClaimTemplates[0].Labels)
+	})
+
+	t.Run("Invalid labels are rejected", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Storage.VolumeClaimLabels = map[string]string{"cost center": "data"}
+		assert.Error(t, validation.ValidateInitalSpec(mdb))
+	})
+
+	t.Run("Labels cannot be combined with a host path", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Storage.HostPath = "/mnt/mongodb"
+		mdb.Spec.Storage.VolumeClaimLabels = map[string]string{"cost-center": "data"}
+		assert.Error(t, validation.ValidateInitalSpec(mdb))
+	})
+}
+
+func TestMemberConfig(t *testing.T) {
+	noBuildIndexes := false
+	mdb := newTestReplicaSet()
+	mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{{}, {}, {Hidden: true, BuildIndexes: &noBuildIndexes}}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	members := ac.ReplicaSets[0].Members
+	assert.Len(t, members, 3)
+	for _, member := range members[:2] {
+		assert.False(t, member.Hidden)
+		assert.Nil(t, member.BuildIndexes)
+	}
+	assert.True(t, members[2].Hidden)
+	assert.Equal(t, 0, members[2].Priority)
+	assert.Equal(t, &noBuildIndexes, members[2].BuildIndexes)
+
+	t.Run("Index builds can only be disabled on hidden members", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{{}, {}, {BuildIndexes: &noBuildIndexes}}
+		assert.Error(t, validation.ValidateInitalSpec(mdb))
+	})
+
+	t.Run("Arbiters cannot be configured", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Arbiters = 1
+		mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{{Hidden: true}}
+		assert.Error(t, validation.ValidateInitalSpec(mdb))
+	})
+
+	t.Run("A member must be able to become the primary", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{{Hidden: true}, {Hidden: true}, {Hidden: true}}
+		assert.Error(t, validation.ValidateInitalSpec(mdb))
+	})
+
+	t.Run("Index builds cannot be changed on existing members", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Members = 4
+		mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{{}, {}, {}, {Hidden: true, BuildIndexes: &noBuildIndexes}}
+		assert.Error(t, validation.ValidateUpdate(mdb, mdbv1.MongoDBCommunitySpec{Members: 4}))
+		assert.NoError(t, validation.ValidateUpdate(mdb, mdbv1.MongoDBCommunitySpec{Members: 3}), "index builds can be disabled on new members")
+	})
+}
+
+func TestMemberHost(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{{}, {Host: "mongo-1.example.com:27017"}}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, "my-rs-0.my-rs-svc.my-ns.svc.cluster.local", ac.Processes[0].HostName)
+	assert.Equal(t, "mongo-1.example.com", ac.Processes[1].HostName)
+	assert.Equal(t, "my-rs-1", ac.ReplicaSets[0].Members[1].Host, "the member keeps referencing its process")
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+	assert.Contains(t, agentContainer.Command[2], `$(case "$(hostname)" in my-rs-1) echo -overrideLocalHost=mongo-1.example.com ;; esac)`)
+
+	t.Run("Members addressed by their pod IPs fall back to the pod IP", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.MemberAddressing.Mode = mdbv1.PodIPAddressing
+		mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{{Host: "mongo-0.example.com:27017"}}
+		sts := BuildStatefulSet(mdb)
+		agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+		assert.Contains(t, agentContainer.Command[2], "my-rs-0) echo -overrideLocalHost=mongo-0.example.com ;; *) echo -overrideLocalHost=${POD_IP} ;; esac)")
+		assert.Equal(t, 1, strings.Count(agentContainer.Command[2], "-overrideLocalHost=${POD_IP}"))
+	})
+
+	t.Run("The host is validated", func(t *testing.T) {
+		for _, host := range []string{"mongo-0.example.com", "mongo_0.example.com:27017", "mongo-0.example.com:30017"} {
+			mdb := newTestReplicaSet()
+			mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{{Host: host}}
+			assert.Error(t, validation.ValidateInitalSpec(mdb), host)
+		}
+
+		mdb := newTestReplicaSet()
+		mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{{Host: "10.0.0.1:27017"}, {Host: "10.0.0.1:27017"}}
+		assert.Error(t, validation.ValidateInitalSpec(mdb), "the hosts must be unique")
+
+		mdb = newTestReplicaSet()
+		mdb.Spec.AdditionalMongodConfig.Object = objx.New(map[string]interface{}{}).Set("net.port", 30017)
+		mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{{Host: "mongo-0.example.com:30017"}}
+		assert.NoError(t, validation.ValidateInitalSpec(mdb))
+	})
+}
+
+func TestStepDownOnShutdown(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Shutdown.StepDownPrimary = true
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+	assert.NotNil(t, mongodContainer.Lifecycle.PreStop)
+	command := mongodContainer.Lifecycle.PreStop.Exec.Command
+	assert.Equal(t, []string{"/bin/sh", "-c"}, command[:2])
+	assert.Contains(t, command[2], "replSetStepDown: 60, secondaryCatchUpPeriodSecs: 10")
+	assert.Contains(t, command[2], scram.AutomationAgentKeyFilePathInContainer)
+	assert.NotContains(t, command[2], "--tls")
+	assert.Equal(t, int64(40), *sts.Spec.Template.Spec.TerminationGracePeriodSeconds)
+
+	t.Run("The timeout extends the grace period", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Shutdown = mdbv1.ShutdownConfiguration{StepDownPrimary: true, StepDownTimeoutSeconds: 90}
+		sts := BuildStatefulSet(mdb)
+		mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+		assert.Contains(t, mongodContainer.Lifecycle.PreStop.Exec.Command[2], "replSetStepDown: 90, secondaryCatchUpPeriodSecs: 90")
+		assert.Equal(t, int64(120), *sts.Spec.Template.Spec.TerminationGracePeriodSeconds)
+	})
+
+	t.Run("The shell connects with TLS", func(t *testing.T) {
+		mdb := newTestReplicaSetWithTLS()
+		mdb.Spec.Shutdown.StepDownPrimary = true
+		sts := BuildStatefulSet(mdb)
+		mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+		assert.Contains(t, mongodContainer.Lifecycle.PreStop.Exec.Command[2], "--tls --tlsCAFile /var/lib/tls/ca/ca.crt --tlsAllowInvalidHostnames")
+		assert.Contains(t, mongodContainer.Lifecycle.PreStop.Exec.Command[2], "--ssl --sslCAFile /var/lib/tls/ca/ca.crt --sslAllowInvalidHostnames")
+	})
+
+	t.Run("The shell and the startup probe use the configured port", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Shutdown.StepDownPrimary = true
+		mdb.Spec.AdditionalMongodConfig.Object = map[string]interface{}{"net": map[string]interface{}{"port": float64(27018)}}
+		sts := BuildStatefulSet(mdb)
+		mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+		assert.Contains(t, mongodContainer.Lifecycle.PreStop.Exec.Command[2], "--port 27018 ")
+		assert.Equal(t, 27018, mongodContainer.StartupProbe.TCPSocket.Port.IntValue())
+		assert.Equal(t, fmt.Sprintf("%s-0.%s:27018", mdb.Name, mdb.ServiceFQDN()), mdb.Hosts()[0])
+	})
+
+	t.Run("The grace period must be longer than the timeout", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Shutdown.StepDownPrimary = true
+		gracePeriod := int64(10)
+		mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.Template.Spec.TerminationGracePeriodSeconds = &gracePeriod
+		assert.Error(t, validation.ValidateInitalSpec(mdb))
+	})
+
+	t.Run("The hook is removed when disabled", func(t *testing.T) {
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Shutdown.StepDownPrimary = false
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+		assert.True(t, mongodContainer.Lifecycle == nil || mongodContainer.Lifecycle.PreStop == nil)
+		assert.Nil(t, sts.Spec.Template.Spec.TerminationGracePeriodSeconds)
+	})
+}
+
+func TestMemberStatuses(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	for i := 0; i < mdb.Spec.Members; i++ {
+		ready := corev1.ConditionTrue
+		if i == 2 {
+			ready = corev1.ConditionFalse
+		}
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%d", mdb.Name, i),
+				Namespace: mdb.Namespace,
+				Labels:    map[string]string{"app": mdb.ServiceName()},
+			},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}}},
+		}
+		assert.NoError(t, mgr.Client.Create(context.TODO(), &pod))
+	}
+	r := NewReconciler(mgr)
+	r.statusGetter = mockedStatusGetter{states: replicaset.MemberStates{
+		mdb.Hosts()[0]: replicaset.SecondaryState,
+		mdb.Hosts()[1]: replicaset.PrimaryState,
+		mdb.Hosts()[2]: "RECOVERING",
+	}}
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	expected := []mdbv1.MemberStatus{
+		{Name: mdb.Name + "-0", Ready: true, Role: secondaryRole},
+		{Name: mdb.Name + "-1", Ready: true, Role: primaryRole},
+		{Name: mdb.Name + "-2", Ready: false, Role: "recovering"},
+	}
+	assert.Eventually(t, func() bool {
+		unlock := r.lockResource(mdb.NamespacedName())
+		defer unlock()
+
+		if err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb); err != nil {
+			return false
+		}
+		return reflect.DeepEqual(expected, mdb.Status.Members)
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestMemberStatuses_NotReadyReason(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	notReadyFor := []time.Duration{0, time.Minute * 5, time.Second * 30}
+	for i, duration := range notReadyFor {
+		condition := corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionTrue}
+		if duration > 0 {
+			condition = corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionFalse, LastTransitionTime: metav1.NewTime(time.Now().Add(-duration))}
+		}
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        fmt.Sprintf("%s-%d", mdb.Name, i),
+				Namespace:   mdb.Namespace,
+				Labels:      map[string]string{"app": mdb.ServiceName()},
+				Annotations: map[string]string{"agent.mongodb.com/not-ready-reason": "the agent has not reached the goal state, current step: WaitRsInit"},
+			},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{condition}},
+		}
+		assert.NoError(t, mgr.Client.Create(context.TODO(), &pod))
+	}
+	r := NewReconciler(mgr)
+
+	members := r.memberStatuses(mdb)
+	assert.Equal(t, []mdbv1.MemberStatus{
+		{Name: mdb.Name + "-0", Ready: true},
+		{Name: mdb.Name + "-1", Ready: false, Message: "the agent has not reached the goal state, current step: WaitRsInit"},
+		{Name: mdb.Name + "-2", Ready: false},
+	}, members, "the reason should only be reported once the pod has not been ready for a while")
+	assert.Equal(t, ", members not ready: "+mdb.Name+"-1: the agent has not reached the goal state, current step: WaitRsInit", notReadyMessage(members))
+	assert.Empty(t, notReadyMessage(members[:1]))
+}
+
+func TestConfigConvergenceLag(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	for i, version := range []string{"1", "", "1"} {
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", mdb.Name, i),
+			Namespace: mdb.Namespace,
+			Labels:    map[string]string{"app": mdb.ServiceName()},
+		}}
+		if version != "" {
+			pod.Annotations = map[string]string{"agent.mongodb.com/version": version}
+		}
+		assert.NoError(t, mgr.Client.Create(context.TODO(), &pod))
+	}
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(configConvergenceLag.WithLabelValues(mdb.Namespace, mdb.Name)), "the agent which hasn't published a version should be behind by the whole version")
+
+	t.Run("The lag of the agent furthest behind is reported", func(t *testing.T) {
+		pods := []corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"agent.mongodb.com/version": "5"}}},
+			{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"agent.mongodb.com/version": "3"}}},
+		}
+		assert.Equal(t, 2, convergenceLag(5, pods))
+		assert.Equal(t, 0, convergenceLag(3, pods[1:]))
+		assert.Equal(t, 0, convergenceLag(5, nil))
+	})
+
+	t.Run("The lag is removed once the resource has been deleted", func(t *testing.T) {
+		assert.NoError(t, mgr.Client.Delete(context.TODO(), &mdb))
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		assert.False(t, configConvergenceLag.DeleteLabelValues(mdb.Namespace, mdb.Name), "the lag of the resource should already have been removed")
+	})
+}
+
+func TestServiceAnnotationsAndLabels(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Service.Annotations = map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"}
+	mdb.Spec.Service.Labels = map[string]string{"team": "data"}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	svc, err := mgr.Client.GetService(types.NamespacedName{Name: mdb.ServiceName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, "true", svc.Annotations["service.beta.kubernetes.io/aws-load-balancer-internal"])
+	assert.Equal(t, "data", svc.Labels["team"])
+
+	t.Run("Annotations added by others are kept", func(t *testing.T) {
+		svc.Annotations["mesh.example.com/inject"] = "enabled"
+		assert.NoError(t, mgr.Client.Update(context.TODO(), &svc))
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Service.Annotations["service.beta.kubernetes.io/aws-load-balancer-internal"] = "false"
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		svc, err := mgr.Client.GetService(types.NamespacedName{Name: mdb.ServiceName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, "false", svc.Annotations["service.beta.kubernetes.io/aws-load-balancer-internal"])
+		assert.Equal(t, "enabled", svc.Annotations["mesh.example.com/inject"])
+		assert.Equal(t, "None", svc.Spec.ClusterIP)
+	})
+}
+
+func TestMemberRoles(t *testing.T) {
+	ac := automationconfig.AutomationConfig{Processes: []automationconfig.Process{
+		{Name: "my-rs-0", HostName: "10.0.0.1", Args26: objx.New(map[string]interface{}{})},
+		{Name: "my-rs-1", HostName: "my-rs-1.my-rs-svc.my-ns.svc.cluster.local", Args26: objx.New(map[string]interface{}{})},
+		{Name: "my-rs-2", HostName: "member-2.example.com", Args26: objx.New(map[string]interface{}{"net": map[string]interface{}{"port": float64(27018)}})},
+	}}
+
+	roles := memberRoles(replicaset.MemberStates{
+		"10.0.0.1:27017": replicaset.PrimaryState,
+		"my-rs-1.my-rs-svc.my-ns.svc.cluster.local:27017": replicaset.SecondaryState,
+		"member-2.example.com:27018":                      "RECOVERING",
+		"my-rs-3.my-rs-svc.my-ns.svc.cluster.local:27017": replicaset.SecondaryState,
+	}, ac)
+
+	assert.Equal(t, map[string]string{
+		"my-rs-0": primaryRole,
+		"my-rs-1": secondaryRole,
+		"my-rs-2": "recovering",
+	}, roles, "the members should be matched to their pods by the hosts of their processes, members of no process are ignored")
+}
+
+func TestSecondaryService(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.EnableSecondaryService = true
+	mgr := client.NewManager(&mdb)
+	for i := 0; i < mdb.Spec.Members; i++ {
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", mdb.Name, i),
+			Namespace: mdb.Namespace,
+			Labels:    map[string]string{"app": mdb.ServiceName()},
+		}}
+		assert.NoError(t, mgr.Client.Create(context.TODO(), &pod))
+	}
+	r := NewReconciler(mgr)
+	r.statusGetter = mockedStatusGetter{states: replicaset.MemberStates{
+		mdb.Hosts()[0]: replicaset.PrimaryState,
+		mdb.Hosts()[1]: replicaset.SecondaryState,
+		mdb.Hosts()[2]: "RECOVERING",
+	}}
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.Equal(t, primaryCacheTTL, res.RequeueAfter, "the resource should be requeued to keep the role labels up to date")
+
+	svc, err := mgr.Client.GetService(types.NamespacedName{Name: mdb.SecondaryServiceName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": mdb.ServiceName(), roleLabel: secondaryRole}, svc.Spec.Selector)
+	assert.Equal(t, corev1.ServiceTypeClusterIP, svc.Spec.Type)
+	assert.Empty(t, svc.Spec.SessionAffinity)
+
+	expectedRoles := []string{primaryRole, secondaryRole, ""}
+	assert.Eventually(t, func() bool {
+		unlock := r.lockResource(mdb.NamespacedName())
+		defer unlock()
+
+		for i, role := range expectedRoles {
+			pod := corev1.Pod{}
+			if err := mgr.Client.Get(context.TODO(), types.NamespacedName{Name: fmt.Sprintf("%s-%d", mdb.Name, i), Namespace: mdb.Namespace}, &pod); err != nil {
+				return false
+			}
+			if pod.Labels[roleLabel] != role {
+				return false
+			}
+		}
+		return true
+	}, time.Second*5, time.Millisecond*10)
+
+	t.Run("Session affinity can be configured", func(t *testing.T) {
+		unlock := r.lockResource(mdb.NamespacedName())
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.SecondaryServiceSessionAffinity = corev1.ServiceAffinityClientIP
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+		unlock()
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		svc, err := mgr.Client.GetService(types.NamespacedName{Name: mdb.SecondaryServiceName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, corev1.ServiceAffinityClientIP, svc.Spec.SessionAffinity)
+
+		headless, err := mgr.Client.GetService(types.NamespacedName{Name: mdb.ServiceName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.True(t, headless.Spec.PublishNotReadyAddresses)
+	})
+
+	t.Run("The Service is deleted when disabled", func(t *testing.T) {
+		unlock := r.lockResource(mdb.NamespacedName())
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.EnableSecondaryService = false
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+		unlock()
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		_, err = mgr.Client.GetService(types.NamespacedName{Name: mdb.SecondaryServiceName(), Namespace: mdb.Namespace})
+		assert.True(t, apiErrors.IsNotFound(err))
+	})
+}
+
+func TestAutomaticVersionRollback(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.UpdateStrategy.AutomaticRollback = &mdbv1.AutomaticRollbackConfiguration{ProgressDeadlineMinutes: 10}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	reconcileAndGet := func(t *testing.T) (reconcile.Result, mdbv1.MongoDBCommunity) {
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		return res, mdb
+	}
+	assertDeployedVersion := func(t *testing.T, version string) {
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		for _, p := range ac.Processes {
+			assert.Equal(t, version, p.Version)
+		}
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+		assert.NotNil(t, mongodContainer)
+		assert.Contains(t, mongodContainer.Image, version)
+	}
+
+	// the pod of the new version is crash-looping, its agent never reaches goal state.
+	crashingPod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      mdb.Name + "-0",
+			Namespace: mdb.Namespace,
+			Labels:    map[string]string{appsv1.ControllerRevisionHashLabelKey: "failed"},
+		},
+	}
+	err = mgr.GetClient().Create(context.TODO(), &crashingPod)
+	assert.NoError(t, err)
+
+	err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mdb.Spec.Version = "4.4.0"
+	err = mgr.GetClient().Update(context.TODO(), &mdb)
+	assert.NoError(t, err)
+
+	// the members are ready when the version change starts, which requires a quorum.
+	_, mdb = reconcileAndGet(t)
+	assert.NotEmpty(t, mdb.Annotations[annotations.VersionChangeStartedAt], "the start of the version change should be recorded")
+	assertDeployedVersion(t, "4.4.0")
+	setStatefulSetReadyReplicas(t, mgr.GetClient(), mdb, 0)
+
+	t.Run("Version change is not rolled back before the deadline", func(t *testing.T) {
+		_, mdb = reconcileAndGet(t)
+		assert.Empty(t, mdb.Annotations[annotations.RolledBackMongoDBVersion])
+		assertDeployedVersion(t, "4.4.0")
+	})
+
+	t.Run("Version change is rolled back after the deadline", func(t *testing.T) {
+		mdb.Annotations[annotations.VersionChangeStartedAt] = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+		err := mgr.GetClient().Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		sts.Status.UpdateRevision = "rolled-back"
+		err = mgr.GetClient().Update(context.TODO(), &sts)
+		assert.NoError(t, err)
+
+		res, mdb := reconcileAndGet(t)
+		assert.True(t, res.Requeue)
+		assert.Equal(t, "4.4.0", mdb.Annotations[annotations.RolledBackMongoDBVersion])
+		assert.Equal(t, "4.4.0", mdb.Spec.Version, "the Spec should not be changed")
+		assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+		assertDeployedVersion(t, "4.2.2")
+
+		sts, err = mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, appsv1.OnDeleteStatefulSetStrategyType, sts.Spec.UpdateStrategy.Type)
+
+		_, err = mgr.Client.GetPod(types.NamespacedName{Name: crashingPod.Name, Namespace: mdb.Namespace})
+		assert.True(t, apiErrors.IsNotFound(err), "the crashing pod should have been deleted")
+	})
+
+	t.Run("Rolled back version is reported as failed", func(t *testing.T) {
+		makeStatefulSetReady(t, mgr.GetClient(), mdb)
+		res, mdb := reconcileAndGet(t)
+		assertReconciliationSuccessful(t, res, nil)
+		assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+		assert.Contains(t, mdb.Status.Message, "MongoDB version 4.4.0 did not become ready within 10m0s and has been rolled back to version 4.2.2")
+		assert.Equal(t, "4.2.2", mdb.Annotations[annotations.LastAppliedMongoDBVersion])
+		assertDeployedVersion(t, "4.2.2")
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, appsv1.RollingUpdateStatefulSetStrategyType, sts.Spec.UpdateStrategy.Type)
+
+		_, mdb = reconcileAndGet(t)
+		assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+		assertDeployedVersion(t, "4.2.2")
+	})
+
+	t.Run("Rollback is lifted once a different version is configured", func(t *testing.T) {
+		mdb.Spec.Version = "4.4.1"
+		err := mgr.GetClient().Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		_, mdb := reconcileAndGet(t)
+		assert.Empty(t, mdb.Annotations[annotations.RolledBackMongoDBVersion])
+		assert.NotEmpty(t, mdb.Annotations[annotations.VersionChangeStartedAt])
+		assertDeployedVersion(t, "4.4.1")
+	})
+}
+
+func TestWiredTigerCacheSize(t *testing.T) {
+	withMemoryLimit := func(limit string) *mdbv1.StatefulSetConfiguration {
+		return &mdbv1.StatefulSetConfiguration{
+			SpecWrapper: mdbv1.StatefulSetSpecWrapper{
+				Spec: appsv1.StatefulSetSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{
+								Name: construct.MongodbName,
+								Resources: corev1.ResourceRequirements{
+									Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(limit)},
+								},
+							}},
+						},
+					},
+				},
+			},
+		}
+	}
+	cacheSizes := func(t *testing.T, mdb mdbv1.MongoDBCommunity) map[string]interface{} {
+		mgr := client.NewManager(&mdb)
+		r := NewReconciler(mgr)
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		sizes := map[string]interface{}{}
+		for _, p := range ac.Processes {
+			sizes[p.Name] = p.Args26.Get("storage.wiredTiger.engineConfig.cacheSizeGB").Data()
+		}
+		return sizes
+	}
+
+	t.Run("Minimum cache size is used for the default memory limit", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		for _, size := range cacheSizes(t, mdb) {
+			assert.Equal(t, 0.25, size)
+		}
+	})
+	t.Run("Cache size is derived from the configured memory limit", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.StatefulSetConfiguration = *withMemoryLimit("4Gi")
+		for _, size := range cacheSizes(t, mdb) {
+			assert.Equal(t, 1.5, size)
+		}
+
+		mdb = newTestReplicaSet()
+		mdb.Spec.StatefulSetConfiguration = *withMemoryLimit("10G")
+		for _, size := range cacheSizes(t, mdb) {
+			assert.InDelta(t, 4.15, size, 0.0001)
+		}
+	})
+	t.Run("Separate arbiters use the memory limit of their StatefulSet", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.StatefulSetConfiguration = *withMemoryLimit("4Gi")
+		mdb.Spec.Arbiters = 1
+		mdb.Spec.ArbiterStatefulSetConfiguration = withMemoryLimit("2Gi")
+		sizes := cacheSizes(t, mdb)
+		assert.Equal(t, 1.5, sizes["my-rs-0"])
+		assert.Equal(t, 0.5, sizes["my-rs-arb-0"])
+	})
+	t.Run("Configured cache size takes precedence", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.StatefulSetConfiguration = *withMemoryLimit("4Gi")
+		mongodConfig := objx.New(map[string]interface{}{})
+		mongodConfig.Set("storage.wiredTiger.engineConfig.cacheSizeGB", 3)
+		mdb.Spec.AdditionalMongodConfig.Object = mongodConfig
+		for _, size := range cacheSizes(t, mdb) {
+			assert.Equal(t, float64(3), size)
+		}
+	})
+	t.Run("No cache size is configured without a memory limit", func(t *testing.T) {
+		assert.Nil(t, wiredTigerCacheSizeGB(func(sts *appsv1.StatefulSet) {}))
+	})
+}
+
+func TestOperatorVersionAnnotation(t *testing.T) {
+	defer func(version string) { OperatorVersion = version }(OperatorVersion)
+	OperatorVersion = "1.2.3"
+
+	mdb := newTestReplicaSet()
+	mdb.Spec.Arbiters = 1
+	mdb.Spec.ArbiterStatefulSetConfiguration = &mdbv1.StatefulSetConfiguration{}
+	mdb.Spec.EnableSecondaryService = true
+	mdb.Spec.Service.Annotations = map[string]string{annotations.ManagedByVersion: "0.0.1", "user": "annotation"}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	// the roles of the members are refreshed periodically with the secondary Service.
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	for _, nsName := range []types.NamespacedName{mdb.NamespacedName(), mdb.ArbiterNamespacedName()} {
+		sts, err := mgr.Client.GetStatefulSet(nsName)
+		assert.NoError(t, err)
+		assert.Equal(t, "1.2.3", sts.Annotations[annotations.ManagedByVersion])
+	}
+
+	svc, err := mgr.Client.GetService(types.NamespacedName{Name: mdb.ServiceName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3", svc.Annotations[annotations.ManagedByVersion], "the version should not be overridden by the configured annotations")
+	assert.Equal(t, "annotation", svc.Annotations["user"])
+
+	secondarySvc, err := mgr.Client.GetService(types.NamespacedName{Name: mdb.SecondaryServiceName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3", secondarySvc.Annotations[annotations.ManagedByVersion])
+
+	t.Run("Annotations are updated by a newer operator", func(t *testing.T) {
+		OperatorVersion = "1.3.0"
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, "1.3.0", sts.Annotations[annotations.ManagedByVersion])
+		secondarySvc, err := mgr.Client.GetService(types.NamespacedName{Name: mdb.SecondaryServiceName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, "1.3.0", secondarySvc.Annotations[annotations.ManagedByVersion])
+	})
+}
+
+func TestKeyfileRotation_KeyfileWithoutKeys(t *testing.T) {
+	for name, keyfile := range map[string]string{
+		"Whitespace": " \n\t ",
+		"Bare dash":  "-",
+	} {
+		t.Run(name, func(t *testing.T) {
+			mdb := newScramReplicaSet()
+			mgr := client.NewManager(&mdb)
+			r := NewReconciler(mgr)
+
+			res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+			assertReconciliationSuccessful(t, res, err)
+
+			keyfileNsName := mdb.GetAgentKeyfileSecretNamespacedName()
+			err = secret.CreateOrUpdate(mgr.Client,
+				secret.Builder().
+					SetName(keyfileNsName.Name).
+					SetNamespace(keyfileNsName.Namespace).
+					SetField(scram.AgentKeyfileKey, keyfile).
+					Build(),
+			)
+			assert.NoError(t, err)
+
+			assert.NotPanics(t, func() {
+				_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+			})
+			assert.NoError(t, err)
+
+			err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+			assert.NoError(t, err)
+			assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+			assert.Contains(t, mdb.Status.Message, "the keyfile secret of the resource holds no key")
+		})
 	}
+}
 
-	return mdb, nil
+func TestKeyfileRotation(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	makeStatefulSetReady(t, mgr.GetClient(), mdb)
+
+	reconcileAndGet := func(t *testing.T) (reconcile.Result, mdbv1.MongoDBCommunity) {
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		return res, mdb
+	}
+	assertDeployedKeyfile := func(t *testing.T, keyfile, step string) {
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, keyfile, ac.Auth.Key)
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, step, sts.Spec.Template.Annotations[annotations.KeyfileRotationStep])
+	}
+	updateKeyfile := func(t *testing.T, keyfile string) {
+		keyfileNsName := mdb.GetAgentKeyfileSecretNamespacedName()
+		err := secret.CreateOrUpdate(mgr.Client,
+			secret.Builder().
+				SetName(keyfileNsName.Name).
+				SetNamespace(keyfileNsName.Namespace).
+				SetField(scram.AgentKeyfileKey, keyfile).
+				Build(),
+		)
+		assert.NoError(t, err)
+	}
+
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	oldKey := ac.Auth.Key
+	assert.NotEmpty(t, oldKey)
+
+	updateKeyfile(t, "newKeyfile1")
+
+	t.Run("Members accept the new key first", func(t *testing.T) {
+		res, mdb := reconcileAndGet(t)
+		assert.True(t, res.Requeue || res.RequeueAfter > 0)
+		assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+		assert.Equal(t, string(keyfileRotationAcceptNewKey), mdb.Annotations[annotations.KeyfileRotationPhase])
+		assertDeployedKeyfile(t, "- "+oldKey+"\n- newKeyfile1\n", "1")
+	})
+
+	t.Run("Next phase waits for the members to be restarted", func(t *testing.T) {
+		setStatefulSetReadyReplicas(t, mgr.GetClient(), mdb, 1)
+		_, mdb := reconcileAndGet(t)
+		assert.Equal(t, string(keyfileRotationAcceptNewKey), mdb.Annotations[annotations.KeyfileRotationPhase])
+		assertDeployedKeyfile(t, "- "+oldKey+"\n- newKeyfile1\n", "1")
+	})
+
+	t.Run("Members use the new key once all of them accept it", func(t *testing.T) {
+		makeStatefulSetReady(t, mgr.GetClient(), mdb)
+		_, mdb := reconcileAndGet(t)
+		assert.Equal(t, string(keyfileRotationUseNewKey), mdb.Annotations[annotations.KeyfileRotationPhase])
+		assertDeployedKeyfile(t, "- newKeyfile1\n- "+oldKey+"\n", "2")
+	})
+
+	t.Run("Old key is removed once all the members use the new key", func(t *testing.T) {
+		makeStatefulSetReady(t, mgr.GetClient(), mdb)
+		_, mdb := reconcileAndGet(t)
+		assert.Equal(t, string(keyfileRotationCompleted), mdb.Annotations[annotations.KeyfileRotationPhase])
+		assertDeployedKeyfile(t, "newKeyfile1", "3")
+
+		makeStatefulSetReady(t, mgr.GetClient(), mdb)
+		res, mdb := reconcileAndGet(t)
+		assertReconciliationSuccessful(t, res, nil)
+		assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+		assertDeployedKeyfile(t, "newKeyfile1", "3")
+	})
+
+	t.Run("Keyfile changed during the rotation is accepted instead", func(t *testing.T) {
+		updateKeyfile(t, "newKeyfile2")
+		_, mdb := reconcileAndGet(t)
+		assertDeployedKeyfile(t, "- newKeyfile1\n- newKeyfile2\n", "4")
+
+		updateKeyfile(t, "newKeyfile3")
+		_, mdb = reconcileAndGet(t)
+		assert.Equal(t, string(keyfileRotationAcceptNewKey), mdb.Annotations[annotations.KeyfileRotationPhase])
+		assertDeployedKeyfile(t, "- newKeyfile1\n- newKeyfile3\n", "5")
+	})
+
+	t.Run("Keyfile of each phase is deployed before the members restart with TLS", func(t *testing.T) {
+		mdb := newTestReplicaSetWithTLS()
+		mgr := client.NewManager(&mdb)
+		err := createTLSSecretAndConfigMap(mgr.GetClient(), mdb)
+		assert.NoError(t, err)
+		r := NewReconciler(mgr)
+		acNsName := types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace}
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		makeStatefulSetReady(t, mgr.GetClient(), mdb)
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, acNsName)
+		assert.NoError(t, err)
+		oldKey := ac.Auth.Key
+
+		keyfileNsName := mdb.GetAgentKeyfileSecretNamespacedName()
+		err = secret.CreateOrUpdate(mgr.Client,
+			secret.Builder().
+				SetName(keyfileNsName.Name).
+				SetNamespace(keyfileNsName.Namespace).
+				SetField(scram.AgentKeyfileKey, "newKeyfile1").
+				Build(),
+		)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, string(keyfileRotationAcceptNewKey), mdb.Annotations[annotations.KeyfileRotationPhase])
+		assert.True(t, r.shouldRunInOrder(mdb), "the automation config must be deployed before the StatefulSet")
+
+		ac, err = automationconfig.ReadFromSecret(mgr.Client, acNsName)
+		assert.NoError(t, err)
+		assert.Equal(t, "- "+oldKey+"\n- newKeyfile1\n", ac.Auth.Key)
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, "1", sts.Spec.Template.Annotations[annotations.KeyfileRotationStep])
+
+		// once the rotation has completed, the StatefulSet of the TLS deployment is updated first again.
+		mdb.Annotations[annotations.KeyfileRotationPhase] = string(keyfileRotationCompleted)
+		assert.False(t, r.shouldRunInOrder(mdb))
+	})
+
+	t.Run("Keyfile is held back during a version change", func(t *testing.T) {
+		mdb := newScramReplicaSet()
+		mgr := client.NewManager(&mdb)
+		r := NewReconciler(mgr)
+		acNsName := types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace}
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		makeStatefulSetReady(t, mgr.GetClient(), mdb)
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, acNsName)
+		assert.NoError(t, err)
+		oldKey := ac.Auth.Key
+
+		err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.Version = "4.4.0"
+		err = mgr.GetClient().Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+		keyfileNsName := mdb.GetAgentKeyfileSecretNamespacedName()
+		err = secret.CreateOrUpdate(mgr.Client,
+			secret.Builder().
+				SetName(keyfileNsName.Name).
+				SetNamespace(keyfileNsName.Namespace).
+				SetField(scram.AgentKeyfileKey, "newKeyfile1").
+				Build(),
+		)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		ac, err = automationconfig.ReadFromSecret(mgr.Client, acNsName)
+		assert.NoError(t, err)
+		assert.Equal(t, "4.4.0", ac.Processes[0].Version)
+		assert.Equal(t, oldKey, ac.Auth.Key, "the new key must not be deployed before its rotation has started")
+
+		// the rotation starts once the version change has completed.
+		makeStatefulSetReady(t, mgr.GetClient(), mdb)
+		_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, string(keyfileRotationAcceptNewKey), mdb.Annotations[annotations.KeyfileRotationPhase])
+		ac, err = automationconfig.ReadFromSecret(mgr.Client, acNsName)
+		assert.NoError(t, err)
+		assert.Equal(t, "- "+oldKey+"\n- newKeyfile1\n", ac.Auth.Key)
+	})
+
+	t.Run("Keyfile is replaced at once before MongoDB 4.2", func(t *testing.T) {
+		mdb := newScramReplicaSet()
+		mdb.Spec.Version = "4.0.6"
+		mgr := client.NewManager(&mdb)
+		r := NewReconciler(mgr)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		makeStatefulSetReady(t, mgr.GetClient(), mdb)
+
+		keyfileNsName := mdb.GetAgentKeyfileSecretNamespacedName()
+		err = secret.CreateOrUpdate(mgr.Client,
+			secret.Builder().
+				SetName(keyfileNsName.Name).
+				SetNamespace(keyfileNsName.Namespace).
+				SetField(scram.AgentKeyfileKey, "newKeyfile1").
+				Build(),
+		)
+		assert.NoError(t, err)
+
+		res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, "newKeyfile1", ac.Auth.Key)
+		err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Empty(t, mdb.Annotations[annotations.KeyfileRotationPhase])
+	})
 }
 
-// marshalRuntimeObjectFromYAMLBytes accepts the bytes of a yaml resource
-// and unmarshals them into the provided runtime Object
-func marshalRuntimeObjectFromYAMLBytes(bytes []byte, obj runtime.Object) error {
-	jsonBytes, err := yaml.YAMLToJSON(bytes)
-	if err != nil {
-		return err
+func TestTopologyStatus(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+
+	topology := mdb.Status.Topology
+	assert.NotNil(t, topology)
+	assert.Equal(t, ac.Version, topology.AutomationConfigVersion)
+	assert.Equal(t, "4.2.2", topology.Version)
+	assert.Equal(t, "4.2", topology.FeatureCompatibilityVersion)
+	assert.Equal(t, "disabled", topology.TLSMode)
+	assert.Equal(t, []string{"SCRAM-SHA-256"}, topology.AuthenticationMechanisms)
+	assert.NotNil(t, topology.LastVersionTransitionTime)
+	assert.NotNil(t, topology.LastFeatureCompatibilityVersionTransitionTime)
+	assert.Len(t, topology.Members, 3)
+	assert.Equal(t, mdbv1.TopologyMember{
+		Name:     "my-rs-0",
+		Host:     "my-rs-0.my-rs-svc.my-ns.svc.cluster.local:27017",
+		Votes:    1,
+		Priority: 1,
+	}, topology.Members[0])
+
+	t.Run("Transition times are kept while the version doesn't change", func(t *testing.T) {
+		lastVersionTransitionTime := *topology.LastVersionTransitionTime
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		err = mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.True(t, lastVersionTransitionTime.Equal(mdb.Status.Topology.LastVersionTransitionTime))
+	})
+}
+
+func TestProbeOverrides_SurviveReconciliations(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.Template.Spec.Containers = []corev1.Container{
+		{
+			Name:           construct.AgentName,
+			ReadinessProbe: &corev1.Probe{TimeoutSeconds: 30},
+		},
+		{
+			Name:         construct.MongodbName,
+			StartupProbe: &corev1.Probe{PeriodSeconds: 20},
+		},
 	}
-	return json.Unmarshal(jsonBytes, &obj)
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	assertProbes := func(t *testing.T, readinessTimeout, startupPeriod int32) {
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		agentContainer := podtemplatespec.FindContainerByName(construct.AgentName, &sts.Spec.Template)
+		assert.NotNil(t, agentContainer)
+		assert.Equal(t, readinessTimeout, agentContainer.ReadinessProbe.TimeoutSeconds)
+		assert.Equal(t, int32(40), agentContainer.ReadinessProbe.FailureThreshold, "the defaults should be kept")
+		assert.NotNil(t, agentContainer.ReadinessProbe.Exec)
+		mongodContainer := podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template)
+		assert.NotNil(t, mongodContainer)
+		assert.Equal(t, startupPeriod, mongodContainer.StartupProbe.PeriodSeconds)
+		assert.Equal(t, int32(180), mongodContainer.StartupProbe.FailureThreshold, "the defaults should be kept")
+	}
+
+	for i := 0; i < 2; i++ {
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		assertProbes(t, 30, 20)
+	}
+
+	t.Run("Defaults are restored once the overrides are removed", func(t *testing.T) {
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.Template.Spec.Containers = nil
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		assertProbes(t, 0, 10)
+	})
+}
+
+func TestMembersToRemove_AreRemovedOneAtATime(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Members = 5
+
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	assertRemovedMembers := func(t *testing.T, removed ...string) {
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Len(t, ac.Processes, 5)
+		assert.Len(t, ac.ReplicaSets[0].Members, 5-len(removed))
+
+		var disabled []string
+		for _, p := range ac.Processes {
+			if p.Disabled {
+				disabled = append(disabled, p.Name)
+			}
+		}
+		assert.ElementsMatch(t, removed, disabled)
+		for _, m := range ac.ReplicaSets[0].Members {
+			assert.NotContains(t, removed, m.Host)
+		}
+	}
+
+	err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mdb.Spec.MembersToRemove = []string{"my-rs-1", "my-rs-2"}
+	err = mgr.GetClient().Update(context.TODO(), &mdb)
+	assert.NoError(t, err)
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.True(t, res.Requeue)
+	assertRemovedMembers(t, "my-rs-1")
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	assertRemovedMembers(t, "my-rs-1", "my-rs-2")
+
+	sts := appsv1.StatefulSet{}
+	err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &sts)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), *sts.Spec.Replicas)
+
+	t.Run("Members are added again once they are no longer listed", func(t *testing.T) {
+		err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.MembersToRemove = nil
+		err = mgr.GetClient().Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+		assertRemovedMembers(t)
+	})
+}
+
+func TestMembersToRemove_HoldBackScaleDown(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Members = 5
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+	assert.NoError(t, err)
+	mdb.Spec.Members = 4
+	mdb.Spec.MembersToRemove = []string{"my-rs-1", "my-rs-2"}
+	err = mgr.GetClient().Update(context.TODO(), &mdb)
+	assert.NoError(t, err)
+
+	assertMembers := func(t *testing.T, processes, members int) {
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Len(t, ac.Processes, processes)
+		assert.Len(t, ac.ReplicaSets[0].Members, members)
+		sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, int32(processes), *sts.Spec.Replicas)
+	}
+
+	for _, removed := range []int{1, 2} {
+		res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		assert.True(t, res.Requeue)
+		assertMembers(t, 5, 5-removed)
+
+		err = mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, mdb.Spec.Members, "the spec should be kept")
+		assert.Equal(t, 5, mdb.Status.CurrentMongoDBMembers)
+		assert.Contains(t, mdb.Status.Message, "scaling down is held back")
+	}
+
+	// the scale down continues once the members have been removed
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	makeStatefulSetReady(t, mgr.Client, mdb)
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	assertMembers(t, 4, 2)
+}
+
+func TestMembersToRemove_AreValidated(t *testing.T) {
+	tests := map[string]struct {
+		membersToRemove []string
+		expectedError   string
+	}{
+		"Member of the StatefulSet": {membersToRemove: []string{"my-rs-1"}},
+		"Unknown member": {
+			membersToRemove: []string{"my-rs-3"},
+			expectedError:   `membersToRemove[0] "my-rs-3" is not a member of the replica set, remove it from membersToRemove when the StatefulSet is scaled down past it`,
+		},
+		"Duplicate member": {
+			membersToRemove: []string{"my-rs-1", "my-rs-1"},
+			expectedError:   `membersToRemove[1] "my-rs-1" is listed more than once`,
+		},
+		"All the members": {
+			membersToRemove: []string{"my-rs-0", "my-rs-1", "my-rs-2"},
+			expectedError:   "membersToRemove can't remove all the members of the replica set",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mdb := newTestReplicaSet()
+			mdb.Spec.MembersToRemove = tc.membersToRemove
+			err := validation.ValidateInitalSpec(mdb)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestConfigVersions_DivergingVersions_MarkTheResourceDegraded(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	r.memberHealthChecks = true
+	r.primaryCache = replicaset.NewPrimaryCache(0)
+
+	degradedCondition := func() *metav1.Condition {
+		unlock := r.lockResource(mdb.NamespacedName())
+		defer unlock()
+
+		if err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb); err != nil {
+			return nil
+		}
+		return meta.FindStatusCondition(mdb.Status.Conditions, mdbv1.ConditionDegraded)
+	}
+
+	calls := int32(0)
+	reconcileWithVersions := func(t *testing.T, versions replicaset.ConfigVersions) {
+		r.statusGetter = countingStatusGetter{
+			mockedStatusGetter: mockedStatusGetter{
+				states:   replicaset.MemberStates{mdb.Hosts()[0]: replicaset.PrimaryState},
+				versions: versions,
+			},
+			calls: &calls,
+		}
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		if r.memberHealthChecks {
+			assert.Equal(t, primaryCacheTTL, res.RequeueAfter, "the checks are repeated without reconciliations")
+		}
+	}
+
+	divergingVersions := replicaset.ConfigVersions{mdb.Hosts()[0]: 2, mdb.Hosts()[1]: 1, mdb.Hosts()[2]: 2}
+	reconcileWithVersions(t, divergingVersions)
+	assert.Eventually(t, func() bool {
+		_, diverged := r.divergingConfigVersions.Load(mdb.NamespacedName())
+		return diverged
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "the states and the versions are fetched together")
+	assert.Nil(t, degradedCondition(), "the versions may still converge")
+
+	reconcileWithVersions(t, divergingVersions)
+	assert.Eventually(t, func() bool {
+		condition := degradedCondition()
+		return condition != nil && condition.Status == metav1.ConditionTrue
+	}, time.Second*5, time.Millisecond*10)
+
+	condition := degradedCondition()
+	assert.Equal(t, configVersionsDivergeReason, condition.Reason)
+	assert.Equal(t, fmt.Sprintf("The members are on different replica set config versions: %s: 2, %s: 1, %s: 2",
+		mdb.Hosts()[0], mdb.Hosts()[1], mdb.Hosts()[2]), condition.Message)
+
+	t.Run("The condition is cleared once the versions agree", func(t *testing.T) {
+		reconcileWithVersions(t, replicaset.ConfigVersions{mdb.Hosts()[0]: 2, mdb.Hosts()[1]: 2, mdb.Hosts()[2]: 2})
+		assert.Eventually(t, func() bool {
+			condition := degradedCondition()
+			return condition != nil && condition.Status == metav1.ConditionFalse && condition.Reason == configVersionsAgreeReason
+		}, time.Second*5, time.Millisecond*10)
+	})
+
+	t.Run("The condition is removed once the checks are disabled", func(t *testing.T) {
+		r.memberHealthChecks = false
+		reconcileWithVersions(t, nil)
+		assert.Eventually(t, func() bool {
+			return degradedCondition() == nil
+		}, time.Second*5, time.Millisecond*10)
+	})
+}
+
+func TestAgentLogRetention_IsValidated(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Agent.MaxLogFileSizeMB = 100
+	mdb.Spec.Agent.MaxLogFiles = 5
+	assert.NoError(t, validation.ValidateInitalSpec(mdb))
+
+	mdb.Spec.Agent.MaxLogFiles = -1
+	assert.EqualError(t, validation.ValidateInitalSpec(mdb), "the agent maxLogFiles must not be negative, got -1")
+
+	mdb.Spec.Agent.MaxLogFiles = 5
+	mdb.Spec.Agent.MaxLogFileSizeMB = -100
+	assert.EqualError(t, validation.ValidateInitalSpec(mdb), "the agent maxLogFileSizeMB must not be negative, got -100")
+
+	mdb.Spec.Agent.MaxLogFileSizeMB = 0
+	mdb.Spec.Agent.MaxLogFiles = 0
+	assert.NoError(t, validation.ValidateInitalSpec(mdb), "0 keeps the defaults of the agent")
+}
+
+func TestAgentStatusService(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.EnableAgentStatusService = true
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	svc, err := mgr.Client.GetService(types.NamespacedName{Name: mdb.AgentStatusServiceName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": mdb.ServiceName()}, svc.Spec.Selector)
+	assert.Equal(t, "None", svc.Spec.ClusterIP)
+	assert.True(t, svc.Spec.PublishNotReadyAddresses)
+	assert.Len(t, svc.Spec.Ports, 1)
+	assert.Equal(t, int32(construct.AgentStatusPort), svc.Spec.Ports[0].Port)
+
+	t.Run("The Service is deleted when disabled", func(t *testing.T) {
+		err := mgr.Client.Get(context.TODO(), mdb.NamespacedName(), &mdb)
+		assert.NoError(t, err)
+		mdb.Spec.EnableAgentStatusService = false
+		err = mgr.Client.Update(context.TODO(), &mdb)
+		assert.NoError(t, err)
+
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		_, err = mgr.Client.GetService(types.NamespacedName{Name: mdb.AgentStatusServiceName(), Namespace: mdb.Namespace})
+		assert.True(t, apiErrors.IsNotFound(err))
+	})
+}
+
+func TestWriteConcernMajorityJournalDefault(t *testing.T) {
+	mdb := newTestReplicaSet()
+	disabled := false
+	mdb.Spec.WriteConcernMajorityJournalDefault = &disabled
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Equal(t, &disabled, ac.ReplicaSets[0].WriteConcernMajorityJournalDefault)
+
+	t.Run("The default of mongod applies if it is not set", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		assert.True(t, mdb.GetWriteConcernMajorityJournalDefault())
+
+		ac, err := buildAutomationConfig(mdb, automationconfig.Auth{}, automationconfig.AutomationConfig{})
+		assert.NoError(t, err)
+		assert.Nil(t, ac.ReplicaSets[0].WriteConcernMajorityJournalDefault)
+	})
+
+	t.Run("Arbiters with the journal default are warned about", func(t *testing.T) {
+		mdb := newTestReplicaSet()
+		mdb.Spec.Arbiters = 1
+		assert.NoError(t, validation.ValidateInitalSpec(mdb))
+		assert.Len(t, validation.Warnings(mdb), 1)
+
+		mdb.Spec.WriteConcernMajorityJournalDefault = &disabled
+		assert.Empty(t, validation.Warnings(mdb))
+	})
 }
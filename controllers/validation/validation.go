@@ -2,11 +2,24 @@ package validation
 
 import (
 	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/versions"
 	"github.com/pkg/errors"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/objx"
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 )
 
 // ValidateInitalSpec checks if the resource's initial Spec is valid.
@@ -19,23 +32,224 @@ func ValidateUpdate(mdb mdbv1.MongoDBCommunity, oldSpec mdbv1.MongoDBCommunitySp
 	if oldSpec.Security.TLS.Enabled && !mdb.Spec.Security.TLS.Enabled {
 		return errors.New("TLS can't be set to disabled after it has been enabled")
 	}
+	// changing the addresses of all the members at once would break the replica set.
+	oldMode := mdbv1.MongoDBCommunity{Spec: oldSpec}.MemberAddressingMode()
+	if oldMode != mdb.MemberAddressingMode() {
+		return fmt.Errorf("memberAddressing.mode can't be changed from %s to %s once the replica set has been deployed", oldMode, mdb.MemberAddressingMode())
+	}
+	if oldSpec.MemberAddressing.ClusterDomain != mdb.Spec.MemberAddressing.ClusterDomain {
+		return errors.New("memberAddressing.clusterDomain can't be changed once the replica set has been deployed")
+	}
+	// mongod doesn't allow changing buildIndexes of a member which is part of the replica set.
+	for i := 0; i < oldSpec.Members && i < mdb.Spec.Members; i++ {
+		if memberConfig(oldSpec, i).BuildsIndexes() != memberConfig(mdb.Spec, i).BuildsIndexes() {
+			return fmt.Errorf("memberConfig[%d].buildIndexes can't be changed once the member has been added to the replica set", i)
+		}
+	}
 	return validateSpec(mdb)
 }
 
-// validateSpec validates the specs of the given resource definition.
+// Warnings returns the settings of the given resource definition which are valid but may cause problems,
+// they are reported without rejecting the spec.
+func Warnings(mdb mdbv1.MongoDBCommunity) []string {
+	var warnings []string
+	if mdb.Spec.Arbiters > 0 && mdb.GetWriteConcernMajorityJournalDefault() {
+		warnings = append(warnings, "the replica set has arbiters and writeConcernMajorityJournalDefault is true, "+
+			"w:majority writes stall while a data-bearing member is unavailable, as arbiters neither acknowledge nor journal writes")
+	}
+	return warnings
+}
+
+// validateSpec validates the specs of the given resource definition, all the problems found are
+// returned in a single error.
 func validateSpec(mdb mdbv1.MongoDBCommunity) error {
-	if err := validateUsers(mdb); err != nil {
-		return err
+	return utilerrors.NewAggregate(Validate(mdb))
+}
+
+// Validate checks the spec of the given resource and returns every problem found, so that they
+// can all be fixed at once. Checks which depend on the MongoDB version are skipped if the version
+// itself is invalid.
+func Validate(mdb mdbv1.MongoDBCommunity) []error {
+	validations := []func(mdbv1.MongoDBCommunity) error{
+		validateMembers,
+		validateUsers,
+		validateUserSecretReferences,
+		validateArbiterSpec,
+		validateAuthModeSpec,
+		validateTLSSpec,
+		validateClusterAuthMode,
+		validateNetConfig,
+		validateStorageSpec,
+		validateAgentSpec,
+		validateSystemLogSpec,
+		validateNetSpec,
+		validateOperationProfilingSpec,
+		validateAdditionalMongodArgs,
+		validateMongodCommand,
+		validateAdditionalInitContainers,
+		validateMemberAddressing,
+		validateMemberConfig,
+		validateMemberHosts,
+		validateReplicaSetHorizons,
+		validateMembersToRemove,
+		validateShutdown,
+	}
+	if err := validateVersion(mdb); err != nil {
+		validations = append(validations, validateVersion)
+	} else {
+		validations = append(validations, validateFeatureCompatibilityVersion, validateProtocolVersion)
 	}
 
-	if err := validateArbiterSpec(mdb); err != nil {
-		return err
+	var errs []error
+	for _, validate := range validations {
+		if err := validate(mdb); err != nil {
+			// validations which report several problems return them aggregated
+			if agg, ok := err.(utilerrors.Aggregate); ok {
+				errs = append(errs, agg.Errors()...)
+			} else {
+				errs = append(errs, err)
+			}
+		}
 	}
+	return errs
+}
+
+// validateMembers checks that the replica set has at least one member.
+func validateMembers(mdb mdbv1.MongoDBCommunity) error {
+	if mdb.Spec.Members <= 0 {
+		return fmt.Errorf("the number of members must be greater than 0, got %d", mdb.Spec.Members)
+	}
+	return nil
+}
+
+// validateVersion checks that the MongoDB version is a valid semantic version.
+func validateVersion(mdb mdbv1.MongoDBCommunity) error {
+	if _, err := semver.Make(mdb.Spec.Version); err != nil {
+		return fmt.Errorf("invalid MongoDB version %q: %s", mdb.Spec.Version, err)
+	}
+	return nil
+}
 
-	if err := validateAuthModeSpec(mdb); err != nil {
+// validateFeatureCompatibilityVersion checks that the configured featureCompatibilityVersion has the format
+// "x.y" and is not greater than the one of the MongoDB version.
+func validateFeatureCompatibilityVersion(mdb mdbv1.MongoDBCommunity) error {
+	fcv := mdb.Spec.FeatureCompatibilityVersion
+	if fcv == "" {
+		return nil
+	}
+	versionFCV := versions.CalculateFeatureCompatibilityVersion(mdb.Spec.Version)
+	if versionFCV == "" {
+		return fmt.Errorf("the featureCompatibilityVersion can't be configured with MongoDB version %s", mdb.Spec.Version)
+	}
+	isLower, err := versions.IsFeatureCompatibilityVersionDowngrade(versionFCV, fcv)
+	if err != nil {
 		return err
 	}
+	if !isLower && fcv != versionFCV {
+		return fmt.Errorf("the featureCompatibilityVersion %q is not compatible with MongoDB version %s", fcv, mdb.Spec.Version)
+	}
+	return nil
+}
+
+// validateTLSSpec checks that the Secret and the ConfigMap holding the certificates are configured if TLS is enabled,
+// and that client certificates are only required if the members have certificates.
+func validateTLSSpec(mdb mdbv1.MongoDBCommunity) error {
+	tls := mdb.Spec.Security.TLS
+	if !tls.Enabled {
+		if tls.ClientCertificateMode == automationconfig.ClientCertificateModeRequired {
+			return fmt.Errorf("clientCertificateMode %s requires TLS to be enabled so that the members have certificates", automationconfig.ClientCertificateModeRequired)
+		}
+		return nil
+	}
+	if tls.CertificateKeySecret.Name == "" {
+		return fmt.Errorf("TLS is enabled but no certificateKeySecretRef is configured")
+	}
+	if tls.CaConfigMap.Name == "" {
+		return fmt.Errorf("TLS is enabled but no caConfigMapRef is configured")
+	}
+	return nil
+}
+
+// validateClusterAuthMode checks that the clusterAuthMode configured through the additional mongod configuration is
+// consistent with TLS. The members authenticate to each other with their X.509 certificates over TLS, which must be
+// enabled, and must be required once the members only accept X.509 certificates, as the connections which don't use
+// TLS could not authenticate.
+func validateClusterAuthMode(mdb mdbv1.MongoDBCommunity) error {
+	mode := objx.New(mdb.Spec.AdditionalMongodConfig.Object).Get("security.clusterAuthMode").Str()
+	switch mode {
+	case "", "keyFile":
+		return nil
+	case "sendKeyFile", "sendX509", "x509":
+	default:
+		return fmt.Errorf("security.clusterAuthMode %q is not supported, it must be one of keyFile, sendKeyFile, sendX509 or x509", mode)
+	}
+
+	tls := mdb.Spec.Security.TLS
+	if !tls.Enabled {
+		return fmt.Errorf("security.clusterAuthMode %s requires TLS to be enabled, as the members authenticate with their X.509 certificates", mode)
+	}
+	if mode == "x509" && tls.Optional {
+		return fmt.Errorf("security.clusterAuthMode x509 can't be combined with optional TLS, the members only authenticate with X.509 certificates which require TLS, set security.tls.optional to false")
+	}
+	return nil
+}
+
+// validateNetConfig checks the network interfaces configured through the additional mongod configuration. The members
+// reach each other through the hostnames of their pods, so mongod can't only bind to the loopback interface, and
+// IPv6 addresses can only be bound if IPv6 is enabled.
+func validateNetConfig(mdb mdbv1.MongoDBCommunity) error {
+	netConfig := objx.New(mdb.Spec.AdditionalMongodConfig.Object)
+	ipv6 := netConfig.Get("net.ipv6")
+	if !ipv6.IsNil() && !ipv6.IsBool() {
+		return fmt.Errorf("net.ipv6 must be a boolean, got %v", ipv6.Data())
+	}
 
+	bindIP := netConfig.Get("net.bindIp")
+	if bindIP.IsNil() {
+		return nil
+	}
+	if !bindIP.IsStr() {
+		return fmt.Errorf("net.bindIp must be a comma separated list of addresses, got %v", bindIP.Data())
+	}
+	if !netConfig.Get("net.bindIpAll").IsNil() {
+		return fmt.Errorf("net.bindIp and net.bindIpAll are mutually exclusive, only one of them can be set")
+	}
+
+	loopbackOnly := true
+	for _, address := range strings.Split(bindIP.Str(), ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			return fmt.Errorf("net.bindIp %q contains an empty address", bindIP.Str())
+		}
+		ip := net.ParseIP(address)
+		if ip != nil && ip.To4() == nil && !ipv6.Bool() {
+			return fmt.Errorf("net.bindIp address %s is an IPv6 address, which requires net.ipv6 to be true", address)
+		}
+		if address != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			loopbackOnly = false
+		}
+	}
+	if loopbackOnly {
+		return fmt.Errorf("net.bindIp %q only binds to the loopback interface, the members of the replica set could not reach each other", bindIP.Str())
+	}
+	return nil
+}
+
+// validateUserSecretReferences checks that every user has a name and references the Secret holding its password.
+func validateUserSecretReferences(mdb mdbv1.MongoDBCommunity) error {
+	var problems []string
+	for i, user := range mdb.Spec.Users {
+		if user.Name == "" {
+			problems = append(problems, fmt.Sprintf("user %d has no name", i))
+			continue
+		}
+		if user.PasswordSecretRef.Name == "" {
+			problems = append(problems, fmt.Sprintf("user %q has no passwordSecretRef", user.Name))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("invalid users: %s", strings.Join(problems, ", "))
+	}
 	return nil
 }
 
@@ -44,6 +258,9 @@ func validateUsers(mdb mdbv1.MongoDBCommunity) error {
 	connectionStringSecretNameMap := map[string]scram.User{}
 	nameCollisions := []string{}
 	for _, user := range mdb.GetScramUsers() {
+		if isReservedUserDatabase(user.Database) {
+			return errors.Errorf(`user "%s" cannot be created in the "%s" database`, user.Username, user.Database)
+		}
 		secretName := user.GetConnectionStringSecretName(mdb)
 		if previousUser, exists := connectionStringSecretNameMap[secretName]; exists {
 			nameCollisions = append(nameCollisions,
@@ -65,12 +282,22 @@ func validateUsers(mdb mdbv1.MongoDBCommunity) error {
 	return nil
 }
 
+// isReservedUserDatabase returns true if SCRAM users cannot be created in the given database.
+func isReservedUserDatabase(db string) bool {
+	switch db {
+	case "$external", "local", "config":
+		return true
+	}
+	return false
+}
+
 // validateArbiterSpec checks if the initial Member spec is valid.
 func validateArbiterSpec(mdb mdbv1.MongoDBCommunity) error {
 	if mdb.Spec.Arbiters < 0 {
 		return fmt.Errorf("number of arbiters must be greater or equal than 0")
 	}
-	if mdb.Spec.Arbiters >= mdb.Spec.Members {
+	// arbiters deployed in their own StatefulSet are not counted in the members
+	if !mdb.HasSeparateArbiters() && mdb.Spec.Arbiters >= mdb.Spec.Members {
 		return fmt.Errorf("number of arbiters specified (%v) is greater or equal than the number of members in the replicaset (%v). At least one member must not be an arbiter", mdb.Spec.Arbiters, mdb.Spec.Members)
 	}
 
@@ -95,3 +322,448 @@ func validateAuthModeSpec(mdb mdbv1.MongoDBCommunity) error {
 
 	return nil
 }
+
+// readWriteOncePod is the access mode of volumes which can only be mounted by a single pod, it is
+// not yet defined by the Kubernetes API version the operator is built with.
+const readWriteOncePod corev1.PersistentVolumeAccessMode = "ReadWriteOncePod"
+
+// validateStorageSpec checks that the configured data path and host path are absolute paths, that
+// the journal commit interval and the sync period are within the ranges accepted by mongod, and that
+// the access mode allows mongod to write to its volumes.
+func validateStorageSpec(mdb mdbv1.MongoDBCommunity) error {
+	storage := mdb.Spec.Storage
+	if storage.JournalCommitIntervalMs < 0 || storage.JournalCommitIntervalMs > 500 {
+		return fmt.Errorf("the journal commit interval must be 0 or between 1 and 500 milliseconds, got %d", storage.JournalCommitIntervalMs)
+	}
+	if storage.SyncPeriodSecs < 0 || storage.SyncPeriodSecs > 31536000 {
+		return fmt.Errorf("the sync period must be 0 or between 1 and 31536000 seconds, got %d", storage.SyncPeriodSecs)
+	}
+
+	dataPath := storage.DataPath
+	if dataPath != "" && !path.IsAbs(dataPath) {
+		return fmt.Errorf("the data path must be an absolute path, got %q", dataPath)
+	}
+	hostPath := storage.HostPath
+	if hostPath != "" && !path.IsAbs(hostPath) {
+		return fmt.Errorf("the storage host path must be an absolute path, got %q", hostPath)
+	}
+
+	switch storage.AccessMode {
+	case "", corev1.ReadWriteOnce, readWriteOncePod, corev1.ReadWriteMany:
+	default:
+		return fmt.Errorf("the storage access mode must be one of %s, %s or %s, got %q", corev1.ReadWriteOnce, readWriteOncePod, corev1.ReadWriteMany, storage.AccessMode)
+	}
+	if storage.AccessMode != "" && hostPath != "" {
+		return fmt.Errorf("the storage access mode only applies to PersistentVolumeClaims and cannot be combined with a host path")
+	}
+	if (len(storage.VolumeClaimLabels) > 0 || len(storage.VolumeClaimAnnotations) > 0) && hostPath != "" {
+		return fmt.Errorf("the volume claim labels and annotations only apply to PersistentVolumeClaims and cannot be combined with a host path")
+	}
+	for key, value := range storage.VolumeClaimLabels {
+		if errs := append(k8svalidation.IsQualifiedName(key), k8svalidation.IsValidLabelValue(value)...); len(errs) > 0 {
+			return fmt.Errorf("invalid volume claim label %s=%s: %s", key, value, strings.Join(errs, ", "))
+		}
+	}
+	for key := range storage.VolumeClaimAnnotations {
+		if errs := k8svalidation.IsQualifiedName(strings.ToLower(key)); len(errs) > 0 {
+			return fmt.Errorf("invalid volume claim annotation %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// maxAgentTimeoutSeconds is the longest timeout of the agent which can be configured, longer timeouts
+// would let the agent stall on unreachable processes instead of retrying.
+const maxAgentTimeoutSeconds = 600
+
+// validateAgentSpec checks that the timeouts of the agent are in range, and that the configured log
+// path is an absolute path. The logs volume is mounted at the log path, which must therefore not
+// overlap with the data path, otherwise one volume would hide the other and the log files would end
+// up in the data volume or the other way around.
+func validateAgentSpec(mdb mdbv1.MongoDBCommunity) error {
+	if timeout := mdb.Spec.Agent.DialTimeoutSeconds; timeout < 0 || timeout > maxAgentTimeoutSeconds {
+		return fmt.Errorf("the agent dial timeout must be 0 or between 1 and %d seconds, got %d", maxAgentTimeoutSeconds, timeout)
+	}
+	if timeout := mdb.Spec.Agent.ServerSelectionTimeoutSeconds; timeout < 0 || timeout > maxAgentTimeoutSeconds {
+		return fmt.Errorf("the agent server selection timeout must be 0 or between 1 and %d seconds, got %d", maxAgentTimeoutSeconds, timeout)
+	}
+	if hours := mdb.Spec.Agent.MaxLogFileDurationHours; hours < 0 {
+		return fmt.Errorf("the agent maxLogFileDurationHours must not be negative, got %d", hours)
+	}
+	if sizeMB := mdb.Spec.Agent.MaxLogFileSizeMB; sizeMB < 0 {
+		return fmt.Errorf("the agent maxLogFileSizeMB must not be negative, got %d", sizeMB)
+	}
+	if files := mdb.Spec.Agent.MaxLogFiles; files < 0 {
+		return fmt.Errorf("the agent maxLogFiles must not be negative, got %d", files)
+	}
+	if downloadBase := mdb.Spec.Agent.DownloadBase; downloadBase != "" && !path.IsAbs(downloadBase) {
+		return fmt.Errorf("the agent download base must be an absolute path, got %q", downloadBase)
+	}
+
+	logPath := mdb.Spec.Agent.LogPath
+	if logPath == "" {
+		return nil
+	}
+	if !path.IsAbs(logPath) {
+		return fmt.Errorf("the agent log path must be an absolute path, got %q", logPath)
+	}
+	if isSameOrNestedPath(logPath, mdb.DataPath()) || isSameOrNestedPath(mdb.DataPath(), logPath) {
+		return fmt.Errorf("the agent log path %q must not overlap with the data path %q", logPath, mdb.DataPath())
+	}
+	return nil
+}
+
+// isSameOrNestedPath returns true if child is parent or a path within parent.
+func isSameOrNestedPath(child, parent string) bool {
+	child, parent = path.Clean(child), path.Clean(parent)
+	return child == parent || strings.HasPrefix(child, strings.TrimSuffix(parent, "/")+"/")
+}
+
+// validateProtocolVersion checks that the replica set protocol version is supported by the MongoDB version.
+func validateProtocolVersion(mdb mdbv1.MongoDBCommunity) error {
+	supported, err := versions.IsProtocolVersionSupported(mdb.Spec.Version, mdb.GetProtocolVersion())
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return fmt.Errorf("replica set protocol version %q is not supported with MongoDB version %s", mdb.GetProtocolVersion(), mdb.Spec.Version)
+	}
+	return nil
+}
+
+// validateSystemLogSpec checks that the log verbosity levels are within the range accepted by mongod.
+func validateSystemLogSpec(mdb mdbv1.MongoDBCommunity) error {
+	systemLog := mdb.Spec.SystemLog
+	if systemLog.Verbosity < 0 || systemLog.Verbosity > 5 {
+		return fmt.Errorf("the log verbosity must be between 0 and 5, got %d", systemLog.Verbosity)
+	}
+	for component, level := range systemLog.Component {
+		if component == "" {
+			return fmt.Errorf("the name of a log component must not be empty")
+		}
+		if level < -1 || level > 5 {
+			return fmt.Errorf("the log verbosity of component %q must be between -1 and 5, got %d", component, level)
+		}
+	}
+	return nil
+}
+
+// validateNetSpec checks that the maximum number of incoming connections is positive if it is set, and that
+// it is not set in the additional mongod configuration as well.
+func validateNetSpec(mdb mdbv1.MongoDBCommunity) error {
+	maxConnections := mdb.Spec.Net.MaxIncomingConnections
+	if maxConnections < 0 {
+		return fmt.Errorf("the maximum number of incoming connections must be positive, got %d", maxConnections)
+	}
+	if maxConnections != 0 {
+		return validateNotInAdditionalMongodConfig(mdb, "net.maxIncomingConnections", "spec.net.maxIncomingConnections")
+	}
+	return nil
+}
+
+// validateOperationProfilingSpec checks that the profiler mode is one accepted by mongod, that the slow
+// operation threshold is positive if it is set, and that the settings are not set in the additional mongod
+// configuration as well.
+func validateOperationProfilingSpec(mdb mdbv1.MongoDBCommunity) error {
+	profiling := mdb.Spec.OperationProfiling
+	switch profiling.Mode {
+	case "", "off", "slowOp", "all":
+	default:
+		return fmt.Errorf("the operation profiling mode must be one of off, slowOp or all, got %q", profiling.Mode)
+	}
+	if profiling.SlowOpThresholdMs < 0 {
+		return fmt.Errorf("the slow operation threshold must be positive, got %d milliseconds", profiling.SlowOpThresholdMs)
+	}
+	if profiling.Mode != "" {
+		if err := validateNotInAdditionalMongodConfig(mdb, "operationProfiling.mode", "spec.operationProfiling.mode"); err != nil {
+			return err
+		}
+	}
+	if profiling.SlowOpThresholdMs != 0 {
+		return validateNotInAdditionalMongodConfig(mdb, "operationProfiling.slowOpThresholdMs", "spec.operationProfiling.slowOpThresholdMs")
+	}
+	return nil
+}
+
+// validateNotInAdditionalMongodConfig checks that a mongod setting which is configured through the given field
+// of the spec is not set in the additional mongod configuration, which would otherwise override it.
+func validateNotInAdditionalMongodConfig(mdb mdbv1.MongoDBCommunity, setting, field string) error {
+	if !objx.New(mdb.Spec.AdditionalMongodConfig.Object).Get(setting).IsNil() {
+		return fmt.Errorf("%s is configured with %s and cannot be set in additionalMongodConfig as well", setting, field)
+	}
+	return nil
+}
+
+// validateAdditionalMongodArgs checks that the additional mongod arguments don't replace the configuration
+// file written by the agent, or run mongod in the background.
+func validateAdditionalMongodArgs(mdb mdbv1.MongoDBCommunity) error {
+	for _, arg := range mdb.Spec.AdditionalMongodArgs {
+		name := strings.SplitN(arg, "=", 2)[0]
+		switch name {
+		case "-f", "--config", "--fork":
+			return fmt.Errorf("the mongod argument %q is managed by the operator and cannot be set", name)
+		}
+	}
+	return nil
+}
+
+// validateMongodCommand checks that a custom mongod command has an entrypoint and is not combined
+// with additional mongod arguments, which are only appended to the command of the operator.
+func validateMongodCommand(mdb mdbv1.MongoDBCommunity) error {
+	if mdb.Spec.MongodCommand == nil {
+		return nil
+	}
+	if len(mdb.Spec.MongodCommand.Command) == 0 {
+		return fmt.Errorf("mongodCommand requires a command")
+	}
+	if len(mdb.Spec.AdditionalMongodArgs) > 0 {
+		return fmt.Errorf("additionalMongodArgs cannot be combined with mongodCommand, add the arguments to mongodCommand.args instead")
+	}
+	return nil
+}
+
+// validateAdditionalInitContainers checks that the additional init containers have unique names which
+// don't collide with the init containers of the operator.
+func validateAdditionalInitContainers(mdb mdbv1.MongoDBCommunity) error {
+	names := map[string]struct{}{}
+	for _, c := range mdb.Spec.AdditionalInitContainers {
+		if c.Name == "" {
+			return fmt.Errorf("the name of an additional init container must not be empty")
+		}
+		if construct.IsOperatorInitContainer(c.Name) {
+			return fmt.Errorf("the init container %q is managed by the operator and cannot be configured", c.Name)
+		}
+		if _, ok := names[c.Name]; ok {
+			return fmt.Errorf("the additional init container %q is declared twice or more", c.Name)
+		}
+		names[c.Name] = struct{}{}
+	}
+	return nil
+}
+
+// validateMemberAddressing checks that the hostnames of the members are valid DNS names, and that the pod IPs,
+// which change when the pods are restarted, are not used together with TLS certificates.
+func validateMemberAddressing(mdb mdbv1.MongoDBCommunity) error {
+	if mdb.MemberAddressingMode() == mdbv1.PodIPAddressing && mdb.Spec.Security.TLS.Enabled {
+		return fmt.Errorf("memberAddressing.mode %s cannot be combined with TLS, as the certificates can't be issued for the IP addresses of the pods", mdbv1.PodIPAddressing)
+	}
+	if domain := mdb.Spec.MemberAddressing.ClusterDomain; domain != "" {
+		if errs := k8svalidation.IsDNS1123Subdomain(domain); len(errs) > 0 {
+			return fmt.Errorf("memberAddressing.clusterDomain %q is not a valid DNS domain: %s", domain, strings.Join(errs, ", "))
+		}
+	}
+	for _, host := range mdb.Hosts() {
+		hostname, _, err := net.SplitHostPort(host)
+		if err != nil {
+			return err
+		}
+		if len(hostname) > k8svalidation.DNS1123SubdomainMaxLength {
+			return fmt.Errorf("the hostname %q of a member must be no more than %d characters", hostname, k8svalidation.DNS1123SubdomainMaxLength)
+		}
+	}
+	return nil
+}
+
+// validateMemberConfig checks that index builds are only disabled on hidden members, as required by mongod, that
+// the arbiters are not configured, and that there is still a voting member which can become the primary.
+func validateMemberConfig(mdb mdbv1.MongoDBCommunity) error {
+	if len(mdb.Spec.MemberConfig) == 0 {
+		return nil
+	}
+	arbiters := 0
+	if !mdb.HasSeparateArbiters() {
+		arbiters = mdb.Spec.Arbiters
+	}
+	for i, c := range mdb.Spec.MemberConfig {
+		if i >= mdb.Spec.Members {
+			break
+		}
+		if i < arbiters && (c.Hidden || !c.BuildsIndexes()) {
+			return fmt.Errorf("memberConfig[%d] configures an arbiter, only data-bearing members can be hidden or have index builds disabled", i)
+		}
+		if !c.BuildsIndexes() && !c.Hidden {
+			return fmt.Errorf("memberConfig[%d].buildIndexes can only be set to false on hidden members", i)
+		}
+	}
+
+	// only the first 7 members vote, the following ones have priority 0
+	for i := arbiters; i < mdb.Spec.Members && i < 7; i++ {
+		if !memberConfig(mdb.Spec, i).Hidden {
+			return nil
+		}
+	}
+	return errors.New("at least one voting data-bearing member must not be hidden, so that a primary can be elected")
+}
+
+// validateMemberHosts checks that the hosts the members advertise instead of their own hostnames are valid
+// "host:port" addresses, which are unique and use the port mongod listens on. The agent of the member builds the
+// address from the hostname of its process and the port of mongod, a different port can't be advertised.
+func validateMemberHosts(mdb mdbv1.MongoDBCommunity) error {
+	hosts := map[string]int{}
+	for i, c := range mdb.Spec.MemberConfig {
+		if i >= mdb.Spec.Members || c.Host == "" {
+			continue
+		}
+		hostname, port, err := net.SplitHostPort(c.Host)
+		if err != nil {
+			return fmt.Errorf("memberConfig[%d].host %q must have the format host:port: %s", i, c.Host, err)
+		}
+		if net.ParseIP(hostname) == nil {
+			if errs := k8svalidation.IsDNS1123Subdomain(hostname); len(errs) > 0 {
+				return fmt.Errorf("memberConfig[%d].host %q is neither a valid hostname nor an IP address: %s", i, c.Host, strings.Join(errs, ", "))
+			}
+		}
+		if port != strconv.Itoa(mdb.GetMongodPort()) {
+			return fmt.Errorf("memberConfig[%d].host %q must use the port mongod listens on, %d", i, c.Host, mdb.GetMongodPort())
+		}
+		if j, ok := hosts[hostname]; ok {
+			return fmt.Errorf("memberConfig[%d].host and memberConfig[%d].host must not be the same", j, i)
+		}
+		hosts[hostname] = i
+	}
+	return nil
+}
+
+// validateReplicaSetHorizons checks that every member declares the same horizons, as mongod rejects replica set
+// configurations whose members have different horizons, and that the address of each member in a horizon is a
+// host:port. All the problems found are returned, so that they can be fixed at once.
+func validateReplicaSetHorizons(mdb mdbv1.MongoDBCommunity) error {
+	horizons := mdb.Spec.ReplicaSetHorizons
+	if len(horizons) == 0 {
+		return nil
+	}
+
+	var errs []error
+	if len(horizons) < mdb.Spec.Members {
+		errs = append(errs, fmt.Errorf("replicaSetHorizons has %d entries, one is required for each of the %d members", len(horizons), mdb.Spec.Members))
+	}
+
+	addresses := map[string]map[string]int{}
+	for i, memberHorizons := range horizons {
+		missing, extra := horizonNamesDiff(horizons[0], memberHorizons)
+		var diffs []string
+		if len(missing) > 0 {
+			diffs = append(diffs, fmt.Sprintf("missing %s", strings.Join(missing, ", ")))
+		}
+		if len(extra) > 0 {
+			diffs = append(diffs, fmt.Sprintf("unexpected %s", strings.Join(extra, ", ")))
+		}
+		if len(diffs) > 0 {
+			errs = append(errs, fmt.Errorf("replicaSetHorizons[%d] must declare the same horizons as replicaSetHorizons[0]: %s", i, strings.Join(diffs, "; ")))
+		}
+
+		for _, name := range sortedHorizonNames(memberHorizons) {
+			address := memberHorizons[name]
+			if name == "" {
+				errs = append(errs, fmt.Errorf("replicaSetHorizons[%d] has a horizon without a name", i))
+			}
+			if err := validateHorizonAddress(address); err != nil {
+				errs = append(errs, fmt.Errorf("replicaSetHorizons[%d] horizon %q: %s", i, name, err))
+				continue
+			}
+			if addresses[name] == nil {
+				addresses[name] = map[string]int{}
+			}
+			if j, ok := addresses[name][address]; ok {
+				errs = append(errs, fmt.Errorf("replicaSetHorizons[%d] and replicaSetHorizons[%d] have the same address %s in horizon %q", j, i, address, name))
+			}
+			addresses[name][address] = i
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateHorizonAddress checks that the address of a member in a horizon is a valid host:port.
+func validateHorizonAddress(address string) error {
+	hostname, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%q must have the format host:port", address)
+	}
+	if net.ParseIP(hostname) == nil {
+		if errs := k8svalidation.IsDNS1123Subdomain(hostname); len(errs) > 0 {
+			return fmt.Errorf("%q is neither a valid hostname nor an IP address: %s", address, strings.Join(errs, ", "))
+		}
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("%q must have a port between 1 and 65535", address)
+	}
+	return nil
+}
+
+// horizonNamesDiff returns the horizon names of expected which are missing in actual, and the names of actual which are not in expected.
+func horizonNamesDiff(expected, actual automationconfig.ReplicaSetHorizons) (missing, extra []string) {
+	for _, name := range sortedHorizonNames(expected) {
+		if _, ok := actual[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	for _, name := range sortedHorizonNames(actual) {
+		if _, ok := expected[name]; !ok {
+			extra = append(extra, name)
+		}
+	}
+	return missing, extra
+}
+
+func sortedHorizonNames(horizons automationconfig.ReplicaSetHorizons) []string {
+	names := make([]string, 0, len(horizons))
+	for name := range horizons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateMembersToRemove checks that the members to remove are members of the resource, and that the replica set
+// keeps at least one of the members of the StatefulSet. The members of the StatefulSet are named after their pods.
+func validateMembersToRemove(mdb mdbv1.MongoDBCommunity) error {
+	names := map[string]bool{}
+	for i := 0; i < mdb.Spec.Members; i++ {
+		names[fmt.Sprintf("%s-%d", mdb.Name, i)] = true
+	}
+	if mdb.HasSeparateArbiters() {
+		for i := 0; i < mdb.Spec.Arbiters; i++ {
+			names[fmt.Sprintf("%s-%d", mdb.ArbiterNamespacedName().Name, i)] = true
+		}
+	}
+
+	removed := map[string]bool{}
+	removedMembers := 0
+	for i, name := range mdb.Spec.MembersToRemove {
+		if !names[name] {
+			return fmt.Errorf("membersToRemove[%d] %q is not a member of the replica set, remove it from membersToRemove when the StatefulSet is scaled down past it", i, name)
+		}
+		if removed[name] {
+			return fmt.Errorf("membersToRemove[%d] %q is listed more than once", i, name)
+		}
+		removed[name] = true
+		if !strings.HasPrefix(name, mdb.ArbiterNamespacedName().Name+"-") {
+			removedMembers++
+		}
+	}
+	if removedMembers > 0 && removedMembers >= mdb.Spec.Members {
+		return errors.New("membersToRemove can't remove all the members of the replica set")
+	}
+	return nil
+}
+
+// memberConfig returns the configuration of the member with the given index, or the default configuration.
+func memberConfig(spec mdbv1.MongoDBCommunitySpec, i int) mdbv1.MemberConfiguration {
+	if i < len(spec.MemberConfig) {
+		return spec.MemberConfig[i]
+	}
+	return mdbv1.MemberConfiguration{}
+}
+
+// validateShutdown checks that a termination grace period configured in the StatefulSet override leaves the
+// primary enough time to step down before the pod is killed.
+func validateShutdown(mdb mdbv1.MongoDBCommunity) error {
+	if !mdb.Spec.Shutdown.StepDownPrimary {
+		return nil
+	}
+	gracePeriod := mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.Template.Spec.TerminationGracePeriodSeconds
+	if gracePeriod != nil && *gracePeriod <= int64(mdb.GetStepDownTimeoutSeconds()) {
+		return fmt.Errorf("the terminationGracePeriodSeconds of the StatefulSet (%d) must be greater than shutdown.stepDownTimeoutSeconds (%d)", *gracePeriod, mdb.GetStepDownTimeoutSeconds())
+	}
+	return nil
+}
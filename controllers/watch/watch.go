@@ -1,6 +1,8 @@
 package watch
 
 import (
+	"sync"
+
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/contains"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -14,17 +16,41 @@ import (
 // If multiple types should be watched, one ResourceWatcher for each type should be used.
 type ResourceWatcher struct {
 	watched map[types.NamespacedName][]types.NamespacedName
+
+	// triggered holds the dependent objects for which a reconciliation has been
+	// enqueued because a watched object changed.
+	triggered map[types.NamespacedName]bool
+
+	// lock guards watched and triggered, which are accessed by concurrent reconciliations
+	// and by the event handlers.
+	lock *sync.Mutex
 }
 
 // New will create a new ResourceWatcher with no watched objects.
 func New() ResourceWatcher {
 	return ResourceWatcher{
-		watched: make(map[types.NamespacedName][]types.NamespacedName),
+		watched:   make(map[types.NamespacedName][]types.NamespacedName),
+		triggered: make(map[types.NamespacedName]bool),
+		lock:      &sync.Mutex{},
 	}
 }
 
+// ConsumeTrigger returns whether a reconciliation of the dependent object has been enqueued because
+// a watched object changed since the last call, and resets it.
+func (w ResourceWatcher) ConsumeTrigger(dependentName types.NamespacedName) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	triggered := w.triggered[dependentName]
+	delete(w.triggered, dependentName)
+	return triggered
+}
+
 // Watch will add a new object to watch.
 func (w ResourceWatcher) Watch(watchedName, dependentName types.NamespacedName) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
 	existing, hasExisting := w.watched[watchedName]
 	if !hasExisting {
 		existing = []types.NamespacedName{}
@@ -63,8 +89,13 @@ func (w ResourceWatcher) handleEvent(meta metav1.Object, queue workqueue.RateLim
 		Namespace: meta.GetNamespace(),
 	}
 
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
 	// Enqueue reconciliation for each dependent object.
 	for _, reconciledObjectName := range w.watched[changedObjectName] {
+		w.triggered[reconciledObjectName] = true
+
 		queue.Add(reconcile.Request{
 			NamespacedName: reconciledObjectName,
 		})
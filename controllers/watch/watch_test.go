@@ -115,6 +115,22 @@ func TestWatcher(t *testing.T) {
 
 		assert.Equal(t, 1, queue.Len())
 	})
+
+	t.Run("Triggered reconciliations are tracked", func(t *testing.T) {
+		watcher := New()
+		queue := controllertest.Queue{Interface: workqueue.New()}
+		watcher.Watch(objNsName, mdb1.NamespacedName())
+		assert.False(t, watcher.ConsumeTrigger(mdb1.NamespacedName()))
+
+		watcher.Update(event.UpdateEvent{
+			ObjectOld: obj,
+			ObjectNew: obj,
+		}, queue)
+
+		assert.True(t, watcher.ConsumeTrigger(mdb1.NamespacedName()))
+		assert.False(t, watcher.ConsumeTrigger(mdb1.NamespacedName()), "The trigger should be reset once consumed")
+		assert.False(t, watcher.ConsumeTrigger(mdb2.NamespacedName()))
+	})
 }
 
 func TestWatcherAdd(t *testing.T) {
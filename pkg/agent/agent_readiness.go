@@ -16,6 +16,10 @@ const (
 	// podAnnotationAgentVersion is the Pod Annotation key which contains the current version of the Automation Config
 	// the Agent on the Pod is on now.
 	podAnnotationAgentVersion = "agent.mongodb.com/version"
+
+	// podAnnotationNotReadyReason is the Pod Annotation key which contains the reason the readiness probe
+	// reported the Pod as not ready, it is empty once the Pod is ready.
+	podAnnotationNotReadyReason = "agent.mongodb.com/not-ready-reason"
 )
 
 // AllReachedGoalState returns whether or not the agents associated with a given StatefulSet have reached goal state.
@@ -77,3 +81,19 @@ func statefulSetPodNames(sts appsv1.StatefulSet, currentMembersCount int) []stri
 	}
 	return names
 }
+
+// AchievedConfigVersion returns the version of the Automation Config the Agent on the Pod has reached, as published
+// by the readiness probe from the health status of the Agent. False is returned if the version hasn't been published yet.
+func AchievedConfigVersion(pod corev1.Pod) (int, bool) {
+	version, ok := pod.Annotations[podAnnotationAgentVersion]
+	if !ok {
+		return 0, false
+	}
+	return cast.ToInt(version), true
+}
+
+// NotReadyReason returns the reason the readiness probe reported the Pod as not ready, e.g. the step the Agent
+// is stuck in. It is empty if the Pod is ready or the readiness probe hasn't recorded a reason.
+func NotReadyReason(pod corev1.Pod) string {
+	return pod.Annotations[podAnnotationNotReadyReason]
+}
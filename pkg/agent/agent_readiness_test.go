@@ -76,6 +76,15 @@ func TestReachedGoalState(t *testing.T) {
 	})
 }
 
+func TestAchievedConfigVersion(t *testing.T) {
+	version, ok := AchievedConfigVersion(createPodWithAgentAnnotation("7"))
+	assert.True(t, ok)
+	assert.Equal(t, 7, version)
+
+	_, ok = AchievedConfigVersion(corev1.Pod{})
+	assert.False(t, ok)
+}
+
 func createPodWithAgentAnnotation(versionStr string) corev1.Pod {
 	return corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -17,3 +17,13 @@ func StartupParametersToAgentFlag(parameters ...StartupParameter) corev1.EnvVar
 	}
 	return corev1.EnvVar{Name: "AGENT_FLAGS", Value: agentParams}
 }
+
+// StartupParametersToFlags converts the given StartupParameters into
+// command line flags of the agent, in the format "-key=value".
+func StartupParametersToFlags(parameters ...StartupParameter) []string {
+	flags := make([]string, 0, len(parameters))
+	for _, param := range parameters {
+		flags = append(flags, "-"+param.Key+"="+param.Value)
+	}
+	return flags
+}
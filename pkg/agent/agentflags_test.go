@@ -32,3 +32,19 @@ func TestAgentFlagEmptyParameters(t *testing.T) {
 	assert.Equal(t, "", envVar.Value)
 
 }
+
+func TestStartupParametersToFlags(t *testing.T) {
+	parameters := []StartupParameter{
+		{
+			Key:   "dialTimeoutSeconds",
+			Value: "20",
+		},
+		{
+			Key:   "serverSelectionTimeoutSeconds",
+			Value: "5",
+		},
+	}
+
+	assert.Equal(t, []string{"-dialTimeoutSeconds=20", "-serverSelectionTimeoutSeconds=5"}, StartupParametersToFlags(parameters...))
+	assert.Empty(t, StartupParametersToFlags())
+}
@@ -3,6 +3,7 @@ package scram
 import (
 	"encoding/base64"
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -27,6 +28,8 @@ const (
 
 	sha1StoredKeyKey   = "sha-1-stored-key"
 	sha256StoredKeyKey = "sha-256-stored-key"
+
+	base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/="
 )
 
 // Configurable is an interface which any resource which can configure ScramSha authentication should implement.
@@ -99,6 +102,17 @@ type Options struct {
 
 	// AutoAuthMechanism is the desired authentication mechanism that the agents will use.
 	AutoAuthMechanism string
+
+	// KeyfileSecret is the NamespacedName of an existing secret which contains the keyfile contents.
+	// If no name is specified, the keyfile contents are generated.
+	KeyfileSecret types.NamespacedName
+
+	// KeyfileSecretKey is the key in the KeyfileSecret which maps to the keyfile contents.
+	KeyfileSecretKey string
+
+	// AgentCredentialsManagedExternally indicates that the agent password and keyfile secrets are not created,
+	// they are required to exist.
+	AgentCredentialsManagedExternally bool
 }
 
 // Enable will configure all of the required Kubernetes resources for SCRAM-SHA to be enabled.
@@ -120,14 +134,12 @@ func Enable(auth *automationconfig.Auth, secretGetUpdateCreateDeleter secret.Get
 		return errors.Errorf("could not convert users to Automation Config users: %s", err)
 	}
 
-	// ensure that the agent password secret exists or read existing password.
-	agentPassword, err := secret.EnsureSecretWithKey(secretGetUpdateCreateDeleter, mdb.GetAgentPasswordSecretNamespacedName(), mdb.GetOwnerReferences(), AgentPasswordKey, generatedPassword)
+	agentPassword, err := ensureAgentPassword(secretGetUpdateCreateDeleter, mdb, generatedPassword)
 	if err != nil {
 		return err
 	}
 
-	// ensure that the agent keyfile secret exists or read existing keyfile.
-	agentKeyFile, err := secret.EnsureSecretWithKey(secretGetUpdateCreateDeleter, mdb.GetAgentKeyfileSecretNamespacedName(), mdb.GetOwnerReferences(), AgentKeyfileKey, generatedContents)
+	agentKeyFile, err := ensureAgentKeyfile(secretGetUpdateCreateDeleter, mdb, generatedContents)
 	if err != nil {
 		return err
 	}
@@ -138,9 +150,122 @@ func Enable(auth *automationconfig.Auth, secretGetUpdateCreateDeleter secret.Get
 	)
 }
 
+// ensureAgentPassword returns the password of the agent. The agent password secret is created with the generated
+// password if it doesn't exist, unless the agent credentials are managed externally.
+func ensureAgentPassword(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdb Configurable, generatedPassword string) (string, error) {
+	if !mdb.GetScramOptions().AgentCredentialsManagedExternally {
+		// ensure that the agent password secret exists or read existing password.
+		return secret.EnsureSecretWithKey(secretGetUpdateCreateDeleter, mdb.GetAgentPasswordSecretNamespacedName(), mdb.GetOwnerReferences(), AgentPasswordKey, generatedPassword)
+	}
+
+	agentPassword, err := secret.ReadKey(secretGetUpdateCreateDeleter, AgentPasswordKey, mdb.GetAgentPasswordSecretNamespacedName())
+	if err != nil {
+		return "", errors.Errorf("could not read the externally managed agent password from secret %s: %s", mdb.GetAgentPasswordSecretNamespacedName(), err)
+	}
+	if agentPassword == "" {
+		return "", errors.Errorf("the externally managed agent password in secret %s is empty", mdb.GetAgentPasswordSecretNamespacedName())
+	}
+	return agentPassword, nil
+}
+
+// ensureAgentKeyfile returns the contents of the keyfile. If an existing keyfile secret has been configured, the contents
+// are read from it, otherwise the keyfile secret managed by the operator is created or read. The keyfile secret
+// managed by the operator is only read if the agent credentials are managed externally.
+func ensureAgentKeyfile(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdb Configurable, generatedContents string) (string, error) {
+	opts := mdb.GetScramOptions()
+	if opts.KeyfileSecret.Name == "" && !opts.AgentCredentialsManagedExternally {
+		// ensure that the agent keyfile secret exists or read existing keyfile.
+		return secret.EnsureSecretWithKey(secretGetUpdateCreateDeleter, mdb.GetAgentKeyfileSecretNamespacedName(), mdb.GetOwnerReferences(), AgentKeyfileKey, generatedContents)
+	}
+
+	keyfileSecret, keyfileSecretKey := keyfileSecretKeyReference(mdb)
+	keyfileContents, err := secret.ReadKey(secretGetUpdateCreateDeleter, keyfileSecretKey, keyfileSecret)
+	if err != nil {
+		return "", errors.Errorf("could not read keyfile from secret %s: %s", keyfileSecret, err)
+	}
+	if err := validateKeyfileContents(keyfileContents); err != nil {
+		return "", errors.Errorf("invalid keyfile in secret %s: %s", keyfileSecret, err)
+	}
+	return keyfileContents, nil
+}
+
+// keyfileSecretKeyReference returns the secret and the key in it which hold the keyfile, the existing keyfile secret
+// if it has been configured, and otherwise the keyfile secret managed by the operator.
+func keyfileSecretKeyReference(mdb Configurable) (types.NamespacedName, string) {
+	opts := mdb.GetScramOptions()
+	if opts.KeyfileSecret.Name != "" {
+		return opts.KeyfileSecret, opts.KeyfileSecretKey
+	}
+	return mdb.GetAgentKeyfileSecretNamespacedName(), AgentKeyfileKey
+}
+
+// ReadAgentKeyfile returns the contents of the keyfile the deployment should use, without creating the keyfile
+// secret. An empty string is returned if the keyfile secret doesn't exist yet. The contents of an existing keyfile
+// secret are validated when the automation config is built.
+func ReadAgentKeyfile(getter secret.Getter, mdb Configurable) (string, error) {
+	keyfileSecret, keyfileSecretKey := keyfileSecretKeyReference(mdb)
+	keyfileContents, err := secret.ReadKey(getter, keyfileSecretKey, keyfileSecret)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errors.Errorf("could not read keyfile from secret %s: %s", keyfileSecret, err)
+	}
+	return keyfileContents, nil
+}
+
+// KeyfileKeys returns the keys of the given keyfile contents. A keyfile holds either a single key, in which
+// whitespace is ignored, or a YAML sequence of keys, which mongod supports from MongoDB 4.2 to rotate keys.
+func KeyfileKeys(contents string) []string {
+	if !strings.HasPrefix(strings.TrimSpace(contents), "-") {
+		if key := strings.Join(strings.Fields(contents), ""); key != "" {
+			return []string{key}
+		}
+		return nil
+	}
+
+	var keys []string
+	for _, line := range strings.Split(contents, "\n") {
+		if key := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "-")); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// KeyfileContents returns the contents of a keyfile with the given keys. A single key is written as it is, multiple
+// keys as a YAML sequence. mongod accepts all the keys, and authenticates to the other members with the first one.
+func KeyfileContents(keys ...string) string {
+	if len(keys) == 1 {
+		return keys[0]
+	}
+	contents := ""
+	for _, key := range keys {
+		contents += "- " + key + "\n"
+	}
+	return contents
+}
+
+// validateKeyfileContents validates the keyfile contents according to the requirements of mongod:
+// the contents must be between 6 and 1024 characters long and may only contain characters of the base64 set.
+// Whitespace characters are ignored by mongod.
+func validateKeyfileContents(contents string) error {
+	keyfile := strings.Join(strings.Fields(contents), "")
+	if len(keyfile) < 6 || len(keyfile) > 1024 {
+		return errors.Errorf("the keyfile must be between 6 and 1024 characters long, it is %d characters long", len(keyfile))
+	}
+	for _, c := range keyfile {
+		if !strings.ContainsRune(base64Chars, c) {
+			return errors.Errorf("the keyfile contains the character %q, only characters of the base64 set are allowed", c)
+		}
+	}
+	return nil
+}
+
 // ensureScramCredentials will ensure that the ScramSha1 & ScramSha256 credentials exist and are stored in the credentials
 // secret corresponding to user of the given MongoDB deployment.
-func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User, mdbNamespacedName types.NamespacedName) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
+func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User, mdb Configurable) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
+	mdbNamespacedName := mdb.NamespacedName()
 
 	password, err := secret.ReadKey(getUpdateCreator, user.PasswordSecretKey, types.NamespacedName{Name: user.PasswordSecretName, Namespace: mdbNamespacedName.Namespace})
 	if err != nil {
@@ -152,6 +277,10 @@ func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User,
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not read secret key: %s", err)
 	}
 
+	if password == "" {
+		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf(`the key "%s" of the password secret %s is empty`, user.PasswordSecretKey, user.PasswordSecretName)
+	}
+
 	// we should only need to generate new credentials in two situations.
 	// 1. We are creating the credentials for the first time
 	// 2. We are changing the password
@@ -174,7 +303,7 @@ func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User,
 	}
 
 	// create or update our credentials secret for this user
-	if err := createScramCredentialsSecret(getUpdateCreator, mdbNamespacedName, user.ScramCredentialsSecretName, sha1Creds, sha256Creds); err != nil {
+	if err := createScramCredentialsSecret(getUpdateCreator, mdbNamespacedName, mdb.GetOwnerReferences(), user.ScramCredentialsSecretName, sha1Creds, sha256Creds); err != nil {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("faild to create scram credentials secret %s: %s", user.ScramCredentialsSecretName, err)
 	}
 
@@ -258,7 +387,7 @@ func computeScramShaCredentials(username, password string, sha1Salt, sha256Salt
 
 // createScramCredentialsSecret will create a Secret that contains all of the fields required to read these credentials
 // back in the future.
-func createScramCredentialsSecret(getUpdateCreator secret.GetUpdateCreator, mdbObjectKey types.NamespacedName, scramCredentialsSecretName string, sha1Creds, sha256Creds scramcredentials.ScramCreds) error {
+func createScramCredentialsSecret(getUpdateCreator secret.GetUpdateCreator, mdbObjectKey types.NamespacedName, ownerReferences []metav1.OwnerReference, scramCredentialsSecretName string, sha1Creds, sha256Creds scramcredentials.ScramCreds) error {
 	scramCredsSecret := secret.Builder().
 		SetName(scramCredentialsSecretName).
 		SetNamespace(mdbObjectKey.Namespace).
@@ -268,6 +397,7 @@ func createScramCredentialsSecret(getUpdateCreator secret.GetUpdateCreator, mdbO
 		SetField(sha256SaltKey, sha256Creds.Salt).
 		SetField(sha256StoredKeyKey, sha256Creds.StoredKey).
 		SetField(sha256ServerKeyKey, sha256Creds.ServerKey).
+		SetOwnerReferences(ownerReferences).
 		Build()
 	return secret.CreateOrUpdate(getUpdateCreator, scramCredsSecret)
 }
@@ -305,7 +435,7 @@ func readExistingCredentials(secretGetter secret.Getter, mdbObjectKey types.Name
 func convertMongoDBResourceUsersToAutomationConfigUsers(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdb Configurable) ([]automationconfig.MongoDBUser, error) {
 	var usersWanted []automationconfig.MongoDBUser
 	for _, u := range mdb.GetScramUsers() {
-		acUser, err := convertMongoDBUserToAutomationConfigUser(secretGetUpdateCreateDeleter, mdb.NamespacedName(), u)
+		acUser, err := convertMongoDBUserToAutomationConfigUser(secretGetUpdateCreateDeleter, mdb, u)
 		if err != nil {
 			return nil, errors.Errorf("failed to convert scram user %s to Automation Config user: %s", u.Username, err)
 		}
@@ -316,7 +446,7 @@ func convertMongoDBResourceUsersToAutomationConfigUsers(secretGetUpdateCreateDel
 
 // convertMongoDBUserToAutomationConfigUser converts a single user configured in the MongoDB resource and converts it to a user
 // that can be added directly to the AutomationConfig.
-func convertMongoDBUserToAutomationConfigUser(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdbNsName types.NamespacedName, user User) (automationconfig.MongoDBUser, error) {
+func convertMongoDBUserToAutomationConfigUser(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdb Configurable, user User) (automationconfig.MongoDBUser, error) {
 	acUser := automationconfig.MongoDBUser{
 		Username: user.Username,
 		Database: user.Database,
@@ -327,7 +457,7 @@ func convertMongoDBUserToAutomationConfigUser(secretGetUpdateCreateDeleter secre
 			Database: role.Database,
 		})
 	}
-	sha1Creds, sha256Creds, err := ensureScramCredentials(secretGetUpdateCreateDeleter, user, mdbNsName)
+	sha1Creds, sha256Creds, err := ensureScramCredentials(secretGetUpdateCreateDeleter, user, mdb)
 	if err != nil {
 		return automationconfig.MongoDBUser{}, errors.Errorf("could not ensure scram credentials: %s", err)
 	}
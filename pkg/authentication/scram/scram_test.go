@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
@@ -98,12 +99,12 @@ func TestComputeScramCredentials_ComputesSameStoredAndServerKey_WithSameSalt(t *
 func TestEnsureScramCredentials(t *testing.T) {
 	mdb, user := buildConfigurableAndUser("mdb-0")
 	t.Run("Fails when there is no password secret, and no credentials secret", func(t *testing.T) {
-		_, _, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(), user, mdb.NamespacedName())
+		_, _, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(), user, mdb)
 		assert.Error(t, err)
 	})
 	t.Run("Existing credentials are used when password does not exist, but credentials secret has been created", func(t *testing.T) {
 		scramCredentialsSecret := validScramCredentialsSecret(mdb.NamespacedName(), user.ScramCredentialsSecretName)
-		scram1Creds, scram256Creds, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(scramCredentialsSecret), user, mdb.NamespacedName())
+		scram1Creds, scram256Creds, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(scramCredentialsSecret), user, mdb)
 		assert.NoError(t, err)
 		assertScramCredsCredentialsValidity(t, scram1Creds, scram256Creds)
 	})
@@ -118,7 +119,7 @@ func TestEnsureScramCredentials(t *testing.T) {
 			Build()
 
 		scramCredentialsSecret := validScramCredentialsSecret(mdb.NamespacedName(), user.ScramCredentialsSecretName)
-		scram1Creds, scram256Creds, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(scramCredentialsSecret, differentPasswordSecret), user, mdb.NamespacedName())
+		scram1Creds, scram256Creds, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(scramCredentialsSecret, differentPasswordSecret), user, mdb)
 		assert.NoError(t, err)
 		assert.NotEqual(t, testSha1Salt, scram1Creds.Salt)
 		assert.NotEmpty(t, scram1Creds.Salt)
@@ -136,6 +137,17 @@ func TestEnsureScramCredentials(t *testing.T) {
 		assert.NotEmpty(t, scram256Creds.ServerKey)
 		assert.Equal(t, 15000, scram256Creds.IterationCount)
 	})
+	t.Run("Fails when the password is empty", func(t *testing.T) {
+		emptyPasswordSecret := secret.Builder().
+			SetName(user.PasswordSecretName).
+			SetNamespace(mdb.NamespacedName().Namespace).
+			SetField(user.PasswordSecretKey, "").
+			Build()
+
+		_, _, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(emptyPasswordSecret), user, mdb)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), user.PasswordSecretKey)
+	})
 
 }
 
@@ -149,11 +161,11 @@ func TestConvertMongoDBUserToAutomationConfigUser(t *testing.T) {
 			SetField(user.PasswordSecretKey, "TDg_DESiScDrJV6").
 			Build()
 
-		acUser, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(passwordSecret), mdb.NamespacedName(), user)
+		acUser, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(passwordSecret), mdb, user)
 
 		assert.NoError(t, err)
 		assert.Equal(t, user.Username, acUser.Username)
-		assert.Equal(t, user.Database, "admin")
+		assert.Equal(t, "admin", acUser.Database)
 		assert.Equal(t, len(user.Roles), len(acUser.Roles))
 		assert.NotNil(t, acUser.ScramSha1Creds)
 		assert.NotNil(t, acUser.ScramSha256Creds)
@@ -163,8 +175,23 @@ func TestConvertMongoDBUserToAutomationConfigUser(t *testing.T) {
 		}
 	})
 
+	t.Run("The user's own auth database is used", func(t *testing.T) {
+		appUser := user
+		appUser.Database = "myapp"
+		passwordSecret := secret.Builder().
+			SetName(appUser.PasswordSecretName).
+			SetNamespace(mdb.NamespacedName().Namespace).
+			SetField(appUser.PasswordSecretKey, "TDg_DESiScDrJV6").
+			Build()
+
+		acUser, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(passwordSecret), mdb, appUser)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "myapp", acUser.Database)
+	})
+
 	t.Run("If there is no password secret, the creation fails", func(t *testing.T) {
-		_, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(), mdb.NamespacedName(), user)
+		_, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(), mdb, user)
 		assert.Error(t, err)
 	})
 }
@@ -246,6 +273,133 @@ func TestConfigureScram(t *testing.T) {
 		err := Enable(&auth, s, mdb)
 		assert.NoError(t, err)
 	})
+
+	t.Run("Existing Keyfile Secret is used if configured", func(t *testing.T) {
+		mdb := buildConfigurable("mdb-0").(mockConfigurable)
+		mdb.opts.KeyfileSecret = types.NamespacedName{Name: "existing-keyfile", Namespace: "default"}
+		mdb.opts.KeyfileSecretKey = "my-keyfile"
+
+		existingKeyfileSecret := secret.Builder().
+			SetName("existing-keyfile").
+			SetNamespace("default").
+			SetField("my-keyfile", "RuPeMaIe2g0SNTTa").
+			Build()
+
+		s := newMockedSecretGetUpdateCreateDeleter(existingKeyfileSecret)
+		auth := automationconfig.Auth{}
+		err := Enable(&auth, s, mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, "RuPeMaIe2g0SNTTa", auth.Key)
+
+		_, err = s.GetSecret(mdb.GetAgentKeyfileSecretNamespacedName())
+		assert.Error(t, err, "the keyfile secret managed by the operator should not be created")
+	})
+
+	t.Run("Enable fails if the existing Keyfile Secret is missing", func(t *testing.T) {
+		mdb := buildConfigurable("mdb-0").(mockConfigurable)
+		mdb.opts.KeyfileSecret = types.NamespacedName{Name: "existing-keyfile", Namespace: "default"}
+		mdb.opts.KeyfileSecretKey = AgentKeyfileKey
+
+		auth := automationconfig.Auth{}
+		err := Enable(&auth, newMockedSecretGetUpdateCreateDeleter(), mdb)
+		assert.Error(t, err)
+	})
+
+	t.Run("Enable fails if the existing Keyfile is invalid", func(t *testing.T) {
+		mdb := buildConfigurable("mdb-0").(mockConfigurable)
+		mdb.opts.KeyfileSecret = types.NamespacedName{Name: "existing-keyfile", Namespace: "default"}
+		mdb.opts.KeyfileSecretKey = AgentKeyfileKey
+
+		existingKeyfileSecret := secret.Builder().
+			SetName("existing-keyfile").
+			SetNamespace("default").
+			SetField(AgentKeyfileKey, "key!").
+			Build()
+
+		auth := automationconfig.Auth{}
+		err := Enable(&auth, newMockedSecretGetUpdateCreateDeleter(existingKeyfileSecret), mdb)
+		assert.Error(t, err)
+	})
+
+	t.Run("Externally managed Agent Credentials Secrets are used as they are", func(t *testing.T) {
+		mdb := buildConfigurable("mdb-0").(mockConfigurable)
+		mdb.opts.AgentCredentialsManagedExternally = true
+
+		agentPasswordSecret := secret.Builder().
+			SetName(mdb.GetAgentPasswordSecretNamespacedName().Name).
+			SetNamespace(mdb.GetAgentPasswordSecretNamespacedName().Namespace).
+			SetField(AgentPasswordKey, "A21Zv5agv3EKXFfM").
+			Build()
+		keyfileSecret := secret.Builder().
+			SetName(mdb.GetAgentKeyfileSecretNamespacedName().Name).
+			SetNamespace(mdb.GetAgentKeyfileSecretNamespacedName().Namespace).
+			SetField(AgentKeyfileKey, "RuPeMaIe2g0SNTTa").
+			Build()
+
+		auth := automationconfig.Auth{}
+		err := Enable(&auth, newMockedSecretGetUpdateCreateDeleter(agentPasswordSecret, keyfileSecret), mdb)
+		assert.NoError(t, err)
+		assert.Equal(t, "A21Zv5agv3EKXFfM", auth.AutoPwd)
+		assert.Equal(t, "RuPeMaIe2g0SNTTa", auth.Key)
+	})
+
+	t.Run("Enable fails if the externally managed Agent Password Secret is missing", func(t *testing.T) {
+		mdb := buildConfigurable("mdb-0").(mockConfigurable)
+		mdb.opts.AgentCredentialsManagedExternally = true
+
+		keyfileSecret := secret.Builder().
+			SetName(mdb.GetAgentKeyfileSecretNamespacedName().Name).
+			SetNamespace(mdb.GetAgentKeyfileSecretNamespacedName().Namespace).
+			SetField(AgentKeyfileKey, "RuPeMaIe2g0SNTTa").
+			Build()
+
+		s := newMockedSecretGetUpdateCreateDeleter(keyfileSecret)
+		auth := automationconfig.Auth{}
+		err := Enable(&auth, s, mdb)
+		assert.Error(t, err)
+
+		_, err = s.GetSecret(mdb.GetAgentPasswordSecretNamespacedName())
+		assert.Error(t, err, "the agent password secret should not be created")
+	})
+
+	t.Run("Enable fails if the externally managed Agent Keyfile Secret is missing", func(t *testing.T) {
+		mdb := buildConfigurable("mdb-0").(mockConfigurable)
+		mdb.opts.AgentCredentialsManagedExternally = true
+
+		agentPasswordSecret := secret.Builder().
+			SetName(mdb.GetAgentPasswordSecretNamespacedName().Name).
+			SetNamespace(mdb.GetAgentPasswordSecretNamespacedName().Namespace).
+			SetField(AgentPasswordKey, "A21Zv5agv3EKXFfM").
+			Build()
+
+		s := newMockedSecretGetUpdateCreateDeleter(agentPasswordSecret)
+		auth := automationconfig.Auth{}
+		err := Enable(&auth, s, mdb)
+		assert.Error(t, err)
+
+		_, err = s.GetSecret(mdb.GetAgentKeyfileSecretNamespacedName())
+		assert.Error(t, err, "the keyfile secret should not be created")
+	})
+}
+
+func TestValidateKeyfileContents(t *testing.T) {
+	assert.NoError(t, validateKeyfileContents("RuPeMaIe2g0SNTTa"))
+	assert.NoError(t, validateKeyfileContents("RuPeMa\nIe2g0S NTTa=\n"))
+	assert.Error(t, validateKeyfileContents("short"))
+	assert.Error(t, validateKeyfileContents(strings.Repeat("a", 1025)))
+	assert.Error(t, validateKeyfileContents("RuPeMaIe2g0SNTTa!"))
+}
+
+func TestKeyfileKeys(t *testing.T) {
+	assert.Empty(t, KeyfileKeys(""))
+	assert.Equal(t, []string{"RuPeMaIe2g0SNTTa"}, KeyfileKeys("RuPeMa\nIe2g0S NTTa\n"))
+	assert.Equal(t, []string{"RuPeMaIe2g0SNTTa", "oldKeyfile"}, KeyfileKeys("- RuPeMaIe2g0SNTTa\n- oldKeyfile\n"))
+}
+
+func TestKeyfileContents(t *testing.T) {
+	assert.Equal(t, "RuPeMaIe2g0SNTTa", KeyfileContents("RuPeMaIe2g0SNTTa"))
+	assert.Equal(t, "- RuPeMaIe2g0SNTTa\n- oldKeyfile\n", KeyfileContents("RuPeMaIe2g0SNTTa", "oldKeyfile"))
+	assert.Equal(t, []string{"RuPeMaIe2g0SNTTa", "oldKeyfile"}, KeyfileKeys(KeyfileContents("RuPeMaIe2g0SNTTa", "oldKeyfile")))
 }
 
 func buildConfigurable(name string, users ...User) Configurable {
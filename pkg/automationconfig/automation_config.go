@@ -3,15 +3,24 @@ package automationconfig
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scramcredentials"
 	"github.com/stretchr/objx"
 )
 
 const (
-	Mongod                ProcessType = "mongod"
-	DefaultMongoDBDataDir string      = "/data"
-	DefaultAgentLogPath   string      = "/var/log/mongodb-mms-automation"
+	Mongod                 ProcessType = "mongod"
+	DefaultMongoDBDataDir  string      = "/data"
+	DefaultAgentLogPath    string      = "/var/log/mongodb-mms-automation"
+	DefaultDownloadBase    string      = "/var/lib/mongodb-mms-automation"
+	DefaultProtocolVersion string      = "1"
+	// MongodLogFileName is the name of the log file each mongod writes into the log directory.
+	MongodLogFileName string = "mongodb.log"
+	// DefaultDBPort is the port mongod listens on, unless it is changed in the mongod configuration.
+	DefaultDBPort int = 27017
 )
 
 type AutomationConfig struct {
@@ -30,6 +39,75 @@ type AutomationConfig struct {
 	MonitoringVersions []MonitoringVersion    `json:"monitoringVersions"`
 	Options            Options                `json:"options"`
 	Roles              []CustomRole           `json:"roles,omitempty"`
+
+	// AdditionalFields holds the top-level fields which have no field in the struct, e.g. fields supported by newer
+	// agents. They are serialized next to the other fields, additional fields named after one of them are ignored.
+	AdditionalFields map[string]interface{} `json:"-"`
+}
+
+// automationConfigFields is used to serialize the fields of the AutomationConfig without its custom (un)marshalling.
+type automationConfigFields AutomationConfig
+
+// MarshalJSON serializes the AutomationConfig together with its additional fields.
+func (ac AutomationConfig) MarshalJSON() ([]byte, error) {
+	acBytes, err := json.Marshal(automationConfigFields(ac))
+	if err != nil || len(ac.AdditionalFields) == 0 {
+		return acBytes, err
+	}
+
+	fields := map[string]interface{}{}
+	known := knownFields()
+	for name, value := range ac.AdditionalFields {
+		if !known[name] {
+			fields[name] = value
+		}
+	}
+	knownValues := map[string]json.RawMessage{}
+	if err := json.Unmarshal(acBytes, &knownValues); err != nil {
+		return nil, err
+	}
+	for name, value := range knownValues {
+		fields[name] = value
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON deserializes the AutomationConfig, the top-level fields it has no field for are kept in AdditionalFields.
+func (ac *AutomationConfig) UnmarshalJSON(data []byte) error {
+	fields := automationConfigFields{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// numbers are kept as they are, so that the additional fields are serialized back unchanged
+	decoder.UseNumber()
+	all := map[string]interface{}{}
+	if err := decoder.Decode(&all); err != nil {
+		return err
+	}
+	for name := range knownFields() {
+		delete(all, name)
+	}
+	if len(all) > 0 {
+		fields.AdditionalFields = all
+	}
+
+	*ac = AutomationConfig(fields)
+	return nil
+}
+
+// knownFields returns the names of the top-level fields the AutomationConfig has a field for.
+func knownFields() map[string]bool {
+	known := map[string]bool{}
+	acType := reflect.TypeOf(automationConfigFields{})
+	for i := 0; i < acType.NumField(); i++ {
+		name := strings.Split(acType.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			known[name] = true
+		}
+	}
+	return known
 }
 
 type BackupVersion struct {
@@ -51,12 +129,30 @@ type Process struct {
 	ProcessType                 ProcessType `json:"processType"`
 	Version                     string      `json:"version"`
 	AuthSchemaVersion           int         `json:"authSchemaVersion"`
+
+	// ManualMode is true if the agent only monitors the process, which is then configured and started by the user.
+	ManualMode bool `json:"manualMode,omitempty"`
+
+	// Disabled is true if the agent shuts the process down and keeps it stopped.
+	Disabled bool `json:"disabled,omitempty"`
 }
 
 func (p *Process) SetPort(port int) *Process {
 	return p.SetArgs26Field("net.port", port)
 }
 
+// Port returns the port the process listens on, which is the default port if it is not configured.
+func (p Process) Port() int {
+	switch port := p.Args26.Get("net.port").Data().(type) {
+	case int:
+		return port
+	case float64:
+		// the port is a float64 once the automation config has been read from JSON.
+		return int(port)
+	}
+	return DefaultDBPort
+}
+
 func (p *Process) SetStoragePath(storagePath string) *Process {
 	return p.SetArgs26Field("storage.dbPath", storagePath)
 }
@@ -68,7 +164,21 @@ func (p *Process) SetReplicaSetName(replSetName string) *Process {
 func (p *Process) SetSystemLog(systemLog SystemLog) *Process {
 	return p.SetArgs26Field("systemLog.path", systemLog.Path).
 		SetArgs26Field("systemLog.destination", systemLog.Destination).
-		SetArgs26Field("systemLog.logAppend", systemLog.LogAppend)
+		SetArgs26Field("systemLog.logAppend", systemLog.LogAppend).
+		SetLogVerbosity(systemLog.Verbosity, systemLog.Component)
+}
+
+// SetLogVerbosity sets the default verbosity of the logs and the verbosity of individual log components,
+// e.g. "replication" or "storage.journal". A default verbosity of 0, which is the default of mongod, is not
+// set so that the process is unchanged unless a verbosity is configured.
+func (p *Process) SetLogVerbosity(verbosity int, componentVerbosity map[string]int) *Process {
+	if verbosity != 0 {
+		p.SetArgs26Field("systemLog.verbosity", verbosity)
+	}
+	for component, level := range componentVerbosity {
+		p.SetArgs26Field(fmt.Sprintf("systemLog.component.%s.verbosity", component), level)
+	}
+	return p
 }
 
 func (p *Process) SetWiredTigerCache(cacheSizeGb *float32) *Process {
@@ -78,6 +188,65 @@ func (p *Process) SetWiredTigerCache(cacheSizeGb *float32) *Process {
 	return p.SetArgs26Field("storage.wiredTiger.engineConfig.cacheSizeGB", cacheSizeGb)
 }
 
+// SetJournalCommitInterval sets the maximum number of milliseconds between journal operations,
+// the default of mongod is kept if it is 0.
+func (p *Process) SetJournalCommitInterval(commitIntervalMs int) *Process {
+	if commitIntervalMs == 0 {
+		return p
+	}
+	return p.SetArgs26Field("storage.journal.commitIntervalMs", commitIntervalMs)
+}
+
+// SetSyncPeriod sets the number of seconds between flushes of the data to disk,
+// the default of mongod is kept if it is 0.
+func (p *Process) SetSyncPeriod(syncPeriodSecs int) *Process {
+	if syncPeriodSecs == 0 {
+		return p
+	}
+	return p.SetArgs26Field("storage.syncPeriodSecs", syncPeriodSecs)
+}
+
+// SetMaxIncomingConnections sets the maximum number of connections the process accepts,
+// the default of mongod is kept if it is 0.
+func (p *Process) SetMaxIncomingConnections(maxConnections int) *Process {
+	if maxConnections == 0 {
+		return p
+	}
+	return p.SetArgs26Field("net.maxIncomingConnections", maxConnections)
+}
+
+// SetOperationProfiling sets the mode of the database profiler and the slow operation threshold,
+// the defaults of mongod are kept for the settings which are empty.
+func (p *Process) SetOperationProfiling(mode string, slowOpThresholdMs int) *Process {
+	if mode != "" {
+		p.SetArgs26Field("operationProfiling.mode", mode)
+	}
+	if slowOpThresholdMs != 0 {
+		p.SetArgs26Field("operationProfiling.slowOpThresholdMs", slowOpThresholdMs)
+	}
+	return p
+}
+
+// DisableFreeMonitoring turns off the free cloud monitoring of the process. As it is part of the process
+// configuration, it stays off across restarts, unlike disabling it at runtime.
+func (p *Process) DisableFreeMonitoring() *Process {
+	return p.SetArgs26Field("cloud.monitoring.free.state", "off")
+}
+
+// DisableDiagnosticDataCollection turns off the full time diagnostic data capture (FTDC) of the process.
+func (p *Process) DisableDiagnosticDataCollection() *Process {
+	return p.SetArgs26Field("setParameter.diagnosticDataCollectionEnabled", false)
+}
+
+// SetDiagnosticDataCollectionPeriod sets the interval at which the diagnostic data is captured,
+// the default of mongod is kept if it is 0.
+func (p *Process) SetDiagnosticDataCollectionPeriod(periodMillis int) *Process {
+	if periodMillis == 0 {
+		return p
+	}
+	return p.SetArgs26Field("setParameter.diagnosticDataCollectionPeriodMillis", periodMillis)
+}
+
 // SetArgs26Field should be used whenever any args26 field needs to be set. It ensures
 // that the args26 map is non nil and assigns the given value.
 func (p *Process) SetArgs26Field(fieldName string, value interface{}) *Process {
@@ -107,6 +276,10 @@ type SystemLog struct {
 	Destination string `json:"destination"`
 	Path        string `json:"path"`
 	LogAppend   bool   `json:"logAppend"`
+	// Verbosity is the default verbosity of all log components, 0 by default.
+	Verbosity int `json:"verbosity,omitempty"`
+	// Component holds the verbosity of individual log components, keyed by the component name.
+	Component map[string]int `json:"component,omitempty"`
 }
 
 type WiredTiger struct {
@@ -122,28 +295,43 @@ type ReplicaSet struct {
 	Members         []ReplicaSetMember `json:"members"`
 	ProtocolVersion string             `json:"protocolVersion"`
 	NumberArbiters  int                `json:"numberArbiters"`
+
+	// WriteConcernMajorityJournalDefault configures whether w:majority writes wait for the journal of the members,
+	// the default of mongod, true, applies if it is not set.
+	WriteConcernMajorityJournalDefault *bool `json:"writeConcernMajorityJournalDefault,omitempty"`
 }
 
 type ReplicaSetMember struct {
-	Id          int                `json:"_id"`
-	Host        string             `json:"host"`
-	Priority    int                `json:"priority"`
-	ArbiterOnly bool               `json:"arbiterOnly"`
-	Votes       int                `json:"votes"`
-	Horizons    ReplicaSetHorizons `json:"horizons,omitempty"`
+	Id           int                `json:"_id"`
+	Host         string             `json:"host"`
+	Priority     int                `json:"priority"`
+	ArbiterOnly  bool               `json:"arbiterOnly"`
+	Votes        int                `json:"votes"`
+	Hidden       bool               `json:"hidden,omitempty"`
+	BuildIndexes *bool              `json:"buildIndexes,omitempty"`
+	Horizons     ReplicaSetHorizons `json:"horizons,omitempty"`
+}
+
+// MemberOptions holds the settings of a data-bearing replica set member which can be configured per member.
+type MemberOptions struct {
+	// Hidden hides the member from the clients, a hidden member has priority 0.
+	Hidden bool
+	// BuildIndexes disables the index builds of the member when set to false.
+	BuildIndexes *bool
 }
 
 type ReplicaSetHorizons map[string]string
 
 func newReplicaSetMember(p Process, id int, horizons ReplicaSetHorizons, totalVotesSoFar int, numberArbiters int) ReplicaSetMember {
 	// ensure that the number of voting members in the replica set is not more than 7
-	// as this is the maximum number of voting members.
+	// as this is the maximum number of voting members. Any further member is added as
+	// a non-voting member, which must also have a priority of 0.
 	votes := 1
 	priority := 1
 
 	isArbiter := totalVotesSoFar < numberArbiters
 
-	if totalVotesSoFar > maxVotingMembers {
+	if totalVotesSoFar >= maxVotingMembers {
 		votes = 0
 		priority = 0
 	}
@@ -158,6 +346,18 @@ func newReplicaSetMember(p Process, id int, horizons ReplicaSetHorizons, totalVo
 	}
 }
 
+// setOptions applies the per member options to the member.
+func (m *ReplicaSetMember) setOptions(options MemberOptions) {
+	if options.Hidden {
+		m.Hidden = true
+		m.Priority = 0
+	}
+	if options.BuildIndexes != nil && !*options.BuildIndexes {
+		buildIndexes := false
+		m.BuildIndexes = &buildIndexes
+	}
+}
+
 type Auth struct {
 	// Users is a list which contains the desired users at the project level.
 	Users    []MongoDBUser `json:"usersWanted,omitempty"`
@@ -226,7 +426,8 @@ type Role struct {
 	Database string `json:"db"`
 }
 
-func disabledAuth() Auth {
+// DisabledAuth returns the Auth configuration for a deployment without authentication.
+func DisabledAuth() Auth {
 	return Auth{
 		Users:                    make([]MongoDBUser, 0),
 		AutoAuthMechanisms:       make([]string, 0),
@@ -2,6 +2,7 @@ package automationconfig
 
 import (
 	"fmt"
+	"path"
 	"reflect"
 	"strings"
 
@@ -18,35 +19,59 @@ const (
 	maxVotingMembers   int      = 7
 )
 
+// Modification changes the AutomationConfig once it has been built from the settings of the Builder. Modifications
+// are applied in the order they have been added, before the AutomationConfig is compared with the previous one,
+// so the changes they make increment its version.
 type Modification func(*AutomationConfig)
 
 func NOOP() Modification {
 	return func(config *AutomationConfig) {}
 }
 
+// SetAdditionalField returns a Modification which sets the top-level field with the given name, for the fields
+// the AutomationConfig has no field for.
+func SetAdditionalField(name string, value interface{}) Modification {
+	return func(config *AutomationConfig) {
+		if config.AdditionalFields == nil {
+			config.AdditionalFields = map[string]interface{}{}
+		}
+		config.AdditionalFields[name] = value
+	}
+}
+
 type Builder struct {
-	processes          []Process
-	replicaSets        []ReplicaSet
-	replicaSetHorizons []ReplicaSetHorizons
-	members            int
-	arbiters           int
-	domain             string
-	name               string
-	fcv                string
-	topology           Topology
-	mongodbVersion     string
-	previousAC         AutomationConfig
+	processes                          []Process
+	replicaSets                        []ReplicaSet
+	replicaSetHorizons                 []ReplicaSetHorizons
+	memberOptions                      []MemberOptions
+	members                            int
+	arbiters                           int
+	arbiterMembers                     int
+	arbiterName                        string
+	arbiterDomain                      string
+	domain                             string
+	name                               string
+	replicaSetId                       string
+	fcv                                string
+	protocolVersion                    string
+	writeConcernMajorityJournalDefault *bool
+	topology                           Topology
+	mongodbVersion                     string
+	dataDir                            string
+	logDir                             string
+	previousAC                         AutomationConfig
 	// MongoDB installable versions
-	versions             []MongoDbVersionConfig
-	backupVersions       []BackupVersion
-	monitoringVersions   []MonitoringVersion
-	options              Options
-	processModifications []func(int, *Process)
-	modifications        []Modification
-	auth                 *Auth
-	cafilePath           string
-	sslConfig            *TLS
-	tlsConfig            *TLS
+	versions              []MongoDbVersionConfig
+	backupVersions        []BackupVersion
+	monitoringVersions    []MonitoringVersion
+	options               Options
+	processModifications  []func(int, *Process)
+	modifications         []Modification
+	auth                  *Auth
+	cafilePath            string
+	clientCertificateMode ClientCertificateMode
+	sslConfig             *TLS
+	tlsConfig             *TLS
 }
 
 func NewBuilder() *Builder {
@@ -58,6 +83,7 @@ func NewBuilder() *Builder {
 		backupVersions:       []BackupVersion{},
 		monitoringVersions:   []MonitoringVersion{},
 		processModifications: []func(int, *Process){},
+		protocolVersion:      DefaultProtocolVersion,
 		tlsConfig:            nil,
 		sslConfig:            nil,
 	}
@@ -68,16 +94,41 @@ func (b *Builder) SetOptions(options Options) *Builder {
 	return b
 }
 
+// SetDownloadBase sets the directory the agent downloads the MongoDB binaries into,
+// DefaultDownloadBase is used if it is not set.
+func (b *Builder) SetDownloadBase(downloadBase string) *Builder {
+	b.options.DownloadBase = downloadBase
+	return b
+}
+
 func (b *Builder) SetTopology(topology Topology) *Builder {
 	b.topology = topology
 	return b
 }
 
+// SetDataDir sets the dbPath of each process, DefaultMongoDBDataDir is used if it is not set.
+func (b *Builder) SetDataDir(dataDir string) *Builder {
+	b.dataDir = dataDir
+	return b
+}
+
+// SetLogDir sets the directory each process writes its log file into, DefaultAgentLogPath is used if it is not set.
+func (b *Builder) SetLogDir(logDir string) *Builder {
+	b.logDir = logDir
+	return b
+}
+
 func (b *Builder) SetReplicaSetHorizons(horizons []ReplicaSetHorizons) *Builder {
 	b.replicaSetHorizons = horizons
 	return b
 }
 
+// SetMemberOptions sets the options of the data-bearing members, the n-th options apply to the n-th member.
+func (b *Builder) SetMemberOptions(memberOptions []MemberOptions) *Builder {
+	b.memberOptions = memberOptions
+	return b
+}
+
 func (b *Builder) SetTLSConfig(tlsConfig TLS) *Builder {
 	b.tlsConfig = &tlsConfig
 	return b
@@ -98,6 +149,16 @@ func (b *Builder) SetArbiters(arbiters int) *Builder {
 	return b
 }
 
+// SetArbiterMembers configures arbiters which are not part of the data-bearing members, e.g. because
+// they are deployed in their own StatefulSet. They are added after the members, with process names
+// derived from the given name and hostnames in the given domain.
+func (b *Builder) SetArbiterMembers(name, domain string, arbiters int) *Builder {
+	b.arbiterName = name
+	b.arbiterDomain = domain
+	b.arbiterMembers = arbiters
+	return b
+}
+
 func (b *Builder) SetDomain(domain string) *Builder {
 	b.domain = domain
 	return b
@@ -108,16 +169,43 @@ func (b *Builder) SetName(name string) *Builder {
 	return b
 }
 
+// SetReplicaSetId sets the id of the replica set, which is used as the replSetName of each process.
+// The processes are still named after the name of the deployment. Defaults to the name if it is not set.
+func (b *Builder) SetReplicaSetId(replicaSetId string) *Builder {
+	b.replicaSetId = replicaSetId
+	return b
+}
+
 func (b *Builder) SetFCV(fcv string) *Builder {
 	b.fcv = fcv
 	return b
 }
 
+// SetProtocolVersion sets the protocol version of the replica set, DefaultProtocolVersion is used if it is not set.
+func (b *Builder) SetProtocolVersion(protocolVersion string) *Builder {
+	b.protocolVersion = protocolVersion
+	return b
+}
+
+// SetWriteConcernMajorityJournalDefault sets writeConcernMajorityJournalDefault of the replica set, the default of
+// mongod applies if it is nil.
+func (b *Builder) SetWriteConcernMajorityJournalDefault(journalDefault *bool) *Builder {
+	b.writeConcernMajorityJournalDefault = journalDefault
+	return b
+}
+
 func (b *Builder) SetCAFilePath(caFilePath string) *Builder {
 	b.cafilePath = caFilePath
 	return b
 }
 
+// SetClientCertificateMode configures if client certificates are required for the connections
+// between the processes, it defaults to ClientCertificateModeOptional.
+func (b *Builder) SetClientCertificateMode(mode ClientCertificateMode) *Builder {
+	b.clientCertificateMode = mode
+	return b
+}
+
 func (b *Builder) AddVersions(versions []MongoDbVersionConfig) *Builder {
 	for _, v := range versions {
 		b.AddVersion(v)
@@ -165,6 +253,7 @@ func (b *Builder) AddProcessModification(f func(int, *Process)) *Builder {
 	return b
 }
 
+// AddModifications adds Modifications which are applied to the AutomationConfig after it has been built.
 func (b *Builder) AddModifications(mod ...Modification) *Builder {
 	b.modifications = append(b.modifications, mod...)
 	return b
@@ -197,24 +286,56 @@ func (b *Builder) setFeatureCompatibilityVersionIfUpgradeIsHappening() error {
 	return nil
 }
 
+// Build returns the AutomationConfig, its version is incremented if it differs from the previous AutomationConfig.
 func (b *Builder) Build() (AutomationConfig, error) {
-	hostnames := make([]string, b.members)
+	currentAc, err := b.BuildWithoutVersioning()
+	if err != nil {
+		return AutomationConfig{}, err
+	}
+	return bumpVersionIfChanged(b.previousAC, currentAc)
+}
+
+// BuildWithoutVersioning returns the AutomationConfig with the version of the previous AutomationConfig,
+// so that it can be compared with the previous AutomationConfig without deciding whether it is a new version.
+func (b *Builder) BuildWithoutVersioning() (AutomationConfig, error) {
+	totalMembers := b.members + b.arbiterMembers
+	processNames := make([]string, totalMembers)
 	for i := 0; i < b.members; i++ {
-		hostnames[i] = fmt.Sprintf("%s-%d.%s", b.name, i, b.domain)
+		processNames[i] = toProcessName(b.name, i)
+	}
+	for i := 0; i < b.arbiterMembers; i++ {
+		processNames[b.members+i] = toProcessName(b.arbiterName, i)
 	}
 
-	members := make([]ReplicaSetMember, b.members)
-	processes := make([]Process, b.members)
+	members := make([]ReplicaSetMember, totalMembers)
+	processes := make([]Process, totalMembers)
 
 	if err := b.setFeatureCompatibilityVersionIfUpgradeIsHappening(); err != nil {
 		return AutomationConfig{}, errors.Errorf("can't build the automation config: %s", err)
 	}
+	dataDir := b.dataDir
+	if dataDir == "" {
+		dataDir = DefaultMongoDBDataDir
+	}
+	logDir := b.logDir
+	if logDir == "" {
+		logDir = DefaultAgentLogPath
+	}
+	replicaSetId := b.replicaSetId
+	if replicaSetId == "" {
+		replicaSetId = b.name
+	}
+
 	totalVotes := 0
-	for i, h := range hostnames {
+	for i, processName := range processNames {
+		domain := b.domain
+		if i >= b.members {
+			domain = b.arbiterDomain
+		}
 
 		process := &Process{
-			Name:                        toProcessName(b.name, i),
-			HostName:                    h,
+			Name:                        processName,
+			HostName:                    fmt.Sprintf("%s.%s", processName, domain),
 			FeatureCompatibilityVersion: versions.CalculateFeatureCompatibilityVersion(b.mongodbVersion),
 			ProcessType:                 Mongod,
 			Version:                     b.mongodbVersion,
@@ -225,9 +346,14 @@ func (b *Builder) Build() (AutomationConfig, error) {
 			process.FeatureCompatibilityVersion = b.fcv
 		}
 
-		process.SetPort(27017)
-		process.SetStoragePath(DefaultMongoDBDataDir)
-		process.SetReplicaSetName(b.name)
+		process.SetPort(DefaultDBPort)
+		process.SetStoragePath(dataDir)
+		process.SetSystemLog(SystemLog{
+			Destination: "file",
+			Path:        path.Join(logDir, MongodLogFileName),
+			LogAppend:   true,
+		})
+		process.SetReplicaSetName(replicaSetId)
 
 		for _, mod := range b.processModifications {
 			mod(i, process)
@@ -235,20 +361,34 @@ func (b *Builder) Build() (AutomationConfig, error) {
 
 		processes[i] = *process
 
-		if b.replicaSetHorizons != nil {
+		if b.replicaSetHorizons != nil && i < len(b.replicaSetHorizons) {
 			members[i] = newReplicaSetMember(*process, i, b.replicaSetHorizons[i], totalVotes, b.arbiters)
 		} else {
 			members[i] = newReplicaSetMember(*process, i, nil, totalVotes, b.arbiters)
 		}
+		if i >= b.members {
+			members[i].ArbiterOnly = true
+		}
+		if i < len(b.memberOptions) && !members[i].ArbiterOnly {
+			members[i].setOptions(b.memberOptions[i])
+		}
 		totalVotes += members[i].Votes
 
 	}
 
 	if b.auth == nil {
-		disabled := disabledAuth()
+		disabled := DisabledAuth()
 		b.auth = &disabled
 	}
 
+	if b.clientCertificateMode == "" {
+		b.clientCertificateMode = ClientCertificateModeOptional
+	}
+
+	if b.options.DownloadBase == "" {
+		b.options.DownloadBase = DefaultDownloadBase
+	}
+
 	dummyConfig := buildDummyMongoDbVersionConfig(b.mongodbVersion)
 	if !versionsContain(b.versions, dummyConfig) {
 		b.versions = append(b.versions, dummyConfig)
@@ -259,9 +399,11 @@ func (b *Builder) Build() (AutomationConfig, error) {
 		Processes: processes,
 		ReplicaSets: []ReplicaSet{
 			{
-				Id:              b.name,
+				Id:              replicaSetId,
 				Members:         members,
-				ProtocolVersion: "1",
+				ProtocolVersion: b.protocolVersion,
+
+				WriteConcernMajorityJournalDefault: b.writeConcernMajorityJournalDefault,
 			},
 		},
 		MonitoringVersions: b.monitoringVersions,
@@ -270,7 +412,7 @@ func (b *Builder) Build() (AutomationConfig, error) {
 		Options:            b.options,
 		Auth:               *b.auth,
 		TLSConfig: &TLS{
-			ClientCertificateMode: ClientCertificateModeOptional,
+			ClientCertificateMode: b.clientCertificateMode,
 			CAFilePath:            b.cafilePath,
 		},
 	}
@@ -288,7 +430,12 @@ func (b *Builder) Build() (AutomationConfig, error) {
 		modification(&currentAc)
 	}
 
-	areEqual, err := AreEqual(b.previousAC, currentAc)
+	return currentAc, nil
+}
+
+// bumpVersionIfChanged increments the version of the current AutomationConfig if it differs from the previous one.
+func bumpVersionIfChanged(previousAc, currentAc AutomationConfig) (AutomationConfig, error) {
+	areEqual, err := AreEqual(previousAc, currentAc)
 	if err != nil {
 		return AutomationConfig{}, err
 	}
@@ -1,11 +1,16 @@
 package automationconfig
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 
+	"go.uber.org/zap"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -13,6 +18,40 @@ import (
 
 const ConfigKey = "cluster-config.json"
 
+// ChecksumAnnotation is set on the AutomationConfig Secret to the checksum of the config written by the operator,
+// a different checksum of the current contents means that the config has been edited by someone else.
+const ChecksumAnnotation = "mongodb.com/automation-config-checksum"
+
+// Checksum returns the hex encoded sha256 digest of the serialized AutomationConfig.
+func Checksum(acBytes []byte) string {
+	sum := sha256.Sum256(acBytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// HasBeenEditedExternally returns true if the contents of the AutomationConfig Secret no longer match the checksum
+// written by the operator. Secrets without the annotation have not been written by this version of the operator
+// and are not considered to have been edited.
+func HasBeenEditedExternally(acSecret corev1.Secret) bool {
+	checksum, ok := acSecret.Annotations[ChecksumAnnotation]
+	if !ok {
+		return false
+	}
+	return checksum != Checksum(acSecret.Data[ConfigKey])
+}
+
+// MaxSizeBytes is the largest serialized AutomationConfig which will be written to the Secret.
+// Kubernetes rejects Secrets larger than 1MiB, some headroom is left for the remaining fields of the object.
+const MaxSizeBytes = 1000 * 1024
+
+// validateSize returns an error if the serialized AutomationConfig would not fit into a Secret.
+func validateSize(acBytes []byte) error {
+	if len(acBytes) > MaxSizeBytes {
+		return fmt.Errorf("the automation config is %d bytes which exceeds the maximum of %d bytes that can be stored in a Secret, "+
+			"consider reducing the number of users, roles or inline mongoDbVersions", len(acBytes), MaxSizeBytes)
+	}
+	return nil
+}
+
 // ReadFromSecret returns the AutomationConfig present in the given Secret. If the Secret is not
 // found, it is not considered an error and an empty AutomationConfig is returned.
 func ReadFromSecret(secretGetter secret.Getter, secretNsName types.NamespacedName) (AutomationConfig, error) {
@@ -24,7 +63,9 @@ func ReadFromSecret(secretGetter secret.Getter, secretNsName types.NamespacedNam
 }
 
 // EnsureSecret makes sure that the AutomationConfig secret exists with the desired config.
-// if the desired config is the same as the current contents, no change is made.
+// if the desired config is the same as the current contents, no change is made. The config is always overwritten if
+// the contents don't match the checksum annotation, i.e. they have been edited externally or have not been annotated yet.
+// Externally edited contents are overwritten without being parsed, and a warning is logged.
 // The most recent AutomationConfig is returned. If no change is made, it will return the existing one, if there
 // is a change, the new AutomationConfig is returned.
 func EnsureSecret(secretGetUpdateCreator secret.GetUpdateCreator, secretNsName types.NamespacedName, owner []metav1.OwnerReference, desiredAutomationConfig AutomationConfig) (AutomationConfig, error) {
@@ -40,9 +81,15 @@ func EnsureSecret(secretGetUpdateCreator secret.GetUpdateCreator, secretNsName t
 	if err != nil {
 		return AutomationConfig{}, err
 	}
+	if err := validateSize(acBytes); err != nil {
+		return AutomationConfig{}, err
+	}
 	if existingAcBytes, ok := existingSecret.Data[ConfigKey]; !ok {
 		// the secret exists but the key is not present. We can update the secret
 		existingSecret.Data[ConfigKey] = acBytes
+	} else if HasBeenEditedExternally(existingSecret) {
+		zap.S().Warnf("The automation config in Secret %s has been edited externally, the changes will be overwritten", secretNsName)
+		existingSecret.Data[ConfigKey] = acBytes
 	} else {
 		// the secret already exists, we should check to see if we're making any changes.
 		existingAutomationConfig, err := FromBytes(existingAcBytes)
@@ -54,12 +101,16 @@ func EnsureSecret(secretGetUpdateCreator secret.GetUpdateCreator, secretNsName t
 		if err != nil {
 			return AutomationConfig{}, err
 		}
-		if areEqual {
+		if areEqual && existingSecret.Annotations[ChecksumAnnotation] == Checksum(existingAcBytes) {
 			return existingAutomationConfig, nil
 		}
 		existingSecret.Data[ConfigKey] = acBytes
 	}
 
+	if existingSecret.Annotations == nil {
+		existingSecret.Annotations = map[string]string{}
+	}
+	existingSecret.Annotations[ChecksumAnnotation] = Checksum(acBytes)
 	return desiredAutomationConfig, secretGetUpdateCreator.UpdateSecret(existingSecret)
 }
 
@@ -68,11 +119,15 @@ func createNewAutomationConfigSecret(secretGetUpdateCreator secret.GetUpdateCrea
 	if err != nil {
 		return AutomationConfig{}, err
 	}
+	if err := validateSize(acBytes); err != nil {
+		return AutomationConfig{}, err
+	}
 
 	newSecret := secret.Builder().
 		SetName(secretNsName.Name).
 		SetNamespace(secretNsName.Namespace).
 		SetField(ConfigKey, string(acBytes)).
+		SetAnnotations(map[string]string{ChecksumAnnotation: Checksum(acBytes)}).
 		SetOwnerReferences(owner).
 		Build()
 
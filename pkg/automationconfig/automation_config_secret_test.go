@@ -2,6 +2,7 @@ package automationconfig
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
@@ -56,6 +57,58 @@ func TestEnsureSecret(t *testing.T) {
 
 	})
 
+	t.Run("The checksum of the Automation Config is annotated", func(t *testing.T) {
+		secretGetUpdateCreator := &mockSecretGetUpdateCreator{}
+		_, err := EnsureSecret(secretGetUpdateCreator, secretNsName, []metav1.OwnerReference{}, desiredAutomationConfig)
+		assert.NoError(t, err)
+
+		acSecret := *secretGetUpdateCreator.secret
+		assert.Equal(t, Checksum(acSecret.Data[ConfigKey]), acSecret.Annotations[ChecksumAnnotation])
+		assert.False(t, HasBeenEditedExternally(acSecret))
+	})
+
+	t.Run("When the Automation Config has been edited externally, it is overwritten", func(t *testing.T) {
+		secretGetUpdateCreator := &mockSecretGetUpdateCreator{}
+		_, err := EnsureSecret(secretGetUpdateCreator, secretNsName, []metav1.OwnerReference{}, desiredAutomationConfig)
+		assert.NoError(t, err)
+		originalBytes := secretGetUpdateCreator.secret.Data[ConfigKey]
+
+		// an equivalent config which is serialized differently is still considered an external edit.
+		secretGetUpdateCreator.secret.Data[ConfigKey] = append([]byte(" "), originalBytes...)
+		assert.True(t, HasBeenEditedExternally(*secretGetUpdateCreator.secret))
+
+		_, err = EnsureSecret(secretGetUpdateCreator, secretNsName, []metav1.OwnerReference{}, desiredAutomationConfig)
+		assert.NoError(t, err)
+		assert.Equal(t, originalBytes, secretGetUpdateCreator.secret.Data[ConfigKey])
+		assert.False(t, HasBeenEditedExternally(*secretGetUpdateCreator.secret))
+	})
+
+	t.Run("When the Automation Config has been edited externally, it is not parsed before being overwritten", func(t *testing.T) {
+		secretGetUpdateCreator := &mockSecretGetUpdateCreator{}
+		_, err := EnsureSecret(secretGetUpdateCreator, secretNsName, []metav1.OwnerReference{}, desiredAutomationConfig)
+		assert.NoError(t, err)
+		originalBytes := secretGetUpdateCreator.secret.Data[ConfigKey]
+
+		secretGetUpdateCreator.secret.Data[ConfigKey] = []byte("not json")
+
+		_, err = EnsureSecret(secretGetUpdateCreator, secretNsName, []metav1.OwnerReference{}, desiredAutomationConfig)
+		assert.NoError(t, err)
+		assert.Equal(t, originalBytes, secretGetUpdateCreator.secret.Data[ConfigKey])
+	})
+
+	t.Run("When the Automation Config is too large, a descriptive error is returned", func(t *testing.T) {
+		largeAc, err := newAutomationConfigBuilder().
+			AddVersion(MongoDbVersionConfig{Name: strings.Repeat("a", MaxSizeBytes)}).
+			Build()
+		assert.NoError(t, err)
+
+		secretGetUpdateCreator := &mockSecretGetUpdateCreator{}
+		_, err = EnsureSecret(secretGetUpdateCreator, secretNsName, []metav1.OwnerReference{}, largeAc)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "exceeds the maximum")
+		assert.Nil(t, secretGetUpdateCreator.secret, "The secret should not have been created.")
+	})
+
 }
 func newAutomationConfig() (AutomationConfig, error) {
 	return NewBuilder().Build()
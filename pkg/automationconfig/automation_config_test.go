@@ -1,6 +1,7 @@
 package automationconfig
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -62,6 +63,145 @@ func TestBuildAutomationConfig(t *testing.T) {
 	}
 }
 
+func TestBuildAutomationConfig_ReplicaSetId(t *testing.T) {
+	builder := func() *Builder {
+		return NewBuilder().
+			SetName("my-deployment").
+			SetDomain("my-ns.svc.cluster.local").
+			SetMongoDBVersion("4.2.0").
+			SetMembers(3).
+			SetFCV("4.0")
+	}
+
+	ac, err := builder().SetReplicaSetId("my-rs").Build()
+	assert.NoError(t, err)
+
+	assert.Len(t, ac.ReplicaSets, 1)
+	assert.Equal(t, "my-rs", ac.ReplicaSets[0].Id)
+	for i, p := range ac.Processes {
+		assert.Equal(t, toProcessName("my-deployment", i), p.Name, "the processes should still be named after the deployment")
+		assert.Equal(t, fmt.Sprintf("my-deployment-%d.my-ns.svc.cluster.local", i), p.HostName)
+		assert.Equal(t, "my-rs", p.Args26.Get("replication.replSetName").Data())
+		assert.Equal(t, p.Name, ac.ReplicaSets[0].Members[i].Host)
+	}
+
+	t.Run("The name is used if no id is set", func(t *testing.T) {
+		ac, err := builder().Build()
+		assert.NoError(t, err)
+		assert.Equal(t, "my-deployment", ac.ReplicaSets[0].Id)
+		assert.Equal(t, "my-deployment", ac.Processes[0].Args26.Get("replication.replSetName").Data())
+	})
+
+	t.Run("Changing the id increments the version", func(t *testing.T) {
+		changedAc, err := builder().SetReplicaSetId("my-other-rs").SetPreviousAutomationConfig(ac).Build()
+		assert.NoError(t, err)
+		assert.Equal(t, ac.Version+1, changedAc.Version)
+
+		sameAc, err := builder().SetReplicaSetId("my-rs").SetPreviousAutomationConfig(ac).Build()
+		assert.NoError(t, err)
+		assert.Equal(t, ac.Version, sameAc.Version)
+	})
+}
+
+func TestBuildAutomationConfig_MemberOptions(t *testing.T) {
+	buildIndexes, noBuildIndexes := true, false
+	ac, err := NewBuilder().
+		SetName("my-deployment").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMongoDBVersion("4.2.0").
+		SetMembers(4).
+		SetArbiters(1).
+		SetFCV("4.0").
+		SetMemberOptions([]MemberOptions{
+			{Hidden: true, BuildIndexes: &noBuildIndexes},
+			{},
+			{Hidden: true, BuildIndexes: &buildIndexes},
+			{Hidden: true, BuildIndexes: &noBuildIndexes},
+		}).
+		Build()
+	assert.NoError(t, err)
+
+	members := ac.ReplicaSets[0].Members
+	assert.True(t, members[0].ArbiterOnly)
+	assert.False(t, members[0].Hidden, "the options should not be applied to arbiters")
+	assert.Nil(t, members[0].BuildIndexes)
+
+	assert.False(t, members[1].Hidden)
+	assert.Equal(t, 1, members[1].Priority)
+
+	assert.True(t, members[2].Hidden)
+	assert.Equal(t, 0, members[2].Priority, "hidden members should have priority 0")
+	assert.Equal(t, 1, members[2].Votes)
+
+	assert.True(t, members[3].Hidden)
+	assert.Equal(t, 0, members[3].Priority)
+
+	bytes, err := json.Marshal(members)
+	assert.NoError(t, err)
+	var rendered []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(bytes, &rendered))
+	for i, member := range rendered[:3] {
+		assert.NotContains(t, member, "buildIndexes", "buildIndexes should only be rendered when disabled, member %d", i)
+	}
+	assert.Equal(t, false, rendered[3]["buildIndexes"])
+	assert.NotContains(t, rendered[1], "hidden")
+}
+
+func TestBuildAutomationConfig_AtMostSevenVotingMembers(t *testing.T) {
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMongoDBVersion("4.2.0").
+		SetMembers(9).
+		Build()
+
+	assert.NoError(t, err)
+	members := ac.ReplicaSets[0].Members
+	assert.Len(t, members, 9)
+
+	votingMembers := 0
+	for i, member := range members {
+		if i < 7 {
+			assert.Equal(t, 1, member.Votes)
+			assert.Equal(t, 1, member.Priority)
+		} else {
+			assert.Equal(t, 0, member.Votes, "members after the 7th must not vote")
+			assert.Equal(t, 0, member.Priority, "non-voting members must have priority 0")
+		}
+		votingMembers += member.Votes
+	}
+	assert.Equal(t, 7, votingMembers)
+}
+
+func TestBuildAutomationConfig_SeparateArbiters(t *testing.T) {
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMembers(3).
+		SetArbiterMembers("my-rs-arb", "my-rs-arb-svc.my-ns.svc.cluster.local", 2).
+		Build()
+	assert.NoError(t, err)
+
+	assert.Len(t, ac.Processes, 5)
+	rs := ac.ReplicaSets[0]
+	assert.Len(t, rs.Members, 5)
+	for i := 0; i < 3; i++ {
+		assert.False(t, rs.Members[i].ArbiterOnly)
+		assert.Equal(t, fmt.Sprintf("my-rs-%d", i), ac.Processes[i].Name)
+		assert.Equal(t, fmt.Sprintf("my-rs-%d.my-ns.svc.cluster.local", i), ac.Processes[i].HostName)
+	}
+	for i := 0; i < 2; i++ {
+		process := ac.Processes[3+i]
+		member := rs.Members[3+i]
+		assert.Equal(t, fmt.Sprintf("my-rs-arb-%d", i), process.Name)
+		assert.Equal(t, fmt.Sprintf("my-rs-arb-%d.my-rs-arb-svc.my-ns.svc.cluster.local", i), process.HostName)
+		assert.Equal(t, "my-rs", process.Args26.Get("replication.replSetName").Str())
+		assert.Equal(t, process.Name, member.Host)
+		assert.Equal(t, 3+i, member.Id)
+		assert.True(t, member.ArbiterOnly)
+	}
+}
+
 func TestBuildAutomationConfigArbiters(t *testing.T) {
 	// Test no arbiter (field specified)
 	noArbiters := 0
@@ -244,6 +384,70 @@ func TestHasOptions(t *testing.T) {
 	assert.Equal(t, ac.Options.DownloadBase, "/var/lib/mongodb-mms-automation")
 }
 
+func TestDownloadBase(t *testing.T) {
+	builder := func() *Builder {
+		return NewBuilder().
+			SetName("my-rs").
+			SetDomain("my-ns.svc.cluster.local").
+			SetMongoDBVersion("4.2.0").
+			SetMembers(3)
+	}
+
+	ac, err := builder().Build()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultDownloadBase, ac.Options.DownloadBase)
+
+	ac, err = builder().SetDownloadBase("/opt/mongodb/binaries").Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "/opt/mongodb/binaries", ac.Options.DownloadBase)
+
+	bytes, err := json.Marshal(ac)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bytes), `"options":{"downloadBase":"/opt/mongodb/binaries"}`)
+}
+
+func TestProtocolVersion(t *testing.T) {
+	builder := func() *Builder {
+		return NewBuilder().
+			SetName("my-rs").
+			SetDomain("my-ns.svc.cluster.local").
+			SetMongoDBVersion("3.6.8").
+			SetMembers(3)
+	}
+
+	ac, err := builder().Build()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultProtocolVersion, ac.ReplicaSets[0].ProtocolVersion)
+
+	ac, err = builder().SetProtocolVersion("0").Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "0", ac.ReplicaSets[0].ProtocolVersion)
+}
+
+func TestWriteConcernMajorityJournalDefault(t *testing.T) {
+	builder := func() *Builder {
+		return NewBuilder().
+			SetName("my-rs").
+			SetDomain("my-ns.svc.cluster.local").
+			SetMongoDBVersion("4.4.0").
+			SetMembers(3)
+	}
+
+	ac, err := builder().Build()
+	assert.NoError(t, err)
+	assert.Nil(t, ac.ReplicaSets[0].WriteConcernMajorityJournalDefault)
+	bytes, err := json.Marshal(ac)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(bytes), "writeConcernMajorityJournalDefault")
+
+	disabled := false
+	ac, err = builder().SetWriteConcernMajorityJournalDefault(&disabled).Build()
+	assert.NoError(t, err)
+	bytes, err = json.Marshal(ac)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bytes), `"protocolVersion":"1","numberArbiters":0,"writeConcernMajorityJournalDefault":false}`)
+}
+
 func TestModulesNotNil(t *testing.T) {
 	// We make sure the .Modules is initialized as an empty list of strings
 	// or it will dumped as null attribute in json.
@@ -275,6 +479,60 @@ func TestProcessHasPortSetToDefault(t *testing.T) {
 	}
 }
 
+func TestProcessHasCustomDataDir(t *testing.T) {
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMongoDBVersion("4.2.0").
+		SetMembers(3).
+		SetDataDir("/var/lib/mongodb/data").
+		Build()
+
+	assert.NoError(t, err)
+	assert.Len(t, ac.Processes, 3)
+	for _, process := range ac.Processes {
+		assert.Equal(t, "/var/lib/mongodb/data", process.Args26.Get("storage.dbPath").Data())
+	}
+}
+
+func TestSetJournalCommitIntervalAndSyncPeriod(t *testing.T) {
+	p := &Process{}
+	p.SetJournalCommitInterval(0).SetSyncPeriod(0)
+	assert.Nil(t, p.Args26, "the defaults of mongod should be kept")
+
+	p.SetJournalCommitInterval(50).SetSyncPeriod(30)
+	bytes, err := json.Marshal(p.Args26)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"storage":{"journal":{"commitIntervalMs":50},"syncPeriodSecs":30}}`, string(bytes))
+}
+
+func TestSetMaxIncomingConnectionsAndOperationProfiling(t *testing.T) {
+	p := &Process{}
+	p.SetMaxIncomingConnections(0).SetOperationProfiling("", 0)
+	assert.Nil(t, p.Args26, "the defaults of mongod should be kept")
+
+	p.SetMaxIncomingConnections(2000).SetOperationProfiling("slowOp", 250)
+	bytes, err := json.Marshal(p.Args26)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"net":{"maxIncomingConnections":2000},"operationProfiling":{"mode":"slowOp","slowOpThresholdMs":250}}`, string(bytes))
+}
+
+func TestSetLogVerbosity(t *testing.T) {
+	t.Run("Default verbosity is not set", func(t *testing.T) {
+		p := Process{}
+		p.SetLogVerbosity(0, nil)
+		assert.Nil(t, p.Args26)
+	})
+
+	t.Run("Verbosity of the components is set", func(t *testing.T) {
+		p := Process{}
+		p.SetLogVerbosity(1, map[string]int{"replication": 2, "storage.journal": -1})
+		assert.Equal(t, 1, p.Args26.Get("systemLog.verbosity").Data())
+		assert.Equal(t, 2, p.Args26.Get("systemLog.component.replication.verbosity").Data())
+		assert.Equal(t, -1, p.Args26.Get("systemLog.component.storage.journal.verbosity").Data())
+	})
+}
+
 func TestModifications(t *testing.T) {
 	incrementVersion := func(config *AutomationConfig) {
 		config.Version += 1
@@ -289,6 +547,114 @@ func TestModifications(t *testing.T) {
 	assert.Equal(t, 4, ac.Version)
 }
 
+func TestModifications_AreComparedWithThePreviousAutomationConfig(t *testing.T) {
+	setAuthoritativeSet := func(config *AutomationConfig) {
+		config.Auth.AuthoritativeSet = true
+	}
+	builder := func() *Builder {
+		return newAutomationConfigBuilder().SetMongoDBVersion("4.4.0").SetFCV("4.4").SetAuth(Auth{Disabled: true})
+	}
+	previousAc, err := builder().Build()
+	assert.NoError(t, err)
+	assert.False(t, previousAc.Auth.AuthoritativeSet)
+
+	ac, err := builder().SetPreviousAutomationConfig(previousAc).AddModifications(setAuthoritativeSet).Build()
+	assert.NoError(t, err)
+	assert.True(t, ac.Auth.AuthoritativeSet)
+	assert.Equal(t, previousAc.Version+1, ac.Version, "the change of the modification increments the version")
+
+	unchangedAc, err := builder().SetPreviousAutomationConfig(ac).AddModifications(setAuthoritativeSet).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, ac.Version, unchangedAc.Version)
+}
+
+func TestAdditionalFields(t *testing.T) {
+	builder := func() *Builder {
+		return newAutomationConfigBuilder().SetMongoDBVersion("4.4.0").SetFCV("4.4")
+	}
+	setBackup := SetAdditionalField("backup", map[string]interface{}{"enabled": false, "intervalHours": 6})
+
+	ac, err := builder().AddModifications(setBackup, SetAdditionalField("options", "ignored")).Build()
+	assert.NoError(t, err)
+
+	acBytes, err := json.Marshal(ac)
+	assert.NoError(t, err)
+	fields := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(acBytes, &fields))
+	assert.Equal(t, map[string]interface{}{"enabled": false, "intervalHours": float64(6)}, fields["backup"])
+	assert.Equal(t, DefaultDownloadBase, fields["options"].(map[string]interface{})["downloadBase"], "the fields of the struct take precedence")
+	assert.Equal(t, "4.4.0", fields["processes"].([]interface{})[0].(map[string]interface{})["version"])
+
+	t.Run("Additional fields are kept when the automation config is read", func(t *testing.T) {
+		readAc, err := FromBytes(acBytes)
+		assert.NoError(t, err)
+		assert.Contains(t, readAc.AdditionalFields, "backup")
+		assert.NotContains(t, readAc.AdditionalFields, "options")
+
+		areEqual, err := AreEqual(ac, readAc)
+		assert.NoError(t, err)
+		assert.True(t, areEqual)
+
+		rebuiltAc, err := builder().SetPreviousAutomationConfig(readAc).AddModifications(setBackup).Build()
+		assert.NoError(t, err)
+		assert.Equal(t, ac.Version, rebuiltAc.Version)
+	})
+
+	t.Run("Changing an additional field increments the version", func(t *testing.T) {
+		changedAc, err := builder().SetPreviousAutomationConfig(ac).AddModifications(SetAdditionalField("backup", map[string]interface{}{"enabled": true})).Build()
+		assert.NoError(t, err)
+		assert.Equal(t, ac.Version+1, changedAc.Version)
+
+		removedAc, err := builder().SetPreviousAutomationConfig(ac).Build()
+		assert.NoError(t, err)
+		assert.Equal(t, ac.Version+1, removedAc.Version)
+		assert.Empty(t, removedAc.AdditionalFields)
+	})
+
+	t.Run("Automation configs without additional fields are unchanged", func(t *testing.T) {
+		plainAc, err := builder().Build()
+		assert.NoError(t, err)
+		plainBytes, err := json.Marshal(plainAc)
+		assert.NoError(t, err)
+		withoutCustomMarshalling, err := json.Marshal(automationConfigFields(plainAc))
+		assert.NoError(t, err)
+		assert.Equal(t, string(withoutCustomMarshalling), string(plainBytes))
+
+		readAc, err := FromBytes(plainBytes)
+		assert.NoError(t, err)
+		assert.Nil(t, readAc.AdditionalFields)
+	})
+}
+
+func TestBuildWithoutVersioning(t *testing.T) {
+	builder := func() *Builder {
+		return newAutomationConfigBuilder().SetMongoDBVersion("4.4.0").SetFCV("4.4")
+	}
+	previousAc, err := builder().Build()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, previousAc.Version)
+
+	t.Run("Version is not incremented when the config changes", func(t *testing.T) {
+		ac, err := builder().SetPreviousAutomationConfig(previousAc).SetMembers(5).BuildWithoutVersioning()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, ac.Version)
+
+		areEqual, err := AreEqual(previousAc, ac)
+		assert.NoError(t, err)
+		assert.False(t, areEqual)
+	})
+
+	t.Run("Build only increments the version when the config changes", func(t *testing.T) {
+		ac, err := builder().SetPreviousAutomationConfig(previousAc).Build()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, ac.Version)
+
+		ac, err = builder().SetPreviousAutomationConfig(previousAc).SetMembers(5).Build()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, ac.Version)
+	})
+}
+
 func TestMongoDBVersionsConfig(t *testing.T) {
 
 	t.Run("Dummy Config is used when no versions are set", func(t *testing.T) {
@@ -24,6 +24,30 @@ type patchValue struct {
 
 const (
 	LastAppliedMongoDBVersion = "mongodb.com/v1.lastAppliedMongoDBVersion"
+	// RestartedAt can be set on a resource to trigger a rolling restart of its pods whenever its value changes.
+	RestartedAt = "mongodb.com/restartedAt"
+	// FreezeAutomationConfigVersion can be set to "true" on a resource to stop the operator from publishing new
+	// AutomationConfig versions. The resource stays Pending while changes are held back, and they are applied once
+	// the annotation is removed.
+	FreezeAutomationConfigVersion = "mongodb.com/freeze-ac-version"
+	// AllowStatefulSetRecreate can be set to "true" on a resource to let the operator delete and recreate the StatefulSet
+	// when a field which can't be updated has been changed. The pods are kept and adopted by the new StatefulSet.
+	AllowStatefulSetRecreate = "mongodb.com/allow-statefulset-recreate"
+	// SkipUpgradeQuorumCheck can be set to "true" on a resource to change the MongoDB version even if the replica set
+	// could lose its primary while the members restart, e.g. to fix a member which is unhealthy because of its version.
+	SkipUpgradeQuorumCheck = "mongodb.com/skip-upgrade-quorum-check"
+	// ManagedByVersion is set on the objects created by the operator to the version of the operator which last reconciled them.
+	ManagedByVersion = "mongodb.com/managed-by-version"
+	// VersionChangeStartedAt records when the operator started to change the MongoDB version of a resource.
+	VersionChangeStartedAt = "mongodb.com/v1.versionChangeStartedAt"
+	// RolledBackMongoDBVersion records the MongoDB version whose change was rolled back automatically. The resource
+	// keeps running the previous version until a different version is configured.
+	RolledBackMongoDBVersion = "mongodb.com/v1.rolledBackMongoDBVersion"
+	// KeyfileRotationPhase records the phase of the rotation of the keyfile of a resource.
+	KeyfileRotationPhase = "mongodb.com/v1.keyfileRotationPhase"
+	// KeyfileRotationStep counts the steps of the keyfile rotations of a resource, it is propagated into the pod
+	// template so that the pods are restarted with the keyfile of every step.
+	KeyfileRotationStep = "mongodb.com/v1.keyfileRotationStep"
 )
 
 func GetAnnotation(object Versioned, key string) string {
@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -45,16 +47,11 @@ func (m *mockedClient) ensureMapFor(obj k8sClient.Object) map[k8sClient.ObjectKe
 func (m *mockedClient) Get(_ context.Context, key k8sClient.ObjectKey, obj k8sClient.Object) error {
 	relevantMap := m.ensureMapFor(obj)
 	if val, ok := relevantMap[key]; ok {
-		if currSts, ok := val.(*appsv1.StatefulSet); ok {
-			// TODO: this currently doesn't work with additional mongodb config
-			// just doing it for StatefulSets for now
-			objCopy := currSts.DeepCopyObject()
-			v := reflect.ValueOf(obj).Elem()
-			v.Set(reflect.ValueOf(objCopy).Elem())
-		} else {
-			v := reflect.ValueOf(obj).Elem()
-			v.Set(reflect.ValueOf(val).Elem())
-		}
+		// the stored object is copied, as the apiserver would, so that modifying
+		// the fetched object doesn't modify the stored one.
+		objCopy := val.DeepCopyObject()
+		v := reflect.ValueOf(obj).Elem()
+		v.Set(reflect.ValueOf(objCopy).Elem())
 		return nil
 	}
 	return notFoundError()
@@ -72,7 +69,7 @@ func (m *mockedClient) Create(_ context.Context, obj k8sClient.Object, _ ...k8sC
 		makeStatefulSetReady(v)
 	}
 
-	relevantMap[objKey] = obj
+	relevantMap[objKey] = obj.DeepCopyObject().(k8sClient.Object)
 	return nil
 }
 
@@ -82,8 +79,37 @@ func makeStatefulSetReady(set *appsv1.StatefulSet) {
 	set.Status.ReadyReplicas = *set.Spec.Replicas
 }
 
-func (m *mockedClient) List(_ context.Context, _ k8sClient.ObjectList, _ ...k8sClient.ListOption) error {
-	return nil
+// List populates the given list with the stored objects of the type of its items, sorted by
+// namespace and name. Only the namespace and label selector of the ListOptions are supported.
+func (m *mockedClient) List(_ context.Context, list k8sClient.ObjectList, opts ...k8sClient.ListOption) error {
+	listOpts := &k8sClient.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	itemsField := reflect.ValueOf(list).Elem().FieldByName("Items")
+	if !itemsField.IsValid() {
+		return fmt.Errorf("list %T has no Items field", list)
+	}
+	relevantMap := m.backingMap[reflect.PtrTo(itemsField.Type().Elem())]
+
+	keys := make([]k8sClient.ObjectKey, 0, len(relevantMap))
+	for key, obj := range relevantMap {
+		if listOpts.Namespace != "" && key.Namespace != listOpts.Namespace {
+			continue
+		}
+		if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	items := make([]runtime.Object, len(keys))
+	for i, key := range keys {
+		items[i] = relevantMap[key].DeepCopyObject()
+	}
+	return meta.SetList(list, items)
 }
 
 func (m *mockedClient) Delete(_ context.Context, obj k8sClient.Object, _ ...k8sClient.DeleteOption) error {
@@ -100,6 +126,37 @@ func (m *mockedClient) Update(_ context.Context, obj k8sClient.Object, _ ...k8sC
 	return nil
 }
 
+// mockedStatusWriter updates the status of the stored objects, as the status subresource
+// of the apiserver would. The other fields of the given object are ignored, and overwritten
+// with the ones of the stored object.
+type mockedStatusWriter struct {
+	client *mockedClient
+}
+
+func (m mockedStatusWriter) Update(_ context.Context, obj k8sClient.Object, _ ...k8sClient.UpdateOption) error {
+	relevantMap := m.client.ensureMapFor(obj)
+	objKey := k8sClient.ObjectKeyFromObject(obj)
+	stored, ok := relevantMap[objKey]
+	if !ok {
+		return notFoundError()
+	}
+
+	statusField := reflect.ValueOf(obj).Elem().FieldByName("Status")
+	if !statusField.IsValid() {
+		return fmt.Errorf("object %T has no Status field", obj)
+	}
+	updated := stored.DeepCopyObject().(k8sClient.Object)
+	reflect.ValueOf(updated).Elem().FieldByName("Status").Set(statusField)
+	relevantMap[objKey] = updated
+
+	reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(updated.DeepCopyObject()).Elem())
+	return nil
+}
+
+func (m mockedStatusWriter) Patch(ctx context.Context, obj k8sClient.Object, patch k8sClient.Patch, opts ...k8sClient.PatchOption) error {
+	return m.client.Patch(ctx, obj, patch, opts...)
+}
+
 type patchValue struct {
 	Op    string      `json:"op"`
 	Path  string      `json:"path"`
@@ -143,7 +200,7 @@ func (m *mockedClient) Patch(_ context.Context, obj k8sClient.Object, patch k8sC
 		}
 	}
 	obj.SetAnnotations(objectAnnotations)
-	relevantMap[objKey] = obj
+	relevantMap[objKey] = obj.DeepCopyObject().(k8sClient.Object)
 	return nil
 }
 
@@ -152,7 +209,7 @@ func (m *mockedClient) DeleteAllOf(_ context.Context, _ k8sClient.Object, _ ...k
 }
 
 func (m *mockedClient) Status() k8sClient.StatusWriter {
-	return m
+	return mockedStatusWriter{client: m}
 }
 
 func (m *mockedClient) RESTMapper() meta.RESTMapper {
@@ -8,7 +8,9 @@ import (
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/service"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func TestMockedClient(t *testing.T) {
@@ -44,3 +46,105 @@ func TestMockedClient(t *testing.T) {
 	assert.Equal(t, "svc-namespace", newSvc.Namespace)
 	assert.Equal(t, "svc-name", newSvc.Name)
 }
+
+func TestMockedClient_List(t *testing.T) {
+	mockedClient := NewMockedClient()
+
+	for _, p := range []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "ns", Labels: map[string]string{"app": "my-rs-svc"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-0", Namespace: "ns", Labels: map[string]string{"app": "my-rs-svc"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "ns", Labels: map[string]string{"app": "other"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-0", Namespace: "other-ns", Labels: map[string]string{"app": "my-rs-svc"}}},
+	} {
+		p := p
+		err := mockedClient.Create(context.TODO(), &p)
+		assert.NoError(t, err)
+	}
+
+	podNames := func(pods corev1.PodList) []string {
+		var names []string
+		for _, p := range pods.Items {
+			names = append(names, p.Namespace+"/"+p.Name)
+		}
+		return names
+	}
+
+	t.Run("All objects are listed", func(t *testing.T) {
+		pods := corev1.PodList{}
+		err := mockedClient.List(context.TODO(), &pods)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ns/pod-0", "ns/pod-1", "ns/pod-2", "other-ns/pod-0"}, podNames(pods))
+	})
+
+	t.Run("Objects are filtered by namespace and labels", func(t *testing.T) {
+		pods := corev1.PodList{}
+		err := mockedClient.List(context.TODO(), &pods, k8sClient.InNamespace("ns"), k8sClient.MatchingLabels{"app": "my-rs-svc"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ns/pod-0", "ns/pod-1"}, podNames(pods))
+	})
+
+	t.Run("Listed objects are copies", func(t *testing.T) {
+		pods := corev1.PodList{}
+		err := mockedClient.List(context.TODO(), &pods, k8sClient.InNamespace("ns"))
+		assert.NoError(t, err)
+		pods.Items[0].Labels["app"] = "changed"
+
+		pod := corev1.Pod{}
+		err = mockedClient.Get(context.TODO(), types.NamespacedName{Name: "pod-0", Namespace: "ns"}, &pod)
+		assert.NoError(t, err)
+		assert.Equal(t, "my-rs-svc", pod.Labels["app"])
+	})
+
+	t.Run("An empty list is returned when no object is stored", func(t *testing.T) {
+		secrets := corev1.SecretList{}
+		err := mockedClient.List(context.TODO(), &secrets)
+		assert.NoError(t, err)
+		assert.Empty(t, secrets.Items)
+	})
+}
+
+func TestMockedClient_Get_ReturnsCopy(t *testing.T) {
+	mockedClient := NewMockedClient()
+
+	cm := configmap.Builder().
+		SetName("cm-name").
+		SetNamespace("cm-namespace").
+		SetField("field-1", "value-1").
+		Build()
+	err := mockedClient.Create(context.TODO(), &cm)
+	assert.NoError(t, err)
+
+	fetched := corev1.ConfigMap{}
+	err = mockedClient.Get(context.TODO(), types.NamespacedName{Name: "cm-name", Namespace: "cm-namespace"}, &fetched)
+	assert.NoError(t, err)
+	fetched.Data["field-1"] = "modified"
+
+	refetched := corev1.ConfigMap{}
+	err = mockedClient.Get(context.TODO(), types.NamespacedName{Name: "cm-name", Namespace: "cm-namespace"}, &refetched)
+	assert.NoError(t, err)
+	assert.Equal(t, "value-1", refetched.Data["field-1"])
+}
+
+func TestMockedClient_StatusUpdate_OnlyUpdatesStatus(t *testing.T) {
+	mockedClient := NewMockedClient()
+
+	svc := service.Builder().
+		SetName("svc-name").
+		SetNamespace("svc-namespace").
+		SetServiceType(corev1.ServiceTypeLoadBalancer).
+		Build()
+	err := mockedClient.Create(context.TODO(), &svc)
+	assert.NoError(t, err)
+
+	svc.Spec.Type = corev1.ServiceTypeClusterIP
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.1"}}
+	err = mockedClient.Status().Update(context.TODO(), &svc)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.ServiceTypeLoadBalancer, svc.Spec.Type, "the object should be refreshed from the stored one")
+
+	fetched := corev1.Service{}
+	err = mockedClient.Get(context.TODO(), types.NamespacedName{Name: "svc-name", Namespace: "svc-namespace"}, &fetched)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.ServiceTypeLoadBalancer, fetched.Spec.Type)
+	assert.Equal(t, "10.0.0.1", fetched.Status.LoadBalancer.Ingress[0].IP)
+}
@@ -55,3 +55,29 @@ func WithStorageClassName(storageClassName string) Modification {
 		claim.Spec.StorageClassName = &storageClassName
 	}
 }
+
+// WithLabels sets the PersistentVolumeClaim's labels
+func WithLabels(labels map[string]string) Modification {
+	return func(claim *corev1.PersistentVolumeClaim) {
+		claim.Labels = copyMap(labels)
+	}
+}
+
+// WithAnnotations sets the PersistentVolumeClaim's annotations
+func WithAnnotations(annotations map[string]string) Modification {
+	return func(claim *corev1.PersistentVolumeClaim) {
+		claim.Annotations = copyMap(annotations)
+	}
+}
+
+// copyMap returns a copy of the given map, or nil if it is empty.
+func copyMap(original map[string]string) map[string]string {
+	if len(original) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(original))
+	for k, v := range original {
+		copied[k] = v
+	}
+	return copied
+}
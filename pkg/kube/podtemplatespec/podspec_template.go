@@ -76,6 +76,17 @@ func WithInitContainer(name string, containerfunc func(*corev1.Container)) Modif
 	}
 }
 
+// WithoutInitContainer removes the init container with the provided name, if it exists
+func WithoutInitContainer(name string) Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		idx := findIndexByName(name, podTemplateSpec.Spec.InitContainers)
+		if idx == notFound {
+			return
+		}
+		podTemplateSpec.Spec.InitContainers = append(podTemplateSpec.Spec.InitContainers[:idx], podTemplateSpec.Spec.InitContainers[idx+1:]...)
+	}
+}
+
 // WithInitContainerByIndex applies the modifications to the container with the provided index
 // if the index is out of range, a new container is added to accept these changes.
 func WithInitContainerByIndex(index int, funcs ...func(container *corev1.Container)) func(podTemplateSpec *corev1.PodTemplateSpec) {
@@ -119,6 +130,29 @@ func WithVolume(volume corev1.Volume) Modification {
 	}
 }
 
+// WithoutVolume removes the volume with the provided name, and its mounts from all the containers, if it exists
+func WithoutVolume(name string) Modification {
+	return func(template *corev1.PodTemplateSpec) {
+		for i, v := range template.Spec.Volumes {
+			if v.Name == name {
+				template.Spec.Volumes = append(template.Spec.Volumes[:i], template.Spec.Volumes[i+1:]...)
+				break
+			}
+		}
+
+		for _, containers := range [][]corev1.Container{template.Spec.InitContainers, template.Spec.Containers} {
+			for i := range containers {
+				for j, m := range containers[i].VolumeMounts {
+					if m.Name == name {
+						containers[i].VolumeMounts = append(containers[i].VolumeMounts[:j], containers[i].VolumeMounts[j+1:]...)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
 func findIndexByName(name string, containers []corev1.Container) int {
 	for idx, c := range containers {
 		if c.Name == name {
@@ -209,6 +243,13 @@ func WithTolerations(tolerations []corev1.Toleration) Modification {
 	}
 }
 
+// WithReadinessGates sets the PodTemplateSpec's readiness gates
+func WithReadinessGates(readinessGates []corev1.PodReadinessGate) Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		podTemplateSpec.Spec.ReadinessGates = readinessGates
+	}
+}
+
 // WithAnnotations sets the PodTemplateSpec's annotations
 func WithAnnotations(annotations map[string]string) Modification {
 	if annotations == nil {
@@ -219,6 +260,16 @@ func WithAnnotations(annotations map[string]string) Modification {
 	}
 }
 
+// WithAnnotation sets a single annotation on the PodTemplateSpec, keeping the existing ones.
+func WithAnnotation(key, value string) Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		if podTemplateSpec.Annotations == nil {
+			podTemplateSpec.Annotations = map[string]string{}
+		}
+		podTemplateSpec.Annotations[key] = value
+	}
+}
+
 // WithVolumeMounts will add volume mounts to a container or init container by name
 func WithVolumeMounts(containerName string, volumeMounts ...corev1.VolumeMount) Modification {
 	return func(podTemplateSpec *corev1.PodTemplateSpec) {
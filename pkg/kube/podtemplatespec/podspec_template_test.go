@@ -113,6 +113,56 @@ func TestPodTemplateSpec_MultipleEditsToContainer(t *testing.T) {
 	assert.Equal(t, "cmd", c.Command[0])
 }
 
+func TestPodTemplateSpec_WithoutInitContainer(t *testing.T) {
+	p := New(
+		WithInitContainer("init-0", container.Apply(container.WithImage("image-0"))),
+		WithInitContainer("init-1", container.Apply(container.WithImage("image-1"))),
+		WithoutInitContainer("init-0"),
+		WithoutInitContainer("does-not-exist"),
+	)
+
+	assert.Len(t, p.Spec.InitContainers, 1)
+	assert.Equal(t, "init-1", p.Spec.InitContainers[0].Name)
+	assert.Equal(t, "image-1", p.Spec.InitContainers[0].Image)
+}
+
+func TestPodTemplateSpec_WithoutVolume(t *testing.T) {
+	mount := func(name string) corev1.VolumeMount {
+		return corev1.VolumeMount{Name: name, MountPath: "/" + name}
+	}
+	p := New(
+		WithVolume(corev1.Volume{Name: "volume-0"}),
+		WithVolume(corev1.Volume{Name: "volume-1"}),
+		WithInitContainer("init", container.WithVolumeMounts([]corev1.VolumeMount{mount("volume-0")})),
+		WithContainer("container", container.WithVolumeMounts([]corev1.VolumeMount{mount("volume-0"), mount("volume-1")})),
+		WithoutVolume("volume-0"),
+		WithoutVolume("does-not-exist"),
+	)
+
+	assert.Equal(t, []corev1.Volume{{Name: "volume-1"}}, p.Spec.Volumes)
+	assert.Empty(t, p.Spec.InitContainers[0].VolumeMounts)
+	assert.Equal(t, []corev1.VolumeMount{mount("volume-1")}, p.Spec.Containers[0].VolumeMounts)
+}
+
+func TestPodTemplateSpec_WithAnnotation(t *testing.T) {
+	p := New(
+		WithAnnotation("key-0", "value-0"),
+		WithAnnotation("key-1", "value-1"),
+		WithAnnotation("key-0", "updated"),
+	)
+
+	assert.Equal(t, map[string]string{"key-0": "updated", "key-1": "value-1"}, p.Annotations)
+}
+
+func TestPodTemplateSpec_WithReadinessGates(t *testing.T) {
+	gates := []corev1.PodReadinessGate{{ConditionType: "target-health.elbv2.k8s.aws/my-tgb"}}
+	p := New(WithReadinessGates(gates))
+	assert.Equal(t, gates, p.Spec.ReadinessGates)
+
+	Apply(WithReadinessGates(nil))(&p)
+	assert.Empty(t, p.Spec.ReadinessGates)
+}
+
 func TestMerge(t *testing.T) {
 	defaultSpec := getDefaultPodSpec()
 	customSpec := getCustomPodSpec()
@@ -20,6 +20,14 @@ func New(funcs ...Modification) corev1.Probe {
 	return probe
 }
 
+// Reset clears the settings of the probe, so that the modifications which follow build it from scratch instead
+// of keeping the settings of the probe which is modified, e.g. an existing StatefulSet.
+func Reset() Modification {
+	return func(probe *corev1.Probe) {
+		*probe = corev1.Probe{}
+	}
+}
+
 func WithExecCommand(cmd []string) Modification {
 	return func(probe *corev1.Probe) {
 		if probe.Handler.Exec == nil {
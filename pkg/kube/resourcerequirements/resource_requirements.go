@@ -17,10 +17,34 @@ func Defaults() corev1.ResourceRequirements {
 	return req
 }
 
+// AgentDefaults returns the default resource requirements for the automation agent container,
+// which needs considerably less memory than mongod.
+func AgentDefaults() corev1.ResourceRequirements {
+	// we can safely ignore the error as we are passing all valid values
+	req, _ := newAgentDefaultRequirements()
+	return req
+}
+
+// VersionUpgradeHookDefaults returns the default resource requirements for the version upgrade
+// hook init container, which only copies the hook binary.
+func VersionUpgradeHookDefaults() corev1.ResourceRequirements {
+	// we can safely ignore the error as we are passing all valid values
+	req, _ := newVersionUpgradeHookDefaultRequirements()
+	return req
+}
+
 func newDefaultRequirements() (corev1.ResourceRequirements, error) {
 	return newRequirements("1.0", "500M", "0.5", "400M")
 }
 
+func newAgentDefaultRequirements() (corev1.ResourceRequirements, error) {
+	return newRequirements("0.5", "250M", "0.2", "200M")
+}
+
+func newVersionUpgradeHookDefaultRequirements() (corev1.ResourceRequirements, error) {
+	return newRequirements("0.1", "50M", "0.05", "20M")
+}
+
 // newRequirements returns a new corev1.ResourceRequirements with the specified arguments, and an error
 // which indicates if there was a problem parsing the input
 func newRequirements(limitsCpu, limitsMemory, requestsCpu, requestsMemory string) (corev1.ResourceRequirements, error) {
@@ -24,4 +24,8 @@ func TestBadInput_ReturnsError(t *testing.T) {
 func TestDefaultValues_DontReturnError(t *testing.T) {
 	_, err := newDefaultRequirements()
 	assert.NoError(t, err, "default requirements should never result in an error")
+	_, err = newAgentDefaultRequirements()
+	assert.NoError(t, err, "default agent requirements should never result in an error")
+	_, err = newVersionUpgradeHookDefaultRequirements()
+	assert.NoError(t, err, "default version upgrade hook requirements should never result in an error")
 }
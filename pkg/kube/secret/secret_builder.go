@@ -6,6 +6,7 @@ import (
 )
 
 type builder struct {
+	annotations     map[string]string
 	data            map[string][]byte
 	labels          map[string]string
 	name            string
@@ -42,6 +43,15 @@ func (b *builder) SetLabels(labels map[string]string) *builder {
 	return b
 }
 
+func (b *builder) SetAnnotations(annotations map[string]string) *builder {
+	newAnnotations := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		newAnnotations[k] = v
+	}
+	b.annotations = newAnnotations
+	return b
+}
+
 func (b *builder) SetByteData(stringData map[string][]byte) *builder {
 	newStringDataBytes := make(map[string][]byte, len(stringData))
 	for k, v := range stringData {
@@ -66,6 +76,7 @@ func (b builder) Build() corev1.Secret {
 			Namespace:       b.namespace,
 			OwnerReferences: b.ownerReferences,
 			Labels:          b.labels,
+			Annotations:     b.annotations,
 		},
 		Data: b.data,
 	}
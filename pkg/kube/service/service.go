@@ -65,13 +65,8 @@ func DeleteServiceIfItExists(getterDeleter GetDeleter, serviceName types.Namespa
 // a new service will be created and returned.
 // The "merging" process is arbitrary and it only handle specific attributes
 func Merge(dest corev1.Service, source corev1.Service) corev1.Service {
-	for k, v := range source.ObjectMeta.Annotations {
-		dest.ObjectMeta.Annotations[k] = v
-	}
-
-	for k, v := range source.ObjectMeta.Labels {
-		dest.ObjectMeta.Labels[k] = v
-	}
+	dest.ObjectMeta.Annotations = mergeMaps(dest.ObjectMeta.Annotations, source.ObjectMeta.Annotations)
+	dest.ObjectMeta.Labels = mergeMaps(dest.ObjectMeta.Labels, source.ObjectMeta.Labels)
 
 	var nodePort int32 = 0
 	if len(dest.Spec.Ports) > 0 {
@@ -90,7 +85,17 @@ func Merge(dest corev1.Service, source corev1.Service) corev1.Service {
 
 	dest.Spec.Type = source.Spec.Type
 	dest.Spec.LoadBalancerIP = source.Spec.LoadBalancerIP
+	dest.Spec.LoadBalancerSourceRanges = source.Spec.LoadBalancerSourceRanges
 	dest.Spec.ExternalTrafficPolicy = source.Spec.ExternalTrafficPolicy
+	dest.Spec.PublishNotReadyAddresses = source.Spec.PublishNotReadyAddresses
+	// the apiserver defaults the session affinity to None, which doesn't allow a session affinity config.
+	dest.Spec.SessionAffinity = source.Spec.SessionAffinity
+	if dest.Spec.SessionAffinity == "" {
+		dest.Spec.SessionAffinity = corev1.ServiceAffinityNone
+	}
+	if dest.Spec.SessionAffinity == corev1.ServiceAffinityNone {
+		dest.Spec.SessionAffinityConfig = nil
+	}
 	return dest
 }
 
@@ -117,3 +122,15 @@ func CreateOrUpdateService(getUpdateCreator GetUpdateCreator, desiredService cor
 	}
 	return nil
 }
+
+// mergeMaps returns a new map holding the entries of both maps, the entries of source take precedence.
+func mergeMaps(dest, source map[string]string) map[string]string {
+	merged := map[string]string{}
+	for k, v := range dest {
+		merged[k] = v
+	}
+	for k, v := range source {
+		merged[k] = v
+	}
+	return merged
+}
@@ -13,11 +13,13 @@ type builder struct {
 	servicePort           corev1.ServicePort
 	labels                map[string]string
 	loadBalancerIP        string
+	loadBalancerSources   []string
 	publishNotReady       bool
 	ownerReferences       []metav1.OwnerReference
 	selector              map[string]string
 	annotations           map[string]string
 	externalTrafficPolicy corev1.ServiceExternalTrafficPolicyType
+	sessionAffinity       corev1.ServiceAffinity
 }
 
 func (b *builder) SetExternalTrafficPolicy(externalTrafficPolicy corev1.ServiceExternalTrafficPolicyType) *builder {
@@ -80,11 +82,23 @@ func (b *builder) SetLoadBalancerIP(ip string) *builder {
 	return b
 }
 
+// SetLoadBalancerSourceRanges restricts the clients of a LoadBalancer Service to the given CIDRs.
+func (b *builder) SetLoadBalancerSourceRanges(sourceRanges []string) *builder {
+	b.loadBalancerSources = sourceRanges
+	return b
+}
+
 func (b *builder) SetPublishNotReadyAddresses(publishNotReady bool) *builder {
 	b.publishNotReady = publishNotReady
 	return b
 }
 
+// SetSessionAffinity routes the connections of a client to the same pod, if set to ClientIP.
+func (b *builder) SetSessionAffinity(sessionAffinity corev1.ServiceAffinity) *builder {
+	b.sessionAffinity = sessionAffinity
+	return b
+}
+
 func (b *builder) SetOwnerReferences(ownerReferences []metav1.OwnerReference) *builder {
 	b.ownerReferences = ownerReferences
 	return b
@@ -101,8 +115,10 @@ func (b *builder) Build() corev1.Service {
 		},
 		Spec: corev1.ServiceSpec{
 			PublishNotReadyAddresses: b.publishNotReady,
+			SessionAffinity:          b.sessionAffinity,
 			ExternalTrafficPolicy:    b.externalTrafficPolicy,
 			LoadBalancerIP:           b.loadBalancerIP,
+			LoadBalancerSourceRanges: b.loadBalancerSources,
 			Type:                     b.serviceType,
 			ClusterIP:                b.clusterIp,
 			Ports:                    []corev1.ServicePort{b.servicePort},
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMerge_LoadBalancerSourceRanges(t *testing.T) {
+	existing := Builder().
+		SetName("my-svc").
+		SetServiceType(corev1.ServiceTypeLoadBalancer).
+		SetPort(27017).
+		SetLoadBalancerSourceRanges([]string{"10.0.0.0/8"}).
+		Build()
+	existing.Spec.Ports[0].NodePort = 30017
+	assert.Equal(t, []string{"10.0.0.0/8"}, existing.Spec.LoadBalancerSourceRanges)
+
+	desired := Builder().
+		SetName("my-svc").
+		SetServiceType(corev1.ServiceTypeLoadBalancer).
+		SetPort(27017).
+		SetLoadBalancerSourceRanges([]string{"10.0.0.0/8", "192.168.0.0/16"}).
+		Build()
+
+	merged := Merge(existing, desired)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.0.0/16"}, merged.Spec.LoadBalancerSourceRanges)
+	assert.Equal(t, int32(30017), merged.Spec.Ports[0].NodePort, "the allocated node port should be kept")
+
+	t.Run("Removed source ranges are removed from the Service", func(t *testing.T) {
+		desired := Builder().SetName("my-svc").SetServiceType(corev1.ServiceTypeLoadBalancer).SetPort(27017).Build()
+		merged := Merge(existing, desired)
+		assert.Empty(t, merged.Spec.LoadBalancerSourceRanges)
+	})
+}
+
+func TestMerge_PublishNotReadyAddressesAndSessionAffinity(t *testing.T) {
+	existing := Builder().SetName("my-svc").SetPort(27017).Build()
+
+	desired := Builder().
+		SetName("my-svc").
+		SetPort(27017).
+		SetPublishNotReadyAddresses(true).
+		SetSessionAffinity(corev1.ServiceAffinityClientIP).
+		Build()
+
+	merged := Merge(existing, desired)
+	assert.True(t, merged.Spec.PublishNotReadyAddresses, "the existing Service should publish the addresses of pods which are not ready")
+	assert.Equal(t, corev1.ServiceAffinityClientIP, merged.Spec.SessionAffinity)
+
+	t.Run("Removed session affinity is reset to None", func(t *testing.T) {
+		timeout := int32(10800)
+		merged.Spec.SessionAffinityConfig = &corev1.SessionAffinityConfig{ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: &timeout}}
+		desired := Builder().SetName("my-svc").SetPort(27017).Build()
+		merged := Merge(merged, desired)
+		assert.Equal(t, corev1.ServiceAffinityNone, merged.Spec.SessionAffinity)
+		assert.Nil(t, merged.Spec.SessionAffinityConfig)
+		assert.False(t, merged.Spec.PublishNotReadyAddresses)
+	})
+}
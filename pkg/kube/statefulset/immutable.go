@@ -0,0 +1,74 @@
+package statefulset
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// immutableFieldsErrorMessage is part of the message of the error returned by the apiserver
+// when an update of a StatefulSet changes any field which can't be updated.
+const immutableFieldsErrorMessage = "updates to statefulset spec for fields other than"
+
+// ChangedImmutableFields compares an existing StatefulSet with the desired version of it, and returns
+// a description of every field which differs but can't be updated on an existing StatefulSet.
+// An empty result means that the desired StatefulSet can be applied with an update.
+func ChangedImmutableFields(existing, desired appsv1.StatefulSet) []string {
+	var changes []string
+	if existing.Spec.ServiceName != desired.Spec.ServiceName {
+		changes = append(changes, fmt.Sprintf("serviceName (%q -> %q)", existing.Spec.ServiceName, desired.Spec.ServiceName))
+	}
+	// an empty podManagementPolicy is defaulted by the apiserver
+	if existing.Spec.PodManagementPolicy != "" && existing.Spec.PodManagementPolicy != desired.Spec.PodManagementPolicy {
+		changes = append(changes, fmt.Sprintf("podManagementPolicy (%q -> %q)", existing.Spec.PodManagementPolicy, desired.Spec.PodManagementPolicy))
+	}
+	if !equality.Semantic.DeepEqual(existing.Spec.Selector, desired.Spec.Selector) {
+		changes = append(changes, "selector")
+	}
+	for _, name := range changedVolumeClaimTemplates(existing.Spec.VolumeClaimTemplates, desired.Spec.VolumeClaimTemplates) {
+		changes = append(changes, fmt.Sprintf("volumeClaimTemplates[%s]", name))
+	}
+	return changes
+}
+
+// changedVolumeClaimTemplates returns the names of the volume claim templates which have been added,
+// removed or whose labels, annotations or spec have changed.
+func changedVolumeClaimTemplates(existing, desired []corev1.PersistentVolumeClaim) []string {
+	existingByName := map[string]corev1.PersistentVolumeClaim{}
+	for _, pvc := range existing {
+		existingByName[pvc.Name] = pvc
+	}
+	desiredByName := map[string]corev1.PersistentVolumeClaim{}
+	for _, pvc := range desired {
+		desiredByName[pvc.Name] = pvc
+	}
+
+	var changed []string
+	for name, pvc := range desiredByName {
+		existingPvc, ok := existingByName[name]
+		if !ok || !equality.Semantic.DeepEqual(existingPvc.Spec, pvc.Spec) ||
+			!equality.Semantic.DeepEqual(existingPvc.Labels, pvc.Labels) ||
+			!equality.Semantic.DeepEqual(existingPvc.Annotations, pvc.Annotations) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range existingByName {
+		if _, ok := desiredByName[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// IsImmutableFieldsError returns true if the error was returned by the apiserver because an update
+// of a StatefulSet changed fields which can't be updated.
+func IsImmutableFieldsError(err error) bool {
+	return apiErrors.IsInvalid(err) && strings.Contains(err.Error(), immutableFieldsErrorMessage)
+}
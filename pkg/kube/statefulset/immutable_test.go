@@ -0,0 +1,87 @@
+package statefulset
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func withClaimRequest(name, storage string) Modification {
+	return WithVolumeClaim(name, func(pvc *corev1.PersistentVolumeClaim) {
+		pvc.Name = name
+		pvc.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(storage)}
+	})
+}
+
+func TestChangedImmutableFields(t *testing.T) {
+	existing := New(
+		WithServiceName("my-svc"),
+		WithMatchLabels(map[string]string{"app": "my-svc"}),
+		WithPodManagementPolicyType(appsv1.OrderedReadyPodManagement),
+		withClaimRequest("data-volume", "10G"),
+		withClaimRequest("logs-volume", "2G"),
+	)
+
+	t.Run("No changes", func(t *testing.T) {
+		desired := *existing.DeepCopy()
+		WithReplicas(5)(&desired)
+		assert.Empty(t, ChangedImmutableFields(existing, desired))
+	})
+
+	t.Run("Equal quantities are not a change", func(t *testing.T) {
+		desired := *existing.DeepCopy()
+		withClaimRequest("data-volume", "10000M")(&desired)
+		assert.Empty(t, ChangedImmutableFields(existing, desired))
+	})
+
+	t.Run("Every changed field is reported", func(t *testing.T) {
+		desired := *existing.DeepCopy()
+		WithServiceName("other-svc")(&desired)
+		WithPodManagementPolicyType(appsv1.ParallelPodManagement)(&desired)
+		withClaimRequest("logs-volume", "5G")(&desired)
+		assert.Equal(t, []string{
+			`serviceName ("my-svc" -> "other-svc")`,
+			`podManagementPolicy ("OrderedReady" -> "Parallel")`,
+			"volumeClaimTemplates[logs-volume]",
+		}, ChangedImmutableFields(existing, desired))
+	})
+
+	t.Run("Changed labels and annotations of volume claim templates are reported", func(t *testing.T) {
+		desired := *existing.DeepCopy()
+		desired.Spec.VolumeClaimTemplates[0].Labels = map[string]string{"team": "data"}
+		desired.Spec.VolumeClaimTemplates[1].Annotations = map[string]string{"backup": "daily"}
+		assert.Equal(t, []string{"volumeClaimTemplates[data-volume]", "volumeClaimTemplates[logs-volume]"}, ChangedImmutableFields(existing, desired))
+	})
+
+	t.Run("Empty labels are not a change", func(t *testing.T) {
+		desired := *existing.DeepCopy()
+		desired.Spec.VolumeClaimTemplates[0].Labels = map[string]string{}
+		assert.Empty(t, ChangedImmutableFields(existing, desired))
+	})
+
+	t.Run("Added and removed volume claim templates are reported", func(t *testing.T) {
+		desired := *existing.DeepCopy()
+		WithoutVolumeClaim("logs-volume")(&desired)
+		withClaimRequest("journal-volume", "1G")(&desired)
+		assert.Equal(t, []string{"volumeClaimTemplates[journal-volume]", "volumeClaimTemplates[logs-volume]"}, ChangedImmutableFields(existing, desired))
+	})
+}
+
+func TestIsImmutableFieldsError(t *testing.T) {
+	err := apiErrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, "my-rs", field.ErrorList{
+		field.Forbidden(field.NewPath("spec"), "updates to statefulset spec for fields other than 'replicas', 'template', 'updateStrategy' and 'minReadySeconds' are forbidden"),
+	})
+	assert.True(t, IsImmutableFieldsError(err))
+
+	assert.False(t, IsImmutableFieldsError(errors.New("updates to statefulset spec for fields other than 'replicas'")))
+	assert.False(t, IsImmutableFieldsError(apiErrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, "my-rs", field.ErrorList{
+		field.Invalid(field.NewPath("spec", "replicas"), -1, "must be greater than or equal to 0"),
+	})))
+}
@@ -123,6 +123,20 @@ func CreateVolumeFromEmptyDir(name string) corev1.Volume {
 	}
 }
 
+// CreateVolumeFromHostPath returns a volume backed by the given directory of the node, which is created if it does not exist.
+func CreateVolumeFromHostPath(name, hostPath string) corev1.Volume {
+	hostPathType := corev1.HostPathDirectoryOrCreate
+	return corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: hostPath,
+				Type: &hostPathType,
+			},
+		},
+	}
+}
+
 // CreateVolumeMount returns a corev1.VolumeMount with options.
 func CreateVolumeMount(name, path string, options ...func(*corev1.VolumeMount)) corev1.VolumeMount {
 	volumeMount := &corev1.VolumeMount{
@@ -156,6 +170,13 @@ func WithSubPath(subPath string) func(*corev1.VolumeMount) {
 	}
 }
 
+// WithSubPathExpr sets the SubPathExpr for this VolumeMount, which is expanded using the environment variables of the container
+func WithSubPathExpr(subPathExpr string) func(*corev1.VolumeMount) {
+	return func(v *corev1.VolumeMount) {
+		v.SubPathExpr = subPathExpr
+	}
+}
+
 // WithReadOnly sets the ReadOnly attribute of this VolumeMount
 func WithReadOnly(readonly bool) func(*corev1.VolumeMount) {
 	return func(v *corev1.VolumeMount) {
@@ -163,8 +184,14 @@ func WithReadOnly(readonly bool) func(*corev1.VolumeMount) {
 	}
 }
 
+// IsReady returns whether all the expected replicas of the StatefulSet are ready and updated.
+// With a RollingUpdate partition, only the pods with an ordinal greater or equal than the partition
+// are expected to be updated.
 func IsReady(sts appsv1.StatefulSet, expectedReplicas int) bool {
 	allUpdated := int32(expectedReplicas) == sts.Status.UpdatedReplicas
+	if rollingUpdate := sts.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.Partition != nil && *rollingUpdate.Partition > 0 {
+		allUpdated = sts.Status.UpdatedReplicas >= int32(expectedReplicas)-*rollingUpdate.Partition
+	}
 	allReady := int32(expectedReplicas) == sts.Status.ReadyReplicas
 	atExpectedGeneration := sts.Generation == sts.Status.ObservedGeneration
 	return allUpdated && allReady && atExpectedGeneration
@@ -211,6 +238,22 @@ func WithLabels(labels map[string]string) Modification {
 		set.Labels = copyMap(labels)
 	}
 }
+
+// WithAnnotations adds the given annotations to the StatefulSet, annotations which have been added by others are kept.
+func WithAnnotations(annotations map[string]string) Modification {
+	return func(set *appsv1.StatefulSet) {
+		if len(annotations) == 0 {
+			return
+		}
+		if set.Annotations == nil {
+			set.Annotations = map[string]string{}
+		}
+		for k, v := range annotations {
+			set.Annotations[k] = v
+		}
+	}
+}
+
 func WithMatchLabels(matchLabels map[string]string) Modification {
 	return func(set *appsv1.StatefulSet) {
 		if set.Spec.Selector == nil {
@@ -261,6 +304,20 @@ func WithUpdateStrategyType(strategyType appsv1.StatefulSetUpdateStrategyType) M
 	}
 }
 
+// WithRollingUpdatePartition sets the partition of the RollingUpdate strategy. The partition is
+// ignored if the StatefulSet does not use the RollingUpdate strategy.
+func WithRollingUpdatePartition(partition *int32) Modification {
+	return func(set *appsv1.StatefulSet) {
+		if partition == nil || set.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+			return
+		}
+		p := *partition
+		set.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{
+			Partition: &p,
+		}
+	}
+}
+
 func WithPodSpecTemplate(templateFunc func(*corev1.PodTemplateSpec)) Modification {
 	return func(set *appsv1.StatefulSet) {
 		template := &set.Spec.Template
@@ -280,6 +337,24 @@ func WithVolumeClaim(name string, f func(*corev1.PersistentVolumeClaim)) Modific
 	}
 }
 
+// WithoutVolumeClaims removes all the volume claim templates of the StatefulSet.
+func WithoutVolumeClaims() Modification {
+	return func(set *appsv1.StatefulSet) {
+		set.Spec.VolumeClaimTemplates = nil
+	}
+}
+
+// WithoutVolumeClaim removes the volume claim template with the given name from the StatefulSet.
+func WithoutVolumeClaim(name string) Modification {
+	return func(set *appsv1.StatefulSet) {
+		idx := findVolumeClaimIndexByName(name, set.Spec.VolumeClaimTemplates)
+		if idx == notFound {
+			return
+		}
+		set.Spec.VolumeClaimTemplates = append(set.Spec.VolumeClaimTemplates[:idx], set.Spec.VolumeClaimTemplates[idx+1:]...)
+	}
+}
+
 func WithCustomSpecs(spec appsv1.StatefulSetSpec) Modification {
 	return func(set *appsv1.StatefulSet) {
 		set.Spec = merge.StatefulSetSpecs(set.Spec, spec)
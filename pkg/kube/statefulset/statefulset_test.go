@@ -123,6 +123,72 @@ func TestAddVolumeClaimTemplates(t *testing.T) {
 	assert.Equal(t, sts.Spec.Template.Spec.Containers[0].VolumeMounts[0].Name, "mount-0")
 }
 
+func TestWithoutVolumeClaims(t *testing.T) {
+	sts := New(
+		WithVolumeClaim("data", func(*corev1.PersistentVolumeClaim) {}),
+		WithVolumeClaim("logs", func(*corev1.PersistentVolumeClaim) {}),
+	)
+	assert.Len(t, sts.Spec.VolumeClaimTemplates, 2)
+
+	WithoutVolumeClaims()(&sts)
+	assert.Empty(t, sts.Spec.VolumeClaimTemplates)
+}
+
+func TestWithAnnotations(t *testing.T) {
+	sts := New(WithAnnotations(map[string]string{"first": "1"}))
+	assert.Equal(t, map[string]string{"first": "1"}, sts.Annotations)
+
+	WithAnnotations(map[string]string{"first": "2", "second": "2"})(&sts)
+	assert.Equal(t, map[string]string{"first": "2", "second": "2"}, sts.Annotations)
+
+	WithAnnotations(map[string]string{"third": "3"})(&sts)
+	assert.Equal(t, map[string]string{"first": "2", "second": "2", "third": "3"}, sts.Annotations, "existing annotations should be kept")
+}
+
+func TestWithoutVolumeClaim(t *testing.T) {
+	sts := New(
+		WithVolumeClaim("data", func(pvc *corev1.PersistentVolumeClaim) { pvc.Name = "data" }),
+		WithVolumeClaim("logs", func(pvc *corev1.PersistentVolumeClaim) { pvc.Name = "logs" }),
+	)
+
+	WithoutVolumeClaim("data")(&sts)
+	assert.Len(t, sts.Spec.VolumeClaimTemplates, 1)
+	assert.Equal(t, "logs", sts.Spec.VolumeClaimTemplates[0].Name)
+
+	WithoutVolumeClaim("data")(&sts)
+	assert.Len(t, sts.Spec.VolumeClaimTemplates, 1, "removing a missing claim is a no-op")
+}
+
+func TestWithRollingUpdatePartition(t *testing.T) {
+	partition := int32(2)
+
+	sts := New(WithUpdateStrategyType(appsv1.RollingUpdateStatefulSetStrategyType), WithRollingUpdatePartition(&partition))
+	assert.Equal(t, int32(2), *sts.Spec.UpdateStrategy.RollingUpdate.Partition)
+
+	sts = New(WithUpdateStrategyType(appsv1.OnDeleteStatefulSetStrategyType), WithRollingUpdatePartition(&partition))
+	assert.Nil(t, sts.Spec.UpdateStrategy.RollingUpdate, "The partition should be ignored with the OnDelete strategy")
+
+	sts = New(WithUpdateStrategyType(appsv1.RollingUpdateStatefulSetStrategyType), WithRollingUpdatePartition(nil))
+	assert.Nil(t, sts.Spec.UpdateStrategy.RollingUpdate)
+}
+
+func TestIsReady_WithPartition(t *testing.T) {
+	partition := int32(2)
+	sts := New(WithUpdateStrategyType(appsv1.RollingUpdateStatefulSetStrategyType), WithRollingUpdatePartition(&partition))
+	sts.Status.ReadyReplicas = 3
+	sts.Status.UpdatedReplicas = 0
+	assert.False(t, IsReady(sts, 3))
+
+	sts.Status.UpdatedReplicas = 1
+	assert.True(t, IsReady(sts, 3), "Only the pods at or above the partition need to be updated")
+
+	sts.Status.UpdatedReplicas = 3
+	assert.True(t, IsReady(sts, 3))
+
+	sts.Status.ReadyReplicas = 2
+	assert.False(t, IsReady(sts, 3))
+}
+
 func TestBuildStructImmutable(t *testing.T) {
 	labels := map[string]string{"label_1": "a", "label_2": "b"}
 
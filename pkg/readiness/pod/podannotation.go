@@ -11,7 +11,11 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-const mongodbAgentVersionAnnotation = "agent.mongodb.com/version"
+const (
+	mongodbAgentVersionAnnotation = "agent.mongodb.com/version"
+	// notReadyReasonAnnotation is read by the operator to report why a member is not ready.
+	notReadyReasonAnnotation = "agent.mongodb.com/not-ready-reason"
+)
 
 func PatchPodAnnotation(podNamespace string, lastVersionAchieved int64, memberName string, clientSet kubernetes.Interface) error {
 	pod, err := clientSet.CoreV1().Pods(podNamespace).Get(context.Background(), memberName, metav1.GetOptions{})
@@ -42,3 +46,34 @@ func PatchPodAnnotation(podNamespace string, lastVersionAchieved int64, memberNa
 	}
 	return err
 }
+
+// PatchPodNotReadyReason records the reason the pod is not ready in its annotations, an empty reason is recorded
+// once the pod is ready. The pod is only patched if the reason has changed.
+func PatchPodNotReadyReason(podNamespace, reason, memberName string, clientSet kubernetes.Interface) error {
+	pod, err := clientSet.CoreV1().Pods(podNamespace).Get(context.Background(), memberName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if pod.Annotations[notReadyReasonAnnotation] == reason {
+		return nil
+	}
+
+	var payload []patchValue
+
+	if len(pod.Annotations) == 0 {
+		payload = append(payload, patchValue{
+			Op:    "add",
+			Path:  "/metadata/annotations",
+			Value: make(map[string]string),
+		})
+	}
+	payload = append(payload, patchValue{
+		Op:    "add",
+		Path:  "/metadata/annotations/" + strings.Replace(notReadyReasonAnnotation, "/", "~1", -1),
+		Value: reason,
+	})
+
+	patcher := NewKubernetesPodPatcher(clientSet)
+	_, err = patcher.patchPod(podNamespace, memberName, payload)
+	return err
+}
@@ -41,6 +41,27 @@ func TestPatchPodAnnotation(t *testing.T) {
 	assert.Equal(t, map[string]string{"agent.mongodb.com/version": "2"}, pod.Annotations)
 }
 
+// TestPatchPodNotReadyReason verifies that the reason is recorded and cleared once the pod is ready
+func TestPatchPodNotReadyReason(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-replica-set-0",
+			Namespace: "test-ns",
+			Annotations: map[string]string{
+				notReadyReasonAnnotation: "",
+			},
+		},
+	})
+
+	assert.NoError(t, PatchPodNotReadyReason("test-ns", "mongod is not up", "my-replica-set-0", clientset))
+	pod, _ := clientset.CoreV1().Pods("test-ns").Get(context.TODO(), "my-replica-set-0", metav1.GetOptions{})
+	assert.Equal(t, "mongod is not up", pod.Annotations[notReadyReasonAnnotation])
+
+	assert.NoError(t, PatchPodNotReadyReason("test-ns", "", "my-replica-set-0", clientset))
+	pod, _ = clientset.CoreV1().Pods("test-ns").Get(context.TODO(), "my-replica-set-0", metav1.GetOptions{})
+	assert.Empty(t, pod.Annotations[notReadyReasonAnnotation])
+}
+
 func TestUpdatePodAnnotationPodNotFound(t *testing.T) {
 	assert.True(t, apiErrors.IsNotFound(PatchPodAnnotation("wrong-ns", 1, "my-replica-set-0", fake.NewSimpleClientset())))
 }
@@ -0,0 +1,123 @@
+package replicaset
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ClientCache holds a connected client for each replica set, so that its connection pool is reused across
+// reconciliations instead of connecting to the members every time. A client is replaced once the options
+// to connect with change, e.g. after the password has been changed or the CA certificate has been rotated.
+type ClientCache struct {
+	lock    *sync.Mutex
+	clients map[types.NamespacedName]cachedClient
+	timeout time.Duration
+}
+
+type cachedClient struct {
+	client      *mongo.Client
+	optionsHash string
+}
+
+// NewClientCache returns an empty ClientCache whose clients bound connecting and selecting a member by the given timeout.
+func NewClientCache(timeout time.Duration) ClientCache {
+	return ClientCache{
+		lock:    &sync.Mutex{},
+		clients: map[types.NamespacedName]cachedClient{},
+		timeout: timeout,
+	}
+}
+
+// Get returns the client of the replica set, a new one is connected if there is none or if it has been
+// connected with different options.
+func (c ClientCache) Get(nsName types.NamespacedName, opts ConnectionOptions) (*mongo.Client, error) {
+	hash := opts.hash()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cached, ok := c.clients[nsName]
+	if ok && cached.optionsHash == hash {
+		return cached.client, nil
+	}
+
+	clientOpts, err := opts.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	clientOpts.SetConnectTimeout(c.timeout).SetServerSelectionTimeout(c.timeout)
+	// the client connects to the members in the background, this only fails for invalid options
+	client, err := mongo.Connect(context.Background(), clientOpts)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to replica set %s: %s", opts.ReplicaSetName, err)
+	}
+	if ok {
+		disconnect(cached.client)
+	}
+	c.clients[nsName] = cachedClient{client: client, optionsHash: hash}
+	return client, nil
+}
+
+// Remove disconnects and removes the client of the replica set, e.g. once the resource has been deleted.
+func (c ClientCache) Remove(nsName types.NamespacedName) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if cached, ok := c.clients[nsName]; ok {
+		disconnect(cached.client)
+		delete(c.clients, nsName)
+	}
+}
+
+// disconnect closes the connections of the client in the background, so that
+// in-flight operations of other callers can complete.
+func disconnect(client *mongo.Client) {
+	go func() {
+		_ = client.Disconnect(context.Background())
+	}()
+}
+
+// clientOptions returns the options of the driver to connect to the replica set with.
+func (opts ConnectionOptions) clientOptions() (*options.ClientOptions, error) {
+	clientOpts := options.Client().
+		SetHosts(opts.Hosts).
+		SetReplicaSet(opts.ReplicaSetName)
+	if opts.Username != "" {
+		clientOpts.SetAuth(options.Credential{
+			AuthSource: "admin",
+			Username:   opts.Username,
+			Password:   opts.Password,
+		})
+	}
+	if opts.CACertificate != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(opts.CACertificate)) {
+			return nil, fmt.Errorf("no certificate found in the CA certificate of replica set %s", opts.ReplicaSetName)
+		}
+		clientOpts.SetTLSConfig(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12})
+	}
+	return clientOpts, nil
+}
+
+// hash returns a digest of the options, so that the credentials are not kept in the cache in plain text.
+func (opts ConnectionOptions) hash() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strings.Join(opts.Hosts, ","),
+		opts.ReplicaSetName,
+		opts.Username,
+		opts.Password,
+		opts.CACertificate,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,73 @@
+package replicaset
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestClientCache(t *testing.T) {
+	nsName := types.NamespacedName{Name: "my-rs", Namespace: "my-ns"}
+	opts := ConnectionOptions{
+		Hosts:          []string{"my-rs-0.my-rs-svc:27017", "my-rs-1.my-rs-svc:27017"},
+		ReplicaSetName: "my-rs",
+		Username:       "mms-automation",
+		Password:       "password",
+	}
+
+	t.Run("Client is reused while the options don't change", func(t *testing.T) {
+		cache := NewClientCache(time.Second)
+		client, err := cache.Get(nsName, opts)
+		assert.NoError(t, err)
+
+		sameClient, err := cache.Get(nsName, opts)
+		assert.NoError(t, err)
+		assert.Same(t, client, sameClient)
+
+		otherClient, err := cache.Get(types.NamespacedName{Name: "other-rs", Namespace: "my-ns"}, opts)
+		assert.NoError(t, err)
+		assert.NotSame(t, client, otherClient)
+	})
+
+	t.Run("Client is replaced once the options change", func(t *testing.T) {
+		cache := NewClientCache(time.Second)
+		client, err := cache.Get(nsName, opts)
+		assert.NoError(t, err)
+
+		changedOpts := opts
+		changedOpts.Password = "changed-password"
+		newClient, err := cache.Get(nsName, changedOpts)
+		assert.NoError(t, err)
+		assert.NotSame(t, client, newClient)
+	})
+
+	t.Run("Client is connected again after it has been removed", func(t *testing.T) {
+		cache := NewClientCache(time.Second)
+		client, err := cache.Get(nsName, opts)
+		assert.NoError(t, err)
+
+		cache.Remove(nsName)
+		newClient, err := cache.Get(nsName, opts)
+		assert.NoError(t, err)
+		assert.NotSame(t, client, newClient)
+	})
+
+	t.Run("Invalid CA certificate is rejected", func(t *testing.T) {
+		tlsOpts := opts
+		tlsOpts.CACertificate = "not a certificate"
+		_, err := NewClientCache(time.Second).Get(nsName, tlsOpts)
+		assert.Error(t, err)
+	})
+}
+
+func TestConnectionOptionsHash(t *testing.T) {
+	opts := ConnectionOptions{Hosts: []string{"my-rs-0:27017"}, ReplicaSetName: "my-rs", Username: "user", Password: "password"}
+	assert.Equal(t, opts.hash(), opts.hash())
+	assert.NotContains(t, opts.hash(), "password")
+
+	rotated := opts
+	rotated.CACertificate = "rotated CA"
+	assert.NotEqual(t, opts.hash(), rotated.hash(), "a rotated CA certificate should connect a new client")
+}
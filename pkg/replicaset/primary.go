@@ -0,0 +1,222 @@
+package replicaset
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	PrimaryState   = "PRIMARY"
+	SecondaryState = "SECONDARY"
+)
+
+// ConnectionOptions holds what is required to connect to the members of a replica set.
+type ConnectionOptions struct {
+	Hosts          []string
+	ReplicaSetName string
+
+	// Username and Password are the credentials to authenticate with against the
+	// admin database, no authentication is performed if Username is empty.
+	Username string
+	Password string
+
+	// CACertificate is the PEM encoded certificate of the CA which signed the certificates of the
+	// members, TLS is used to connect to the members if it is not empty.
+	CACertificate string
+}
+
+// MemberStates holds the state of each member of a replica set, e.g. "PRIMARY" or "SECONDARY", keyed by its host.
+type MemberStates map[string]string
+
+// Primary returns the host of the primary, or an empty string if the replica set has no primary.
+func (s MemberStates) Primary() string {
+	for host, state := range s {
+		if state == PrimaryState {
+			return host
+		}
+	}
+	return ""
+}
+
+// ConfigVersions holds the version of the replica set config each member is on, keyed by its host.
+type ConfigVersions map[string]int
+
+// Diverge returns true if the members are not all on the same version of the replica set config.
+func (v ConfigVersions) Diverge() bool {
+	version := -1
+	for _, memberVersion := range v {
+		if version != -1 && memberVersion != version {
+			return true
+		}
+		version = memberVersion
+	}
+	return false
+}
+
+// Status holds the state of the members of a replica set, as reported by a single replSetGetStatus.
+type Status struct {
+	States MemberStates
+
+	// ConfigVersions holds the version of the replica set config of each member which can be reached.
+	ConfigVersions ConfigVersions
+}
+
+// StatusGetter returns the current state of the members of a replica set.
+type StatusGetter interface {
+	GetStatus(ctx context.Context, nsName types.NamespacedName, opts ConnectionOptions) (Status, error)
+
+	// Forget releases the resources held for the replica set, e.g. once the resource has been deleted.
+	Forget(nsName types.NamespacedName)
+}
+
+// NewStatusGetter returns a StatusGetter which reads the state of the members with replSetGetStatus.
+// The client of each replica set is reused across calls, and every attempt is bounded by the given timeout.
+func NewStatusGetter(timeout time.Duration) StatusGetter {
+	return driverStatusGetter{timeout: timeout, clients: NewClientCache(timeout)}
+}
+
+type driverStatusGetter struct {
+	timeout time.Duration
+	clients ClientCache
+}
+
+type replSetStatus struct {
+	Members []memberStatus `bson:"members"`
+}
+
+type memberStatus struct {
+	Name     string `bson:"name"`
+	StateStr string `bson:"stateStr"`
+	Health   int    `bson:"health"`
+
+	// ConfigVersion is the version of the replica set config the member is on, as reported by the member itself
+	// for the member which runs replSetGetStatus and through the heartbeats for the other members.
+	ConfigVersion int `bson:"configVersion"`
+}
+
+// GetStatus returns the state and the config version of each member of the replica set.
+func (g driverStatusGetter) GetStatus(ctx context.Context, nsName types.NamespacedName, opts ConnectionOptions) (Status, error) {
+	status, err := g.replSetGetStatus(ctx, nsName, opts)
+	if err != nil {
+		return Status{}, err
+	}
+	return Status{
+		States:         memberStatesFromStatus(status),
+		ConfigVersions: configVersionsFromStatus(status),
+	}, nil
+}
+
+// replSetGetStatus runs replSetGetStatus on any member of the replica set.
+func (g driverStatusGetter) replSetGetStatus(ctx context.Context, nsName types.NamespacedName, opts ConnectionOptions) (replSetStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	client, err := g.clients.Get(nsName, opts)
+	if err != nil {
+		return replSetStatus{}, err
+	}
+
+	status := replSetStatus{}
+	// any member can report the status of the replica set, even when there is no primary
+	cmdOpts := options.RunCmd().SetReadPreference(readpref.Nearest())
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "replSetGetStatus", Value: 1}}, cmdOpts).Decode(&status); err != nil {
+		return replSetStatus{}, fmt.Errorf("could not run replSetGetStatus on replica set %s: %s", opts.ReplicaSetName, err)
+	}
+	return status, nil
+}
+
+// Forget disconnects the client of the replica set.
+func (g driverStatusGetter) Forget(nsName types.NamespacedName) {
+	g.clients.Remove(nsName)
+}
+
+// memberStatesFromStatus returns the state of each member in the output of replSetGetStatus.
+func memberStatesFromStatus(status replSetStatus) MemberStates {
+	states := MemberStates{}
+	for _, member := range status.Members {
+		states[member.Name] = member.StateStr
+	}
+	return states
+}
+
+// configVersionsFromStatus returns the config version of each member in the output of replSetGetStatus. Members
+// which can't be reached are left out, they don't report a version.
+func configVersionsFromStatus(status replSetStatus) ConfigVersions {
+	versions := ConfigVersions{}
+	for _, member := range status.Members {
+		if member.Health == 1 && member.ConfigVersion > 0 {
+			versions[member.Name] = member.ConfigVersion
+		}
+	}
+	return versions
+}
+
+// PrimaryCache holds the last known primary of each replica set for a limited time.
+type PrimaryCache struct {
+	ttl     time.Duration
+	lock    *sync.Mutex
+	entries map[types.NamespacedName]cachedPrimary
+}
+
+type cachedPrimary struct {
+	host      string
+	fetchedAt time.Time
+}
+
+// NewPrimaryCache returns an empty PrimaryCache whose entries are stale after the given ttl.
+func NewPrimaryCache(ttl time.Duration) PrimaryCache {
+	return PrimaryCache{
+		ttl:     ttl,
+		lock:    &sync.Mutex{},
+		entries: map[types.NamespacedName]cachedPrimary{},
+	}
+}
+
+// Get returns the last known primary of the replica set and whether it is still fresh.
+func (c PrimaryCache) Get(nsName types.NamespacedName) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[nsName]
+	if !ok {
+		return "", false
+	}
+	return entry.host, time.Since(entry.fetchedAt) < c.ttl
+}
+
+// Set stores the primary of the replica set.
+func (c PrimaryCache) Set(nsName types.NamespacedName, host string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries[nsName] = cachedPrimary{host: host, fetchedAt: time.Now()}
+}
+
+// Remove deletes the primary of the replica set, e.g. once the resource has been deleted.
+func (c PrimaryCache) Remove(nsName types.NamespacedName) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.entries, nsName)
+}
+
+// ShouldRefresh returns whether the primary of the replica set should be fetched again.
+// It returns true at most once per ttl, so that a single refresh is in flight at a time.
+func (c PrimaryCache) ShouldRefresh(nsName types.NamespacedName) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[nsName]
+	if ok && time.Since(entry.fetchedAt) < c.ttl {
+		return false
+	}
+	c.entries[nsName] = cachedPrimary{host: entry.host, fetchedAt: time.Now()}
+	return true
+}
@@ -0,0 +1,89 @@
+package replicaset
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestMemberStatesFromStatus(t *testing.T) {
+	status := replSetStatus{
+		Members: []memberStatus{
+			{Name: "my-rs-0.my-rs-svc:27017", StateStr: "SECONDARY"},
+			{Name: "my-rs-1.my-rs-svc:27017", StateStr: "PRIMARY"},
+			{Name: "my-rs-2.my-rs-svc:27017", StateStr: "RECOVERING"},
+		},
+	}
+	states := memberStatesFromStatus(status)
+	assert.Equal(t, MemberStates{
+		"my-rs-0.my-rs-svc:27017": SecondaryState,
+		"my-rs-1.my-rs-svc:27017": PrimaryState,
+		"my-rs-2.my-rs-svc:27017": "RECOVERING",
+	}, states)
+	assert.Equal(t, "my-rs-1.my-rs-svc:27017", states.Primary())
+
+	status.Members[1].StateStr = "SECONDARY"
+	assert.Equal(t, "", memberStatesFromStatus(status).Primary())
+}
+
+func TestConfigVersionsFromStatus(t *testing.T) {
+	status := replSetStatus{
+		Members: []memberStatus{
+			{Name: "my-rs-0.my-rs-svc:27017", Health: 1, ConfigVersion: 3},
+			{Name: "my-rs-1.my-rs-svc:27017", Health: 1, ConfigVersion: 3},
+			{Name: "my-rs-2.my-rs-svc:27017", Health: 0, ConfigVersion: -1},
+		},
+	}
+	versions := configVersionsFromStatus(status)
+	assert.Equal(t, ConfigVersions{
+		"my-rs-0.my-rs-svc:27017": 3,
+		"my-rs-1.my-rs-svc:27017": 3,
+	}, versions)
+	assert.False(t, versions.Diverge())
+
+	status.Members[1].ConfigVersion = 2
+	assert.True(t, configVersionsFromStatus(status).Diverge())
+	assert.False(t, ConfigVersions{}.Diverge())
+}
+
+func TestPrimaryCache(t *testing.T) {
+	nsName := types.NamespacedName{Name: "my-rs", Namespace: "my-ns"}
+
+	t.Run("Entries are fresh until the ttl expires", func(t *testing.T) {
+		cache := NewPrimaryCache(time.Hour)
+		_, fresh := cache.Get(nsName)
+		assert.False(t, fresh)
+
+		cache.Set(nsName, "my-rs-0:27017")
+		host, fresh := cache.Get(nsName)
+		assert.True(t, fresh)
+		assert.Equal(t, "my-rs-0:27017", host)
+
+		expired := NewPrimaryCache(0)
+		expired.Set(nsName, "my-rs-0:27017")
+		host, fresh = expired.Get(nsName)
+		assert.False(t, fresh)
+		assert.Equal(t, "my-rs-0:27017", host)
+	})
+
+	t.Run("A single refresh is allowed per ttl", func(t *testing.T) {
+		cache := NewPrimaryCache(time.Hour)
+		assert.True(t, cache.ShouldRefresh(nsName))
+		assert.False(t, cache.ShouldRefresh(nsName))
+
+		cache = NewPrimaryCache(0)
+		assert.True(t, cache.ShouldRefresh(nsName))
+		assert.True(t, cache.ShouldRefresh(nsName))
+	})
+
+	t.Run("Removed entries are refreshed", func(t *testing.T) {
+		cache := NewPrimaryCache(time.Hour)
+		cache.Set(nsName, "my-rs-0:27017")
+		cache.Remove(nsName)
+		_, fresh := cache.Get(nsName)
+		assert.False(t, fresh)
+		assert.True(t, cache.ShouldRefresh(nsName))
+	})
+}
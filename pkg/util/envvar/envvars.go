@@ -29,6 +29,25 @@ func MergeWithOverride(existing, desired []corev1.EnvVar) []corev1.EnvVar {
 	return mergedEnv
 }
 
+// Remove returns the environment variables without the ones with the given names.
+func Remove(envs []corev1.EnvVar, names ...string) []corev1.EnvVar {
+	if len(names) == 0 {
+		return envs
+	}
+	removed := map[string]bool{}
+	for _, name := range names {
+		removed[name] = true
+	}
+
+	var remaining []corev1.EnvVar
+	for _, env := range envs {
+		if !removed[env.Name] {
+			remaining = append(remaining, env)
+		}
+	}
+	return remaining
+}
+
 func GetEnvOrDefault(envVar, defaultValue string) string {
 	if val, ok := os.LookupEnv(envVar); ok {
 		return val
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestGetEnvOrDefault(t *testing.T) {
@@ -18,3 +19,11 @@ func TestGetEnvOrDefault(t *testing.T) {
 	val2 := GetEnvOrDefault("env2", "defaultVal2")
 	assert.Equal(t, "defaultVal2", val2)
 }
+
+func TestRemove(t *testing.T) {
+	envs := []corev1.EnvVar{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}, {Name: "c", Value: "3"}}
+
+	assert.Equal(t, envs, Remove(envs))
+	assert.Equal(t, []corev1.EnvVar{{Name: "b", Value: "2"}}, Remove(envs, "a", "c", "d"))
+	assert.Nil(t, Remove(envs, "a", "b", "c"))
+}
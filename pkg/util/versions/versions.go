@@ -20,3 +20,54 @@ func CalculateFeatureCompatibilityVersion(versionStr string) string {
 
 	return ""
 }
+
+// IsProtocolVersionSupported returns true if the replica set protocol version can be used
+// with the given MongoDB version. Protocol version 0 was removed in MongoDB 4.0.
+func IsProtocolVersionSupported(mongodbVersion, protocolVersion string) (bool, error) {
+	switch protocolVersion {
+	case "1":
+		return true, nil
+	case "0":
+		v, err := semver.Make(mongodbVersion)
+		if err != nil {
+			return false, fmt.Errorf("invalid MongoDB version %q: %s", mongodbVersion, err)
+		}
+		return v.LT(semver.MustParse("4.0.0")), nil
+	default:
+		return false, nil
+	}
+}
+
+// IsFeatureCompatibilityVersionDowngrade returns true if the desired feature compatibility
+// version, in the format of "x.y", is lower than the current one.
+func IsFeatureCompatibilityVersionDowngrade(current, desired string) (bool, error) {
+	currentSemver, err := semver.Make(fmt.Sprintf("%s.0", current))
+	if err != nil {
+		return false, fmt.Errorf("invalid featureCompatibilityVersion %s: %s", current, err)
+	}
+	desiredSemver, err := semver.Make(fmt.Sprintf("%s.0", desired))
+	if err != nil {
+		return false, fmt.Errorf("invalid featureCompatibilityVersion %s: %s", desired, err)
+	}
+	return desiredSemver.LT(currentSemver), nil
+}
+
+// IsFreeMonitoringSupported returns true if the given MongoDB version has free cloud monitoring, which was
+// added in MongoDB 4.0 and removed in MongoDB 7.0.
+func IsFreeMonitoringSupported(mongodbVersion string) (bool, error) {
+	v, err := semver.Make(mongodbVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid MongoDB version %q: %s", mongodbVersion, err)
+	}
+	return v.GTE(semver.MustParse("4.0.0")) && v.LT(semver.MustParse("7.0.0")), nil
+}
+
+// IsKeyfileRotationSupported returns true if mongod accepts keyfiles with multiple keys, which are required
+// to rotate the keyfile without downtime, from MongoDB 4.2.
+func IsKeyfileRotationSupported(mongodbVersion string) (bool, error) {
+	v, err := semver.Make(mongodbVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid MongoDB version %q: %s", mongodbVersion, err)
+	}
+	return v.GTE(semver.MustParse("4.2.0")), nil
+}
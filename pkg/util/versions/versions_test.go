@@ -32,3 +32,63 @@ func TestCalculateFCV(t *testing.T) {
 		assert.Equal(t, "", CalculateFeatureCompatibilityVersion("1.4.5"))
 	})
 }
+
+func TestIsFeatureCompatibilityVersionDowngrade(t *testing.T) {
+	isDowngrade, err := IsFeatureCompatibilityVersionDowngrade("4.2", "4.0")
+	assert.NoError(t, err)
+	assert.True(t, isDowngrade)
+
+	isDowngrade, err = IsFeatureCompatibilityVersionDowngrade("4.0", "4.2")
+	assert.NoError(t, err)
+	assert.False(t, isDowngrade)
+
+	isDowngrade, err = IsFeatureCompatibilityVersionDowngrade("4.2", "4.2")
+	assert.NoError(t, err)
+	assert.False(t, isDowngrade)
+
+	_, err = IsFeatureCompatibilityVersionDowngrade("4.2", "invalid")
+	assert.Error(t, err)
+}
+
+func TestIsProtocolVersionSupported(t *testing.T) {
+	supported, err := IsProtocolVersionSupported("4.4.0", "1")
+	assert.NoError(t, err)
+	assert.True(t, supported)
+
+	supported, err = IsProtocolVersionSupported("3.6.8", "0")
+	assert.NoError(t, err)
+	assert.True(t, supported)
+
+	supported, err = IsProtocolVersionSupported("4.0.0", "0")
+	assert.NoError(t, err)
+	assert.False(t, supported)
+
+	supported, err = IsProtocolVersionSupported("4.4.0", "2")
+	assert.NoError(t, err)
+	assert.False(t, supported)
+
+	_, err = IsProtocolVersionSupported("invalid", "0")
+	assert.Error(t, err)
+}
+
+func TestIsFreeMonitoringSupported(t *testing.T) {
+	for version, expected := range map[string]bool{"3.6.8": false, "4.0.0": true, "6.0.5": true, "7.0.0": false} {
+		supported, err := IsFreeMonitoringSupported(version)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, supported, version)
+	}
+
+	_, err := IsFreeMonitoringSupported("invalid")
+	assert.Error(t, err)
+}
+
+func TestIsKeyfileRotationSupported(t *testing.T) {
+	for version, expected := range map[string]bool{"4.0.20": false, "4.2.0": true, "6.0.5": true} {
+		supported, err := IsKeyfileRotationSupported(version)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, supported, version)
+	}
+
+	_, err := IsKeyfileRotationSupported("invalid")
+	assert.Error(t, err)
+}
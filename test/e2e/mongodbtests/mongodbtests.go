@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/container"
+	"github.com/mongodb/mongodb-kubernetes-operator/test/e2e/util/mongotester"
 	"github.com/mongodb/mongodb-kubernetes-operator/test/e2e/util/wait"
 
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
@@ -20,6 +21,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // SkipTestIfLocal skips tests locally which tests connectivity to mongodb pods
@@ -235,6 +237,17 @@ func AutomationConfigVersionHasTheExpectedVersion(mdb *mdbv1.MongoDBCommunity, e
 	}
 }
 
+// WaitForAutomationConfigVersion waits until the automation config reaches the expected version, and verifies
+// that it has not been increased any further. Unlike AutomationConfigVersionHasTheExpectedVersion, it doesn't
+// fail if the automation config is read right before the operator has updated it.
+func WaitForAutomationConfigVersion(mdb *mdbv1.MongoDBCommunity, expectedVersion int, timeout time.Duration) func(t *testing.T) {
+	return func(t *testing.T) {
+		version, err := wait.ForAutomationConfigVersion(t, mdb, expectedVersion, time.Second*5, timeout)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedVersion, version)
+	}
+}
+
 // AutomationConfigVersionHasTheExpectedVersion verifies that the automation config has the expected version.
 func AutomationConfigReplicaSetsHaveExpectedArbiters(mdb *mdbv1.MongoDBCommunity, expectedArbiters int) func(t *testing.T) {
 	return func(t *testing.T) {
@@ -364,6 +377,40 @@ func Scale(mdb *mdbv1.MongoDBCommunity, newMembers int) func(*testing.T) {
 	}
 }
 
+// RotatePassword changes the password of the user in its password Secret. The operator watches the Secret
+// and recomputes the SCRAM credentials of the user once the Secret has changed.
+func RotatePassword(mdb *mdbv1.MongoDBCommunity, user mdbv1.MongoDBUser, newPassword string) func(*testing.T) {
+	return func(t *testing.T) {
+		passwordSecret := corev1.Secret{}
+		secretNsName := types.NamespacedName{Name: user.PasswordSecretRef.Name, Namespace: mdb.Namespace}
+		if err := e2eutil.TestClient.Get(context.TODO(), secretNsName, &passwordSecret); err != nil {
+			t.Fatal(err)
+		}
+		if passwordSecret.Data == nil {
+			passwordSecret.Data = map[string][]byte{}
+		}
+		passwordSecret.Data[user.GetPasswordSecretKey()] = []byte(newPassword)
+		if err := e2eutil.TestClient.Update(context.TODO(), &passwordSecret); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("Rotated the password of user %s in Secret %s", user.Name, secretNsName)
+	}
+}
+
+// PasswordIsRotated checks that the user can authenticate with the new password once the deployment has
+// converged, and that the old password is rejected.
+func PasswordIsRotated(mdb *mdbv1.MongoDBCommunity, user mdbv1.MongoDBUser, oldPassword, newPassword string) func(*testing.T) {
+	return func(t *testing.T) {
+		tester, err := mongotester.FromResource(t, *mdb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run("New password authenticates", tester.ConnectivitySucceedsWithRetry(time.Second, 5*time.Minute, mongotester.WithScram(user.Name, newPassword)))
+		t.Run("MongoDB reaches Running phase", MongoDBReachesRunningPhase(mdb))
+		t.Run("Old password is rejected", tester.ConnectivityFails(mongotester.WithScram(user.Name, oldPassword)))
+	}
+}
+
 // DisableTLS changes the tls.enabled attribute to false.
 func DisableTLS(mdb *mdbv1.MongoDBCommunity) func(*testing.T) {
 	return tls(mdb, false)
@@ -453,6 +500,49 @@ func StatefulSetMessageIsReceived(mdb *mdbv1.MongoDBCommunity, ctx *e2eutil.Cont
 	}
 }
 
+// AssertNoPodRestartsDuring runs the given test and fails if any of the pods which existed before it
+// have been recreated or any of their containers have been restarted. Pods created by the test, e.g.
+// when scaling up, are not considered.
+func AssertNoPodRestartsDuring(mdb *mdbv1.MongoDBCommunity, testFunc func(t *testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		before := podRestartCounts(t, mdb)
+		testFunc(t)
+		after := podRestartCounts(t, mdb)
+
+		for podName, containers := range before {
+			if _, ok := after[podName]; !ok {
+				t.Errorf("pod %s was recreated or deleted", podName)
+				continue
+			}
+			for containerName, restartCount := range containers {
+				if after[podName][containerName] != restartCount {
+					t.Errorf("container %s of pod %s was restarted %d times", containerName, podName, after[podName][containerName]-restartCount)
+				}
+			}
+		}
+	}
+}
+
+// podRestartCounts returns the restart count of every container of the pods of the resource, keyed by
+// the name and UID of the pod, so that a recreated pod is not mistaken for the original one.
+func podRestartCounts(t *testing.T, mdb *mdbv1.MongoDBCommunity) map[string]map[string]int32 {
+	pods := corev1.PodList{}
+	err := e2eutil.TestClient.Client.List(context.TODO(), &pods, client.InNamespace(mdb.Namespace), client.MatchingLabels{"app": mdb.ServiceName()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restartCounts := map[string]map[string]int32{}
+	for _, pod := range pods.Items {
+		podName := fmt.Sprintf("%s (%s)", pod.Name, pod.UID)
+		restartCounts[podName] = map[string]int32{}
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			restartCounts[podName][status.Name] = status.RestartCount
+		}
+	}
+	return restartCounts
+}
+
 func podFromMongoDBCommunity(mdb *mdbv1.MongoDBCommunity, podNum int) corev1.Pod {
 	return corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/generate"
 	. "github.com/mongodb/mongodb-kubernetes-operator/test/e2e/util/mongotester"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
@@ -35,10 +36,18 @@ func TestReplicaSetAuthentication(t *testing.T) {
 
 	// Run all the possible configuration using sha256 or sha1
 	t.Run("Auth test with SHA-256", testConfigAuthentication(mdb, user, pw))
-	t.Run("Auth test with SHA-256 and SHA-1", testConfigAuthentication(mdb, user, pw, withSha1()))
-	t.Run("Auth test with SHA-256 (using label)", testConfigAuthentication(mdb, user, pw, withLabeledSha256()))
-	t.Run("Auth test with SHA-256 (using label) and SHA-1", testConfigAuthentication(mdb, user, pw, withSha1(), withLabeledSha256()))
-	t.Run("Auth test with SHA-1", testConfigAuthentication(mdb, user, pw, withSha1(), withoutSha256()))
+
+	newPw, err := generate.RandomFixedLengthStringOfSize(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("Rotate the password of the user", mongodbtests.RotatePassword(&mdb, user, newPw))
+	t.Run("Password has been rotated", mongodbtests.PasswordIsRotated(&mdb, user, pw, newPw))
+
+	t.Run("Auth test with SHA-256 and SHA-1", testConfigAuthentication(mdb, user, newPw, withSha1()))
+	t.Run("Auth test with SHA-256 (using label)", testConfigAuthentication(mdb, user, newPw, withLabeledSha256()))
+	t.Run("Auth test with SHA-256 (using label) and SHA-1", testConfigAuthentication(mdb, user, newPw, withSha1(), withLabeledSha256()))
+	t.Run("Auth test with SHA-1", testConfigAuthentication(mdb, user, newPw, withSha1(), withoutSha256()))
 }
 
 type authOptions struct {
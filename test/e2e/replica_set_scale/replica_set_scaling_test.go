@@ -45,10 +45,12 @@ func TestReplicaSetScaleUp(t *testing.T) {
 	t.Run("AutomationConfig has the correct version", mongodbtests.AutomationConfigVersionHasTheExpectedVersion(&mdb, 1))
 	t.Run("MongoDB is reachable", func(t *testing.T) {
 		defer tester.StartBackgroundConnectivityTest(t, time.Second*10)()
-		t.Run("Scale MongoDB Resource Up", mongodbtests.Scale(&mdb, 5))
-		t.Run("Stateful Set Scaled Up Correctly", mongodbtests.StatefulSetBecomesReady(&mdb))
+		t.Run("Existing Pods Are Not Restarted", mongodbtests.AssertNoPodRestartsDuring(&mdb, func(t *testing.T) {
+			t.Run("Scale MongoDB Resource Up", mongodbtests.Scale(&mdb, 5))
+			t.Run("Stateful Set Scaled Up Correctly", mongodbtests.StatefulSetBecomesReady(&mdb))
+		}))
 		t.Run("MongoDB Reaches Running Phase", mongodbtests.MongoDBReachesRunningPhase(&mdb))
-		t.Run("AutomationConfig's version has been increased", mongodbtests.AutomationConfigVersionHasTheExpectedVersion(&mdb, 3))
+		t.Run("AutomationConfig's version has been increased", mongodbtests.WaitForAutomationConfigVersion(&mdb, 3, time.Minute*2))
 		t.Run("Test Status Was Updated", mongodbtests.Status(&mdb,
 			mdbv1.MongoDBCommunityStatus{
 				MongoURI:                   mdb.MongoURI(),
@@ -56,6 +58,8 @@ func TestReplicaSetScaleUp(t *testing.T) {
 				CurrentMongoDBMembers:      5,
 				CurrentStatefulSetReplicas: 5,
 			}))
+		t.Run("Replica Set Has The Expected Members", tester.HasMemberCount(5, 3))
+		t.Run("Replica Set Has A Primary", tester.HasPrimary(3))
 
 		// TODO: Currently the scale down process takes too long to reasonably include this in the test
 		//t.Run("Scale MongoDB Resource Down", mongodbtests.Scale(&mdb, 3))
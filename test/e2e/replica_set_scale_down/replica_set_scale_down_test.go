@@ -51,7 +51,7 @@ func TestReplicaSetScaleDown(t *testing.T) {
 		t.Run("Scale MongoDB Resource Down", mongodbtests.Scale(&mdb, 1))
 		t.Run("Stateful Set Scaled Down Correctly", mongodbtests.StatefulSetIsReadyAfterScaleDown(&mdb))
 		t.Run("MongoDB Reaches Running Phase", mongodbtests.MongoDBReachesRunningPhase(&mdb))
-		t.Run("AutomationConfig's version has been increased", mongodbtests.AutomationConfigVersionHasTheExpectedVersion(&mdb, 3))
+		t.Run("AutomationConfig's version has been increased", mongodbtests.WaitForAutomationConfigVersion(&mdb, 3, time.Minute*2))
 		t.Run("Test Status Was Updated", mongodbtests.Status(&mdb,
 			mdbv1.MongoDBCommunityStatus{
 				MongoURI:                   mdb.MongoURI(),
@@ -46,7 +46,7 @@ func TestReplicaSetTLSRotate(t *testing.T) {
 	t.Run("Create MongoDB Resource", mongodbtests.CreateMongoDBResource(&mdb, ctx))
 	t.Run("Basic tests", mongodbtests.BasicFunctionality(&mdb))
 	t.Run("Wait for TLS to be enabled", tester.HasTlsMode("requireSSL", 60, WithTls()))
-	t.Run("Test Basic TLS Connectivity", tester.ConnectivitySucceeds(WithTls()))
+	t.Run("Test Basic TLS Connectivity", tester.ConnectivitySucceedsWithRetry(time.Second, 5*time.Minute, WithTls()))
 	t.Run("Ensure Authentication", tester.EnsureAuthenticationIsConfigured(3, WithTls()))
 	t.Run("Test TLS required", tester.ConnectivityFails(WithoutTls()))
 
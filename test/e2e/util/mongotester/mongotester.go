@@ -23,6 +23,7 @@ import (
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
 	e2eutil "github.com/mongodb/mongodb-kubernetes-operator/test/e2e"
+	e2ewait "github.com/mongodb/mongodb-kubernetes-operator/test/e2e/util/wait"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	corev1 "k8s.io/api/core/v1"
@@ -108,6 +109,15 @@ func (m *Tester) ConnectivitySucceeds(opts ...OptionApplier) func(t *testing.T)
 	return m.connectivityCheck(true, opts...)
 }
 
+// ConnectivitySucceedsWithRetry performs the same check as ConnectivitySucceeds, the check is
+// retried with an exponential backoff starting at the given interval until the timeout is reached.
+func (m *Tester) ConnectivitySucceedsWithRetry(interval, timeout time.Duration, opts ...OptionApplier) func(t *testing.T) {
+	connectivityOpts := defaults()
+	connectivityOpts.IntervalTime = interval
+	connectivityOpts.TimeoutTime = timeout
+	return m.connectivityCheckWithOpts(true, connectivityOpts, opts...)
+}
+
 // ConnectivityFails performs a basic check that ensures that it is not possible
 // to connect to the MongoDB resource
 func (m *Tester) ConnectivityFails(opts ...OptionApplier) func(t *testing.T) {
@@ -172,6 +182,77 @@ func (m *Tester) VerifyRoles(expectedRoles []automationconfig.CustomRole, tries
 	}, tries, opts...)
 }
 
+// ReplSetStatus is a type to decode the result of replSetGetStatus.
+type ReplSetStatus struct {
+	Set     string                `bson:"set"`
+	Members []ReplSetStatusMember `bson:"members"`
+}
+
+// ReplSetStatusMember is a single member reported by replSetGetStatus.
+type ReplSetStatusMember struct {
+	Name     string  `bson:"name"`
+	Health   float64 `bson:"health"`
+	StateStr string  `bson:"stateStr"`
+}
+
+// healthyMembers returns the number of members which are up and are either primary, secondary or arbiter.
+func (r ReplSetStatus) healthyMembers() int {
+	healthy := 0
+	for _, member := range r.Members {
+		if member.Health != 1 {
+			continue
+		}
+		switch member.StateStr {
+		case "PRIMARY", "SECONDARY", "ARBITER":
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// hasPrimary returns whether exactly one healthy member is the primary.
+func (r ReplSetStatus) hasPrimary() bool {
+	primaries := 0
+	for _, member := range r.Members {
+		if member.Health == 1 && member.StateStr == "PRIMARY" {
+			primaries++
+		}
+	}
+	return primaries == 1
+}
+
+// HasMemberCount ensures the replica set reports the expected number of members and
+// that all of them are healthy. It catches cases in which the StatefulSet is ready, but
+// the replica set has not been reconfigured.
+func (m *Tester) HasMemberCount(expectedMembers int, tries int, opts ...OptionApplier) func(t *testing.T) {
+	return m.hasReplSetStatus(func(t *testing.T, status ReplSetStatus) bool {
+		t.Logf("Replica set %s has %d member(s), %d of them healthy, expected %d", status.Set, len(status.Members), status.healthyMembers(), expectedMembers)
+		return len(status.Members) == expectedMembers && status.healthyMembers() == expectedMembers
+	}, tries, opts...)
+}
+
+// HasPrimary ensures the replica set reports a single healthy primary.
+func (m *Tester) HasPrimary(tries int, opts ...OptionApplier) func(t *testing.T) {
+	return m.hasReplSetStatus(func(t *testing.T, status ReplSetStatus) bool {
+		t.Logf("Replica set %s members: %+v", status.Set, status.Members)
+		return status.hasPrimary()
+	}, tries, opts...)
+}
+
+func (m *Tester) hasReplSetStatus(verify func(t *testing.T, status ReplSetStatus) bool, tries int, opts ...OptionApplier) func(t *testing.T) {
+	return m.hasAdminCommandResult(func(t *testing.T) bool {
+		var status ReplSetStatus
+		err := m.mongoClient.Database("admin").
+			RunCommand(context.TODO(), bson.D{{Key: "replSetGetStatus", Value: 1}}).
+			Decode(&status)
+		if err != nil {
+			t.Logf("Unable to get the replica set status: %s", err)
+			return false
+		}
+		return verify(t, status)
+	}, tries, opts...)
+}
+
 type verifyAdminResultFunc func(t *testing.T) bool
 
 func (m *Tester) hasAdminCommandResult(verify verifyAdminResultFunc, tries int, opts ...OptionApplier) func(t *testing.T) {
@@ -215,13 +296,16 @@ func (m *Tester) hasAdminParameter(key string, expectedValue interface{}, tries
 }
 
 func (m *Tester) connectivityCheck(shouldSucceed bool, opts ...OptionApplier) func(t *testing.T) {
+	return m.connectivityCheckWithOpts(shouldSucceed, defaults(), opts...)
+}
+
+func (m *Tester) connectivityCheckWithOpts(shouldSucceed bool, connectivityOpts connectivityOpts, opts ...OptionApplier) func(t *testing.T) {
 
 	clientOpts := make([]*options.ClientOptions, 0)
 	for _, optApplier := range opts {
 		clientOpts = optApplier.ApplyOption(clientOpts...)
 	}
 
-	connectivityOpts := defaults()
 	return func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), connectivityOpts.ContextTimeout)
 		defer cancel()
@@ -232,7 +316,7 @@ func (m *Tester) connectivityCheck(shouldSucceed bool, opts ...OptionApplier) fu
 
 		attempts := 0
 		// There can be a short time before the user can auth as the user
-		err := wait.Poll(connectivityOpts.IntervalTime, connectivityOpts.TimeoutTime, func() (done bool, err error) {
+		err := e2ewait.PollWithBackoff(connectivityOpts.IntervalTime, connectivityOpts.MaxIntervalTime, connectivityOpts.TimeoutTime, func() (done bool, err error) {
 			attempts++
 			collection := m.mongoClient.Database(connectivityOpts.Database).Collection(connectivityOpts.Collection)
 			_, err = collection.InsertOne(ctx, bson.M{"name": "pi", "value": 3.14159})
@@ -488,22 +572,23 @@ func getClientTLSConfig() (*tls.Config, error) {
 
 // defaults returns the default connectivity options
 // that our used in our tests.
-// TODO: allow these to be configurable
 func defaults() connectivityOpts {
 	return connectivityOpts{
-		IntervalTime:   1 * time.Second,
-		TimeoutTime:    30 * time.Second,
-		ContextTimeout: 10 * time.Minute,
-		Database:       "testing",
-		Collection:     "numbers",
+		IntervalTime:    1 * time.Second,
+		MaxIntervalTime: 10 * time.Second,
+		TimeoutTime:     2 * time.Minute,
+		ContextTimeout:  10 * time.Minute,
+		Database:        "testing",
+		Collection:      "numbers",
 	}
 }
 
 type connectivityOpts struct {
-	Retries        int
-	IntervalTime   time.Duration
-	TimeoutTime    time.Duration
-	ContextTimeout time.Duration
-	Database       string
-	Collection     string
+	Retries         int
+	IntervalTime    time.Duration
+	MaxIntervalTime time.Duration
+	TimeoutTime     time.Duration
+	ContextTimeout  time.Duration
+	Database        string
+	Collection      string
 }
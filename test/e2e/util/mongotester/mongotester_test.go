@@ -41,3 +41,30 @@ func TestWithScram_AddsScramOption(t *testing.T) {
 	assert.Equal(t, opts[0].Auth.Password, "password")
 	assert.Equal(t, opts[0].Auth.AuthSource, "admin")
 }
+
+func TestReplSetStatus_HealthyMembers(t *testing.T) {
+	status := ReplSetStatus{
+		Members: []ReplSetStatusMember{
+			{Name: "a", Health: 1, StateStr: "PRIMARY"},
+			{Name: "b", Health: 1, StateStr: "SECONDARY"},
+			{Name: "c", Health: 1, StateStr: "ARBITER"},
+			{Name: "d", Health: 1, StateStr: "STARTUP2"},
+			{Name: "e", Health: 0, StateStr: "(not reachable/healthy)"},
+		},
+	}
+	assert.Equal(t, 3, status.healthyMembers())
+	assert.True(t, status.hasPrimary())
+}
+
+func TestReplSetStatus_HasPrimary(t *testing.T) {
+	status := ReplSetStatus{
+		Members: []ReplSetStatusMember{
+			{Name: "a", Health: 1, StateStr: "SECONDARY"},
+			{Name: "b", Health: 0, StateStr: "PRIMARY"},
+		},
+	}
+	assert.False(t, status.hasPrimary())
+
+	status.Members[1].Health = 1
+	assert.True(t, status.hasPrimary())
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/statefulset"
 	e2eutil "github.com/mongodb/mongodb-kubernetes-operator/test/e2e"
 	"github.com/pkg/errors"
@@ -59,6 +60,28 @@ func waitForMongoDBCondition(mdb *mdbv1.MongoDBCommunity, retryInterval, timeout
 	})
 }
 
+// ForAutomationConfigVersion waits until the version of the automation config of the given MongoDB resource
+// reaches the expected version, using the provided retryInterval and timeout. The version of the automation
+// config which has been read last is returned.
+func ForAutomationConfigVersion(t *testing.T, mdb *mdbv1.MongoDBCommunity, expectedVersion int, retryInterval, timeout time.Duration) (int, error) {
+	version := 0
+	err := wait.Poll(retryInterval, timeout, func() (done bool, err error) {
+		s := corev1.Secret{}
+		err = e2eutil.TestClient.Get(context.TODO(), types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace}, &s)
+		if err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		ac, err := automationconfig.FromBytes(s.Data[automationconfig.ConfigKey])
+		if err != nil {
+			return false, err
+		}
+		version = ac.Version
+		t.Logf("current automation config version: %d, waiting for version: %d", version, expectedVersion)
+		return version >= expectedVersion, nil
+	})
+	return version, err
+}
+
 // ForStatefulSetToExist waits until a StatefulSet of the given name exists
 // using the provided retryInterval and timeout
 func ForStatefulSetToExist(stsName string, retryInterval, timeout time.Duration, namespace string) (appsv1.StatefulSet, error) {
@@ -137,6 +160,37 @@ func ForPodReadiness(t *testing.T, isReady bool, containerName string, timeout t
 	})
 }
 
+// PollWithBackoff checks the condition until it returns true or the timeout is reached.
+// The interval between attempts starts at initialInterval and is doubled after every
+// unsuccessful attempt, up to maxInterval.
+func PollWithBackoff(initialInterval, maxInterval, timeout time.Duration, condition wait.ConditionFunc) error {
+	deadline := time.Now().Add(timeout)
+	interval := initialInterval
+	for {
+		done, err := condition()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return wait.ErrWaitTimeout
+		}
+		if interval > remaining {
+			interval = remaining
+		}
+		time.Sleep(interval)
+
+		interval *= 2
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
 // waitForRuntimeObjectToExist waits until a runtime.Object of the given name exists
 // using the provided retryInterval and timeout provided.
 func waitForRuntimeObjectToExist(name string, retryInterval, timeout time.Duration, obj client.Object, namespace string) error {
@@ -0,0 +1,41 @@
+package wait
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestPollWithBackoff_SucceedsEventually(t *testing.T) {
+	attempts := 0
+	err := PollWithBackoff(time.Millisecond, 4*time.Millisecond, time.Second, func() (bool, error) {
+		attempts++
+		return attempts == 4, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, attempts)
+}
+
+func TestPollWithBackoff_TimesOut(t *testing.T) {
+	attempts := 0
+	start := time.Now()
+	err := PollWithBackoff(10*time.Millisecond, 20*time.Millisecond, 100*time.Millisecond, func() (bool, error) {
+		attempts++
+		return false, nil
+	})
+	assert.Equal(t, wait.ErrWaitTimeout, err)
+	assert.Less(t, time.Since(start), time.Second)
+	// the interval is doubled, but never exceeds the max interval
+	assert.GreaterOrEqual(t, attempts, 5)
+	assert.LessOrEqual(t, attempts, 8)
+}
+
+func TestPollWithBackoff_ReturnsConditionError(t *testing.T) {
+	err := PollWithBackoff(time.Millisecond, time.Millisecond, time.Second, func() (bool, error) {
+		return false, errors.New("condition failed")
+	})
+	assert.EqualError(t, err, "condition failed")
+}